@@ -31,6 +31,36 @@ func run() error {
 		return err
 	}
 
+	err = generateSchema(&source.SourceDaprComponent{}, "dapr-component")
+	if err != nil {
+		return err
+	}
+
+	err = generateSchema(&source.SourceCertificate{}, "certificate")
+	if err != nil {
+		return err
+	}
+
+	err = generateSchema(&source.SourceStorage{}, "storage")
+	if err != nil {
+		return err
+	}
+
+	err = generateSchema(&source.SourcePatch{}, "patch")
+	if err != nil {
+		return err
+	}
+
+	err = generateSchema(&source.SourceAppTemplate{}, "app-template")
+	if err != nil {
+		return err
+	}
+
+	err = generateSchema(&source.SourceAppInstance{}, "app-instance")
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -5,7 +5,9 @@ import (
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/appcontainers/armappcontainers/v2"
+	"github.com/xenitab/azcagit/src/history"
 	"github.com/xenitab/azcagit/src/notification"
+	"github.com/xenitab/azcagit/src/remote"
 )
 
 type CacheEntry struct {
@@ -32,18 +34,61 @@ type JobCache interface {
 	NeedsUpdate(ctx context.Context, name string, remoteJob, sourceJob *armappcontainers.Job) (bool, string, error)
 }
 
+type DaprComponentCache interface {
+	Set(ctx context.Context, name string, remoteComponent, sourceComponent *armappcontainers.DaprComponent) error
+	NeedsUpdate(ctx context.Context, name string, remoteComponent, sourceComponent *armappcontainers.DaprComponent) (bool, string, error)
+}
+
+type CertificateCache interface {
+	Set(ctx context.Context, name string, remoteCertificate, sourceCertificate *remote.CertificateResource) error
+	NeedsUpdate(ctx context.Context, name string, remoteCertificate, sourceCertificate *remote.CertificateResource) (bool, string, error)
+}
+
+type StorageCache interface {
+	Set(ctx context.Context, name string, remoteStorage, sourceStorage *armappcontainers.ManagedEnvironmentStorage) error
+	NeedsUpdate(ctx context.Context, name string, remoteStorage, sourceStorage *armappcontainers.ManagedEnvironmentStorage) (bool, string, error)
+}
+
 type SecretCacheEntry struct {
 	name     string
 	value    string
+	hasValue bool
 	modified time.Time
 }
 
+// NotificationCache stores the last event per notification name
 type NotificationCache interface {
 	Set(ctx context.Context, event notification.NotificationEvent) error
-	Get(ctx context.Context) (notification.NotificationEvent, bool, error)
+	Get(ctx context.Context, name string) (notification.NotificationEvent, bool, error)
 }
 
 type RevisionCache interface {
 	Set(ctx context.Context, revision string) error
 	Get(ctx context.Context) (string, error)
 }
+
+type ReconcileState struct {
+	Revision    string `json:"revision"`
+	SourcesHash string `json:"sources_hash"`
+	SecretsHash string `json:"secrets_hash"`
+	RemoteHash  string `json:"remote_hash"`
+}
+
+type ReconcileStateCache interface {
+	Set(ctx context.Context, state ReconcileState) error
+	Get(ctx context.Context) (ReconcileState, bool, error)
+}
+
+// SecretFingerprintCache stores the last change of every referenced secret, never the values
+type SecretFingerprintCache interface {
+	Set(ctx context.Context, fingerprints map[string]time.Time) error
+	Get(ctx context.Context) (map[string]time.Time, bool, error)
+}
+
+type HistoryCache interface {
+	// Add stores the run and sets its number, the oldest runs are removed when the history is full.
+	// Consecutive no-op runs of the same revision are collapsed into the first one.
+	Add(ctx context.Context, run *history.Run) error
+	// List returns the stored runs with the newest first, all runs are returned if limit is 0
+	List(ctx context.Context, limit int) ([]history.Run, error)
+}
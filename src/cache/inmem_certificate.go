@@ -0,0 +1,85 @@
+package cache
+
+import (
+	"context"
+	"crypto/md5"
+	"fmt"
+	"time"
+
+	"github.com/xenitab/azcagit/src/remote"
+)
+
+type InMemCertificateCache map[string]CacheEntry
+
+var _ CertificateCache = (*InMemCertificateCache)(nil)
+
+func NewInMemCertificateCache() *InMemCertificateCache {
+	c := make(InMemCertificateCache)
+	return &c
+}
+
+func (c *InMemCertificateCache) Set(ctx context.Context, name string, remoteCertificate, sourceCertificate *remote.CertificateResource) error {
+	if remoteCertificate == nil {
+		return nil
+	}
+	systemData := remoteCertificate.SystemData()
+	if systemData == nil {
+		return nil
+	}
+
+	timestamp := systemData.LastModifiedAt
+	if timestamp == nil {
+		if systemData.CreatedAt == nil {
+			return nil
+		}
+		timestamp = systemData.CreatedAt
+	}
+
+	b, err := sourceCertificate.MarshalJSON()
+	if err != nil {
+		return nil
+	}
+	hash := fmt.Sprintf("%x", md5.Sum(b))
+
+	(*c)[name] = newCacheEntry(name, *timestamp, hash)
+
+	return nil
+}
+
+func (c *InMemCertificateCache) NeedsUpdate(ctx context.Context, name string, remoteCertificate, sourceCertificate *remote.CertificateResource) (bool, string, error) {
+	entry, ok := (*c)[name]
+	if !ok {
+		return true, "not in CertificateCache", nil
+	}
+
+	if remoteCertificate == nil {
+		return true, "remoteCertificate nil", nil
+	}
+	systemData := remoteCertificate.SystemData()
+	if systemData == nil {
+		return true, "remoteCertificate SystemData nil", nil
+	}
+
+	if systemData.LastModifiedAt != nil {
+		if entry.Modified.Round(time.Millisecond) != (*systemData.LastModifiedAt).Round(time.Millisecond) {
+			return true, "changed LastModifiedAt", nil
+		}
+	} else if systemData.CreatedAt != nil {
+		if entry.Modified.Round(time.Millisecond) != (*systemData.CreatedAt).Round(time.Millisecond) {
+			return true, "changed CreatedAt", nil
+		}
+	}
+
+	b, err := sourceCertificate.MarshalJSON()
+	if err != nil {
+		return true, "sourceCertificate MarshalJSON() failed", nil
+	}
+
+	hash := fmt.Sprintf("%x", md5.Sum(b))
+
+	if entry.Hash != hash {
+		return true, "changed sourceCertificate hash", nil
+	}
+
+	return false, "no changes", nil
+}
@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"context"
+	"crypto/md5"
+	"fmt"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/appcontainers/armappcontainers/v2"
+)
+
+type InMemDaprComponentCache map[string]CacheEntry
+
+var _ DaprComponentCache = (*InMemDaprComponentCache)(nil)
+
+func NewInMemDaprComponentCache() *InMemDaprComponentCache {
+	c := make(InMemDaprComponentCache)
+	return &c
+}
+
+func (c *InMemDaprComponentCache) Set(ctx context.Context, name string, remoteComponent, sourceComponent *armappcontainers.DaprComponent) error {
+	if remoteComponent == nil {
+		return nil
+	}
+	if remoteComponent.SystemData == nil {
+		return nil
+	}
+
+	timestamp := remoteComponent.SystemData.LastModifiedAt
+	if timestamp == nil {
+		if remoteComponent.SystemData.CreatedAt == nil {
+			return nil
+		}
+		timestamp = remoteComponent.SystemData.CreatedAt
+	}
+
+	b, err := sourceComponent.MarshalJSON()
+	if err != nil {
+		return nil
+	}
+	hash := fmt.Sprintf("%x", md5.Sum(b))
+
+	(*c)[name] = newCacheEntry(name, *timestamp, hash)
+
+	return nil
+}
+
+func (c *InMemDaprComponentCache) NeedsUpdate(ctx context.Context, name string, remoteComponent, sourceComponent *armappcontainers.DaprComponent) (bool, string, error) {
+	entry, ok := (*c)[name]
+	if !ok {
+		return true, "not in DaprComponentCache", nil
+	}
+
+	if remoteComponent == nil {
+		return true, "remoteComponent nil", nil
+	}
+	if remoteComponent.SystemData == nil {
+		return true, "remoteComponent SystemData nil", nil
+	}
+
+	if remoteComponent.SystemData.LastModifiedAt != nil {
+		if entry.Modified.Round(time.Millisecond) != (*remoteComponent.SystemData.LastModifiedAt).Round(time.Millisecond) {
+			return true, "changed LastModifiedAt", nil
+		}
+	} else if remoteComponent.SystemData.CreatedAt != nil {
+		if entry.Modified.Round(time.Millisecond) != (*remoteComponent.SystemData.CreatedAt).Round(time.Millisecond) {
+			return true, "changed CreatedAt", nil
+		}
+	}
+
+	b, err := sourceComponent.MarshalJSON()
+	if err != nil {
+		return true, "sourceComponent MarshalJSON() failed", nil
+	}
+
+	hash := fmt.Sprintf("%x", md5.Sum(b))
+
+	if entry.Hash != hash {
+		return true, "changed sourceComponent hash", nil
+	}
+
+	return false, "no changes", nil
+}
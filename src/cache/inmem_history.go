@@ -0,0 +1,44 @@
+package cache
+
+import (
+	"context"
+
+	"github.com/xenitab/azcagit/src/history"
+)
+
+type InMemHistoryCache struct {
+	runs []history.Run
+}
+
+var _ HistoryCache = (*InMemHistoryCache)(nil)
+
+func NewInMemHistoryCache() *InMemHistoryCache {
+	return &InMemHistoryCache{}
+}
+
+func (c *InMemHistoryCache) Add(ctx context.Context, run *history.Run) error {
+	if len(c.runs) > 0 && c.runs[len(c.runs)-1].Collapse(*run) {
+		run.Number = len(c.runs)
+		return nil
+	}
+
+	run.Number = len(c.runs) + 1
+	c.runs = append(c.runs, *run)
+	return nil
+}
+
+func (c *InMemHistoryCache) List(ctx context.Context, limit int) ([]history.Run, error) {
+	runs := []history.Run{}
+	for i := len(c.runs) - 1; i >= 0; i-- {
+		if limit > 0 && len(runs) >= limit {
+			break
+		}
+		runs = append(runs, c.runs[i])
+	}
+
+	return runs, nil
+}
+
+func (c *InMemHistoryCache) Reset() {
+	c.runs = nil
+}
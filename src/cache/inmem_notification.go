@@ -7,28 +7,31 @@ import (
 )
 
 type InMemNotificationCache struct {
-	event *notification.NotificationEvent
+	events map[string]notification.NotificationEvent
 }
 
 var _ NotificationCache = (*InMemNotificationCache)(nil)
 
 func NewInMemNotificationCache() *InMemNotificationCache {
-	return &InMemNotificationCache{}
+	return &InMemNotificationCache{
+		events: make(map[string]notification.NotificationEvent),
+	}
 }
 
 func (c *InMemNotificationCache) Set(ctx context.Context, event notification.NotificationEvent) error {
-	c.event = &event
+	c.events[event.Name] = event
 	return nil
 }
 
-func (c *InMemNotificationCache) Get(ctx context.Context) (notification.NotificationEvent, bool, error) {
-	if c.event == nil {
+func (c *InMemNotificationCache) Get(ctx context.Context, name string) (notification.NotificationEvent, bool, error) {
+	event, ok := c.events[name]
+	if !ok {
 		return notification.NotificationEvent{}, false, nil
 	}
 
-	return *c.event, true, nil
+	return event, true, nil
 }
 
 func (c *InMemNotificationCache) Reset() {
-	c.event = nil
+	c.events = make(map[string]notification.NotificationEvent)
 }
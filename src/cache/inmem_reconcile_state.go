@@ -0,0 +1,32 @@
+package cache
+
+import (
+	"context"
+)
+
+type InMemReconcileStateCache struct {
+	state *ReconcileState
+}
+
+var _ ReconcileStateCache = (*InMemReconcileStateCache)(nil)
+
+func NewInMemReconcileStateCache() *InMemReconcileStateCache {
+	return &InMemReconcileStateCache{}
+}
+
+func (c *InMemReconcileStateCache) Set(ctx context.Context, state ReconcileState) error {
+	c.state = &state
+	return nil
+}
+
+func (c *InMemReconcileStateCache) Get(ctx context.Context) (ReconcileState, bool, error) {
+	if c.state == nil {
+		return ReconcileState{}, false, nil
+	}
+
+	return *c.state, true, nil
+}
+
+func (c *InMemReconcileStateCache) Reset() {
+	c.state = nil
+}
@@ -13,11 +13,40 @@ func (c *InMemSecretCache) Set(name string, value string, modified time.Time) {
 	(*c)[name] = SecretCacheEntry{
 		name,
 		value,
+		true,
 		modified,
 	}
 }
 
+// SetFingerprint caches the last change of a secret without the value, the value is only read when needed
+func (c *InMemSecretCache) SetFingerprint(name string, modified time.Time) {
+	entry, ok := (*c)[name]
+	if ok && entry.modified.Equal(modified) {
+		return
+	}
+
+	(*c)[name] = SecretCacheEntry{
+		name:     name,
+		modified: modified,
+	}
+}
+
 func (c *InMemSecretCache) Get(name string) (string, bool) {
 	entry, ok := (*c)[name]
-	return entry.value, ok
+	if !ok || !entry.hasValue {
+		return "", false
+	}
+
+	return entry.value, true
+}
+
+func (c *InMemSecretCache) GetFingerprint(name string) (time.Time, bool) {
+	entry, ok := (*c)[name]
+	return entry.modified, ok
+}
+
+func (c *InMemSecretCache) Reset() {
+	for name := range *c {
+		delete(*c, name)
+	}
 }
@@ -0,0 +1,33 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+type InMemSecretFingerprintCache struct {
+	fingerprints map[string]time.Time
+}
+
+var _ SecretFingerprintCache = (*InMemSecretFingerprintCache)(nil)
+
+func NewInMemSecretFingerprintCache() *InMemSecretFingerprintCache {
+	return &InMemSecretFingerprintCache{}
+}
+
+func (c *InMemSecretFingerprintCache) Set(ctx context.Context, fingerprints map[string]time.Time) error {
+	c.fingerprints = fingerprints
+	return nil
+}
+
+func (c *InMemSecretFingerprintCache) Get(ctx context.Context) (map[string]time.Time, bool, error) {
+	if c.fingerprints == nil {
+		return nil, false, nil
+	}
+
+	return c.fingerprints, true, nil
+}
+
+func (c *InMemSecretFingerprintCache) Reset() {
+	c.fingerprints = nil
+}
@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"context"
+	"crypto/md5"
+	"fmt"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/appcontainers/armappcontainers/v2"
+)
+
+type InMemStorageCache map[string]CacheEntry
+
+var _ StorageCache = (*InMemStorageCache)(nil)
+
+func NewInMemStorageCache() *InMemStorageCache {
+	c := make(InMemStorageCache)
+	return &c
+}
+
+func (c *InMemStorageCache) Set(ctx context.Context, name string, remoteStorage, sourceStorage *armappcontainers.ManagedEnvironmentStorage) error {
+	if remoteStorage == nil {
+		return nil
+	}
+	if remoteStorage.SystemData == nil {
+		return nil
+	}
+
+	timestamp := remoteStorage.SystemData.LastModifiedAt
+	if timestamp == nil {
+		if remoteStorage.SystemData.CreatedAt == nil {
+			return nil
+		}
+		timestamp = remoteStorage.SystemData.CreatedAt
+	}
+
+	b, err := sourceStorage.MarshalJSON()
+	if err != nil {
+		return nil
+	}
+	hash := fmt.Sprintf("%x", md5.Sum(b))
+
+	(*c)[name] = newCacheEntry(name, *timestamp, hash)
+
+	return nil
+}
+
+func (c *InMemStorageCache) NeedsUpdate(ctx context.Context, name string, remoteStorage, sourceStorage *armappcontainers.ManagedEnvironmentStorage) (bool, string, error) {
+	entry, ok := (*c)[name]
+	if !ok {
+		return true, "not in StorageCache", nil
+	}
+
+	if remoteStorage == nil {
+		return true, "remoteStorage nil", nil
+	}
+	if remoteStorage.SystemData == nil {
+		return true, "remoteStorage SystemData nil", nil
+	}
+
+	if remoteStorage.SystemData.LastModifiedAt != nil {
+		if entry.Modified.Round(time.Millisecond) != (*remoteStorage.SystemData.LastModifiedAt).Round(time.Millisecond) {
+			return true, "changed LastModifiedAt", nil
+		}
+	} else if remoteStorage.SystemData.CreatedAt != nil {
+		if entry.Modified.Round(time.Millisecond) != (*remoteStorage.SystemData.CreatedAt).Round(time.Millisecond) {
+			return true, "changed CreatedAt", nil
+		}
+	}
+
+	b, err := sourceStorage.MarshalJSON()
+	if err != nil {
+		return true, "sourceStorage MarshalJSON() failed", nil
+	}
+
+	hash := fmt.Sprintf("%x", md5.Sum(b))
+
+	if entry.Hash != hash {
+		return true, "changed sourceStorage hash", nil
+	}
+
+	return false, "no changes", nil
+}
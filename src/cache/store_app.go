@@ -7,29 +7,29 @@ import (
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/appcontainers/armappcontainers/v2"
-	"github.com/xenitab/azcagit/src/azure"
 	"github.com/xenitab/azcagit/src/config"
+	"github.com/xenitab/azcagit/src/store"
 )
 
-type CosmosDBAppCache struct {
-	client *azure.CosmosDBContainerClient[CacheEntry]
+type StoreAppCache struct {
+	client *store.Client[CacheEntry]
 }
 
-var _ AppCache = (*CosmosDBAppCache)(nil)
+var _ AppCache = (*StoreAppCache)(nil)
 
-func NewCosmosDBAppCache(cfg config.ReconcileConfig, cosmosDBClient *azure.CosmosDBClient) (*CosmosDBAppCache, error) {
+func NewStoreAppCache(cfg config.ReconcileConfig, cacheStore store.Store) (*StoreAppCache, error) {
 	ttl := 3600
-	client, err := azure.NewCosmosDBContainerClient[CacheEntry](cosmosDBClient, "app-cache", &ttl)
+	client, err := store.NewClient[CacheEntry](cacheStore, "app-cache", &ttl)
 	if err != nil {
 		return nil, err
 	}
 
-	return &CosmosDBAppCache{
+	return &StoreAppCache{
 		client,
 	}, nil
 }
 
-func (c *CosmosDBAppCache) Set(ctx context.Context, name string, remoteApp, sourceApp *armappcontainers.ContainerApp) error {
+func (c *StoreAppCache) Set(ctx context.Context, name string, remoteApp, sourceApp *armappcontainers.ContainerApp) error {
 	if remoteApp == nil {
 		return nil
 	}
@@ -55,10 +55,10 @@ func (c *CosmosDBAppCache) Set(ctx context.Context, name string, remoteApp, sour
 	return c.client.Set(ctx, name, cacheEntry)
 }
 
-func (c *CosmosDBAppCache) NeedsUpdate(ctx context.Context, name string, remoteApp, sourceApp *armappcontainers.ContainerApp) (bool, string, error) {
+func (c *StoreAppCache) NeedsUpdate(ctx context.Context, name string, remoteApp, sourceApp *armappcontainers.ContainerApp) (bool, string, error) {
 	entry, err := c.client.Get(ctx, name)
 	if err != nil {
-		return false, "CosmosDB client returned an error", err
+		return false, "cache store returned an error", err
 	}
 
 	if entry == nil {
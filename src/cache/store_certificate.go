@@ -0,0 +1,99 @@
+package cache
+
+import (
+	"context"
+	"crypto/md5"
+	"fmt"
+	"time"
+
+	"github.com/xenitab/azcagit/src/config"
+	"github.com/xenitab/azcagit/src/remote"
+	"github.com/xenitab/azcagit/src/store"
+)
+
+type StoreCertificateCache struct {
+	client *store.Client[CacheEntry]
+}
+
+var _ CertificateCache = (*StoreCertificateCache)(nil)
+
+func NewStoreCertificateCache(cfg config.ReconcileConfig, cacheStore store.Store) (*StoreCertificateCache, error) {
+	ttl := 3600
+	client, err := store.NewClient[CacheEntry](cacheStore, "certificate-cache", &ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	return &StoreCertificateCache{
+		client,
+	}, nil
+}
+
+func (c *StoreCertificateCache) Set(ctx context.Context, name string, remoteCertificate, sourceCertificate *remote.CertificateResource) error {
+	if remoteCertificate == nil {
+		return nil
+	}
+	systemData := remoteCertificate.SystemData()
+	if systemData == nil {
+		return nil
+	}
+
+	timestamp := systemData.LastModifiedAt
+	if timestamp == nil {
+		if systemData.CreatedAt == nil {
+			return nil
+		}
+		timestamp = systemData.CreatedAt
+	}
+
+	b, err := sourceCertificate.MarshalJSON()
+	if err != nil {
+		return nil
+	}
+
+	hash := fmt.Sprintf("%x", md5.Sum(b))
+	cacheEntry := newCacheEntry(name, *timestamp, hash)
+	return c.client.Set(ctx, name, cacheEntry)
+}
+
+func (c *StoreCertificateCache) NeedsUpdate(ctx context.Context, name string, remoteCertificate, sourceCertificate *remote.CertificateResource) (bool, string, error) {
+	entry, err := c.client.Get(ctx, name)
+	if err != nil {
+		return false, "cache store returned an error", err
+	}
+
+	if entry == nil {
+		return true, "not in CertificateCache", nil
+	}
+
+	if remoteCertificate == nil {
+		return true, "remoteCertificate nil", nil
+	}
+	systemData := remoteCertificate.SystemData()
+	if systemData == nil {
+		return true, "remoteCertificate SystemData nil", nil
+	}
+
+	if systemData.LastModifiedAt != nil {
+		if (entry.Modified).Round(time.Millisecond) != (*systemData.LastModifiedAt).Round(time.Millisecond) {
+			return true, "changed LastModifiedAt", nil
+		}
+	} else if systemData.CreatedAt != nil {
+		if entry.Modified.Round(time.Millisecond) != (*systemData.CreatedAt).Round(time.Millisecond) {
+			return true, "changed CreatedAt", nil
+		}
+	}
+
+	b, err := sourceCertificate.MarshalJSON()
+	if err != nil {
+		return true, "remoteCertificate MarshalJSON() failed", nil
+	}
+
+	hash := fmt.Sprintf("%x", md5.Sum(b))
+
+	if entry.Hash != hash {
+		return true, "changed remoteCertificate hash", nil
+	}
+
+	return false, "no changes", nil
+}
@@ -0,0 +1,97 @@
+package cache
+
+import (
+	"context"
+	"crypto/md5"
+	"fmt"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/appcontainers/armappcontainers/v2"
+	"github.com/xenitab/azcagit/src/config"
+	"github.com/xenitab/azcagit/src/store"
+)
+
+type StoreDaprComponentCache struct {
+	client *store.Client[CacheEntry]
+}
+
+var _ DaprComponentCache = (*StoreDaprComponentCache)(nil)
+
+func NewStoreDaprComponentCache(cfg config.ReconcileConfig, cacheStore store.Store) (*StoreDaprComponentCache, error) {
+	ttl := 3600
+	client, err := store.NewClient[CacheEntry](cacheStore, "dapr-component-cache", &ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	return &StoreDaprComponentCache{
+		client,
+	}, nil
+}
+
+func (c *StoreDaprComponentCache) Set(ctx context.Context, name string, remoteComponent, sourceComponent *armappcontainers.DaprComponent) error {
+	if remoteComponent == nil {
+		return nil
+	}
+	if remoteComponent.SystemData == nil {
+		return nil
+	}
+
+	timestamp := remoteComponent.SystemData.LastModifiedAt
+	if timestamp == nil {
+		if remoteComponent.SystemData.CreatedAt == nil {
+			return nil
+		}
+		timestamp = remoteComponent.SystemData.CreatedAt
+	}
+
+	b, err := sourceComponent.MarshalJSON()
+	if err != nil {
+		return nil
+	}
+
+	hash := fmt.Sprintf("%x", md5.Sum(b))
+	cacheEntry := newCacheEntry(name, *timestamp, hash)
+	return c.client.Set(ctx, name, cacheEntry)
+}
+
+func (c *StoreDaprComponentCache) NeedsUpdate(ctx context.Context, name string, remoteComponent, sourceComponent *armappcontainers.DaprComponent) (bool, string, error) {
+	entry, err := c.client.Get(ctx, name)
+	if err != nil {
+		return false, "cache store returned an error", err
+	}
+
+	if entry == nil {
+		return true, "not in DaprComponentCache", nil
+	}
+
+	if remoteComponent == nil {
+		return true, "remoteComponent nil", nil
+	}
+	if remoteComponent.SystemData == nil {
+		return true, "remoteComponent SystemData nil", nil
+	}
+
+	if remoteComponent.SystemData.LastModifiedAt != nil {
+		if (entry.Modified).Round(time.Millisecond) != (*remoteComponent.SystemData.LastModifiedAt).Round(time.Millisecond) {
+			return true, "changed LastModifiedAt", nil
+		}
+	} else if remoteComponent.SystemData.CreatedAt != nil {
+		if entry.Modified.Round(time.Millisecond) != (*remoteComponent.SystemData.CreatedAt).Round(time.Millisecond) {
+			return true, "changed CreatedAt", nil
+		}
+	}
+
+	b, err := sourceComponent.MarshalJSON()
+	if err != nil {
+		return true, "remoteComponent MarshalJSON() failed", nil
+	}
+
+	hash := fmt.Sprintf("%x", md5.Sum(b))
+
+	if entry.Hash != hash {
+		return true, "changed remoteComponent hash", nil
+	}
+
+	return false, "no changes", nil
+}
@@ -0,0 +1,130 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/xenitab/azcagit/src/history"
+	"github.com/xenitab/azcagit/src/store"
+)
+
+// StoreHistoryCache keeps the last runs in a ring of size keys, the index key contains the number of the last run
+type StoreHistoryCache struct {
+	indexClient *store.Client[historyIndex]
+	runClient   *store.Client[history.Run]
+	size        int
+}
+
+var _ HistoryCache = (*StoreHistoryCache)(nil)
+
+type historyIndex struct {
+	LastRun int `json:"last_run"`
+	Size    int `json:"size"`
+}
+
+const historyIndexKey = "index"
+
+func NewStoreHistoryCache(cacheStore store.Store, size int) (*StoreHistoryCache, error) {
+	ttl := -1 // -1 disables time to live
+	indexClient, err := store.NewClient[historyIndex](cacheStore, "history", &ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	runClient, err := store.NewClient[history.Run](cacheStore, "history", &ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	return &StoreHistoryCache{
+		indexClient,
+		runClient,
+		size,
+	}, nil
+}
+
+func getHistoryRunKey(number int, size int) string {
+	return fmt.Sprintf("run-%d", number%size)
+}
+
+func (c *StoreHistoryCache) Add(ctx context.Context, run *history.Run) error {
+	if c.size <= 0 {
+		return nil
+	}
+
+	index, err := c.indexClient.Get(ctx, historyIndexKey)
+	if err != nil {
+		return err
+	}
+
+	if index == nil {
+		index = &historyIndex{}
+	}
+
+	collapsed, err := c.collapse(ctx, index, run)
+	if err != nil || collapsed {
+		return err
+	}
+
+	run.Number = index.LastRun + 1
+	err = c.runClient.Set(ctx, getHistoryRunKey(run.Number, c.size), *run)
+	if err != nil {
+		return err
+	}
+
+	return c.indexClient.Set(ctx, historyIndexKey, historyIndex{
+		LastRun: run.Number,
+		Size:    c.size,
+	})
+}
+
+// collapse adds the run to the last run when both are no-op runs of the same revision
+func (c *StoreHistoryCache) collapse(ctx context.Context, index *historyIndex, run *history.Run) (bool, error) {
+	if !run.NoOp || index.LastRun == 0 || index.Size != c.size {
+		return false, nil
+	}
+
+	lastRun, err := c.runClient.Get(ctx, getHistoryRunKey(index.LastRun, c.size))
+	if err != nil {
+		return false, err
+	}
+
+	if lastRun == nil || lastRun.Number != index.LastRun || !lastRun.Collapse(*run) {
+		return false, nil
+	}
+
+	run.Number = lastRun.Number
+	return true, c.runClient.Set(ctx, getHistoryRunKey(lastRun.Number, c.size), *lastRun)
+}
+
+func (c *StoreHistoryCache) List(ctx context.Context, limit int) ([]history.Run, error) {
+	runs := []history.Run{}
+	index, err := c.indexClient.Get(ctx, historyIndexKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if index == nil || index.Size <= 0 {
+		return runs, nil
+	}
+
+	for number := index.LastRun; number > 0 && number > index.LastRun-index.Size; number-- {
+		if limit > 0 && len(runs) >= limit {
+			break
+		}
+
+		run, err := c.runClient.Get(ctx, getHistoryRunKey(number, index.Size))
+		if err != nil {
+			return nil, err
+		}
+
+		// the run may have been overwritten if the size has changed
+		if run == nil || run.Number != number {
+			continue
+		}
+
+		runs = append(runs, *run)
+	}
+
+	return runs, nil
+}
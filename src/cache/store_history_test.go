@@ -0,0 +1,78 @@
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xenitab/azcagit/src/history"
+	"github.com/xenitab/azcagit/src/store"
+)
+
+func TestStoreHistoryCache(t *testing.T) {
+	ctx := context.Background()
+	cacheStore := store.NewFileStore(t.TempDir())
+
+	historyCache, err := NewStoreHistoryCache(cacheStore, 3)
+	require.NoError(t, err)
+
+	runs, err := historyCache.List(ctx, 0)
+	require.NoError(t, err)
+	require.Empty(t, runs)
+
+	for _, revision := range []string{"a", "b", "c", "d"} {
+		err := historyCache.Add(ctx, &history.Run{Revision: revision})
+		require.NoError(t, err)
+	}
+
+	getRevisions := func(runs []history.Run) []string {
+		revisions := []string{}
+		for _, run := range runs {
+			revisions = append(revisions, run.Revision)
+		}
+		return revisions
+	}
+
+	runs, err = historyCache.List(ctx, 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"d", "c", "b"}, getRevisions(runs))
+	require.Equal(t, 4, runs[0].Number)
+
+	runs, err = historyCache.List(ctx, 2)
+	require.NoError(t, err)
+	require.Equal(t, []string{"d", "c"}, getRevisions(runs))
+
+	// a reader doesn't need to know the size
+	reader, err := NewStoreHistoryCache(cacheStore, 0)
+	require.NoError(t, err)
+	runs, err = reader.List(ctx, 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"d", "c", "b"}, getRevisions(runs))
+
+	err = reader.Add(ctx, &history.Run{Revision: "ignored"})
+	require.NoError(t, err)
+
+	// after the size has changed, runs stored at another key are lost and overwritten runs are skipped
+	larger, err := NewStoreHistoryCache(cacheStore, 5)
+	require.NoError(t, err)
+	err = larger.Add(ctx, &history.Run{Revision: "e"})
+	require.NoError(t, err)
+	runs, err = larger.List(ctx, 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"e", "b"}, getRevisions(runs))
+
+	// consecutive no-op runs of the same revision are collapsed
+	for i := 0; i < 3; i++ {
+		run := &history.Run{Revision: "e", NoOp: true}
+		err := larger.Add(ctx, run)
+		require.NoError(t, err)
+		require.Equal(t, 6, run.Number)
+	}
+	err = larger.Add(ctx, &history.Run{Revision: "f", NoOp: true})
+	require.NoError(t, err)
+	runs, err = larger.List(ctx, 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"f", "e", "e"}, getRevisions(runs))
+	require.Equal(t, 3, runs[1].NoOpRuns)
+	require.Equal(t, 0, runs[0].NoOpRuns)
+}
@@ -7,29 +7,29 @@ import (
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/appcontainers/armappcontainers/v2"
-	"github.com/xenitab/azcagit/src/azure"
 	"github.com/xenitab/azcagit/src/config"
+	"github.com/xenitab/azcagit/src/store"
 )
 
-type CosmosDBJobCache struct {
-	client *azure.CosmosDBContainerClient[CacheEntry]
+type StoreJobCache struct {
+	client *store.Client[CacheEntry]
 }
 
-var _ JobCache = (*CosmosDBJobCache)(nil)
+var _ JobCache = (*StoreJobCache)(nil)
 
-func NewCosmosDBJobCache(cfg config.ReconcileConfig, cosmosDBClient *azure.CosmosDBClient) (*CosmosDBJobCache, error) {
+func NewStoreJobCache(cfg config.ReconcileConfig, cacheStore store.Store) (*StoreJobCache, error) {
 	ttl := 3600
-	client, err := azure.NewCosmosDBContainerClient[CacheEntry](cosmosDBClient, "job-cache", &ttl)
+	client, err := store.NewClient[CacheEntry](cacheStore, "job-cache", &ttl)
 	if err != nil {
 		return nil, err
 	}
 
-	return &CosmosDBJobCache{
+	return &StoreJobCache{
 		client,
 	}, nil
 }
 
-func (c *CosmosDBJobCache) Set(ctx context.Context, name string, remoteJob, sourceJob *armappcontainers.Job) error {
+func (c *StoreJobCache) Set(ctx context.Context, name string, remoteJob, sourceJob *armappcontainers.Job) error {
 	if remoteJob == nil {
 		return nil
 	}
@@ -55,10 +55,10 @@ func (c *CosmosDBJobCache) Set(ctx context.Context, name string, remoteJob, sour
 	return c.client.Set(ctx, name, cacheEntry)
 }
 
-func (c *CosmosDBJobCache) NeedsUpdate(ctx context.Context, name string, remoteJob, sourceJob *armappcontainers.Job) (bool, string, error) {
+func (c *StoreJobCache) NeedsUpdate(ctx context.Context, name string, remoteJob, sourceJob *armappcontainers.Job) (bool, string, error) {
 	entry, err := c.client.Get(ctx, name)
 	if err != nil {
-		return false, "CosmosDB client returned an error", err
+		return false, "cache store returned an error", err
 	}
 
 	if entry == nil {
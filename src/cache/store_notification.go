@@ -0,0 +1,53 @@
+package cache
+
+import (
+	"context"
+	"crypto/md5"
+	"fmt"
+
+	"github.com/xenitab/azcagit/src/config"
+	"github.com/xenitab/azcagit/src/notification"
+	"github.com/xenitab/azcagit/src/store"
+)
+
+type StoreNotificationCache struct {
+	client *store.Client[notification.NotificationEvent]
+}
+
+var _ NotificationCache = (*StoreNotificationCache)(nil)
+
+const notificationCacheKey = "previous_notification"
+
+func NewStoreNotificationCache(cfg config.ReconcileConfig, cacheStore store.Store) (*StoreNotificationCache, error) {
+	ttl := -1 // -1 disables time to live
+	client, err := store.NewClient[notification.NotificationEvent](cacheStore, "notification-cache", &ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	return &StoreNotificationCache{
+		client,
+	}, nil
+}
+
+// getNotificationCacheKey hashes the name, as it contains slashes which some stores don't allow in keys
+func getNotificationCacheKey(name string) string {
+	return fmt.Sprintf("%s-%x", notificationCacheKey, md5.Sum([]byte(name)))
+}
+
+func (c *StoreNotificationCache) Set(ctx context.Context, event notification.NotificationEvent) error {
+	return c.client.Set(ctx, getNotificationCacheKey(event.Name), event)
+}
+
+func (c *StoreNotificationCache) Get(ctx context.Context, name string) (notification.NotificationEvent, bool, error) {
+	value, err := c.client.Get(ctx, getNotificationCacheKey(name))
+	if err != nil {
+		return notification.NotificationEvent{}, false, err
+	}
+
+	if value == nil {
+		return notification.NotificationEvent{}, false, nil
+	}
+
+	return *value, true, nil
+}
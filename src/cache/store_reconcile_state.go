@@ -0,0 +1,46 @@
+package cache
+
+import (
+	"context"
+
+	"github.com/xenitab/azcagit/src/config"
+	"github.com/xenitab/azcagit/src/store"
+)
+
+type StoreReconcileStateCache struct {
+	client *store.Client[ReconcileState]
+}
+
+var _ ReconcileStateCache = (*StoreReconcileStateCache)(nil)
+
+const reconcileStateCacheKey = "reconcile_state"
+
+func NewStoreReconcileStateCache(cfg config.ReconcileConfig, cacheStore store.Store) (*StoreReconcileStateCache, error) {
+	// same ttl as the app and job cache, making sure a full reconcile is done at least once an hour
+	ttl := 3600
+	client, err := store.NewClient[ReconcileState](cacheStore, "reconcile-state-cache", &ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	return &StoreReconcileStateCache{
+		client,
+	}, nil
+}
+
+func (c *StoreReconcileStateCache) Set(ctx context.Context, state ReconcileState) error {
+	return c.client.Set(ctx, reconcileStateCacheKey, state)
+}
+
+func (c *StoreReconcileStateCache) Get(ctx context.Context) (ReconcileState, bool, error) {
+	value, err := c.client.Get(ctx, reconcileStateCacheKey)
+	if err != nil {
+		return ReconcileState{}, false, err
+	}
+
+	if value == nil {
+		return ReconcileState{}, false, nil
+	}
+
+	return *value, true, nil
+}
@@ -0,0 +1,45 @@
+package cache
+
+import (
+	"context"
+
+	"github.com/xenitab/azcagit/src/config"
+	"github.com/xenitab/azcagit/src/store"
+)
+
+type StoreRevisionCache struct {
+	client *store.Client[string]
+}
+
+var _ RevisionCache = (*StoreRevisionCache)(nil)
+
+const revisionCacheKey = "revision"
+
+func NewStoreRevisionCache(cfg config.ReconcileConfig, cacheStore store.Store) (*StoreRevisionCache, error) {
+	ttl := -1 // -1 disables time to live
+	client, err := store.NewClient[string](cacheStore, "revision-cache", &ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	return &StoreRevisionCache{
+		client,
+	}, nil
+}
+
+func (c *StoreRevisionCache) Set(ctx context.Context, revision string) error {
+	return c.client.Set(ctx, revisionCacheKey, revision)
+}
+
+func (c *StoreRevisionCache) Get(ctx context.Context) (string, error) {
+	value, err := c.client.Get(ctx, revisionCacheKey)
+	if err != nil {
+		return "", err
+	}
+
+	if value == nil {
+		return "", nil
+	}
+
+	return *value, nil
+}
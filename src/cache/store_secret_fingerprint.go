@@ -0,0 +1,47 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/xenitab/azcagit/src/config"
+	"github.com/xenitab/azcagit/src/store"
+)
+
+type StoreSecretFingerprintCache struct {
+	client *store.Client[map[string]time.Time]
+}
+
+var _ SecretFingerprintCache = (*StoreSecretFingerprintCache)(nil)
+
+const secretFingerprintCacheKey = "secret_fingerprints"
+
+func NewStoreSecretFingerprintCache(cfg config.ReconcileConfig, cacheStore store.Store) (*StoreSecretFingerprintCache, error) {
+	// the fingerprints don't expire with the reconcile state, otherwise rotations after the state expired would be missed
+	ttl := -1 // -1 disables time to live
+	client, err := store.NewClient[map[string]time.Time](cacheStore, "secret-fingerprint-cache", &ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	return &StoreSecretFingerprintCache{
+		client,
+	}, nil
+}
+
+func (c *StoreSecretFingerprintCache) Set(ctx context.Context, fingerprints map[string]time.Time) error {
+	return c.client.Set(ctx, secretFingerprintCacheKey, fingerprints)
+}
+
+func (c *StoreSecretFingerprintCache) Get(ctx context.Context) (map[string]time.Time, bool, error) {
+	value, err := c.client.Get(ctx, secretFingerprintCacheKey)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if value == nil {
+		return nil, false, nil
+	}
+
+	return *value, true, nil
+}
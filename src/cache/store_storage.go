@@ -0,0 +1,97 @@
+package cache
+
+import (
+	"context"
+	"crypto/md5"
+	"fmt"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/appcontainers/armappcontainers/v2"
+	"github.com/xenitab/azcagit/src/config"
+	"github.com/xenitab/azcagit/src/store"
+)
+
+type StoreStorageCache struct {
+	client *store.Client[CacheEntry]
+}
+
+var _ StorageCache = (*StoreStorageCache)(nil)
+
+func NewStoreStorageCache(cfg config.ReconcileConfig, cacheStore store.Store) (*StoreStorageCache, error) {
+	ttl := 3600
+	client, err := store.NewClient[CacheEntry](cacheStore, "storage-cache", &ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	return &StoreStorageCache{
+		client,
+	}, nil
+}
+
+func (c *StoreStorageCache) Set(ctx context.Context, name string, remoteStorage, sourceStorage *armappcontainers.ManagedEnvironmentStorage) error {
+	if remoteStorage == nil {
+		return nil
+	}
+	if remoteStorage.SystemData == nil {
+		return nil
+	}
+
+	timestamp := remoteStorage.SystemData.LastModifiedAt
+	if timestamp == nil {
+		if remoteStorage.SystemData.CreatedAt == nil {
+			return nil
+		}
+		timestamp = remoteStorage.SystemData.CreatedAt
+	}
+
+	b, err := sourceStorage.MarshalJSON()
+	if err != nil {
+		return nil
+	}
+
+	hash := fmt.Sprintf("%x", md5.Sum(b))
+	cacheEntry := newCacheEntry(name, *timestamp, hash)
+	return c.client.Set(ctx, name, cacheEntry)
+}
+
+func (c *StoreStorageCache) NeedsUpdate(ctx context.Context, name string, remoteStorage, sourceStorage *armappcontainers.ManagedEnvironmentStorage) (bool, string, error) {
+	entry, err := c.client.Get(ctx, name)
+	if err != nil {
+		return false, "cache store returned an error", err
+	}
+
+	if entry == nil {
+		return true, "not in StorageCache", nil
+	}
+
+	if remoteStorage == nil {
+		return true, "remoteStorage nil", nil
+	}
+	if remoteStorage.SystemData == nil {
+		return true, "remoteStorage SystemData nil", nil
+	}
+
+	if remoteStorage.SystemData.LastModifiedAt != nil {
+		if (entry.Modified).Round(time.Millisecond) != (*remoteStorage.SystemData.LastModifiedAt).Round(time.Millisecond) {
+			return true, "changed LastModifiedAt", nil
+		}
+	} else if remoteStorage.SystemData.CreatedAt != nil {
+		if entry.Modified.Round(time.Millisecond) != (*remoteStorage.SystemData.CreatedAt).Round(time.Millisecond) {
+			return true, "changed CreatedAt", nil
+		}
+	}
+
+	b, err := sourceStorage.MarshalJSON()
+	if err != nil {
+		return true, "remoteStorage MarshalJSON() failed", nil
+	}
+
+	hash := fmt.Sprintf("%x", md5.Sum(b))
+
+	if entry.Hash != hash {
+		return true, "changed remoteStorage hash", nil
+	}
+
+	return false, "no changes", nil
+}
@@ -0,0 +1,120 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type CacheStoreType string
+
+const (
+	CacheStoreTypeCosmosDB CacheStoreType = "cosmosdb"
+	CacheStoreTypeTable    CacheStoreType = "table"
+	CacheStoreTypeBlob     CacheStoreType = "blob"
+	CacheStoreTypeRedis    CacheStoreType = "redis"
+	CacheStoreTypeFile     CacheStoreType = "file"
+)
+
+type CacheStore struct {
+	Type CacheStoreType
+	// Account is the storage account used by table and blob, Table is used by table and Container by blob
+	Account   string
+	Table     string
+	Container string
+	// Address, Password and TLS are used by redis
+	Address  string
+	Password string
+	TLS      bool
+	// Path is the local directory used by file
+	Path string
+}
+
+// GetCacheStore returns the store used by the caches, CosmosDB (configured with --cosmosdb-account) is
+// used if --cache-store isn't set
+func (cfg *CacheConfig) GetCacheStore() (CacheStore, error) {
+	cacheStore := CacheStore{
+		Type: CacheStoreTypeCosmosDB,
+	}
+
+	if cfg.CacheStore != "" {
+		var err error
+		cacheStore, err = parseCacheStore(cfg.CacheStore)
+		if err != nil {
+			return CacheStore{}, err
+		}
+	}
+
+	if cacheStore.Type == CacheStoreTypeCosmosDB && cfg.CosmosDBAccount == "" {
+		return CacheStore{}, fmt.Errorf("cosmosdb account needs to be set when the cache store is %s", CacheStoreTypeCosmosDB)
+	}
+
+	return cacheStore, nil
+}
+
+func parseCacheStore(s string) (CacheStore, error) {
+	cacheStore := CacheStore{
+		TLS: true,
+	}
+	var tlsErr error
+	err := parseKeyValues(s, func(key string, value string) bool {
+		switch key {
+		case "type":
+			cacheStore.Type = CacheStoreType(strings.TrimSpace(value))
+		case "account":
+			cacheStore.Account = strings.TrimSpace(value)
+		case "table":
+			cacheStore.Table = strings.TrimSpace(value)
+		case "container":
+			cacheStore.Container = strings.TrimSpace(value)
+		case "address":
+			cacheStore.Address = strings.TrimSpace(value)
+		case "password":
+			cacheStore.Password = value
+		case "tls":
+			cacheStore.TLS, tlsErr = strconv.ParseBool(strings.TrimSpace(value))
+		case "path":
+			cacheStore.Path = strings.TrimSpace(value)
+		default:
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return CacheStore{}, fmt.Errorf("unable to parse cache store, %w", err)
+	}
+
+	if tlsErr != nil {
+		return CacheStore{}, fmt.Errorf("unable to parse cache store tls, %w", tlsErr)
+	}
+
+	switch cacheStore.Type {
+	case CacheStoreTypeCosmosDB:
+	case CacheStoreTypeTable:
+		if cacheStore.Account == "" {
+			return CacheStore{}, fmt.Errorf("account needs to be set for cache store %s", cacheStore.Type)
+		}
+		if cacheStore.Table == "" {
+			cacheStore.Table = "azcagit"
+		}
+	case CacheStoreTypeBlob:
+		if cacheStore.Account == "" {
+			return CacheStore{}, fmt.Errorf("account needs to be set for cache store %s", cacheStore.Type)
+		}
+		if cacheStore.Container == "" {
+			cacheStore.Container = "azcagit"
+		}
+	case CacheStoreTypeRedis:
+		if cacheStore.Address == "" {
+			return CacheStore{}, fmt.Errorf("address needs to be set for cache store %s", cacheStore.Type)
+		}
+	case CacheStoreTypeFile:
+		if cacheStore.Path == "" {
+			return CacheStore{}, fmt.Errorf("path needs to be set for cache store %s", cacheStore.Type)
+		}
+	default:
+		return CacheStore{}, fmt.Errorf("cache store has unknown type %q, should be one of %s, %s, %s, %s or %s", cacheStore.Type, CacheStoreTypeCosmosDB, CacheStoreTypeTable, CacheStoreTypeBlob, CacheStoreTypeRedis, CacheStoreTypeFile)
+	}
+
+	return cacheStore, nil
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetCacheStore(t *testing.T) {
+	cases := []struct {
+		testDescription string
+		cfg             CacheConfig
+		expectedResult  CacheStore
+		expectedError   string
+	}{
+		{
+			testDescription: "cosmosdb by default",
+			cfg: CacheConfig{
+				CosmosDBAccount: "ze-cosmosdb-account",
+			},
+			expectedResult: CacheStore{Type: CacheStoreTypeCosmosDB},
+		},
+		{
+			testDescription: "cosmosdb without account",
+			cfg:             CacheConfig{},
+			expectedError:   "cosmosdb account needs to be set when the cache store is cosmosdb",
+		},
+		{
+			testDescription: "table with default table name",
+			cfg: CacheConfig{
+				CacheStore: "type=table;account=zestorage",
+			},
+			expectedResult: CacheStore{Type: CacheStoreTypeTable, Account: "zestorage", Table: "azcagit", TLS: true},
+		},
+		{
+			testDescription: "blob",
+			cfg: CacheConfig{
+				CacheStore: "type=blob;account=zestorage;container=cache",
+			},
+			expectedResult: CacheStore{Type: CacheStoreTypeBlob, Account: "zestorage", Container: "cache", TLS: true},
+		},
+		{
+			testDescription: "redis without tls",
+			cfg: CacheConfig{
+				CacheStore: "type=redis;address=localhost:6379;password=ze-password;tls=false",
+			},
+			expectedResult: CacheStore{Type: CacheStoreTypeRedis, Address: "localhost:6379", Password: "ze-password", TLS: false},
+		},
+		{
+			testDescription: "file",
+			cfg: CacheConfig{
+				CacheStore: "type=file;path=/var/cache/azcagit",
+			},
+			expectedResult: CacheStore{Type: CacheStoreTypeFile, Path: "/var/cache/azcagit", TLS: true},
+		},
+		{
+			testDescription: "table without account",
+			cfg: CacheConfig{
+				CacheStore: "type=table",
+			},
+			expectedError: "account needs to be set for cache store table",
+		},
+		{
+			testDescription: "invalid tls",
+			cfg: CacheConfig{
+				CacheStore: "type=redis;address=localhost:6379;tls=foo",
+			},
+			expectedError: "unable to parse cache store tls",
+		},
+		{
+			testDescription: "unknown type",
+			cfg: CacheConfig{
+				CacheStore: "type=foo",
+			},
+			expectedError: "cache store has unknown type \"foo\"",
+		},
+	}
+
+	for i, c := range cases {
+		t.Logf("Test #%d: %s", i, c.testDescription)
+		cacheStore, err := c.cfg.GetCacheStore()
+		if c.expectedError != "" {
+			require.ErrorContains(t, err, c.expectedError)
+			continue
+		}
+		require.NoError(t, err)
+		require.Equal(t, c.expectedResult, cacheStore)
+	}
+}
+
+func TestRedactCacheStore(t *testing.T) {
+	cfg := ReconcileConfig{
+		CacheConfig: CacheConfig{
+			CacheStore: "type=redis;address=localhost:6380;password=ze-password",
+		},
+	}
+	require.Equal(t, "type=redis;address=localhost:6380;password=redacted", cfg.Redacted().CacheStore)
+}
@@ -9,27 +9,58 @@ import (
 )
 
 type ReconcileConfig struct {
-	ResourceGroupName         string `json:"resource_group_name" arg:"-g,--resource-group-name,env:RESOURCE_GROUP_NAME,required" help:"Azure Resource Group Name"`
-	Environment               string `json:"environment" arg:"--environment,env:ENVIRONMENT,required" help:"The current environment that azcagit is running in"`
-	SubscriptionID            string `json:"subscription_id" arg:"-s,--subscription-id,env:AZURE_SUBSCRIPTION_ID,required" help:"Azure Subscription ID"`
-	ManagedEnvironmentID      string `json:"managed_environment_id" arg:"-m,--managed-environment-id,env:MANAGED_ENVIRONMENT_ID,required" help:"Azure Container Apps Managed Environment ID"`
-	KeyVaultName              string `json:"key_vault_name" arg:"-k,--key-vault-name,env:KEY_VAULT_NAME,required" help:"Azure KeyVault name to extract secrets from"`
-	OwnContainerJobName       string `json:"own_container_job_name" arg:"--own-container-job-name,env:OWN_CONTAINER_JOB_NAME" default:"azcagit-reconcile" help:"The name of the Container App job that is running azcagit"`
-	OwnResourceGroupName      string `json:"own_resource_group" arg:"--own-resource-group-name,env:OWN_RESOURCE_GROUP_NAME,required" help:"The name of the resource group that the azcagit Container App is located in"`
-	ContainerRegistryServer   string `json:"container_registry_server" arg:"--container-registry-server,env:CONTAINER_REGISTRY_SERVER" help:"The container registry server"`
-	ContainerRegistryUsername string `json:"container_registry_username" arg:"--container-registry-username,env:CONTAINER_REGISTRY_USERNAME" help:"The container registry username"`
-	ContainerRegistryPassword string `json:"container_registry_password" arg:"--container-registry-password,env:CONTAINER_REGISTRY_PASSWORD" help:"The container registry password"`
-	Location                  string `json:"location" arg:"-l,--location,env:LOCATION,required" help:"Azure Region (location)"`
-	CheckoutPath              string `json:"checkout_path" arg:"-c,--checkout-path,env:CHECKOUT_PATH" default:"/tmp" help:"The local path where the git repository should be checked out"`
-	GitUrl                    string `json:"git_url" arg:"-u,--git-url,env:GIT_URL,required" help:"The git url to checkout"`
-	GitBranch                 string `json:"git_branch" arg:"-b,--git-branch,env:GIT_BRANCH" default:"main" help:"The git branch to checkout"`
-	GitYamlPath               string `json:"git_yaml_path" arg:"--git-yaml-path,env:GIT_YAML_ROOT" default:"" help:"The path where the yaml files are located"`
-	NotificationsEnabled      bool   `json:"notifications_enabled" arg:"--notifications-enabled,env:NOTIFICATIONS_ENABLED" default:"false" help:"Sets if Notifications should be sent to the git provider, should be disabled if no token is provided in git url"`
-	NotificationGroup         string `json:"notification_group" arg:"--notification-group,env:NOTIFICATION_GROUP" default:"apps" help:"The notification group used by gitops-promotion"`
-	CosmosDBAccount           string `json:"cosmosdb_account" arg:"--cosmosdb-account,env:COSMOSDB_ACCOUNT,required" help:"The CosmosDB account to be used for cache"`
-	CosmosDBSqlDb             string `json:"cosmosdb_sql_db" arg:"--cosmosdb-sql-db,env:COSMOSDB_SQL_DB" default:"azcagit" help:"The CosmosDB SQL database to be used for cache"`
-	CosmosDBCacheContainer    string `json:"cosmosdb_cache_container" arg:"--cosmosdb-cache-container,env:COSMOSDB_CACHE_CONTAINER" default:"cache" help:"The CosmosDB container used for the cache"`
-	DebugEnabled              bool   `json:"debug_enabled" arg:"--debug,env:DEBUG" default:"false" help:"Enabled debug logging"`
+	ResourceGroupName         string   `json:"resource_group_name" arg:"-g,--resource-group-name,env:RESOURCE_GROUP_NAME,required" help:"Azure Resource Group Name"`
+	Environment               string   `json:"environment" arg:"--environment,env:ENVIRONMENT,required" help:"The current environment that azcagit is running in"`
+	SubscriptionID            string   `json:"subscription_id" arg:"-s,--subscription-id,env:AZURE_SUBSCRIPTION_ID,required" help:"Azure Subscription ID"`
+	ManagedEnvironmentID      string   `json:"managed_environment_id" arg:"-m,--managed-environment-id,env:MANAGED_ENVIRONMENT_ID,required" help:"Azure Container Apps Managed Environment ID"`
+	KeyVaultName              string   `json:"key_vault_name" arg:"-k,--key-vault-name,env:KEY_VAULT_NAME,required" help:"Azure KeyVault name to extract secrets from"`
+	SecretVaults              []string `json:"secret_vaults" arg:"--secret-vaults,env:SECRET_VAULTS" help:"Additional secret vaults, selected with vault in remoteSecrets. Formatted as name=<name>;type=keyvault;keyVaultName=<name>, name=<name>;type=hashicorp-vault;address=<url>;mount=<mount>;path=<path>;token=<token> or name=<name>;type=sops;path=<path>;ageKey=<key>"`
+	OwnContainerJobName       string   `json:"own_container_job_name" arg:"--own-container-job-name,env:OWN_CONTAINER_JOB_NAME" default:"azcagit-reconcile" help:"The name of the Container App job that is running azcagit"`
+	OwnResourceGroupName      string   `json:"own_resource_group" arg:"--own-resource-group-name,env:OWN_RESOURCE_GROUP_NAME,required" help:"The name of the resource group that the azcagit Container App is located in"`
+	ContainerRegistryServer   string   `json:"container_registry_server" arg:"--container-registry-server,env:CONTAINER_REGISTRY_SERVER" help:"The container registry server"`
+	ContainerRegistryUsername string   `json:"container_registry_username" arg:"--container-registry-username,env:CONTAINER_REGISTRY_USERNAME" help:"The container registry username"`
+	ContainerRegistryPassword string   `json:"container_registry_password" arg:"--container-registry-password,env:CONTAINER_REGISTRY_PASSWORD" help:"The container registry password"`
+	ContainerRegistryIdentity string   `json:"container_registry_identity" arg:"--container-registry-identity,env:CONTAINER_REGISTRY_IDENTITY" help:"The managed identity (resource id or system) the apps use to pull from the container registry server, instead of username and password"`
+	ContainerRegistries       []string `json:"container_registries" arg:"--container-registries,env:CONTAINER_REGISTRIES" help:"Additional container registries, only added to the apps and jobs using images from them. Formatted as server=<server>;identity=<identity> or server=<server>;username=<username>;password=<password>"`
+	SecretRotationRestart     bool     `json:"secret_rotation_restart" arg:"--secret-rotation-restart,env:SECRET_ROTATION_RESTART" default:"false" help:"Restarts the active revisions of apps using a remote secret that has changed, making them read the new value"`
+	ResolveImageDigests       bool     `json:"resolve_image_digests" arg:"--resolve-image-digests,env:RESOLVE_IMAGE_DIGESTS" default:"false" help:"Resolves the image tags to digests before apply, making a moved tag trigger an update"`
+	Location                  string   `json:"location" arg:"-l,--location,env:LOCATION,required" help:"Azure Region (location)"`
+	CheckoutPath              string   `json:"checkout_path" arg:"-c,--checkout-path,env:CHECKOUT_PATH" default:"/tmp" help:"The local path where the git repository should be checked out"`
+	GitUrl                    string   `json:"git_url" arg:"-u,--git-url,env:GIT_URL,required" help:"The git url to checkout"`
+	GitBranch                 string   `json:"git_branch" arg:"-b,--git-branch,env:GIT_BRANCH" default:"main" help:"The git branch to checkout"`
+	GitYamlPath               string   `json:"git_yaml_path" arg:"--git-yaml-path,env:GIT_YAML_ROOT" default:"" help:"The path where the yaml files are located"`
+	GitOverlaysPath           string   `json:"git_overlays_path" arg:"--git-overlays-path,env:GIT_OVERLAYS_PATH" default:"" help:"The path, relative to the yaml path, with one overlay directory per environment. Overlays are disabled if empty"`
+	VariablesEnabled          bool     `json:"variables_enabled" arg:"--variables-enabled,env:VARIABLES_ENABLED" default:"false" help:"Enables substitution of ${var} placeholders in the manifests"`
+	GitVariablesPath          string   `json:"git_variables_path" arg:"--git-variables-path,env:GIT_VARIABLES_PATH" default:"variables" help:"The path, relative to the yaml path, with one variables file per environment (<environment>.yaml)"`
+	GitPolicyPath             string   `json:"git_policy_path" arg:"--git-policy-path,env:GIT_POLICY_PATH" default:"" help:"The path, relative to the repository root, of the policy file mapping directories to tenants and the constraints of their apps and jobs. Policies are disabled if empty"`
+	NotificationsEnabled      bool     `json:"notifications_enabled" arg:"--notifications-enabled,env:NOTIFICATIONS_ENABLED" default:"false" help:"Sets if Notifications should be sent to the git provider, should be disabled if no token is provided in git url"`
+	NotificationProvider      string   `json:"notification_provider" arg:"--notification-provider,env:NOTIFICATION_PROVIDER" default:"" help:"The git provider receiving the notifications (azure-devops, github, gitlab, bitbucket, bitbucket-server or gitea), detected from the git url hostname if empty"`
+	NotificationApiUrl        string   `json:"notification_api_url" arg:"--notification-api-url,env:NOTIFICATION_API_URL" default:"" help:"The api url of the git provider, for self-hosted providers like GitHub Enterprise Server (https://<host>/api/v3) or Azure DevOps Server (https://<host>/<collection>)"`
+	NotificationGroup         string   `json:"notification_group" arg:"--notification-group,env:NOTIFICATION_GROUP" default:"apps" help:"The notification group used by gitops-promotion"`
+	NotificationWebhooks      []string `json:"notification_webhooks" arg:"--notification-webhooks,env:NOTIFICATION_WEBHOOKS" help:"Chat and webhook notifications, sent in addition to the git provider. Formatted as type=slack;url=<url>, type=teams;url=<url> or type=webhook;url=<url>;secret=<secret>;format=<json|cloudevents>"`
+	NotificationDescription   string   `json:"notification_description" arg:"--notification-description-template,env:NOTIFICATION_DESCRIPTION_TEMPLATE" default:"" help:"Go template for the notification description, see the README for the available fields. The default description (reconcile succeeded or the error) is used if empty"`
+	NotificationTargetUrl     string   `json:"notification_target_url" arg:"--notification-target-url-template,env:NOTIFICATION_TARGET_URL_TEMPLATE" default:"" help:"Go template for the notification target url (the link of the commit status), like {{ .PortalUrl }} for the resource group in the Azure portal"`
+	NotificationPerResource   bool     `json:"notification_per_resource" arg:"--notification-per-resource,env:NOTIFICATION_PER_RESOURCE" default:"false" help:"Sends a notification per changed app, job, dapr component, certificate and storage (named <resource-group>/<environment>/<kind>/<name>) in addition to the notification for the reconcile"`
+	Targets                   []string `json:"targets" arg:"--targets,env:TARGETS" help:"Additional resource groups that manifests can target, with metadata.resourceGroup or by being in the path of the target. Formatted as resourceGroup=<name>;subscriptionID=<id>;managedEnvironmentID=<id>;path=<path>, where the subscription and managed environment default to the ones of azcagit"`
+	PreviewEnabled            bool     `json:"preview_enabled" arg:"--preview-enabled,env:PREVIEW_ENABLED" default:"false" help:"Deploys the apps of open pull requests (GitHub and Azure DevOps) targeting the git branch as preview apps named <name>-pr<number>, deleted when the pull request is closed"`
+	PreviewAllowedSecretNames []string `json:"preview_allowed_secret_names" arg:"--preview-allowed-secret-names,env:PREVIEW_ALLOWED_SECRET_NAMES" help:"Patterns of the remote secrets (<vault>/<name> for the secret vaults) and KeyVault secrets the preview apps can use, when their tenant doesn't set allowedSecretNames. Previews can't use any secret by default"`
+	DebugEnabled              bool     `json:"debug_enabled" arg:"--debug,env:DEBUG" default:"false" help:"Enabled debug logging"`
+	HistorySize               int      `json:"history_size" arg:"--history-size,env:HISTORY_SIZE" default:"500" help:"The number of reconcile runs kept in the history, 0 disables the history"`
+	CacheConfig
+	// defaultTarget is set by ForTarget, as the resource group of the configuration is replaced
+	defaultTarget *Target
+	// sharedEnvironmentWith is set by ForTarget to the resource group reconciling the managed environment
+	sharedEnvironmentWith string
+	// policyBranch is set by ForPullRequest, as the policy of a pull request is read from the branch it targets
+	policyBranch string
+}
+
+// CacheConfig configures the store used by the caches, shared by the subcommands reading the cache
+type CacheConfig struct {
+	CacheStore             string `json:"cache_store" arg:"--cache-store,env:CACHE_STORE" default:"" help:"The store used for the cache, CosmosDB is used if empty. Formatted as type=cosmosdb, type=table;account=<account>;table=<table>, type=blob;account=<account>;container=<container>, type=redis;address=<host:port>;password=<password>;tls=<true|false> or type=file;path=<path>"`
+	CosmosDBAccount        string `json:"cosmosdb_account" arg:"--cosmosdb-account,env:COSMOSDB_ACCOUNT" help:"The CosmosDB account to be used for cache, required when the cache store is cosmosdb"`
+	CosmosDBSqlDb          string `json:"cosmosdb_sql_db" arg:"--cosmosdb-sql-db,env:COSMOSDB_SQL_DB" default:"azcagit" help:"The CosmosDB SQL database to be used for cache"`
+	CosmosDBCacheContainer string `json:"cosmosdb_cache_container" arg:"--cosmosdb-cache-container,env:COSMOSDB_CACHE_CONTAINER" default:"cache" help:"The CosmosDB container used for the cache"`
 }
 
 func (cfg *ReconcileConfig) Redacted() ReconcileConfig {
@@ -42,10 +73,55 @@ func (cfg *ReconcileConfig) Redacted() ReconcileConfig {
 	if redactedCfg.ContainerRegistryPassword != "" {
 		redactedCfg.ContainerRegistryPassword = "redacted"
 	}
+	if len(redactedCfg.ContainerRegistries) != 0 {
+		redactedCfg.ContainerRegistries = redactKeyValues(redactedCfg.ContainerRegistries, "password")
+	}
+	if len(redactedCfg.SecretVaults) != 0 {
+		redactedCfg.SecretVaults = redactKeyValues(redactedCfg.SecretVaults, "token", "ageKey")
+	}
+	if len(redactedCfg.NotificationWebhooks) != 0 {
+		redactedCfg.NotificationWebhooks = redactKeyValues(redactedCfg.NotificationWebhooks, "url", "secret")
+	}
+	redactedCfg.CacheConfig = cfg.CacheConfig.Redacted()
 
 	return redactedCfg
 }
 
+// ForPullRequest returns the configuration used to reconcile the preview of the pull request branch, with
+// notifications disabled and the policy read from the git branch
+func (cfg ReconcileConfig) ForPullRequest(branch string) ReconcileConfig {
+	if cfg.policyBranch == "" {
+		cfg.policyBranch = cfg.GitBranch
+	}
+
+	cfg.GitBranch = branch
+	cfg.NotificationsEnabled = false
+
+	return cfg
+}
+
+// PullRequest returns true if the configuration is used to reconcile the preview of a pull request
+func (cfg ReconcileConfig) PullRequest() bool {
+	return cfg.policyBranch != ""
+}
+
+// PolicyBranch returns the git branch the policy file is read from
+func (cfg ReconcileConfig) PolicyBranch() string {
+	if cfg.policyBranch == "" {
+		return cfg.GitBranch
+	}
+
+	return cfg.policyBranch
+}
+
+func (cfg CacheConfig) Redacted() CacheConfig {
+	if cfg.CacheStore != "" {
+		cfg.CacheStore = redactKeyValues([]string{cfg.CacheStore}, "password")[0]
+	}
+
+	return cfg
+}
+
 func redactUrl(u string) string {
 	if u == "" {
 		return ""
@@ -76,9 +152,60 @@ type TriggerConfig struct {
 	ServiceBusQueue     string `json:"service_bus_queue" arg:"--service-bus-queue,env:SERVICE_BUS_QUEUE,required" help:"The queue name of where to consume the messages from the service bus"`
 }
 
+type ImageUpdateConfig struct {
+	CheckoutPath              string `json:"checkout_path" arg:"-c,--checkout-path,env:CHECKOUT_PATH" default:"/tmp" help:"The local path where the git repository should be checked out"`
+	GitUrl                    string `json:"git_url" arg:"-u,--git-url,env:GIT_URL,required" help:"The git url to checkout and push to"`
+	GitBranch                 string `json:"git_branch" arg:"-b,--git-branch,env:GIT_BRANCH" default:"main" help:"The git branch to checkout"`
+	GitYamlPath               string `json:"git_yaml_path" arg:"--git-yaml-path,env:GIT_YAML_ROOT" default:"" help:"The path where the yaml files are located"`
+	GitPushBranch             string `json:"git_push_branch" arg:"--git-push-branch,env:GIT_PUSH_BRANCH" default:"" help:"The git branch to push the updates to, the checked out branch is used if empty"`
+	GitAuthorName             string `json:"git_author_name" arg:"--git-author-name,env:GIT_AUTHOR_NAME" default:"azcagit" help:"The author name of the update commits"`
+	GitAuthorEmail            string `json:"git_author_email" arg:"--git-author-email,env:GIT_AUTHOR_EMAIL" default:"azcagit@xenit.se" help:"The author email of the update commits"`
+	PullRequestEnabled        bool   `json:"pull_request_enabled" arg:"--pull-request-enabled,env:PULL_REQUEST_ENABLED" default:"false" help:"Opens a pull request from the push branch to the checked out branch, only supported for GitHub"`
+	ContainerRegistryServer   string `json:"container_registry_server" arg:"--container-registry-server,env:CONTAINER_REGISTRY_SERVER" help:"The container registry server"`
+	ContainerRegistryUsername string `json:"container_registry_username" arg:"--container-registry-username,env:CONTAINER_REGISTRY_USERNAME" help:"The container registry username"`
+	ContainerRegistryPassword string `json:"container_registry_password" arg:"--container-registry-password,env:CONTAINER_REGISTRY_PASSWORD" help:"The container registry password"`
+	DryRun                    bool   `json:"dry_run" arg:"--dry-run,env:DRY_RUN" default:"false" help:"Only log the image updates without committing them"`
+	DebugEnabled              bool   `json:"debug_enabled" arg:"--debug,env:DEBUG" default:"false" help:"Enabled debug logging"`
+}
+
+func (cfg *ImageUpdateConfig) Redacted() ImageUpdateConfig {
+	if cfg == nil {
+		return ImageUpdateConfig{}
+	}
+
+	redactedCfg := *cfg
+	redactedCfg.GitUrl = redactUrl(redactedCfg.GitUrl)
+	if redactedCfg.ContainerRegistryPassword != "" {
+		redactedCfg.ContainerRegistryPassword = "redacted"
+	}
+
+	return redactedCfg
+}
+
+type HistoryConfig struct {
+	App               string `json:"app" arg:"--app" help:"Only list the runs that changed the app (or job, dapr component, certificate or storage) with the name, and what changed"`
+	Limit             int    `json:"limit" arg:"--limit" default:"20" help:"The maximum number of runs to list"`
+	ResourceGroupName string `json:"resource_group_name" arg:"--resource-group-name" help:"List the runs of the additional target (configured with --targets) with the resource group, instead of the default resource group"`
+	DebugEnabled      bool   `json:"debug_enabled" arg:"--debug,env:DEBUG" default:"false" help:"Enabled debug logging"`
+	CacheConfig
+}
+
+func (cfg *HistoryConfig) Redacted() HistoryConfig {
+	if cfg == nil {
+		return HistoryConfig{}
+	}
+
+	redactedCfg := *cfg
+	redactedCfg.CacheConfig = cfg.CacheConfig.Redacted()
+
+	return redactedCfg
+}
+
 type Config struct {
-	ReconcileCfg *ReconcileConfig `arg:"subcommand:reconcile" help:"run reconciliation"`
-	TriggerCfg   *TriggerConfig   `arg:"subcommand:trigger" help:"run trigger"`
+	ReconcileCfg   *ReconcileConfig   `arg:"subcommand:reconcile" help:"run reconciliation"`
+	TriggerCfg     *TriggerConfig     `arg:"subcommand:trigger" help:"run trigger"`
+	ImageUpdateCfg *ImageUpdateConfig `arg:"subcommand:image-update" help:"update image tags in git based on the image policies"`
+	HistoryCfg     *HistoryConfig     `arg:"subcommand:history" help:"list the previous reconcile runs and what they changed"`
 }
 
 func NewConfig(args []string) (Config, error) {
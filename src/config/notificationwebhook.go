@@ -0,0 +1,92 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+type NotificationWebhookType string
+
+const (
+	NotificationWebhookTypeSlack   NotificationWebhookType = "slack"
+	NotificationWebhookTypeTeams   NotificationWebhookType = "teams"
+	NotificationWebhookTypeWebhook NotificationWebhookType = "webhook"
+)
+
+type NotificationWebhookFormat string
+
+const (
+	NotificationWebhookFormatJSON        NotificationWebhookFormat = "json"
+	NotificationWebhookFormatCloudEvents NotificationWebhookFormat = "cloudevents"
+)
+
+type NotificationWebhook struct {
+	Type NotificationWebhookType
+	URL  string
+	// Secret (optional) and Format are only used by the generic webhook, the body is signed with HMAC
+	// SHA256 if the secret is set
+	Secret string
+	Format NotificationWebhookFormat
+}
+
+// GetNotificationWebhooks returns the chat and webhook notifications configured with --notification-webhooks
+func (cfg *ReconcileConfig) GetNotificationWebhooks() ([]NotificationWebhook, error) {
+	webhooks := []NotificationWebhook{}
+	for _, s := range cfg.NotificationWebhooks {
+		webhook, err := parseNotificationWebhook(s)
+		if err != nil {
+			return nil, err
+		}
+
+		webhooks = append(webhooks, webhook)
+	}
+
+	return webhooks, nil
+}
+
+func parseNotificationWebhook(s string) (NotificationWebhook, error) {
+	webhook := NotificationWebhook{}
+	err := parseKeyValues(s, func(key string, value string) bool {
+		switch key {
+		case "type":
+			webhook.Type = NotificationWebhookType(strings.TrimSpace(value))
+		case "url":
+			webhook.URL = strings.TrimSpace(value)
+		case "secret":
+			webhook.Secret = value
+		case "format":
+			webhook.Format = NotificationWebhookFormat(strings.TrimSpace(value))
+		default:
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return NotificationWebhook{}, fmt.Errorf("unable to parse notification webhook, %w", err)
+	}
+
+	switch webhook.Type {
+	case NotificationWebhookTypeSlack, NotificationWebhookTypeTeams:
+		if webhook.Secret != "" || webhook.Format != "" {
+			return NotificationWebhook{}, fmt.Errorf("secret and format are only supported by notification webhook type %s", NotificationWebhookTypeWebhook)
+		}
+	case NotificationWebhookTypeWebhook:
+		switch webhook.Format {
+		case "":
+			webhook.Format = NotificationWebhookFormatJSON
+		case NotificationWebhookFormatJSON, NotificationWebhookFormatCloudEvents:
+		default:
+			return NotificationWebhook{}, fmt.Errorf("notification webhook has unknown format %q, should be one of %s or %s", webhook.Format, NotificationWebhookFormatJSON, NotificationWebhookFormatCloudEvents)
+		}
+	default:
+		return NotificationWebhook{}, fmt.Errorf("notification webhook has unknown type %q, should be one of %s, %s or %s", webhook.Type, NotificationWebhookTypeSlack, NotificationWebhookTypeTeams, NotificationWebhookTypeWebhook)
+	}
+
+	parsedUrl, err := url.Parse(webhook.URL)
+	if err != nil || parsedUrl.Host == "" || (parsedUrl.Scheme != "https" && parsedUrl.Scheme != "http") {
+		return NotificationWebhook{}, fmt.Errorf("notification webhook %s needs a valid http(s) url", webhook.Type)
+	}
+
+	return webhook, nil
+}
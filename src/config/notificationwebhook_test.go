@@ -0,0 +1,88 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetNotificationWebhooks(t *testing.T) {
+	cases := []struct {
+		testDescription string
+		webhooks        []string
+		expectedResult  []NotificationWebhook
+		expectedError   string
+	}{
+		{
+			testDescription: "none",
+			webhooks:        nil,
+			expectedResult:  []NotificationWebhook{},
+		},
+		{
+			testDescription: "slack, teams and webhook",
+			webhooks: []string{
+				"type=slack;url=https://hooks.slack.com/services/foo",
+				"type=teams;url=https://ze-tenant.webhook.office.com/webhookb2/bar",
+				"type=webhook;url=https://example.com/hook;secret=ze-secret;format=cloudevents",
+			},
+			expectedResult: []NotificationWebhook{
+				{Type: NotificationWebhookTypeSlack, URL: "https://hooks.slack.com/services/foo"},
+				{Type: NotificationWebhookTypeTeams, URL: "https://ze-tenant.webhook.office.com/webhookb2/bar"},
+				{Type: NotificationWebhookTypeWebhook, URL: "https://example.com/hook", Secret: "ze-secret", Format: NotificationWebhookFormatCloudEvents},
+			},
+		},
+		{
+			testDescription: "webhook defaults to json",
+			webhooks:        []string{"type=webhook;url=http://localhost:8080/hook"},
+			expectedResult: []NotificationWebhook{
+				{Type: NotificationWebhookTypeWebhook, URL: "http://localhost:8080/hook", Format: NotificationWebhookFormatJSON},
+			},
+		},
+		{
+			testDescription: "secret for slack",
+			webhooks:        []string{"type=slack;url=https://hooks.slack.com/services/foo;secret=ze-secret"},
+			expectedError:   "secret and format are only supported by notification webhook type webhook",
+		},
+		{
+			testDescription: "unknown format",
+			webhooks:        []string{"type=webhook;url=https://example.com/hook;format=xml"},
+			expectedError:   "notification webhook has unknown format \"xml\"",
+		},
+		{
+			testDescription: "unknown type",
+			webhooks:        []string{"type=discord;url=https://example.com/hook"},
+			expectedError:   "notification webhook has unknown type \"discord\"",
+		},
+		{
+			testDescription: "missing url",
+			webhooks:        []string{"type=teams"},
+			expectedError:   "notification webhook teams needs a valid http(s) url",
+		},
+		{
+			testDescription: "unknown key",
+			webhooks:        []string{"type=slack;channel=foo"},
+			expectedError:   "unable to parse notification webhook",
+		},
+	}
+
+	for i, c := range cases {
+		t.Logf("Test #%d: %s", i, c.testDescription)
+		cfg := ReconcileConfig{
+			NotificationWebhooks: c.webhooks,
+		}
+		webhooks, err := cfg.GetNotificationWebhooks()
+		if c.expectedError != "" {
+			require.ErrorContains(t, err, c.expectedError)
+			continue
+		}
+		require.NoError(t, err)
+		require.Equal(t, c.expectedResult, webhooks)
+	}
+}
+
+func TestRedactNotificationWebhooks(t *testing.T) {
+	cfg := ReconcileConfig{
+		NotificationWebhooks: []string{"type=webhook;url=https://example.com/hook;secret=ze-secret"},
+	}
+	require.Equal(t, []string{"type=webhook;url=redacted;secret=redacted"}, cfg.Redacted().NotificationWebhooks)
+}
@@ -0,0 +1,226 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+type ContainerRegistry struct {
+	Server   string
+	Username string
+	Password string
+	Identity string
+	// Default is true for the registry configured with --container-registry-server, which is added to
+	// all apps and jobs
+	Default bool
+}
+
+func (r ContainerRegistry) UsesIdentity() bool {
+	return r.Identity != ""
+}
+
+// GetContainerRegistries returns the default container registry (if configured) followed by the
+// additional container registries
+func (cfg *ReconcileConfig) GetContainerRegistries() ([]ContainerRegistry, error) {
+	registries := []ContainerRegistry{}
+	if cfg.ContainerRegistryServer != "" || cfg.ContainerRegistryUsername != "" || cfg.ContainerRegistryPassword != "" || cfg.ContainerRegistryIdentity != "" {
+		registry := ContainerRegistry{
+			Server:   cfg.ContainerRegistryServer,
+			Username: cfg.ContainerRegistryUsername,
+			Password: cfg.ContainerRegistryPassword,
+			Identity: cfg.ContainerRegistryIdentity,
+			Default:  true,
+		}
+
+		err := registry.validate()
+		if err != nil {
+			return nil, err
+		}
+
+		registries = append(registries, registry)
+	}
+
+	for _, s := range cfg.ContainerRegistries {
+		registry, err := parseContainerRegistry(s)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, existing := range registries {
+			if existing.Server == registry.Server {
+				return nil, fmt.Errorf("container registry %q is configured more than once", registry.Server)
+			}
+		}
+
+		registries = append(registries, registry)
+	}
+
+	return registries, nil
+}
+
+func (r ContainerRegistry) validate() error {
+	if r.Server == "" {
+		return fmt.Errorf("container registry server needs to be set")
+	}
+
+	if r.Identity != "" && (r.Username != "" || r.Password != "") {
+		return fmt.Errorf("container registry %q can't use both identity and username and password", r.Server)
+	}
+
+	if r.Identity == "" && (r.Username == "" || r.Password == "") {
+		return fmt.Errorf("all of container registry server, username and password needs to be set, or server and identity")
+	}
+
+	return nil
+}
+
+func parseContainerRegistry(s string) (ContainerRegistry, error) {
+	registry := ContainerRegistry{}
+	err := parseKeyValues(s, func(key string, value string) bool {
+		switch key {
+		case "server":
+			registry.Server = strings.TrimSpace(value)
+		case "username":
+			registry.Username = strings.TrimSpace(value)
+		case "password":
+			registry.Password = value
+		case "identity":
+			registry.Identity = strings.TrimSpace(value)
+		default:
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return ContainerRegistry{}, fmt.Errorf("unable to parse container registry, %w", err)
+	}
+
+	err = registry.validate()
+	if err != nil {
+		return ContainerRegistry{}, err
+	}
+
+	return registry, nil
+}
+
+// parseKeyValues parses key=value pairs separated by semicolons, setValue returns false for unknown keys
+func parseKeyValues(s string, setValue func(key string, value string) bool) error {
+	parts, err := splitKeyValues(s)
+	if err != nil {
+		return err
+	}
+
+	for _, part := range parts {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return fmt.Errorf("expected key=value but received %q", part)
+		}
+
+		value, err := unquoteValue(value)
+		if err != nil {
+			return err
+		}
+
+		key = strings.TrimSpace(key)
+		if !setValue(key, value) {
+			return fmt.Errorf("unknown key %q", key)
+		}
+	}
+
+	return nil
+}
+
+// splitKeyValues splits key=value pairs separated by semicolons. A value starting with a single quote
+// is quoted until the next single quote and can contain semicolons, ” is a single quote in a quoted value.
+func splitKeyValues(s string) ([]string, error) {
+	parts := []string{}
+	start := 0
+	valueStart := -1
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == ';':
+			parts = append(parts, s[start:i])
+			start = i + 1
+			valueStart = -1
+		case s[i] == '=' && valueStart == -1:
+			valueStart = i + 1
+			value := strings.TrimLeft(s[valueStart:], " ")
+			if !strings.HasPrefix(value, "'") {
+				continue
+			}
+
+			end, err := findClosingQuote(s, len(s)-len(value)+1)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+		}
+	}
+
+	return append(parts, s[start:]), nil
+}
+
+func findClosingQuote(s string, from int) (int, error) {
+	for i := from; i < len(s); i++ {
+		if s[i] != '\'' {
+			continue
+		}
+
+		if i+1 < len(s) && s[i+1] == '\'' {
+			i++
+			continue
+		}
+
+		return i, nil
+	}
+
+	return 0, fmt.Errorf("unterminated quoted value")
+}
+
+func unquoteValue(value string) (string, error) {
+	trimmed := strings.TrimSpace(value)
+	if !strings.HasPrefix(trimmed, "'") {
+		return value, nil
+	}
+
+	if len(trimmed) < 2 || !strings.HasSuffix(trimmed, "'") {
+		return "", fmt.Errorf("unexpected characters after quoted value")
+	}
+
+	inner := trimmed[1 : len(trimmed)-1]
+	if strings.Contains(strings.ReplaceAll(inner, "''", ""), "'") {
+		return "", fmt.Errorf("unexpected characters after quoted value")
+	}
+
+	return strings.ReplaceAll(inner, "''", "'"), nil
+}
+
+// redactKeyValues replaces the values of the secret keys in key=value pairs separated by semicolons
+func redactKeyValues(values []string, secretKeys ...string) []string {
+	redacted := []string{}
+	for _, s := range values {
+		parts, err := splitKeyValues(s)
+		if err != nil {
+			redacted = append(redacted, "redacted")
+			continue
+		}
+		for i, part := range parts {
+			key, _, ok := strings.Cut(part, "=")
+			if !ok {
+				continue
+			}
+			for _, secretKey := range secretKeys {
+				if strings.TrimSpace(key) == secretKey {
+					parts[i] = fmt.Sprintf("%s=redacted", secretKey)
+				}
+			}
+		}
+		redacted = append(redacted, strings.Join(parts, ";"))
+	}
+
+	return redacted
+}
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetContainerRegistries(t *testing.T) {
+	cases := []struct {
+		testDescription string
+		cfg             ReconcileConfig
+		expectedResult  []ContainerRegistry
+		expectedError   string
+	}{
+		{
+			testDescription: "no registries",
+			cfg:             ReconcileConfig{},
+			expectedResult:  []ContainerRegistry{},
+		},
+		{
+			testDescription: "default registry with username and password",
+			cfg: ReconcileConfig{
+				ContainerRegistryServer:   "foobar.io",
+				ContainerRegistryUsername: "foo",
+				ContainerRegistryPassword: "bar",
+			},
+			expectedResult: []ContainerRegistry{
+				{Server: "foobar.io", Username: "foo", Password: "bar", Default: true},
+			},
+		},
+		{
+			testDescription: "default registry with identity and additional registries",
+			cfg: ReconcileConfig{
+				ContainerRegistryServer:   "foobar.azurecr.io",
+				ContainerRegistryIdentity: "system",
+				ContainerRegistries: []string{
+					"server=other.azurecr.io;identity=/subscriptions/ze-sub/resourceGroups/ze-rg/providers/Microsoft.ManagedIdentity/userAssignedIdentities/foo",
+					"server=ghcr.io;username=foo;password=bar=baz",
+				},
+			},
+			expectedResult: []ContainerRegistry{
+				{Server: "foobar.azurecr.io", Identity: "system", Default: true},
+				{Server: "other.azurecr.io", Identity: "/subscriptions/ze-sub/resourceGroups/ze-rg/providers/Microsoft.ManagedIdentity/userAssignedIdentities/foo"},
+				{Server: "ghcr.io", Username: "foo", Password: "bar=baz"},
+			},
+		},
+		{
+			testDescription: "quoted password with semicolon and quote",
+			cfg: ReconcileConfig{
+				ContainerRegistries: []string{"server=ghcr.io;username=foo;password='bar;b''az'"},
+			},
+			expectedResult: []ContainerRegistry{
+				{Server: "ghcr.io", Username: "foo", Password: "bar;b'az"},
+			},
+		},
+		{
+			testDescription: "unterminated quoted password",
+			cfg: ReconcileConfig{
+				ContainerRegistries: []string{"server=ghcr.io;username=foo;password='bar;baz"},
+			},
+			expectedError: "unterminated quoted value",
+		},
+		{
+			testDescription: "characters after quoted password",
+			cfg: ReconcileConfig{
+				ContainerRegistries: []string{"server=ghcr.io;username=foo;password='bar'baz"},
+			},
+			expectedError: "unexpected characters after quoted value",
+		},
+		{
+			testDescription: "default registry missing password",
+			cfg: ReconcileConfig{
+				ContainerRegistryServer:   "foobar.io",
+				ContainerRegistryUsername: "foo",
+			},
+			expectedError: "all of container registry server, username and password needs to be set, or server and identity",
+		},
+		{
+			testDescription: "identity and password",
+			cfg: ReconcileConfig{
+				ContainerRegistries: []string{"server=foobar.io;identity=system;username=foo;password=bar"},
+			},
+			expectedError: "container registry \"foobar.io\" can't use both identity and username and password",
+		},
+		{
+			testDescription: "unknown key",
+			cfg: ReconcileConfig{
+				ContainerRegistries: []string{"server=foobar.io;foo=bar"},
+			},
+			expectedError: "unknown key \"foo\"",
+		},
+		{
+			testDescription: "duplicate server",
+			cfg: ReconcileConfig{
+				ContainerRegistryServer:   "foobar.io",
+				ContainerRegistryIdentity: "system",
+				ContainerRegistries:       []string{"server=foobar.io;identity=system"},
+			},
+			expectedError: "container registry \"foobar.io\" is configured more than once",
+		},
+	}
+
+	for i, c := range cases {
+		t.Logf("Test #%d: %s", i, c.testDescription)
+		registries, err := c.cfg.GetContainerRegistries()
+		if c.expectedError != "" {
+			require.ErrorContains(t, err, c.expectedError)
+			continue
+		}
+		require.NoError(t, err)
+		require.Equal(t, c.expectedResult, registries)
+	}
+}
+
+func TestRedactContainerRegistries(t *testing.T) {
+	cfg := ReconcileConfig{
+		ContainerRegistries: []string{
+			"server=ghcr.io;username=foo;password=bar", // secretlint-disable
+			"server=foobar.azurecr.io;identity=system",
+			"server=docker.io;username=foo;password='bar;baz'", // secretlint-disable
+			"server=quay.io;username=foo;password='bar;baz",    // secretlint-disable
+		},
+	}
+	require.Equal(t, []string{
+		"server=ghcr.io;username=foo;password=redacted",
+		"server=foobar.azurecr.io;identity=system",
+		"server=docker.io;username=foo;password=redacted",
+		"redacted",
+	}, cfg.Redacted().ContainerRegistries)
+	require.Equal(t, "server=ghcr.io;username=foo;password=bar", cfg.ContainerRegistries[0]) // secretlint-disable
+}
@@ -0,0 +1,133 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+type SecretVaultType string
+
+const (
+	SecretVaultTypeKeyVault       SecretVaultType = "keyvault"
+	SecretVaultTypeHashicorpVault SecretVaultType = "hashicorp-vault"
+	SecretVaultTypeSops           SecretVaultType = "sops"
+)
+
+type SecretVault struct {
+	Name string
+	Type SecretVaultType
+	// KeyVaultName is used by keyvault
+	KeyVaultName string
+	// Address, Mount, Path, Token and Field are used by hashicorp-vault, Field is the key in the secret data
+	Address string
+	Mount   string
+	Token   string
+	Field   string
+	// Path is the path of the secrets in the mount for hashicorp-vault and the file (relative to the git
+	// repository root) for sops. AgeKey is used by sops, and only needed for age encrypted files
+	Path   string
+	AgeKey string
+}
+
+// GetSecretVaults returns the additional secret vaults, the default KeyVault (--key-vault-name) isn't included
+func (cfg *ReconcileConfig) GetSecretVaults() ([]SecretVault, error) {
+	vaults := []SecretVault{}
+	for _, s := range cfg.SecretVaults {
+		vault, err := parseSecretVault(s)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, existing := range vaults {
+			if existing.Name == vault.Name {
+				return nil, fmt.Errorf("secret vault %q is configured more than once", vault.Name)
+			}
+		}
+
+		vaults = append(vaults, vault)
+	}
+
+	return vaults, nil
+}
+
+// GetSecretVault returns the secret vault with the name
+func (cfg *ReconcileConfig) GetSecretVault(name string) (SecretVault, error) {
+	vaults, err := cfg.GetSecretVaults()
+	if err != nil {
+		return SecretVault{}, err
+	}
+
+	for _, vault := range vaults {
+		if vault.Name == name {
+			return vault, nil
+		}
+	}
+
+	return SecretVault{}, fmt.Errorf("secret vault %q is not configured", name)
+}
+
+func parseSecretVault(s string) (SecretVault, error) {
+	vault := SecretVault{}
+	err := parseKeyValues(s, func(key string, value string) bool {
+		switch key {
+		case "name":
+			vault.Name = strings.TrimSpace(value)
+		case "type":
+			vault.Type = SecretVaultType(strings.TrimSpace(value))
+		case "keyVaultName":
+			vault.KeyVaultName = strings.TrimSpace(value)
+		case "address":
+			vault.Address = strings.TrimSuffix(strings.TrimSpace(value), "/")
+		case "mount":
+			vault.Mount = strings.Trim(strings.TrimSpace(value), "/")
+		case "token":
+			vault.Token = value
+		case "field":
+			vault.Field = strings.TrimSpace(value)
+		case "path":
+			vault.Path = strings.TrimSpace(value)
+		case "ageKey":
+			vault.AgeKey = strings.TrimSpace(value)
+		default:
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return SecretVault{}, fmt.Errorf("unable to parse secret vault, %w", err)
+	}
+
+	if vault.Name == "" {
+		return SecretVault{}, fmt.Errorf("secret vault name needs to be set")
+	}
+
+	if strings.Contains(vault.Name, "/") {
+		return SecretVault{}, fmt.Errorf("secret vault name %q can't contain /", vault.Name)
+	}
+
+	switch vault.Type {
+	case SecretVaultTypeKeyVault:
+		if vault.KeyVaultName == "" {
+			return SecretVault{}, fmt.Errorf("keyVaultName needs to be set for secret vault %q", vault.Name)
+		}
+	case SecretVaultTypeHashicorpVault:
+		if vault.Address == "" || vault.Token == "" {
+			return SecretVault{}, fmt.Errorf("address and token needs to be set for secret vault %q", vault.Name)
+		}
+		if vault.Mount == "" {
+			vault.Mount = "secret"
+		}
+		vault.Path = strings.Trim(vault.Path, "/")
+		if vault.Field == "" {
+			vault.Field = "value"
+		}
+	case SecretVaultTypeSops:
+		if vault.Path == "" {
+			return SecretVault{}, fmt.Errorf("path needs to be set for secret vault %q", vault.Name)
+		}
+	default:
+		return SecretVault{}, fmt.Errorf("secret vault %q has unknown type %q, should be one of %s, %s or %s", vault.Name, vault.Type, SecretVaultTypeKeyVault, SecretVaultTypeHashicorpVault, SecretVaultTypeSops)
+	}
+
+	return vault, nil
+}
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetSecretVaults(t *testing.T) {
+	cases := []struct {
+		testDescription string
+		cfg             ReconcileConfig
+		expectedResult  []SecretVault
+		expectedError   string
+	}{
+		{
+			testDescription: "no secret vaults",
+			cfg:             ReconcileConfig{},
+			expectedResult:  []SecretVault{},
+		},
+		{
+			testDescription: "all secret vault types",
+			cfg: ReconcileConfig{
+				SecretVaults: []string{
+					"name=other;type=keyvault;keyVaultName=other-kv",
+					"name=vault;type=hashicorp-vault;address=https://vault.example.com/;token=ze-token",
+					"name=team;type=hashicorp-vault;address=https://vault.example.com;token=ze-token;path=/team/",
+					"name=sops;type=sops;path=secrets/secrets.enc.yaml;ageKey=AGE-SECRET-KEY-1FOO",
+				},
+			},
+			expectedResult: []SecretVault{
+				{Name: "other", Type: SecretVaultTypeKeyVault, KeyVaultName: "other-kv"},
+				{Name: "vault", Type: SecretVaultTypeHashicorpVault, Address: "https://vault.example.com", Mount: "secret", Token: "ze-token", Field: "value"},
+				{Name: "team", Type: SecretVaultTypeHashicorpVault, Address: "https://vault.example.com", Mount: "secret", Path: "team", Token: "ze-token", Field: "value"},
+				{Name: "sops", Type: SecretVaultTypeSops, Path: "secrets/secrets.enc.yaml", AgeKey: "AGE-SECRET-KEY-1FOO"},
+			},
+		},
+		{
+			testDescription: "missing keyVaultName",
+			cfg: ReconcileConfig{
+				SecretVaults: []string{"name=other;type=keyvault"},
+			},
+			expectedError: "keyVaultName needs to be set for secret vault \"other\"",
+		},
+		{
+			testDescription: "name with slash",
+			cfg: ReconcileConfig{
+				SecretVaults: []string{"name=foo/bar;type=keyvault;keyVaultName=other-kv"},
+			},
+			expectedError: "secret vault name \"foo/bar\" can't contain /",
+		},
+		{
+			testDescription: "unknown type",
+			cfg: ReconcileConfig{
+				SecretVaults: []string{"name=foo;type=bar"},
+			},
+			expectedError: "secret vault \"foo\" has unknown type \"bar\"",
+		},
+		{
+			testDescription: "duplicate name",
+			cfg: ReconcileConfig{
+				SecretVaults: []string{
+					"name=foo;type=keyvault;keyVaultName=foo-kv",
+					"name=foo;type=keyvault;keyVaultName=bar-kv",
+				},
+			},
+			expectedError: "secret vault \"foo\" is configured more than once",
+		},
+	}
+
+	for i, c := range cases {
+		t.Logf("Test #%d: %s", i, c.testDescription)
+		vaults, err := c.cfg.GetSecretVaults()
+		if c.expectedError != "" {
+			require.ErrorContains(t, err, c.expectedError)
+			continue
+		}
+		require.NoError(t, err)
+		require.Equal(t, c.expectedResult, vaults)
+	}
+}
+
+func TestRedactSecretVaults(t *testing.T) {
+	cfg := ReconcileConfig{
+		SecretVaults: []string{
+			"name=vault;type=hashicorp-vault;address=https://vault.example.com;token=ze-token",
+			"name=sops;type=sops;path=secrets.enc.yaml;ageKey=AGE-SECRET-KEY-1FOO",
+		},
+	}
+	require.Equal(t, []string{
+		"name=vault;type=hashicorp-vault;address=https://vault.example.com;token=redacted",
+		"name=sops;type=sops;path=secrets.enc.yaml;ageKey=redacted",
+	}, cfg.Redacted().SecretVaults)
+}
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Target is a resource group reconciled by azcagit, the default target is configured with --resource-group-name
+// and the additional targets with --targets
+type Target struct {
+	ResourceGroupName    string
+	SubscriptionID       string
+	ManagedEnvironmentID string
+	// Path is the directory, relative to the yaml path, with the manifests of the target
+	Path string
+	// Default is true for the target configured with --resource-group-name, which has the manifests not
+	// targeting another resource group
+	Default bool
+	// SharedEnvironmentWith is the resource group of the earlier target using the same managed environment,
+	// the dapr components, certificates and storages of the environment are only reconciled by that target
+	SharedEnvironmentWith string
+}
+
+// GetTargets returns the default target followed by the additional targets, which can be used by manifests
+// with metadata.resourceGroup or the path of the target
+func (cfg *ReconcileConfig) GetTargets() ([]Target, error) {
+	defaultTarget := Target{
+		ResourceGroupName:    cfg.ResourceGroupName,
+		SubscriptionID:       cfg.SubscriptionID,
+		ManagedEnvironmentID: cfg.ManagedEnvironmentID,
+		Default:              true,
+	}
+	if cfg.defaultTarget != nil {
+		defaultTarget = *cfg.defaultTarget
+	}
+
+	targets := []Target{defaultTarget}
+	for _, s := range cfg.Targets {
+		target, err := parseTarget(s, defaultTarget)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, existing := range targets {
+			if strings.EqualFold(existing.ResourceGroupName, target.ResourceGroupName) {
+				return nil, fmt.Errorf("target resource group %q is configured more than once", target.ResourceGroupName)
+			}
+			if target.Path != "" && existing.Path == target.Path {
+				return nil, fmt.Errorf("target path %q is used by both resource group %q and %q", target.Path, existing.ResourceGroupName, target.ResourceGroupName)
+			}
+			if target.SharedEnvironmentWith == "" && strings.EqualFold(existing.ManagedEnvironmentID, target.ManagedEnvironmentID) {
+				target.SharedEnvironmentWith = existing.ResourceGroupName
+			}
+		}
+
+		targets = append(targets, target)
+	}
+
+	return targets, nil
+}
+
+// ForTarget returns the configuration used to reconcile the target
+func (cfg ReconcileConfig) ForTarget(target Target) ReconcileConfig {
+	if cfg.defaultTarget == nil {
+		cfg.defaultTarget = &Target{
+			ResourceGroupName:    cfg.ResourceGroupName,
+			SubscriptionID:       cfg.SubscriptionID,
+			ManagedEnvironmentID: cfg.ManagedEnvironmentID,
+			Default:              true,
+		}
+	}
+
+	cfg.ResourceGroupName = target.ResourceGroupName
+	cfg.SubscriptionID = target.SubscriptionID
+	cfg.ManagedEnvironmentID = target.ManagedEnvironmentID
+	cfg.sharedEnvironmentWith = target.SharedEnvironmentWith
+
+	return cfg
+}
+
+// SharedEnvironmentWith returns the resource group of the target reconciling the managed environment, empty if
+// the configuration reconciles it
+func (cfg ReconcileConfig) SharedEnvironmentWith() string {
+	return cfg.sharedEnvironmentWith
+}
+
+func parseTarget(s string, defaultTarget Target) (Target, error) {
+	target := Target{
+		SubscriptionID:       defaultTarget.SubscriptionID,
+		ManagedEnvironmentID: defaultTarget.ManagedEnvironmentID,
+	}
+	err := parseKeyValues(s, func(key string, value string) bool {
+		switch key {
+		case "resourceGroup":
+			target.ResourceGroupName = strings.TrimSpace(value)
+		case "subscriptionID":
+			target.SubscriptionID = strings.TrimSpace(value)
+		case "managedEnvironmentID":
+			target.ManagedEnvironmentID = strings.TrimSpace(value)
+		case "path":
+			target.Path = strings.TrimSpace(value)
+		default:
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return Target{}, fmt.Errorf("unable to parse target, %w", err)
+	}
+
+	if target.ResourceGroupName == "" {
+		return Target{}, fmt.Errorf("resourceGroup needs to be set for target")
+	}
+
+	if target.Path != "" {
+		target.Path = path.Clean(strings.Trim(target.Path, "/"))
+		if target.Path == "." || target.Path == ".." || strings.HasPrefix(target.Path, "../") {
+			return Target{}, fmt.Errorf("path %q of target %q needs to be a directory in the yaml path", target.Path, target.ResourceGroupName)
+		}
+	}
+
+	return target, nil
+}
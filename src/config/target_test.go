@@ -0,0 +1,110 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetTargets(t *testing.T) {
+	defaultCfg := ReconcileConfig{
+		ResourceGroupName:    "rg-default",
+		SubscriptionID:       "ze-sub",
+		ManagedEnvironmentID: "ze-me",
+	}
+	defaultTarget := Target{ResourceGroupName: "rg-default", SubscriptionID: "ze-sub", ManagedEnvironmentID: "ze-me", Default: true}
+
+	cases := []struct {
+		testDescription string
+		targets         []string
+		expectedResult  []Target
+		expectedError   string
+	}{
+		{
+			testDescription: "only default target",
+			expectedResult:  []Target{defaultTarget},
+		},
+		{
+			testDescription: "targets with shared and own managed environment",
+			targets: []string{
+				"resourceGroup=rg-foo;path=/tenants/foo/",
+				"resourceGroup=rg-bar;subscriptionID=other-sub;managedEnvironmentID=other-me",
+				"resourceGroup=rg-baz;managedEnvironmentID=other-me;path=tenants/baz",
+			},
+			expectedResult: []Target{
+				defaultTarget,
+				{ResourceGroupName: "rg-foo", SubscriptionID: "ze-sub", ManagedEnvironmentID: "ze-me", Path: "tenants/foo", SharedEnvironmentWith: "rg-default"},
+				{ResourceGroupName: "rg-bar", SubscriptionID: "other-sub", ManagedEnvironmentID: "other-me"},
+				{ResourceGroupName: "rg-baz", SubscriptionID: "ze-sub", ManagedEnvironmentID: "other-me", Path: "tenants/baz", SharedEnvironmentWith: "rg-bar"},
+			},
+		},
+		{
+			testDescription: "duplicate resource group",
+			targets:         []string{"resourceGroup=RG-Default"},
+			expectedError:   "target resource group \"RG-Default\" is configured more than once",
+		},
+		{
+			testDescription: "duplicate path",
+			targets:         []string{"resourceGroup=rg-foo;path=foo", "resourceGroup=rg-bar;path=foo/"},
+			expectedError:   "target path \"foo\" is used by both resource group \"rg-foo\" and \"rg-bar\"",
+		},
+		{
+			testDescription: "path outside the yaml path",
+			targets:         []string{"resourceGroup=rg-foo;path=../foo"},
+			expectedError:   "path \"../foo\" of target \"rg-foo\" needs to be a directory in the yaml path",
+		},
+		{
+			testDescription: "missing resource group",
+			targets:         []string{"path=foo"},
+			expectedError:   "resourceGroup needs to be set for target",
+		},
+		{
+			testDescription: "unknown key",
+			targets:         []string{"resourceGroup=rg-foo;location=westeurope"},
+			expectedError:   "unable to parse target, unknown key \"location\"",
+		},
+	}
+
+	for i, c := range cases {
+		t.Logf("Test #%d: %s", i, c.testDescription)
+		cfg := defaultCfg
+		cfg.Targets = c.targets
+		targets, err := cfg.GetTargets()
+		if c.expectedError != "" {
+			require.ErrorContains(t, err, c.expectedError)
+			continue
+		}
+		require.NoError(t, err)
+		require.Equal(t, c.expectedResult, targets)
+	}
+}
+
+func TestForTarget(t *testing.T) {
+	cfg := ReconcileConfig{
+		ResourceGroupName:    "rg-default",
+		SubscriptionID:       "ze-sub",
+		ManagedEnvironmentID: "ze-me",
+		Targets:              []string{"resourceGroup=rg-foo;subscriptionID=other-sub;managedEnvironmentID=other-me"},
+	}
+
+	targets, err := cfg.GetTargets()
+	require.NoError(t, err)
+
+	targetCfg := cfg.ForTarget(targets[1])
+	require.Equal(t, "rg-foo", targetCfg.ResourceGroupName)
+	require.Equal(t, "other-sub", targetCfg.SubscriptionID)
+	require.Equal(t, "other-me", targetCfg.ManagedEnvironmentID)
+	require.Equal(t, "rg-default", cfg.ResourceGroupName)
+	require.Empty(t, targetCfg.SharedEnvironmentWith())
+
+	// the targets are the same for the configuration of a target
+	targetTargets, err := targetCfg.GetTargets()
+	require.NoError(t, err)
+	require.Equal(t, targets, targetTargets)
+
+	cfg.Targets = []string{"resourceGroup=rg-bar"}
+	targets, err = cfg.GetTargets()
+	require.NoError(t, err)
+	require.Equal(t, "rg-default", cfg.ForTarget(targets[1]).SharedEnvironmentWith())
+	require.Empty(t, cfg.ForTarget(targets[0]).SharedEnvironmentWith())
+}
@@ -0,0 +1,207 @@
+package history
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+type ResourceKind string
+
+const (
+	ResourceKindApp           ResourceKind = "app"
+	ResourceKindJob           ResourceKind = "job"
+	ResourceKindDaprComponent ResourceKind = "dapr-component"
+	ResourceKindCertificate   ResourceKind = "certificate"
+	ResourceKindStorage       ResourceKind = "storage"
+)
+
+type ActionType string
+
+const (
+	ActionTypeCreate  ActionType = "create"
+	ActionTypeUpdate  ActionType = "update"
+	ActionTypeDelete  ActionType = "delete"
+	ActionTypeRestart ActionType = "restart"
+)
+
+// Action is a change (or failed change) of a resource made by a reconcile run
+type Action struct {
+	Kind   ResourceKind `json:"kind"`
+	Name   string       `json:"name"`
+	Action ActionType   `json:"action"`
+	Reason string       `json:"reason,omitempty"`
+	Error  string       `json:"error,omitempty"`
+}
+
+// Run is the record of a reconcile run
+type Run struct {
+	Number    int       `json:"number"`
+	Revision  string    `json:"revision"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	NoOp      bool      `json:"noop,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	Actions   []Action  `json:"actions,omitempty"`
+	// NoOpRuns is the number of consecutive no-op runs collapsed into the run and LastEndTime the end
+	// time of the last one, both are only set for collapsed runs
+	NoOpRuns    int       `json:"noop_runs,omitempty"`
+	LastEndTime time.Time `json:"last_end_time,omitempty"`
+}
+
+// Collapse adds the next run to the run if both are successful no-op runs of the same revision, so
+// only one entry is kept while nothing changes. It returns false if the next run can't be collapsed.
+func (run *Run) Collapse(next Run) bool {
+	if !run.NoOp || !next.NoOp || run.Error != "" || next.Error != "" || run.Revision != next.Revision {
+		return false
+	}
+
+	if run.NoOpRuns == 0 {
+		run.NoOpRuns = 1
+	}
+	run.NoOpRuns++
+	run.LastEndTime = next.EndTime
+
+	return true
+}
+
+func (run *Run) AddAction(kind ResourceKind, name string, action ActionType, reason string, err error) {
+	errString := ""
+	if err != nil {
+		errString = err.Error()
+	}
+
+	run.Actions = append(run.Actions, Action{
+		Kind:   kind,
+		Name:   name,
+		Action: action,
+		Reason: reason,
+		Error:  errString,
+	})
+}
+
+func (run Run) Result() string {
+	switch {
+	case run.Error != "":
+		return "failed"
+	case run.NoOp && run.NoOpRuns > 1:
+		return fmt.Sprintf("no-op (%d runs, last %s)", run.NoOpRuns, run.LastEndTime.UTC().Format(time.RFC3339))
+	case run.NoOp:
+		return "no-op"
+	}
+
+	return "succeeded"
+}
+
+// ActionsFor returns the actions of the resource with the name, regardless of the kind
+func (run Run) ActionsFor(name string) []Action {
+	actions := []Action{}
+	for _, action := range run.Actions {
+		if action.Name == name {
+			actions = append(actions, action)
+		}
+	}
+
+	return actions
+}
+
+// FilterRuns returns the runs changing the resource with the name, the runs should be sorted with the newest first
+func FilterRuns(runs []Run, name string, limit int) []Run {
+	filtered := []Run{}
+	for _, run := range runs {
+		if limit > 0 && len(filtered) >= limit {
+			break
+		}
+
+		if len(run.ActionsFor(name)) == 0 {
+			continue
+		}
+
+		filtered = append(filtered, run)
+	}
+
+	return filtered
+}
+
+// PrintRuns writes a table with a line per run
+func PrintRuns(w io.Writer, runs []Run) error {
+	buf := &bytes.Buffer{}
+	tw := tabwriter.NewWriter(buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "RUN\tSTARTED\tDURATION\tREVISION\tRESULT\tACTIONS\tERROR")
+	for _, run := range runs {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n", run.Number, run.StartTime.UTC().Format(time.RFC3339), run.EndTime.Sub(run.StartTime).Round(time.Second), shortRevision(run.Revision), run.Result(), summarizeActions(run.Actions), firstLine(run.Error))
+	}
+
+	return writeTable(w, tw, buf)
+}
+
+// PrintResourceRuns writes a table with a line per action on the resource with the name
+func PrintResourceRuns(w io.Writer, runs []Run, name string) error {
+	buf := &bytes.Buffer{}
+	tw := tabwriter.NewWriter(buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "RUN\tSTARTED\tREVISION\tKIND\tACTION\tREASON\tERROR")
+	for _, run := range runs {
+		for _, action := range run.ActionsFor(name) {
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n", run.Number, run.StartTime.UTC().Format(time.RFC3339), shortRevision(run.Revision), action.Kind, action.Action, action.Reason, firstLine(action.Error))
+		}
+	}
+
+	return writeTable(w, tw, buf)
+}
+
+// writeTable removes the padding after the last column, added when it's empty
+func writeTable(w io.Writer, tw *tabwriter.Writer, buf *bytes.Buffer) error {
+	err := tw.Flush()
+	if err != nil {
+		return err
+	}
+
+	for _, line := range strings.SplitAfter(buf.String(), "\n") {
+		if line == "" {
+			continue
+		}
+		_, err := io.WriteString(w, strings.TrimRight(line, " \n")+"\n")
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func summarizeActions(actions []Action) string {
+	if len(actions) == 0 {
+		return "-"
+	}
+
+	counts := make(map[ActionType]int)
+	for _, action := range actions {
+		counts[action.Action]++
+	}
+
+	parts := []string{}
+	for _, actionType := range []ActionType{ActionTypeCreate, ActionTypeUpdate, ActionTypeDelete, ActionTypeRestart} {
+		if counts[actionType] == 0 {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s=%d", actionType, counts[actionType]))
+	}
+
+	return strings.Join(parts, ",")
+}
+
+func shortRevision(revision string) string {
+	if len(revision) > 7 {
+		return revision[:7]
+	}
+
+	return revision
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
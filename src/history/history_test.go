@@ -0,0 +1,113 @@
+package history
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testRuns() []Run {
+	startTime := time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+	failed := Run{
+		Number:    3,
+		Revision:  "6ffa5a7b2da7dc37e186e2581a903e325bbd38be",
+		StartTime: startTime.Add(2 * time.Minute),
+		EndTime:   startTime.Add(2*time.Minute + 5*time.Second),
+		Error:     "sourceApps error: failed to update foo: update foobar\nmore details",
+	}
+	failed.AddAction(ResourceKindApp, "foo", ActionTypeUpdate, "changed sourceApp hash", fmt.Errorf("update foobar"))
+
+	noop := Run{
+		Number:    2,
+		Revision:  "6ffa5a7b2da7dc37e186e2581a903e325bbd38be",
+		StartTime: startTime.Add(time.Minute),
+		EndTime:   startTime.Add(time.Minute + time.Second),
+		NoOp:      true,
+	}
+
+	succeeded := Run{
+		Number:    1,
+		Revision:  "1d0e14be",
+		StartTime: startTime,
+		EndTime:   startTime.Add(30 * time.Second),
+	}
+	succeeded.AddAction(ResourceKindApp, "foo", ActionTypeCreate, "not in AppCache", nil)
+	succeeded.AddAction(ResourceKindJob, "bar", ActionTypeCreate, "not in JobCache", nil)
+	succeeded.AddAction(ResourceKindApp, "baz", ActionTypeDelete, "not in source", nil)
+
+	return []Run{failed, noop, succeeded}
+}
+
+func TestRunResult(t *testing.T) {
+	runs := testRuns()
+	require.Equal(t, "failed", runs[0].Result())
+	require.Equal(t, "no-op", runs[1].Result())
+	require.Equal(t, "succeeded", runs[2].Result())
+}
+
+func TestCollapse(t *testing.T) {
+	runs := testRuns()
+	noop := runs[1]
+	next := Run{
+		Revision:  noop.Revision,
+		StartTime: noop.EndTime.Add(time.Minute),
+		EndTime:   noop.EndTime.Add(time.Minute + time.Second),
+		NoOp:      true,
+	}
+
+	require.True(t, noop.Collapse(next))
+	require.True(t, noop.Collapse(next))
+	require.Equal(t, 3, noop.NoOpRuns)
+	require.Equal(t, next.EndTime, noop.LastEndTime)
+	require.Equal(t, "no-op (3 runs, last 2023-10-01T12:02:02Z)", noop.Result())
+
+	otherRevision := next
+	otherRevision.Revision = "1d0e14be"
+	require.False(t, noop.Collapse(otherRevision))
+
+	failed := next
+	failed.Error = "foobar"
+	require.False(t, noop.Collapse(failed))
+
+	require.False(t, runs[0].Collapse(next))
+	require.False(t, runs[2].Collapse(next))
+}
+
+func TestFilterRuns(t *testing.T) {
+	runs := testRuns()
+
+	fooRuns := FilterRuns(runs, "foo", 0)
+	require.Len(t, fooRuns, 2)
+	require.Equal(t, 3, fooRuns[0].Number)
+	require.Equal(t, 1, fooRuns[1].Number)
+
+	fooRuns = FilterRuns(runs, "foo", 1)
+	require.Len(t, fooRuns, 1)
+	require.Equal(t, 3, fooRuns[0].Number)
+
+	require.Empty(t, FilterRuns(runs, "qux", 0))
+}
+
+func TestPrintRuns(t *testing.T) {
+	buf := &bytes.Buffer{}
+	err := PrintRuns(buf, testRuns())
+	require.NoError(t, err)
+	require.Equal(t, `RUN  STARTED               DURATION  REVISION  RESULT     ACTIONS            ERROR
+3    2023-10-01T12:02:00Z  5s        6ffa5a7   failed     update=1           sourceApps error: failed to update foo: update foobar
+2    2023-10-01T12:01:00Z  1s        6ffa5a7   no-op      -
+1    2023-10-01T12:00:00Z  30s       1d0e14b   succeeded  create=2,delete=1
+`, buf.String())
+}
+
+func TestPrintResourceRuns(t *testing.T) {
+	buf := &bytes.Buffer{}
+	err := PrintResourceRuns(buf, FilterRuns(testRuns(), "foo", 0), "foo")
+	require.NoError(t, err)
+	require.Equal(t, `RUN  STARTED               REVISION  KIND  ACTION  REASON                  ERROR
+3    2023-10-01T12:02:00Z  6ffa5a7   app   update  changed sourceApp hash  update foobar
+1    2023-10-01T12:00:00Z  1d0e14b   app   create  not in AppCache
+`, buf.String())
+}
@@ -0,0 +1,227 @@
+package imageupdate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fluxcd/pkg/git"
+	"github.com/fluxcd/pkg/git/gogit"
+	"github.com/fluxcd/pkg/git/repository"
+	"github.com/go-logr/logr"
+	"github.com/xenitab/azcagit/src/config"
+	"github.com/xenitab/azcagit/src/registry"
+)
+
+type ImageUpdater struct {
+	cfg         config.ImageUpdateConfig
+	registry    registry.Registry
+	pullRequest PullRequest
+}
+
+func NewImageUpdater(cfg config.ImageUpdateConfig, reg registry.Registry, pullRequest PullRequest) (*ImageUpdater, error) {
+	if cfg.PullRequestEnabled && (cfg.GitPushBranch == "" || cfg.GitPushBranch == cfg.GitBranch) {
+		return nil, fmt.Errorf("pull requests require a push branch separate from the git branch")
+	}
+
+	return &ImageUpdater{
+		cfg,
+		reg,
+		pullRequest,
+	}, nil
+}
+
+// Run checks out the git branch, updates the image tags based on the image policies and
+// pushes a commit with the changes. It returns the updates that were made.
+func (u *ImageUpdater) Run(ctx context.Context) ([]ImageUpdate, error) {
+	log := logr.FromContextOrDiscard(ctx)
+
+	tmpDir, err := os.MkdirTemp(u.cfg.CheckoutPath, "azcagit*")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err := os.RemoveAll(tmpDir)
+		if err != nil {
+			log.Error(err, "failed to remove temporary working directory", "tmp_dir", tmpDir)
+		}
+	}()
+
+	gitClient, err := u.clone(ctx, tmpDir)
+	if err != nil {
+		return nil, err
+	}
+	defer gitClient.Close()
+
+	files, err := listYamlFiles(filepath.Join(tmpDir, u.cfg.GitYamlPath))
+	if err != nil {
+		return nil, err
+	}
+
+	updatedFiles, updates, err := findImageUpdates(ctx, u.registry, files)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, update := range updates {
+		log.Info("image update", "path", update.Path, "image", update.ImageName, "old_tag", update.OldTag, "new_tag", update.NewTag)
+	}
+
+	if len(updates) == 0 {
+		log.Info("no image updates found")
+		return updates, nil
+	}
+
+	if u.cfg.DryRun {
+		log.Info("dry run enabled, skipping commit")
+		return updates, nil
+	}
+
+	pushBranch := u.cfg.GitBranch
+	if u.cfg.GitPushBranch != "" && u.cfg.GitPushBranch != u.cfg.GitBranch {
+		pushBranch = u.cfg.GitPushBranch
+		err := gitClient.SwitchBranch(ctx, pushBranch)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	commitFiles := make(map[string]io.Reader)
+	for path, content := range updatedFiles {
+		commitFiles[filepath.ToSlash(filepath.Join(u.cfg.GitYamlPath, path))] = strings.NewReader(string(content))
+	}
+
+	title := commitTitle(updates)
+	revision, err := gitClient.Commit(
+		git.Commit{
+			Author: git.Signature{
+				Name:  u.cfg.GitAuthorName,
+				Email: u.cfg.GitAuthorEmail,
+			},
+			Message: fmt.Sprintf("%s\n\n%s", title, commitBody(updates)),
+		},
+		repository.WithFiles(commitFiles),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// the push branch is always recreated from the git branch, so it has to be force pushed
+	err = gitClient.Push(ctx, repository.PushConfig{
+		Force: pushBranch != u.cfg.GitBranch,
+	})
+	if err != nil {
+		return nil, redactGitSecret(u.cfg.GitUrl, err)
+	}
+
+	log.Info("pushed image updates", "branch", pushBranch, "revision", revision)
+
+	if u.cfg.PullRequestEnabled {
+		err := u.pullRequest.Open(ctx, pushBranch, u.cfg.GitBranch, title, commitBody(updates))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return updates, nil
+}
+
+func (u *ImageUpdater) clone(ctx context.Context, tmpDir string) (*gogit.Client, error) {
+	gitUrl, err := url.Parse(u.cfg.GitUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	authOpts, err := git.NewAuthOptions(*gitUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	clientOpts := []gogit.ClientOption{gogit.WithDiskStorage()}
+	if authOpts.Transport == git.HTTP {
+		clientOpts = append(clientOpts, gogit.WithInsecureCredentialsOverHTTP())
+	}
+
+	gitClient, err := gogit.NewClient(tmpDir, authOpts, clientOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = gitClient.Clone(ctx, u.cfg.GitUrl, repository.CloneConfig{
+		CheckoutStrategy: repository.CheckoutStrategy{
+			Branch: u.cfg.GitBranch,
+		},
+	})
+	if err != nil {
+		gitClient.Close()
+		return nil, redactGitSecret(u.cfg.GitUrl, err)
+	}
+
+	return gitClient, nil
+}
+
+func commitTitle(updates []ImageUpdate) string {
+	if len(updates) == 1 {
+		return fmt.Sprintf("Update %s to %s", updates[0].ImageName, updates[0].NewTag)
+	}
+
+	return fmt.Sprintf("Update %d images", len(updates))
+}
+
+func commitBody(updates []ImageUpdate) string {
+	lines := []string{}
+	for _, update := range updates {
+		lines = append(lines, fmt.Sprintf("- %s", update.String()))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func listYamlFiles(path string) (map[string][]byte, error) {
+	fsys := os.DirFS(path)
+	files := make(map[string][]byte)
+	err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		switch {
+		case err != nil:
+			return err
+		case d.IsDir():
+			return nil
+		case !strings.HasSuffix(path, ".yaml"):
+			return nil
+		}
+
+		b, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return err
+		}
+
+		files[path] = b
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+func redactGitSecret(gitUrl string, inputErr error) error {
+	parsedGitUrl, err := url.Parse(gitUrl)
+	if err != nil {
+		return inputErr
+	}
+
+	gitSecret, ok := parsedGitUrl.User.Password()
+	if !ok || gitSecret == "" {
+		return inputErr
+	}
+
+	return errors.New(strings.ReplaceAll(inputErr.Error(), gitSecret, "redacted"))
+}
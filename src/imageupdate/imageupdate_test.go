@@ -0,0 +1,140 @@
+package imageupdate
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fluxcd/pkg/git"
+	gg "github.com/fluxcd/pkg/git/gogit"
+	"github.com/fluxcd/pkg/git/repository"
+	"github.com/fluxcd/pkg/gittestserver"
+	"github.com/stretchr/testify/require"
+	"github.com/xenitab/azcagit/src/config"
+	"github.com/xenitab/azcagit/src/registry"
+)
+
+const testFixtureYAML = `kind: AzureContainerApp
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+  name: foo
+spec:
+  replacements:
+    images:
+      - imageName: foo
+        newImageTag: 1.0.0
+        policy:
+          semver: "^1.0.0"
+  app:
+    properties:
+      template:
+        containers:
+        - name: foo
+          image: foo:latest
+`
+
+type testPullRequest struct {
+	head string
+	base string
+}
+
+func (p *testPullRequest) Open(ctx context.Context, head string, base string, title string, body string) error {
+	p.head = head
+	p.base = base
+	return nil
+}
+
+func TestImageUpdater(t *testing.T) {
+	server, err := gittestserver.NewTempGitServer()
+	require.NoError(t, err)
+	defer os.RemoveAll(server.Root())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err = server.StartHTTP()
+	require.NoError(t, err)
+	defer server.StopHTTP()
+
+	repoPath := "test.git"
+	defaultBranch := "master"
+
+	tmpFixtureDir := t.TempDir()
+	err = os.MkdirAll(filepath.Join(tmpFixtureDir, "apps"), 0700)
+	require.NoError(t, err)
+	err = os.WriteFile(filepath.Join(tmpFixtureDir, "apps", "foo.yaml"), []byte(testFixtureYAML), 0600)
+	require.NoError(t, err)
+	err = server.InitRepo(tmpFixtureDir, defaultBranch, repoPath)
+	require.NoError(t, err)
+
+	repoURL := server.HTTPAddress() + "/" + repoPath
+
+	reg := registry.NewInMemRegistry()
+	reg.SetTag("foo", "1.0.0", "sha256:a", time.Now())
+
+	cfg := config.ImageUpdateConfig{
+		CheckoutPath:   t.TempDir(),
+		GitUrl:         repoURL,
+		GitBranch:      defaultBranch,
+		GitYamlPath:    "apps",
+		GitAuthorName:  "azcagit",
+		GitAuthorEmail: "azcagit@example.com",
+	}
+
+	updater, err := NewImageUpdater(cfg, reg, NewDiscardPullRequest())
+	require.NoError(t, err)
+
+	updates, err := updater.Run(ctx)
+	require.NoError(t, err)
+	require.Empty(t, updates)
+
+	reg.SetTag("foo", "1.1.0", "sha256:b", time.Now())
+	updates, err = updater.Run(ctx)
+	require.NoError(t, err)
+	require.Len(t, updates, 1)
+	require.Contains(t, testReadFile(t, ctx, repoURL, defaultBranch), "newImageTag: 1.1.0\n")
+
+	reg.SetTag("foo", "1.2.0", "sha256:c", time.Now())
+	pullRequest := &testPullRequest{}
+	cfg.GitPushBranch = "image-updates"
+	cfg.PullRequestEnabled = true
+	updater, err = NewImageUpdater(cfg, reg, pullRequest)
+	require.NoError(t, err)
+
+	updates, err = updater.Run(ctx)
+	require.NoError(t, err)
+	require.Len(t, updates, 1)
+	require.Contains(t, testReadFile(t, ctx, repoURL, defaultBranch), "newImageTag: 1.1.0\n")
+	require.Contains(t, testReadFile(t, ctx, repoURL, "image-updates"), "newImageTag: 1.2.0\n")
+	require.Equal(t, "image-updates", pullRequest.head)
+	require.Equal(t, defaultBranch, pullRequest.base)
+
+	cfg.GitPushBranch = ""
+	_, err = NewImageUpdater(cfg, reg, pullRequest)
+	require.ErrorContains(t, err, "pull requests require a push branch")
+}
+
+func testReadFile(t *testing.T, ctx context.Context, repoURL string, branch string) string {
+	t.Helper()
+
+	tmp := t.TempDir()
+	ggc, err := gg.NewClient(tmp, &git.AuthOptions{
+		Transport: git.HTTP,
+	})
+	require.NoError(t, err)
+	defer ggc.Close()
+
+	_, err = ggc.Clone(ctx, repoURL, repository.CloneConfig{
+		CheckoutStrategy: repository.CheckoutStrategy{
+			Branch: branch,
+		},
+	})
+	require.NoError(t, err)
+
+	b, err := os.ReadFile(filepath.Join(tmp, "apps", "foo.yaml"))
+	require.NoError(t, err)
+
+	return string(b)
+}
@@ -0,0 +1,107 @@
+package imageupdate
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/xenitab/azcagit/src/registry"
+	"github.com/xenitab/azcagit/src/source"
+)
+
+// selectTag returns the tag of the image that matches the policy best.
+func selectTag(ctx context.Context, reg registry.Registry, imageName string, policy source.ImagePolicySpecification) (string, error) {
+	err := policy.Validate()
+	if err != nil {
+		return "", err
+	}
+
+	tags, err := reg.ListTags(ctx, imageName)
+	if err != nil {
+		return "", err
+	}
+
+	if policy.Pattern != nil && *policy.Pattern != "" {
+		tags, err = filterTags(tags, *policy.Pattern)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if len(tags) == 0 {
+		return "", fmt.Errorf("no tags found for %s", imageName)
+	}
+
+	if policy.Semver != nil && *policy.Semver != "" {
+		return selectSemverTag(tags, *policy.Semver, imageName)
+	}
+
+	return selectLatestTag(ctx, reg, tags, imageName)
+}
+
+func filterTags(tags []string, pattern string) ([]string, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := []string{}
+	for _, tag := range tags {
+		if re.MatchString(tag) {
+			filtered = append(filtered, tag)
+		}
+	}
+
+	return filtered, nil
+}
+
+func selectSemverTag(tags []string, semverRange string, imageName string) (string, error) {
+	constraint, err := semver.NewConstraint(semverRange)
+	if err != nil {
+		return "", err
+	}
+
+	var latestVersion *semver.Version
+	latestTag := ""
+	for _, tag := range tags {
+		version, err := semver.NewVersion(tag)
+		if err != nil {
+			continue
+		}
+
+		if !constraint.Check(version) {
+			continue
+		}
+
+		if latestVersion == nil || version.GreaterThan(latestVersion) {
+			latestVersion = version
+			latestTag = tag
+		}
+	}
+
+	if latestTag == "" {
+		return "", fmt.Errorf("no tags for %s matches the semver range %q", imageName, semverRange)
+	}
+
+	return latestTag, nil
+}
+
+func selectLatestTag(ctx context.Context, reg registry.Registry, tags []string, imageName string) (string, error) {
+	latestCreated := time.Time{}
+	latestTag := ""
+	for _, tag := range tags {
+		created, err := reg.GetCreated(ctx, imageName, tag)
+		if err != nil {
+			return "", err
+		}
+
+		if latestTag == "" || created.After(latestCreated) {
+			latestCreated = created
+			latestTag = tag
+		}
+	}
+
+	return latestTag, nil
+}
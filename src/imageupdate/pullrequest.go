@@ -0,0 +1,97 @@
+package imageupdate
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/google/go-github/v41/github"
+	"golang.org/x/oauth2"
+)
+
+type PullRequest interface {
+	Open(ctx context.Context, head string, base string, title string, body string) error
+}
+
+type DiscardPullRequest struct{}
+
+var _ PullRequest = (*DiscardPullRequest)(nil)
+
+func NewDiscardPullRequest() *DiscardPullRequest {
+	return &DiscardPullRequest{}
+}
+
+func (p *DiscardPullRequest) Open(ctx context.Context, head string, base string, title string, body string) error {
+	return nil
+}
+
+type GitHubPullRequest struct {
+	owner  string
+	repo   string
+	client *github.Client
+}
+
+var _ PullRequest = (*GitHubPullRequest)(nil)
+
+func NewGitHubPullRequest(gitUrl string) (*GitHubPullRequest, error) {
+	parsedGitUrl, err := url.Parse(gitUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	if parsedGitUrl.Hostname() != "github.com" {
+		return nil, fmt.Errorf("pull requests are only supported for github.com, not: %s", parsedGitUrl.Hostname())
+	}
+
+	token, ok := parsedGitUrl.User.Password()
+	if !ok {
+		token = parsedGitUrl.User.Username()
+	}
+	if token == "" {
+		return nil, fmt.Errorf("unable to parse token from gitUrl")
+	}
+
+	id := strings.TrimSuffix(strings.TrimLeft(parsedGitUrl.Path, "/"), ".git")
+	comp := strings.Split(id, "/")
+	if len(comp) != 2 {
+		return nil, fmt.Errorf("invalid repository id %q", id)
+	}
+
+	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
+	tc := oauth2.NewClient(context.Background(), ts)
+
+	return &GitHubPullRequest{
+		owner:  comp[0],
+		repo:   comp[1],
+		client: github.NewClient(tc),
+	}, nil
+}
+
+// Open creates a pull request, unless there already is an open one for the head branch.
+func (p *GitHubPullRequest) Open(ctx context.Context, head string, base string, title string, body string) error {
+	existing, _, err := p.client.PullRequests.List(ctx, p.owner, p.repo, &github.PullRequestListOptions{
+		State: "open",
+		Head:  fmt.Sprintf("%s:%s", p.owner, head),
+		Base:  base,
+	})
+	if err != nil {
+		return fmt.Errorf("could not list pull requests: %w", err)
+	}
+
+	if len(existing) != 0 {
+		return nil
+	}
+
+	_, _, err = p.client.PullRequests.Create(ctx, p.owner, p.repo, &github.NewPullRequest{
+		Title: &title,
+		Head:  &head,
+		Base:  &base,
+		Body:  &body,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create pull request: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,239 @@
+package imageupdate
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/xenitab/azcagit/src/registry"
+	"github.com/xenitab/azcagit/src/source"
+	"gopkg.in/yaml.v3"
+)
+
+type ImageUpdate struct {
+	Path      string
+	ImageName string
+	OldTag    string
+	NewTag    string
+}
+
+func (u ImageUpdate) String() string {
+	return fmt.Sprintf("%s: %s %s -> %s", u.Path, u.ImageName, u.OldTag, u.NewTag)
+}
+
+type tagEdit struct {
+	line   int
+	column int
+	node   *yaml.Node
+	newTag string
+}
+
+// findImageUpdates looks for `spec.replacements.images` with a policy in all documents and returns
+// the files where newImageTag has been updated. Only the tag values are rewritten, so the rest of
+// the file (comments and formatting) is kept as is.
+func findImageUpdates(ctx context.Context, reg registry.Registry, files map[string][]byte) (map[string][]byte, []ImageUpdate, error) {
+	paths := []string{}
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	selectedTags := make(map[string]string)
+	updatedFiles := make(map[string][]byte)
+	updates := []ImageUpdate{}
+	for _, path := range paths {
+		content := files[path]
+		images, err := findPolicyImages(content)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to parse %s: %w", path, err)
+		}
+
+		edits := []tagEdit{}
+		for _, image := range images {
+			key := fmt.Sprintf("%s|%s|%s|%t", image.imageName, toString(image.policy.Semver), toString(image.policy.Pattern), image.policy.Latest != nil && *image.policy.Latest)
+			newTag, ok := selectedTags[key]
+			if !ok {
+				newTag, err = selectTag(ctx, reg, image.imageName, image.policy)
+				if err != nil {
+					return nil, nil, fmt.Errorf("unable to select tag for %s in %s: %w", image.imageName, path, err)
+				}
+				selectedTags[key] = newTag
+			}
+
+			if newTag == image.tagNode.Value {
+				continue
+			}
+
+			edits = append(edits, tagEdit{
+				line:   image.tagNode.Line,
+				column: image.tagNode.Column,
+				node:   image.tagNode,
+				newTag: newTag,
+			})
+			updates = append(updates, ImageUpdate{
+				Path:      path,
+				ImageName: image.imageName,
+				OldTag:    image.tagNode.Value,
+				NewTag:    newTag,
+			})
+		}
+
+		if len(edits) == 0 {
+			continue
+		}
+
+		updatedContent, err := applyTagEdits(content, edits)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to update %s: %w", path, err)
+		}
+		updatedFiles[path] = updatedContent
+	}
+
+	return updatedFiles, updates, nil
+}
+
+type policyImage struct {
+	imageName string
+	tagNode   *yaml.Node
+	policy    source.ImagePolicySpecification
+}
+
+func findPolicyImages(content []byte) ([]policyImage, error) {
+	images := []policyImage{}
+	dec := yaml.NewDecoder(bytes.NewReader(content))
+	for {
+		var doc yaml.Node
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if len(doc.Content) == 0 {
+			continue
+		}
+
+		imagesNode := lookupNode(doc.Content[0], "spec", "replacements", "images")
+		if imagesNode == nil || imagesNode.Kind != yaml.SequenceNode {
+			continue
+		}
+
+		for _, imageNode := range imagesNode.Content {
+			policyNode := lookupNode(imageNode, "policy")
+			if policyNode == nil {
+				continue
+			}
+
+			var policy source.ImagePolicySpecification
+			err := policyNode.Decode(&policy)
+			if err != nil {
+				return nil, err
+			}
+
+			imageNameNode := lookupNode(imageNode, "imageName")
+			if imageNameNode == nil || imageNameNode.Value == "" {
+				return nil, fmt.Errorf("image replacement with policy on line %d is missing imageName", imageNode.Line)
+			}
+
+			tagNode := lookupNode(imageNode, "newImageTag")
+			if tagNode == nil || tagNode.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("image replacement for %s with policy is missing newImageTag", imageNameNode.Value)
+			}
+
+			images = append(images, policyImage{
+				imageName: imageNameNode.Value,
+				tagNode:   tagNode,
+				policy:    policy,
+			})
+		}
+	}
+
+	return images, nil
+}
+
+func lookupNode(node *yaml.Node, keys ...string) *yaml.Node {
+	current := node
+	for _, key := range keys {
+		if current == nil || current.Kind != yaml.MappingNode {
+			return nil
+		}
+
+		var next *yaml.Node
+		for i := 0; i+1 < len(current.Content); i += 2 {
+			if current.Content[i].Value == key {
+				next = current.Content[i+1]
+				break
+			}
+		}
+		current = next
+	}
+
+	return current
+}
+
+func applyTagEdits(content []byte, edits []tagEdit) ([]byte, error) {
+	// edits are applied from the end so that the columns of the remaining edits stay valid
+	sort.Slice(edits, func(i, j int) bool {
+		if edits[i].line != edits[j].line {
+			return edits[i].line > edits[j].line
+		}
+		return edits[i].column > edits[j].column
+	})
+
+	lines := strings.SplitAfter(string(content), "\n")
+	for _, edit := range edits {
+		lineIdx := edit.line - 1
+		if lineIdx < 0 || lineIdx >= len(lines) {
+			return nil, fmt.Errorf("line %d out of range", edit.line)
+		}
+
+		line := lines[lineIdx]
+		start := edit.column - 1
+		oldToken, newToken := scalarTokens(edit.node, edit.newTag)
+		if start < 0 || start > len(line) || !strings.HasPrefix(line[start:], oldToken) {
+			return nil, fmt.Errorf("unable to find %q on line %d", oldToken, edit.line)
+		}
+
+		lines[lineIdx] = line[:start] + newToken + line[start+len(oldToken):]
+	}
+
+	return []byte(strings.Join(lines, "")), nil
+}
+
+func scalarTokens(node *yaml.Node, newValue string) (string, string) {
+	switch node.Style {
+	case yaml.DoubleQuotedStyle:
+		return fmt.Sprintf("%q", node.Value), fmt.Sprintf("%q", newValue)
+	case yaml.SingleQuotedStyle:
+		return fmt.Sprintf("'%s'", node.Value), fmt.Sprintf("'%s'", newValue)
+	default:
+		if !isPlainString(newValue) {
+			return node.Value, fmt.Sprintf("%q", newValue)
+		}
+		return node.Value, newValue
+	}
+}
+
+// isPlainString returns false for values that would change type (like 10 or true) if written unquoted
+func isPlainString(value string) bool {
+	var v interface{}
+	err := yaml.Unmarshal([]byte(value), &v)
+	if err != nil {
+		return false
+	}
+	_, ok := v.(string)
+	return ok
+}
+
+func toString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,172 @@
+package imageupdate
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xenitab/azcagit/src/registry"
+	"github.com/xenitab/azcagit/src/source"
+)
+
+func toPtr[T any](a T) *T {
+	return &a
+}
+
+func TestSelectTag(t *testing.T) {
+	reg := registry.NewInMemRegistry()
+	now := time.Now()
+	reg.SetTag("foo", "1.0.0", "sha256:a", now.Add(-3*time.Hour))
+	reg.SetTag("foo", "1.2.0", "sha256:b", now.Add(-1*time.Hour))
+	reg.SetTag("foo", "v1.10.1", "sha256:c", now.Add(-2*time.Hour))
+	reg.SetTag("foo", "2.0.0", "sha256:d", now.Add(-4*time.Hour))
+	reg.SetTag("foo", "main-abc", "sha256:e", now.Add(-5*time.Hour))
+	reg.SetTag("foo", "main-def", "sha256:f", now.Add(-30*time.Minute))
+
+	cases := []struct {
+		testDescription string
+		policy          source.ImagePolicySpecification
+		expectedTag     string
+		expectedError   string
+	}{
+		{
+			testDescription: "semver range",
+			policy:          source.ImagePolicySpecification{Semver: toPtr("^1.0.0")},
+			expectedTag:     "v1.10.1",
+		},
+		{
+			testDescription: "semver range with pattern",
+			policy:          source.ImagePolicySpecification{Semver: toPtr(">=1.0.0"), Pattern: toPtr(`^\d`)},
+			expectedTag:     "2.0.0",
+		},
+		{
+			testDescription: "latest",
+			policy:          source.ImagePolicySpecification{Latest: toPtr(true)},
+			expectedTag:     "main-def",
+		},
+		{
+			testDescription: "latest with pattern",
+			policy:          source.ImagePolicySpecification{Latest: toPtr(true), Pattern: toPtr(`^\d+\.\d+\.\d+$`)},
+			expectedTag:     "1.2.0",
+		},
+		{
+			testDescription: "no match",
+			policy:          source.ImagePolicySpecification{Semver: toPtr("^3.0.0")},
+			expectedError:   "no tags for foo matches the semver range",
+		},
+		{
+			testDescription: "invalid policy",
+			policy:          source.ImagePolicySpecification{},
+			expectedError:   "policy should have exactly one of semver or latest",
+		},
+	}
+
+	for i, c := range cases {
+		t.Logf("Test #%d: %s", i, c.testDescription)
+		tag, err := selectTag(context.Background(), reg, "foo", c.policy)
+		if c.expectedError != "" {
+			require.ErrorContains(t, err, c.expectedError)
+			continue
+		}
+		require.NoError(t, err)
+		require.Equal(t, c.expectedTag, tag)
+	}
+}
+
+func TestFindImageUpdates(t *testing.T) {
+	reg := registry.NewInMemRegistry()
+	reg.SetTag("myregistry:5000/foo", "1.0.0", "sha256:a", time.Now())
+	reg.SetTag("myregistry:5000/foo", "1.1.0", "sha256:b", time.Now())
+	reg.SetTag("bar", "10", "sha256:c", time.Now())
+	reg.SetTag("bar", "9", "sha256:d", time.Now().Add(-1*time.Hour))
+
+	files := map[string][]byte{
+		"app.yaml": []byte(`# keep this comment
+kind: AzureContainerApp
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+  name: foo
+spec:
+  replacements:
+    images:
+      - imageName: "myregistry:5000/foo"
+        newImageTag: "1.0.0" # {"policy": "semver"}
+        policy:
+          semver: "^1.0.0"
+      - imageName: bar
+        newImageTag: 9
+        policy:
+          latest: true
+  app:
+    properties: {}
+---
+kind: AzureContainerJob
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+  name: foo
+spec:
+  replacements:
+    images:
+      - imageName: myregistry:5000/foo
+        newImageTag: '1.0.0'
+        policy:
+          semver: "^1.0.0"
+`),
+		"unchanged.yaml": []byte(`kind: AzureContainerApp
+spec:
+  replacements:
+    images:
+      - imageName: bar
+        newImageTag: v1
+`),
+	}
+
+	updatedFiles, updates, err := findImageUpdates(context.Background(), reg, files)
+	require.NoError(t, err)
+	require.Len(t, updates, 3)
+	require.Equal(t, ImageUpdate{Path: "app.yaml", ImageName: "myregistry:5000/foo", OldTag: "1.0.0", NewTag: "1.1.0"}, updates[0])
+	require.Len(t, updatedFiles, 1)
+	require.Equal(t, `# keep this comment
+kind: AzureContainerApp
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+  name: foo
+spec:
+  replacements:
+    images:
+      - imageName: "myregistry:5000/foo"
+        newImageTag: "1.1.0" # {"policy": "semver"}
+        policy:
+          semver: "^1.0.0"
+      - imageName: bar
+        newImageTag: "10"
+        policy:
+          latest: true
+  app:
+    properties: {}
+---
+kind: AzureContainerJob
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+  name: foo
+spec:
+  replacements:
+    images:
+      - imageName: myregistry:5000/foo
+        newImageTag: '1.1.0'
+        policy:
+          semver: "^1.0.0"
+`, string(updatedFiles["app.yaml"]))
+
+	_, _, err = findImageUpdates(context.Background(), reg, map[string][]byte{
+		"missing.yaml": []byte(`spec:
+  replacements:
+    images:
+      - imageName: bar
+        policy:
+          latest: true
+`),
+	})
+	require.ErrorContains(t, err, "image replacement for bar with policy is missing newImageTag")
+}
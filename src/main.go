@@ -5,22 +5,30 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"time"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
 	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/appcontainers/armappcontainers/v2"
 	"github.com/go-logr/logr"
+	"github.com/hashicorp/go-multierror"
 	"github.com/xenitab/azcagit/src/azure"
 	"github.com/xenitab/azcagit/src/cache"
 	"github.com/xenitab/azcagit/src/config"
+	"github.com/xenitab/azcagit/src/history"
+	"github.com/xenitab/azcagit/src/imageupdate"
 	"github.com/xenitab/azcagit/src/logger"
 	"github.com/xenitab/azcagit/src/metrics"
 	"github.com/xenitab/azcagit/src/notification"
+	"github.com/xenitab/azcagit/src/preview"
 	"github.com/xenitab/azcagit/src/reconcile"
+	"github.com/xenitab/azcagit/src/registry"
 	"github.com/xenitab/azcagit/src/remote"
 	"github.com/xenitab/azcagit/src/secret"
 	"github.com/xenitab/azcagit/src/source"
+	"github.com/xenitab/azcagit/src/store"
 )
 
 func main() {
@@ -53,6 +61,12 @@ func run(ctx context.Context, cfg config.Config) error {
 		return runReconcile(ctx, *cfg.ReconcileCfg)
 	case cfg.TriggerCfg != nil:
 		return runTrigger(ctx, *cfg.TriggerCfg)
+	case cfg.ImageUpdateCfg != nil:
+		log.Info("image-update configuration loaded", "config", cfg.ImageUpdateCfg.Redacted())
+		return runImageUpdate(ctx, *cfg.ImageUpdateCfg)
+	case cfg.HistoryCfg != nil:
+		log.V(1).Info("history configuration loaded", "config", cfg.HistoryCfg.Redacted())
+		return runHistory(ctx, *cfg.HistoryCfg)
 	}
 
 	return fmt.Errorf("no subcommand executed")
@@ -64,17 +78,22 @@ func runReconcile(ctx context.Context, cfg config.ReconcileConfig) error {
 		return err
 	}
 
-	cosmosDBClient, err := azure.NewCosmosDBClient(cfg.CosmosDBAccount, cfg.CosmosDBSqlDb, cfg.CosmosDBCacheContainer, cred)
+	cacheStore, err := store.NewStore(cfg.CacheConfig, cred)
 	if err != nil {
 		return err
 	}
 
-	revisionCache, err := cache.NewCosmosDBRevisionCache(cfg, cosmosDBClient)
+	revisionCache, err := cache.NewStoreRevisionCache(cfg, cacheStore)
 	if err != nil {
 		return err
 	}
 
-	sourceClient, err := source.NewGitSource(cfg, revisionCache)
+	secretClient, err := secret.NewSecretClient(cfg, cred)
+	if err != nil {
+		return err
+	}
+
+	sourceClient, err := source.NewGitSource(cfg, revisionCache, secretClient)
 	if err != nil {
 		return err
 	}
@@ -94,11 +113,40 @@ func runReconcile(ctx context.Context, cfg config.ReconcileConfig) error {
 		return err
 	}
 
-	secretClient, err := secret.NewKeyVaultSecret(cfg, cred)
+	remoteDaprComponentClient, err := remote.NewAzureDaprComponent(cfg, cred)
+	if err != nil {
+		return err
+	}
+
+	remoteCertificateClient, err := remote.NewAzureCertificate(cfg, cred)
 	if err != nil {
 		return err
 	}
 
+	remoteStorageClient, err := remote.NewAzureStorage(cfg, cred)
+	if err != nil {
+		return err
+	}
+
+	containerRegistries, err := cfg.GetContainerRegistries()
+	if err != nil {
+		return err
+	}
+
+	registryCredentials := []registry.Credential{}
+	for _, containerRegistry := range containerRegistries {
+		if containerRegistry.UsesIdentity() {
+			continue
+		}
+		registryCredentials = append(registryCredentials, registry.Credential{
+			Server:   containerRegistry.Server,
+			Username: containerRegistry.Username,
+			Password: containerRegistry.Password,
+		})
+	}
+
+	registryClient := registry.NewHTTPRegistry(registryCredentials, cred)
+
 	notificationClient, err := notification.NewNotificationClient(cfg)
 	if err != nil {
 		return err
@@ -106,24 +154,54 @@ func runReconcile(ctx context.Context, cfg config.ReconcileConfig) error {
 
 	metricsClient := metrics.NewAzureMetrics(cfg, cred)
 
-	appCache, err := cache.NewCosmosDBAppCache(cfg, cosmosDBClient)
+	appCache, err := cache.NewStoreAppCache(cfg, cacheStore)
 	if err != nil {
 		return err
 	}
 
-	jobCache, err := cache.NewCosmosDBJobCache(cfg, cosmosDBClient)
+	jobCache, err := cache.NewStoreJobCache(cfg, cacheStore)
+	if err != nil {
+		return err
+	}
+
+	daprComponentCache, err := cache.NewStoreDaprComponentCache(cfg, cacheStore)
+	if err != nil {
+		return err
+	}
+
+	certificateCache, err := cache.NewStoreCertificateCache(cfg, cacheStore)
+	if err != nil {
+		return err
+	}
+
+	storageCache, err := cache.NewStoreStorageCache(cfg, cacheStore)
 	if err != nil {
 		return err
 	}
 
 	secretCache := cache.NewInMemSecretCache()
 
-	notificationCache, err := cache.NewCosmosDBNotificationCache(cfg, cosmosDBClient)
+	notificationCache, err := cache.NewStoreNotificationCache(cfg, cacheStore)
+	if err != nil {
+		return err
+	}
+
+	reconcileStateCache, err := cache.NewStoreReconcileStateCache(cfg, cacheStore)
+	if err != nil {
+		return err
+	}
+
+	secretFingerprintCache, err := cache.NewStoreSecretFingerprintCache(cfg, cacheStore)
 	if err != nil {
 		return err
 	}
 
-	reconciler, err := reconcile.NewReconciler(cfg, sourceClient, remoteAppClient, remoteJobClient, secretClient, notificationClient, metricsClient, appCache, jobCache, secretCache, notificationCache)
+	historyCache, err := cache.NewStoreHistoryCache(cacheStore, cfg.HistorySize)
+	if err != nil {
+		return err
+	}
+
+	reconciler, err := reconcile.NewReconciler(cfg, sourceClient, remoteAppClient, remoteJobClient, remoteDaprComponentClient, remoteCertificateClient, remoteStorageClient, secretClient, registryClient, notificationClient, metricsClient, appCache, jobCache, daprComponentCache, certificateCache, storageCache, secretCache, notificationCache, reconcileStateCache, secretFingerprintCache, historyCache)
 	if err != nil {
 		return err
 	}
@@ -131,12 +209,225 @@ func runReconcile(ctx context.Context, cfg config.ReconcileConfig) error {
 	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt)
 	defer cancel()
 
+	var result *multierror.Error
 	err = reconciler.Run(ctx)
 	if err != nil {
-		return fmt.Errorf("reconcile error: %w", err)
+		result = multierror.Append(fmt.Errorf("reconcile error: %w", err), result)
 	}
 
-	return nil
+	err = runTargets(ctx, cfg, cred, cacheStore, sourceClient, secretClient, registryClient, notificationClient)
+	if err != nil {
+		result = multierror.Append(err, result)
+	}
+
+	// previews are deployed even if the reconcile of the environment fails
+	if cfg.PreviewEnabled {
+		err := runPreviews(ctx, cfg, cacheStore, secretClient, remoteAppClient, remoteStorageClient, registryClient)
+		if err != nil {
+			result = multierror.Append(fmt.Errorf("preview error: %w", err), result)
+		}
+	}
+
+	return result.ErrorOrNil()
+}
+
+// runTargets reconciles the additional targets, each with its own remote clients and caches, using the checkout
+// of the source of the environment
+func runTargets(ctx context.Context, cfg config.ReconcileConfig, cred azcore.TokenCredential, cacheStore store.Store, sourceClient *source.GitSource, secretClient secret.Secret, registryClient registry.Registry, notificationClient notification.Notification) error {
+	targets, err := cfg.GetTargets()
+	if err != nil {
+		return err
+	}
+
+	var result *multierror.Error
+	for _, target := range targets[1:] {
+		reconciler, err := newTargetReconciler(cfg.ForTarget(target), target, cred, cacheStore, sourceClient, secretClient, registryClient, notificationClient)
+		if err != nil {
+			result = multierror.Append(fmt.Errorf("unable to create reconciler for target %s: %w", target.ResourceGroupName, err), result)
+			continue
+		}
+
+		err = reconciler.Run(ctx)
+		if err != nil {
+			result = multierror.Append(fmt.Errorf("reconcile error for target %s: %w", target.ResourceGroupName, err), result)
+		}
+	}
+
+	return result.ErrorOrNil()
+}
+
+// newTargetReconciler creates a reconciler for the resource group of the target, the dapr components and
+// certificates of a shared managed environment are reconciled by the target that first uses it
+func newTargetReconciler(cfg config.ReconcileConfig, target config.Target, cred azcore.TokenCredential, cacheStore store.Store, environmentSourceClient *source.GitSource, secretClient secret.Secret, registryClient registry.Registry, notificationClient notification.Notification) (*reconcile.Reconciler, error) {
+	targetStore := getTargetStore(cacheStore, target.ResourceGroupName)
+
+	revisionCache, err := cache.NewStoreRevisionCache(cfg, targetStore)
+	if err != nil {
+		return nil, err
+	}
+
+	sourceClient := environmentSourceClient.ForTarget(cfg, revisionCache)
+
+	remoteAppClient, err := remote.NewAzureApp(cfg, cred)
+	if err != nil {
+		return nil, err
+	}
+
+	remoteJobClient, err := remote.NewAzureJob(cfg, cred)
+	if err != nil {
+		return nil, err
+	}
+
+	azureCertificateClient, err := remote.NewAzureCertificate(cfg, cred)
+	if err != nil {
+		return nil, err
+	}
+
+	var remoteCertificateClient remote.Certificate = azureCertificateClient
+	remoteDaprComponentClient := remote.NewDiscardDaprComponent()
+	if target.SharedEnvironmentWith == "" {
+		remoteDaprComponentClient, err = remote.NewAzureDaprComponent(cfg, cred)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		// the custom domains of the target are bound to the certificates reconciled by the target owning the environment
+		remoteCertificateClient = remote.NewReadOnlyCertificate(azureCertificateClient)
+	}
+
+	remoteStorageClient, err := remote.NewAzureStorage(cfg, cred)
+	if err != nil {
+		return nil, err
+	}
+
+	appCache, err := cache.NewStoreAppCache(cfg, targetStore)
+	if err != nil {
+		return nil, err
+	}
+
+	jobCache, err := cache.NewStoreJobCache(cfg, targetStore)
+	if err != nil {
+		return nil, err
+	}
+
+	daprComponentCache, err := cache.NewStoreDaprComponentCache(cfg, targetStore)
+	if err != nil {
+		return nil, err
+	}
+
+	certificateCache, err := cache.NewStoreCertificateCache(cfg, targetStore)
+	if err != nil {
+		return nil, err
+	}
+
+	storageCache, err := cache.NewStoreStorageCache(cfg, targetStore)
+	if err != nil {
+		return nil, err
+	}
+
+	notificationCache, err := cache.NewStoreNotificationCache(cfg, targetStore)
+	if err != nil {
+		return nil, err
+	}
+
+	reconcileStateCache, err := cache.NewStoreReconcileStateCache(cfg, targetStore)
+	if err != nil {
+		return nil, err
+	}
+
+	secretFingerprintCache, err := cache.NewStoreSecretFingerprintCache(cfg, targetStore)
+	if err != nil {
+		return nil, err
+	}
+
+	historyCache, err := cache.NewStoreHistoryCache(targetStore, cfg.HistorySize)
+	if err != nil {
+		return nil, err
+	}
+
+	return reconcile.NewReconciler(cfg, sourceClient, remoteAppClient, remoteJobClient, remoteDaprComponentClient, remoteCertificateClient, remoteStorageClient, secretClient, registryClient, notificationClient, metrics.NewDiscardMetrics(), appCache, jobCache, daprComponentCache, certificateCache, storageCache, cache.NewInMemSecretCache(), notificationCache, reconcileStateCache, secretFingerprintCache, historyCache)
+}
+
+// getTargetStore returns the store used by the caches of an additional target
+func getTargetStore(cacheStore store.Store, resourceGroupName string) store.Store {
+	return store.NewPrefixStore(cacheStore, fmt.Sprintf("target-%s-", strings.ToLower(resourceGroupName)))
+}
+
+func runPreviews(ctx context.Context, cfg config.ReconcileConfig, cacheStore store.Store, secretClient secret.Secret, remoteAppClient remote.App, remoteStorageClient remote.Storage, registryClient registry.Registry) error {
+	pullRequests, err := preview.NewPullRequests(cfg)
+	if err != nil {
+		return err
+	}
+
+	previewer := preview.NewPreviewer(pullRequests, remoteAppClient, func(ctx context.Context, pullRequest preview.PullRequest, previewAppClient remote.App) (preview.Reconciler, error) {
+		return newPreviewReconciler(cfg, pullRequest, cacheStore, secretClient, previewAppClient, remoteStorageClient, registryClient)
+	})
+
+	return previewer.Run(ctx)
+}
+
+// newPreviewReconciler creates a reconciler for the apps of the pull request, with caches separated from the
+// environment and without notifications, metrics and history
+func newPreviewReconciler(cfg config.ReconcileConfig, pullRequest preview.PullRequest, cacheStore store.Store, secretClient secret.Secret, previewAppClient remote.App, remoteStorageClient remote.Storage, registryClient registry.Registry) (*reconcile.Reconciler, error) {
+	previewCfg := cfg.ForPullRequest(pullRequest.Branch)
+	previewStore := store.NewPrefixStore(cacheStore, fmt.Sprintf("preview-%d-", pullRequest.Number))
+
+	revisionCache, err := cache.NewStoreRevisionCache(previewCfg, previewStore)
+	if err != nil {
+		return nil, err
+	}
+
+	sourceClient, err := source.NewGitSource(previewCfg, revisionCache, secretClient)
+	if err != nil {
+		return nil, err
+	}
+
+	appCache, err := cache.NewStoreAppCache(previewCfg, previewStore)
+	if err != nil {
+		return nil, err
+	}
+
+	jobCache, err := cache.NewStoreJobCache(previewCfg, previewStore)
+	if err != nil {
+		return nil, err
+	}
+
+	daprComponentCache, err := cache.NewStoreDaprComponentCache(previewCfg, previewStore)
+	if err != nil {
+		return nil, err
+	}
+
+	certificateCache, err := cache.NewStoreCertificateCache(previewCfg, previewStore)
+	if err != nil {
+		return nil, err
+	}
+
+	storageCache, err := cache.NewStoreStorageCache(previewCfg, previewStore)
+	if err != nil {
+		return nil, err
+	}
+
+	notificationCache, err := cache.NewStoreNotificationCache(previewCfg, previewStore)
+	if err != nil {
+		return nil, err
+	}
+
+	reconcileStateCache, err := cache.NewStoreReconcileStateCache(previewCfg, previewStore)
+	if err != nil {
+		return nil, err
+	}
+
+	secretFingerprintCache, err := cache.NewStoreSecretFingerprintCache(previewCfg, previewStore)
+	if err != nil {
+		return nil, err
+	}
+
+	historyCache, err := cache.NewStoreHistoryCache(previewStore, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return reconcile.NewReconciler(previewCfg, preview.NewSource(sourceClient, pullRequest.Number), previewAppClient, remote.NewDiscardJob(), remote.NewDiscardDaprComponent(), remote.NewDiscardCertificate(), remoteStorageClient, secretClient, registryClient, notification.NewDiscardNotification(), metrics.NewDiscardMetrics(), appCache, jobCache, daprComponentCache, certificateCache, storageCache, cache.NewInMemSecretCache(), notificationCache, reconcileStateCache, secretFingerprintCache, historyCache)
 }
 
 func runTrigger(ctx context.Context, cfg config.TriggerConfig) error {
@@ -204,6 +495,82 @@ func runTrigger(ctx context.Context, cfg config.TriggerConfig) error {
 	return nil
 }
 
+func runImageUpdate(ctx context.Context, cfg config.ImageUpdateConfig) error {
+	cred, err := azure.NewAzureCredential()
+	if err != nil {
+		return err
+	}
+
+	registryClient := registry.NewHTTPRegistry([]registry.Credential{
+		{
+			Server:   cfg.ContainerRegistryServer,
+			Username: cfg.ContainerRegistryUsername,
+			Password: cfg.ContainerRegistryPassword,
+		},
+	}, cred)
+
+	var pullRequestClient imageupdate.PullRequest = imageupdate.NewDiscardPullRequest()
+	if cfg.PullRequestEnabled {
+		pullRequestClient, err = imageupdate.NewGitHubPullRequest(cfg.GitUrl)
+		if err != nil {
+			return err
+		}
+	}
+
+	imageUpdater, err := imageupdate.NewImageUpdater(cfg, registryClient, pullRequestClient)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt)
+	defer cancel()
+
+	_, err = imageUpdater.Run(ctx)
+	if err != nil {
+		return fmt.Errorf("image update error: %w", err)
+	}
+
+	return nil
+}
+
+func runHistory(ctx context.Context, cfg config.HistoryConfig) error {
+	cred, err := azure.NewAzureCredential()
+	if err != nil {
+		return err
+	}
+
+	cacheStore, err := store.NewStore(cfg.CacheConfig, cred)
+	if err != nil {
+		return err
+	}
+
+	historyStore := cacheStore
+	if cfg.ResourceGroupName != "" {
+		historyStore = getTargetStore(cacheStore, cfg.ResourceGroupName)
+	}
+
+	historyCache, err := cache.NewStoreHistoryCache(historyStore, 0)
+	if err != nil {
+		return err
+	}
+
+	if cfg.App == "" {
+		runs, err := historyCache.List(ctx, cfg.Limit)
+		if err != nil {
+			return fmt.Errorf("unable to list history: %w", err)
+		}
+
+		return history.PrintRuns(os.Stdout, runs)
+	}
+
+	runs, err := historyCache.List(ctx, 0)
+	if err != nil {
+		return fmt.Errorf("unable to list history: %w", err)
+	}
+
+	return history.PrintResourceRuns(os.Stdout, history.FilterRuns(runs, cfg.App, cfg.Limit), cfg.App)
+}
+
 func isDebugEnabled(args []string) bool {
 	for _, v := range args {
 		if v == "--debug" {
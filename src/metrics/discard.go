@@ -0,0 +1,26 @@
+package metrics
+
+import (
+	"context"
+	"time"
+)
+
+type DiscardMetrics struct{}
+
+var _ Metrics = (*DiscardMetrics)(nil)
+
+func NewDiscardMetrics() *DiscardMetrics {
+	return &DiscardMetrics{}
+}
+
+func (m *DiscardMetrics) Int(_ context.Context, _ string, _ int) error {
+	return nil
+}
+
+func (m *DiscardMetrics) Duration(_ context.Context, _ string, _ time.Duration) error {
+	return nil
+}
+
+func (m *DiscardMetrics) Success(_ context.Context, _ string, _ bool) error {
+	return nil
+}
@@ -32,7 +32,8 @@ type AzureDevOpsNotification struct {
 
 var _ Notification = (*AzureDevOpsNotification)(nil)
 
-func NewAzureDevOpsNotification(address string, token string) (*AzureDevOpsNotification, error) {
+// NewAzureDevOpsNotification uses apiUrl as the organization (or collection for Azure DevOps Server) url if set
+func NewAzureDevOpsNotification(address string, token string, apiUrl string) (*AzureDevOpsNotification, error) {
 	if len(token) == 0 {
 		return nil, errors.New("azure devops token cannot be empty")
 	}
@@ -43,14 +44,24 @@ func NewAzureDevOpsNotification(address string, token string) (*AzureDevOpsNotif
 	}
 
 	comp := strings.Split(id, "/")
-	if len(comp) != 4 {
-		return nil, fmt.Errorf("invalid repository id %q", id)
+	var orgURL, proj, repo string
+	if apiUrl != "" {
+		// the path of azure devops server depends on the collection, but always ends with <project>/_git/<repo>
+		if len(comp) < 3 || comp[len(comp)-2] != "_git" {
+			return nil, fmt.Errorf("invalid repository id %q", id)
+		}
+		orgURL = strings.TrimSuffix(apiUrl, "/")
+		proj = comp[len(comp)-3]
+		repo = comp[len(comp)-1]
+	} else {
+		if len(comp) != 4 {
+			return nil, fmt.Errorf("invalid repository id %q", id)
+		}
+		orgURL = fmt.Sprintf("%v/%v", host, comp[0])
+		proj = comp[1]
+		repo = comp[3]
 	}
-	org := comp[0]
-	proj := comp[1]
-	repo := comp[3]
 
-	orgURL := fmt.Sprintf("%v/%v", host, org)
 	connection := azuredevops.NewPatConnection(orgURL, token)
 	client := connection.GetClientByUrl(orgURL)
 	gitClient := &git.ClientImpl{
@@ -77,6 +88,7 @@ func (a *AzureDevOpsNotification) Send(ctx context.Context, event NotificationEv
 		GitCommitStatusToCreate: &git.GitStatus{
 			Description: &event.Description,
 			State:       &state,
+			TargetUrl:   toTargetUrl(event.TargetUrl),
 			Context: &git.GitStatusContext{
 				// using fluxcd to be compatible with gitops-promotion
 				Genre: toPtr("fluxcd"),
@@ -105,12 +117,22 @@ func (a *AzureDevOpsNotification) Send(ctx context.Context, event NotificationEv
 	return nil
 }
 
+func toTargetUrl(targetUrl string) *string {
+	if targetUrl == "" {
+		return nil
+	}
+
+	return &targetUrl
+}
+
 func toAzureDevOpsState(state NotificationState) (git.GitStatusState, error) {
 	switch state {
 	case NotificationStateSuccess:
 		return git.GitStatusStateValues.Succeeded, nil
 	case NotificationStateFailure:
 		return git.GitStatusStateValues.Error, nil
+	case NotificationStatePending:
+		return git.GitStatusStateValues.Pending, nil
 	default:
 		return "", errors.New("can't convert to azure devops state")
 	}
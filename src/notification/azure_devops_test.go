@@ -0,0 +1,22 @@
+package notification
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAzureDevOpsNotification(t *testing.T) {
+	azdo, err := NewAzureDevOpsNotification("https://dev.azure.com/xenitab/ze-project/_git/ze-repo", "ze-token", "")
+	require.NoError(t, err)
+	require.Equal(t, "ze-project", azdo.Project)
+	require.Equal(t, "ze-repo", azdo.Repo)
+
+	azdo, err = NewAzureDevOpsNotification("https://tfs.example.com/tfs/DefaultCollection/ze-project/_git/ze-repo", "ze-token", "https://tfs.example.com/tfs/DefaultCollection")
+	require.NoError(t, err)
+	require.Equal(t, "ze-project", azdo.Project)
+	require.Equal(t, "ze-repo", azdo.Repo)
+
+	_, err = NewAzureDevOpsNotification("https://tfs.example.com/tfs/DefaultCollection/ze-repo", "ze-token", "https://tfs.example.com/tfs/DefaultCollection")
+	require.ErrorContains(t, err, "invalid repository id")
+}
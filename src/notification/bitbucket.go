@@ -0,0 +1,168 @@
+package notification
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type BitbucketNotification struct {
+	apiUrl        string
+	workspace     string
+	repo          string
+	repoUrl       string
+	authorization string
+	httpClient    *http.Client
+}
+
+var _ Notification = (*BitbucketNotification)(nil)
+
+// NewBitbucketNotification uses basic auth if the username is set (app passwords), otherwise the token
+// is used as a bearer token (access tokens)
+func NewBitbucketNotification(address string, username string, token string, apiUrl string) (*BitbucketNotification, error) {
+	if len(token) == 0 {
+		return nil, errors.New("bitbucket token cannot be empty")
+	}
+
+	host, id, err := parseGitAddress(address)
+	if err != nil {
+		return nil, err
+	}
+
+	comp := strings.Split(id, "/")
+	if len(comp) != 2 {
+		return nil, fmt.Errorf("invalid repository id %q", id)
+	}
+
+	authorization := fmt.Sprintf("Bearer %s", token)
+	if username != "" {
+		authorization = fmt.Sprintf("Basic %s", base64.StdEncoding.EncodeToString([]byte(username+":"+token)))
+	}
+
+	if apiUrl == "" {
+		apiUrl = "https://api.bitbucket.org/2.0"
+	}
+
+	return &BitbucketNotification{
+		apiUrl:        strings.TrimSuffix(apiUrl, "/"),
+		workspace:     comp[0],
+		repo:          comp[1],
+		repoUrl:       fmt.Sprintf("%s/%s", host, id),
+		authorization: authorization,
+		httpClient:    newHttpClient(),
+	}, nil
+}
+
+type bitbucketBuildStatus struct {
+	Key         string `json:"key"`
+	Name        string `json:"name"`
+	State       string `json:"state"`
+	Description string `json:"description"`
+	Url         string `json:"url"`
+}
+
+func (b *BitbucketNotification) Send(ctx context.Context, event NotificationEvent) error {
+	state, err := toBitbucketState(event.State)
+	if err != nil {
+		return err
+	}
+
+	status := bitbucketBuildStatus{
+		Key:         toBitbucketKey(event.Name),
+		Name:        event.Name,
+		State:       state,
+		Description: event.Description,
+		Url:         toBitbucketUrl(event.TargetUrl, b.repoUrl),
+	}
+
+	statusUrl := fmt.Sprintf("%s/repositories/%s/%s/commit/%s/statuses/build", b.apiUrl, url.PathEscape(b.workspace), url.PathEscape(b.repo), url.PathEscape(event.Revision))
+	err = doJSON(ctx, b.httpClient, http.MethodPost, statusUrl, map[string]string{"Authorization": b.authorization}, status)
+	if err != nil {
+		return fmt.Errorf("could not create commit status: %v", err)
+	}
+
+	return nil
+}
+
+type BitbucketServerNotification struct {
+	apiUrl     string
+	repoUrl    string
+	token      string
+	httpClient *http.Client
+}
+
+var _ Notification = (*BitbucketServerNotification)(nil)
+
+// NewBitbucketServerNotification uses <git host>/rest as the api url if apiUrl isn't set
+func NewBitbucketServerNotification(address string, token string, apiUrl string) (*BitbucketServerNotification, error) {
+	if len(token) == 0 {
+		return nil, errors.New("bitbucket server token cannot be empty")
+	}
+
+	host, id, err := parseGitAddress(address)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BitbucketServerNotification{
+		apiUrl:     getApiUrl(apiUrl, host, "/rest"),
+		repoUrl:    fmt.Sprintf("%s/%s", host, id),
+		token:      token,
+		httpClient: newHttpClient(),
+	}, nil
+}
+
+func (b *BitbucketServerNotification) Send(ctx context.Context, event NotificationEvent) error {
+	state, err := toBitbucketState(event.State)
+	if err != nil {
+		return err
+	}
+
+	status := bitbucketBuildStatus{
+		Key:         toBitbucketKey(event.Name),
+		Name:        event.Name,
+		State:       state,
+		Description: event.Description,
+		Url:         toBitbucketUrl(event.TargetUrl, b.repoUrl),
+	}
+
+	statusUrl := fmt.Sprintf("%s/build-status/1.0/commits/%s", b.apiUrl, url.PathEscape(event.Revision))
+	err = doJSON(ctx, b.httpClient, http.MethodPost, statusUrl, map[string]string{"Authorization": fmt.Sprintf("Bearer %s", b.token)}, status)
+	if err != nil {
+		return fmt.Errorf("could not create commit status: %v", err)
+	}
+
+	return nil
+}
+
+// toBitbucketUrl falls back to the repository, as the url is required
+func toBitbucketUrl(targetUrl string, repoUrl string) string {
+	if targetUrl != "" {
+		return targetUrl
+	}
+
+	return repoUrl
+}
+
+// toBitbucketKey hashes the name, as the key is limited to 40 characters
+func toBitbucketKey(name string) string {
+	return fmt.Sprintf("%x", md5.Sum([]byte(name)))
+}
+
+func toBitbucketState(state NotificationState) (string, error) {
+	switch state {
+	case NotificationStateSuccess:
+		return "SUCCESSFUL", nil
+	case NotificationStateFailure:
+		return "FAILED", nil
+	case NotificationStatePending:
+		return "INPROGRESS", nil
+	default:
+		return "", errors.New("can't convert to bitbucket state")
+	}
+}
@@ -0,0 +1,63 @@
+package notification
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBitbucketNotification(t *testing.T) {
+	srv, requests := newTestWebhookServer(t, http.StatusCreated)
+
+	t.Run("access token", func(t *testing.T) {
+		bitbucket, err := NewBitbucketNotification("https://bitbucket.org/xenitab/azcagit.git", "", "ze-token", srv.URL)
+		require.NoError(t, err)
+
+		err = bitbucket.Send(context.Background(), testNotificationEvent())
+		require.NoError(t, err)
+		req := (*requests)[len(*requests)-1]
+		require.Equal(t, "/repositories/xenitab/azcagit/commit/6ffa5a7b2da7dc37e186e2581a903e325bbd38be/statuses/build", req.path)
+		require.Equal(t, "Bearer ze-token", req.header.Get("Authorization"))
+		require.JSONEq(t, `{
+			"key": "`+toBitbucketKey("ze-rg/apps-dev")+`",
+			"name": "ze-rg/apps-dev",
+			"state": "FAILED",
+			"description": "sourceApps error: failed to update foo",
+			"url": "https://bitbucket.org/xenitab/azcagit"
+		}`, string(req.body))
+	})
+
+	t.Run("app password", func(t *testing.T) {
+		bitbucket, err := NewBitbucketNotification("https://bitbucket.org/xenitab/azcagit.git", "ze-user", "ze-password", srv.URL)
+		require.NoError(t, err)
+
+		err = bitbucket.Send(context.Background(), testNotificationEvent())
+		require.NoError(t, err)
+		req := (*requests)[len(*requests)-1]
+		require.Equal(t, "Basic emUtdXNlcjp6ZS1wYXNzd29yZA==", req.header.Get("Authorization"))
+	})
+}
+
+func TestBitbucketServerNotification(t *testing.T) {
+	srv, requests := newTestWebhookServer(t, http.StatusNoContent)
+	bitbucket, err := NewBitbucketServerNotification("https://bitbucket.example.com/scm/xen/azcagit.git", "ze-token", srv.URL+"/rest")
+	require.NoError(t, err)
+
+	event := testNotificationEvent()
+	event.State = NotificationStateSuccess
+	err = bitbucket.Send(context.Background(), event)
+	require.NoError(t, err)
+	require.Len(t, *requests, 1)
+	req := (*requests)[0]
+	require.Equal(t, "/rest/build-status/1.0/commits/6ffa5a7b2da7dc37e186e2581a903e325bbd38be", req.path)
+	require.Equal(t, "Bearer ze-token", req.header.Get("Authorization"))
+	require.Contains(t, string(req.body), `"state":"SUCCESSFUL"`)
+}
+
+func TestToBitbucketKey(t *testing.T) {
+	key := toBitbucketKey("rg-dev-we-aca/dev/dapr-component/a-very-long-dapr-component-name")
+	require.Len(t, key, 32)
+	require.NotEqual(t, key, toBitbucketKey("rg-dev-we-aca/apps-dev"))
+}
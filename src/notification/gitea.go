@@ -0,0 +1,87 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type GiteaNotification struct {
+	apiUrl     string
+	owner      string
+	repo       string
+	token      string
+	httpClient *http.Client
+}
+
+var _ Notification = (*GiteaNotification)(nil)
+
+// NewGiteaNotification uses <git host>/api/v1 as the api url if apiUrl isn't set
+func NewGiteaNotification(address string, token string, apiUrl string) (*GiteaNotification, error) {
+	if len(token) == 0 {
+		return nil, errors.New("gitea token cannot be empty")
+	}
+
+	host, id, err := parseGitAddress(address)
+	if err != nil {
+		return nil, err
+	}
+
+	comp := strings.Split(id, "/")
+	if len(comp) != 2 {
+		return nil, fmt.Errorf("invalid repository id %q", id)
+	}
+
+	return &GiteaNotification{
+		apiUrl:     getApiUrl(apiUrl, host, "/api/v1"),
+		owner:      comp[0],
+		repo:       comp[1],
+		token:      token,
+		httpClient: newHttpClient(),
+	}, nil
+}
+
+type giteaCommitStatus struct {
+	State       string `json:"state"`
+	Context     string `json:"context"`
+	Description string `json:"description"`
+	TargetUrl   string `json:"target_url,omitempty"`
+}
+
+func (g *GiteaNotification) Send(ctx context.Context, event NotificationEvent) error {
+	state, err := toGiteaState(event.State)
+	if err != nil {
+		return err
+	}
+
+	status := giteaCommitStatus{
+		State:       state,
+		Context:     event.Name,
+		Description: event.Description,
+		TargetUrl:   event.TargetUrl,
+	}
+
+	statusUrl := fmt.Sprintf("%s/repos/%s/%s/statuses/%s", g.apiUrl, url.PathEscape(g.owner), url.PathEscape(g.repo), url.PathEscape(event.Revision))
+	err = doJSON(ctx, g.httpClient, http.MethodPost, statusUrl, map[string]string{"Authorization": fmt.Sprintf("token %s", g.token)}, status)
+	if err != nil {
+		return fmt.Errorf("could not create commit status: %v", err)
+	}
+
+	return nil
+}
+
+func toGiteaState(state NotificationState) (string, error) {
+	switch state {
+	case NotificationStateSuccess:
+		return "success", nil
+	case NotificationStateFailure:
+		return "failure", nil
+	case NotificationStatePending:
+		return "pending", nil
+	default:
+		return "", errors.New("can't convert to gitea state")
+	}
+}
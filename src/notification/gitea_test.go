@@ -0,0 +1,26 @@
+package notification
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGiteaNotification(t *testing.T) {
+	srv, requests := newTestWebhookServer(t, http.StatusCreated)
+	gitea, err := NewGiteaNotification("https://gitea.example.com/xenitab/azcagit.git", "ze-token", srv.URL+"/api/v1")
+	require.NoError(t, err)
+
+	err = gitea.Send(context.Background(), testNotificationEvent())
+	require.NoError(t, err)
+	require.Len(t, *requests, 1)
+	req := (*requests)[0]
+	require.Equal(t, "/api/v1/repos/xenitab/azcagit/statuses/6ffa5a7b2da7dc37e186e2581a903e325bbd38be", req.path)
+	require.Equal(t, "token ze-token", req.header.Get("Authorization"))
+	require.JSONEq(t, `{"state": "failure", "context": "ze-rg/apps-dev", "description": "sourceApps error: failed to update foo"}`, string(req.body))
+
+	_, err = NewGiteaNotification("https://gitea.example.com/azcagit.git", "ze-token", "")
+	require.ErrorContains(t, err, "invalid repository id")
+}
@@ -32,12 +32,13 @@ type GitHubNotification struct {
 
 var _ Notification = (*GitHubNotification)(nil)
 
-func NewGitHubNotification(address string, token string) (*GitHubNotification, error) {
+// NewGitHubNotification uses the GitHub Enterprise Server api if apiUrl is set or the git host isn't github.com
+func NewGitHubNotification(address string, token string, apiUrl string) (*GitHubNotification, error) {
 	if len(token) == 0 {
 		return nil, errors.New("github token cannot be empty")
 	}
 
-	_, id, err := parseGitAddress(address)
+	host, id, err := parseGitAddress(address)
 	if err != nil {
 		return nil, err
 	}
@@ -50,6 +51,12 @@ func NewGitHubNotification(address string, token string) (*GitHubNotification, e
 	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
 	tc := oauth2.NewClient(context.Background(), ts)
 	client := github.NewClient(tc)
+	if apiUrl != "" || !strings.HasSuffix(host, "://github.com") {
+		client, err = github.NewEnterpriseClient(getApiUrl(apiUrl, host, "/api/v3"), "", tc)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	return &GitHubNotification{
 		Owner:  comp[0],
@@ -68,6 +75,9 @@ func (g *GitHubNotification) Send(ctx context.Context, event NotificationEvent)
 		Context:     &event.Name,
 		Description: toGitHubDescription(event.Description),
 	}
+	if event.TargetUrl != "" {
+		status.TargetURL = &event.TargetUrl
+	}
 
 	opts := &github.ListOptions{PerPage: 50}
 	statuses, _, err := g.Client.Repositories.ListStatuses(ctx, g.Owner, g.Repo, event.Revision, opts)
@@ -101,6 +111,8 @@ func toGitHubState(state NotificationState) (string, error) {
 		return "success", nil
 	case NotificationStateFailure:
 		return "failure", nil
+	case NotificationStatePending:
+		return "pending", nil
 	default:
 		return "", errors.New("can't convert to github state")
 	}
@@ -34,3 +34,19 @@ func TestToGitHubDescription(t *testing.T) {
 		require.Equal(t, c.expectedResult, *result)
 	}
 }
+
+func TestNewGitHubNotification(t *testing.T) {
+	gh, err := NewGitHubNotification("https://github.com/xenitab/azcagit.git", "ze-token", "")
+	require.NoError(t, err)
+	require.Equal(t, "https://api.github.com/", gh.Client.BaseURL.String())
+
+	gh, err = NewGitHubNotification("https://github.example.com/xenitab/azcagit.git", "ze-token", "")
+	require.NoError(t, err)
+	require.Equal(t, "https://github.example.com/api/v3/", gh.Client.BaseURL.String())
+
+	gh, err = NewGitHubNotification("https://github.example.com/xenitab/azcagit.git", "ze-token", "https://api.github.example.com/")
+	require.NoError(t, err)
+	require.Equal(t, "https://api.github.example.com/", gh.Client.BaseURL.String())
+	require.Equal(t, "xenitab", gh.Owner)
+	require.Equal(t, "azcagit", gh.Repo)
+}
@@ -0,0 +1,83 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+type GitLabNotification struct {
+	apiUrl     string
+	projectId  string
+	token      string
+	httpClient *http.Client
+}
+
+var _ Notification = (*GitLabNotification)(nil)
+
+// NewGitLabNotification uses <git host>/api/v4 as the api url if apiUrl isn't set
+func NewGitLabNotification(address string, token string, apiUrl string) (*GitLabNotification, error) {
+	if len(token) == 0 {
+		return nil, errors.New("gitlab token cannot be empty")
+	}
+
+	host, id, err := parseGitAddress(address)
+	if err != nil {
+		return nil, err
+	}
+
+	if id == "" {
+		return nil, fmt.Errorf("invalid repository id %q", id)
+	}
+
+	return &GitLabNotification{
+		apiUrl:     getApiUrl(apiUrl, host, "/api/v4"),
+		projectId:  id,
+		token:      token,
+		httpClient: newHttpClient(),
+	}, nil
+}
+
+type gitLabCommitStatus struct {
+	State       string `json:"state"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	TargetUrl   string `json:"target_url,omitempty"`
+}
+
+func (g *GitLabNotification) Send(ctx context.Context, event NotificationEvent) error {
+	state, err := toGitLabState(event.State)
+	if err != nil {
+		return err
+	}
+
+	status := gitLabCommitStatus{
+		State:       state,
+		Name:        event.Name,
+		Description: event.Description,
+		TargetUrl:   event.TargetUrl,
+	}
+
+	statusUrl := fmt.Sprintf("%s/projects/%s/statuses/%s", g.apiUrl, url.PathEscape(g.projectId), url.PathEscape(event.Revision))
+	err = doJSON(ctx, g.httpClient, http.MethodPost, statusUrl, map[string]string{"PRIVATE-TOKEN": g.token}, status)
+	if err != nil {
+		return fmt.Errorf("could not create commit status: %v", err)
+	}
+
+	return nil
+}
+
+func toGitLabState(state NotificationState) (string, error) {
+	switch state {
+	case NotificationStateSuccess:
+		return "success", nil
+	case NotificationStateFailure:
+		return "failed", nil
+	case NotificationStatePending:
+		return "running", nil
+	default:
+		return "", errors.New("can't convert to gitlab state")
+	}
+}
@@ -0,0 +1,36 @@
+package notification
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitLabNotification(t *testing.T) {
+	gitlab, err := NewGitLabNotification("https://gitlab.example.com/xenitab/sub/azcagit.git", "ze-token", "")
+	require.NoError(t, err)
+	require.Equal(t, "https://gitlab.example.com/api/v4", gitlab.apiUrl)
+
+	srv, requests := newTestWebhookServer(t, http.StatusCreated)
+	gitlab, err = NewGitLabNotification("https://gitlab.example.com/xenitab/sub/azcagit.git", "ze-token", srv.URL+"/api/v4/")
+	require.NoError(t, err)
+
+	err = gitlab.Send(context.Background(), testNotificationEvent())
+	require.NoError(t, err)
+	require.Len(t, *requests, 1)
+	req := (*requests)[0]
+	require.Equal(t, http.MethodPost, req.method)
+	require.Equal(t, "/api/v4/projects/xenitab%2Fsub%2Fazcagit/statuses/6ffa5a7b2da7dc37e186e2581a903e325bbd38be", req.path)
+	require.Equal(t, "ze-token", req.header.Get("PRIVATE-TOKEN"))
+	require.JSONEq(t, `{"state": "failed", "name": "ze-rg/apps-dev", "description": "sourceApps error: failed to update foo"}`, string(req.body))
+
+	event := testNotificationEvent()
+	event.State = NotificationStatePending
+	event.TargetUrl = "https://portal.azure.com/#resource/subscriptions/ze-sub/resourceGroups/ze-rg/overview"
+	err = gitlab.Send(context.Background(), event)
+	require.NoError(t, err)
+	require.Contains(t, string((*requests)[1].body), `"state":"running"`)
+	require.Contains(t, string((*requests)[1].body), `"target_url":"https://portal.azure.com/#resource/subscriptions/ze-sub/resourceGroups/ze-rg/overview"`)
+}
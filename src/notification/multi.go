@@ -0,0 +1,32 @@
+package notification
+
+import (
+	"context"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+// MultiNotification sends the event to all notifications, also when one of them fails
+type MultiNotification struct {
+	notifications []Notification
+}
+
+var _ Notification = (*MultiNotification)(nil)
+
+func NewMultiNotification(notifications ...Notification) *MultiNotification {
+	return &MultiNotification{
+		notifications: notifications,
+	}
+}
+
+func (n *MultiNotification) Send(ctx context.Context, event NotificationEvent) error {
+	var result *multierror.Error
+	for _, notification := range n.notifications {
+		err := notification.Send(ctx, event)
+		if err != nil {
+			result = multierror.Append(err, result)
+		}
+	}
+
+	return result.ErrorOrNil()
+}
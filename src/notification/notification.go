@@ -4,8 +4,10 @@ import (
 	"context"
 	"fmt"
 	"net/url"
+	"strings"
 
 	"github.com/xenitab/azcagit/src/config"
+	"github.com/xenitab/azcagit/src/history"
 )
 
 type Notification interface {
@@ -17,8 +19,15 @@ type NotificationEvent struct {
 	State       NotificationState `json:"state"`
 	Name        string            `json:"name"`
 	Description string            `json:"description"`
+	TargetUrl   string            `json:"target_url,omitempty"`
+	// Actions are the changed resources, only sent to chat and webhook notifications
+	Actions []history.Action `json:"actions,omitempty"`
+	// CommitStatusOnly events (like the pending and per resource events) are only sent to the git provider as a
+	// commit status
+	CommitStatusOnly bool `json:"-"`
 }
 
+// Equal ignores the actions, only a changed status is notified again
 func (e *NotificationEvent) Equal(other NotificationEvent) bool {
 	if e.Revision != other.Revision {
 		return false
@@ -36,6 +45,10 @@ func (e *NotificationEvent) Equal(other NotificationEvent) bool {
 		return false
 	}
 
+	if e.TargetUrl != other.TargetUrl {
+		return false
+	}
+
 	return true
 }
 
@@ -44,21 +57,73 @@ type NotificationState int
 const (
 	NotificationStateSuccess NotificationState = iota
 	NotificationStateFailure
+	// NotificationStatePending is sent when the reconcile of a new revision has started
+	NotificationStatePending
 )
 
+func (s NotificationState) String() string {
+	switch s {
+	case NotificationStateSuccess:
+		return "success"
+	case NotificationStateFailure:
+		return "failure"
+	case NotificationStatePending:
+		return "pending"
+	}
+
+	return "unknown"
+}
+
 type NotificationProvider int
 
 const (
 	NotificationProviderAzureDevOps NotificationProvider = iota
 	NotificationProviderGitHub
+	NotificationProviderGitLab
+	NotificationProviderBitbucket
+	NotificationProviderBitbucketServer
+	NotificationProviderGitea
 	NotificationProviderUnknown
 )
 
+// NewNotificationClient returns the git provider notification (if enabled) together with the
+// configured chat and webhook notifications
 func NewNotificationClient(cfg config.ReconcileConfig) (Notification, error) {
-	if !cfg.NotificationsEnabled {
+	notifications := []Notification{}
+	if cfg.NotificationsEnabled {
+		gitNotification, err := newGitNotificationClient(cfg)
+		if err != nil {
+			return nil, err
+		}
+
+		notifications = append(notifications, gitNotification)
+	}
+
+	webhooks, err := cfg.GetNotificationWebhooks()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, webhook := range webhooks {
+		webhookNotification, err := newWebhookNotificationClient(webhook)
+		if err != nil {
+			return nil, err
+		}
+
+		notifications = append(notifications, webhookNotification)
+	}
+
+	switch len(notifications) {
+	case 0:
 		return NewDiscardNotification(), nil
+	case 1:
+		return notifications[0], nil
 	}
 
+	return NewMultiNotification(notifications...), nil
+}
+
+func newGitNotificationClient(cfg config.ReconcileConfig) (Notification, error) {
 	parsedGitUrl, err := url.Parse(cfg.GitUrl)
 	if err != nil {
 		return nil, err
@@ -69,14 +134,48 @@ func NewNotificationClient(cfg config.ReconcileConfig) (Notification, error) {
 		return nil, err
 	}
 
-	switch parseNotificationProvider(parsedGitUrl) {
+	provider := parseNotificationProvider(parsedGitUrl)
+	if cfg.NotificationProvider != "" {
+		provider, err = parseNotificationProviderName(cfg.NotificationProvider)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch provider {
 	case NotificationProviderAzureDevOps:
-		return NewAzureDevOpsNotification(address, token)
+		return NewAzureDevOpsNotification(address, token, cfg.NotificationApiUrl)
 	case NotificationProviderGitHub:
-		return NewGitHubNotification(address, token)
+		return NewGitHubNotification(address, token, cfg.NotificationApiUrl)
+	case NotificationProviderGitLab:
+		return NewGitLabNotification(address, token, cfg.NotificationApiUrl)
+	case NotificationProviderBitbucket:
+		// app passwords use basic auth, access tokens (x-token-auth or only a token) use bearer auth
+		username := ""
+		if _, ok := parsedGitUrl.User.Password(); ok && parsedGitUrl.User.Username() != "x-token-auth" {
+			username = parsedGitUrl.User.Username()
+		}
+		return NewBitbucketNotification(address, username, token, cfg.NotificationApiUrl)
+	case NotificationProviderBitbucketServer:
+		return NewBitbucketServerNotification(address, token, cfg.NotificationApiUrl)
+	case NotificationProviderGitea:
+		return NewGiteaNotification(address, token, cfg.NotificationApiUrl)
+	}
+
+	return nil, fmt.Errorf("can't find notification provider for hostname: %s, it can be set with --notification-provider", parsedGitUrl.Hostname())
+}
+
+func newWebhookNotificationClient(webhook config.NotificationWebhook) (Notification, error) {
+	switch webhook.Type {
+	case config.NotificationWebhookTypeSlack:
+		return NewSlackNotification(webhook.URL)
+	case config.NotificationWebhookTypeTeams:
+		return NewTeamsNotification(webhook.URL)
+	case config.NotificationWebhookTypeWebhook:
+		return NewWebhookNotification(webhook.URL, webhook.Secret, webhook.Format == config.NotificationWebhookFormatCloudEvents)
 	}
 
-	return nil, fmt.Errorf("can't find notification provider for hostname: %s", parsedGitUrl.Hostname())
+	return nil, fmt.Errorf("unknown notification webhook type: %s", webhook.Type)
 }
 
 func parseGitAddressAndToken(gitUrl *url.URL) (string, string, error) {
@@ -105,7 +204,39 @@ func parseNotificationProvider(gitUrl *url.URL) NotificationProvider {
 		return NotificationProviderGitHub
 	case "dev.azure.com":
 		return NotificationProviderAzureDevOps
+	case "gitlab.com":
+		return NotificationProviderGitLab
+	case "bitbucket.org":
+		return NotificationProviderBitbucket
 	}
 
 	return NotificationProviderUnknown
 }
+
+func parseNotificationProviderName(name string) (NotificationProvider, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "azure-devops":
+		return NotificationProviderAzureDevOps, nil
+	case "github":
+		return NotificationProviderGitHub, nil
+	case "gitlab":
+		return NotificationProviderGitLab, nil
+	case "bitbucket":
+		return NotificationProviderBitbucket, nil
+	case "bitbucket-server":
+		return NotificationProviderBitbucketServer, nil
+	case "gitea":
+		return NotificationProviderGitea, nil
+	}
+
+	return NotificationProviderUnknown, fmt.Errorf("unknown notification provider %q, should be one of azure-devops, github, gitlab, bitbucket, bitbucket-server or gitea", name)
+}
+
+// getApiUrl returns the api url if set, otherwise the default path on the git host
+func getApiUrl(apiUrl string, host string, defaultPath string) string {
+	if apiUrl != "" {
+		return strings.TrimSuffix(apiUrl, "/")
+	}
+
+	return host + defaultPath
+}
@@ -0,0 +1,79 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// SlackNotification posts the event to a Slack incoming webhook
+type SlackNotification struct {
+	url        string
+	httpClient *http.Client
+}
+
+var _ Notification = (*SlackNotification)(nil)
+
+func NewSlackNotification(url string) (*SlackNotification, error) {
+	if url == "" {
+		return nil, errors.New("slack webhook url cannot be empty")
+	}
+
+	return &SlackNotification{
+		url:        url,
+		httpClient: newHttpClient(),
+	}, nil
+}
+
+type slackMessage struct {
+	Text        string            `json:"text"`
+	Attachments []slackAttachment `json:"attachments"`
+}
+
+type slackAttachment struct {
+	Color     string `json:"color"`
+	Title     string `json:"title,omitempty"`
+	TitleLink string `json:"title_link,omitempty"`
+	Text      string `json:"text"`
+}
+
+func (s *SlackNotification) Send(ctx context.Context, event NotificationEvent) error {
+	if event.CommitStatusOnly {
+		return nil
+	}
+
+	color := "good"
+	switch event.State {
+	case NotificationStateFailure:
+		color = "danger"
+	case NotificationStatePending:
+		color = "warning"
+	}
+
+	lines := []string{event.Description}
+	for _, action := range event.Actions {
+		lines = append(lines, fmt.Sprintf("• %s/%s %s", action.Kind, action.Name, describeAction(action)))
+	}
+
+	attachment := slackAttachment{
+		Color: color,
+		Text:  strings.Join(lines, "\n"),
+	}
+	if event.TargetUrl != "" {
+		attachment.Title = "Details"
+		attachment.TitleLink = event.TargetUrl
+	}
+
+	b, err := json.Marshal(slackMessage{
+		Text:        eventTitle(event),
+		Attachments: []slackAttachment{attachment},
+	})
+	if err != nil {
+		return err
+	}
+
+	return postWebhook(ctx, s.httpClient, s.url, map[string]string{"Content-Type": "application/json"}, b)
+}
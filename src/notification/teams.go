@@ -0,0 +1,110 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// TeamsNotification posts the event as a MessageCard to a Microsoft Teams connector
+type TeamsNotification struct {
+	url        string
+	httpClient *http.Client
+}
+
+var _ Notification = (*TeamsNotification)(nil)
+
+func NewTeamsNotification(url string) (*TeamsNotification, error) {
+	if url == "" {
+		return nil, errors.New("teams webhook url cannot be empty")
+	}
+
+	return &TeamsNotification{
+		url:        url,
+		httpClient: newHttpClient(),
+	}, nil
+}
+
+type teamsMessageCard struct {
+	Type       string         `json:"@type"`
+	Context    string         `json:"@context"`
+	ThemeColor string         `json:"themeColor"`
+	Summary    string         `json:"summary"`
+	Title      string         `json:"title"`
+	Text       string         `json:"text"`
+	Sections   []teamsSection `json:"sections,omitempty"`
+	Actions    []teamsAction  `json:"potentialAction,omitempty"`
+}
+
+type teamsAction struct {
+	Type    string        `json:"@type"`
+	Name    string        `json:"name"`
+	Targets []teamsTarget `json:"targets"`
+}
+
+type teamsTarget struct {
+	OS  string `json:"os"`
+	Uri string `json:"uri"`
+}
+
+type teamsSection struct {
+	Facts []teamsFact `json:"facts"`
+}
+
+type teamsFact struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+func (n *TeamsNotification) Send(ctx context.Context, event NotificationEvent) error {
+	if event.CommitStatusOnly {
+		return nil
+	}
+
+	color := "2EB886"
+	switch event.State {
+	case NotificationStateFailure:
+		color = "A30200"
+	case NotificationStatePending:
+		color = "DAA038"
+	}
+
+	card := teamsMessageCard{
+		Type:       "MessageCard",
+		Context:    "http://schema.org/extensions",
+		ThemeColor: color,
+		Summary:    eventTitle(event),
+		Title:      eventTitle(event),
+		Text:       event.Description,
+	}
+
+	if len(event.Actions) > 0 {
+		facts := []teamsFact{}
+		for _, action := range event.Actions {
+			facts = append(facts, teamsFact{
+				Name:  fmt.Sprintf("%s/%s", action.Kind, action.Name),
+				Value: describeAction(action),
+			})
+		}
+		card.Sections = []teamsSection{{Facts: facts}}
+	}
+
+	if event.TargetUrl != "" {
+		card.Actions = []teamsAction{
+			{
+				Type:    "OpenUri",
+				Name:    "Details",
+				Targets: []teamsTarget{{OS: "default", Uri: event.TargetUrl}},
+			},
+		}
+	}
+
+	b, err := json.Marshal(card)
+	if err != nil {
+		return err
+	}
+
+	return postWebhook(ctx, n.httpClient, n.url, map[string]string{"Content-Type": "application/json"}, b)
+}
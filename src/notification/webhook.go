@@ -0,0 +1,195 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/xenitab/azcagit/src/history"
+)
+
+const (
+	webhookSignatureHeader = "X-Signature"
+	cloudEventsType        = "io.xenit.azcagit.notification"
+	cloudEventsSource      = "azcagit"
+)
+
+// WebhookNotification posts the event as JSON, optionally as a CloudEvent (structured mode)
+type WebhookNotification struct {
+	url         string
+	secret      string
+	cloudEvents bool
+	httpClient  *http.Client
+	now         func() time.Time
+}
+
+var _ Notification = (*WebhookNotification)(nil)
+
+func NewWebhookNotification(url string, secret string, cloudEvents bool) (*WebhookNotification, error) {
+	if url == "" {
+		return nil, errors.New("webhook url cannot be empty")
+	}
+
+	return &WebhookNotification{
+		url:         url,
+		secret:      secret,
+		cloudEvents: cloudEvents,
+		httpClient:  newHttpClient(),
+		now:         time.Now,
+	}, nil
+}
+
+type webhookPayload struct {
+	Revision    string           `json:"revision"`
+	State       string           `json:"state"`
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	TargetUrl   string           `json:"target_url,omitempty"`
+	Actions     []history.Action `json:"actions"`
+}
+
+type cloudEvent struct {
+	SpecVersion     string         `json:"specversion"`
+	ID              string         `json:"id"`
+	Source          string         `json:"source"`
+	Type            string         `json:"type"`
+	Subject         string         `json:"subject"`
+	Time            time.Time      `json:"time"`
+	DataContentType string         `json:"datacontenttype"`
+	Data            webhookPayload `json:"data"`
+}
+
+func (w *WebhookNotification) Send(ctx context.Context, event NotificationEvent) error {
+	if event.CommitStatusOnly {
+		return nil
+	}
+
+	payload := webhookPayload{
+		Revision:    event.Revision,
+		State:       event.State.String(),
+		Name:        event.Name,
+		Description: event.Description,
+		TargetUrl:   event.TargetUrl,
+		Actions:     event.Actions,
+	}
+	if payload.Actions == nil {
+		payload.Actions = []history.Action{}
+	}
+
+	var body any = payload
+	contentType := "application/json"
+	if w.cloudEvents {
+		id, err := newCloudEventID()
+		if err != nil {
+			return err
+		}
+
+		body = cloudEvent{
+			SpecVersion:     "1.0",
+			ID:              id,
+			Source:          cloudEventsSource,
+			Type:            cloudEventsType,
+			Subject:         event.Name,
+			Time:            w.now().UTC(),
+			DataContentType: "application/json",
+			Data:            payload,
+		}
+		contentType = "application/cloudevents+json"
+	}
+
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	headers := map[string]string{
+		"Content-Type": contentType,
+	}
+	if w.secret != "" {
+		headers[webhookSignatureHeader] = fmt.Sprintf("sha256=%s", signWebhookBody(w.secret, b))
+	}
+
+	return postWebhook(ctx, w.httpClient, w.url, headers, b)
+}
+
+// signWebhookBody returns the hex encoded HMAC SHA256 of the body
+func signWebhookBody(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func newCloudEventID() (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+func postWebhook(ctx context.Context, httpClient *http.Client, url string, headers map[string]string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("could not post webhook: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		resBody, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("unexpected status code from webhook: %d %s", res.StatusCode, string(resBody))
+	}
+
+	return nil
+}
+
+// eventTitle is the first line of chat notifications
+func eventTitle(event NotificationEvent) string {
+	result := "succeeded"
+	switch event.State {
+	case NotificationStateFailure:
+		result = "failed"
+	case NotificationStatePending:
+		result = "in progress"
+	}
+
+	return fmt.Sprintf("%s %s (revision %s)", event.Name, result, shortRevision(event.Revision))
+}
+
+func describeAction(action history.Action) string {
+	switch {
+	case action.Error != "":
+		return fmt.Sprintf("%s failed: %s", action.Action, action.Error)
+	case action.Reason != "":
+		return fmt.Sprintf("%s: %s", action.Action, action.Reason)
+	}
+
+	return string(action.Action)
+}
+
+func shortRevision(revision string) string {
+	if len(revision) > 7 {
+		return revision[:7]
+	}
+
+	return revision
+}
@@ -0,0 +1,199 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xenitab/azcagit/src/history"
+)
+
+type testWebhookRequest struct {
+	method string
+	path   string
+	header http.Header
+	body   []byte
+}
+
+func newTestWebhookServer(t *testing.T, statusCode int) (*httptest.Server, *[]testWebhookRequest) {
+	t.Helper()
+
+	requests := []testWebhookRequest{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		requests = append(requests, testWebhookRequest{
+			method: r.Method,
+			path:   r.URL.EscapedPath(),
+			header: r.Header.Clone(),
+			body:   body,
+		})
+		w.WriteHeader(statusCode)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, &requests
+}
+
+func testNotificationEvent() NotificationEvent {
+	return NotificationEvent{
+		Revision:    "6ffa5a7b2da7dc37e186e2581a903e325bbd38be",
+		State:       NotificationStateFailure,
+		Name:        "ze-rg/apps-dev",
+		Description: "sourceApps error: failed to update foo",
+		Actions: []history.Action{
+			{Kind: history.ResourceKindApp, Name: "foo", Action: history.ActionTypeUpdate, Error: "update foobar"},
+			{Kind: history.ResourceKindJob, Name: "bar", Action: history.ActionTypeCreate, Reason: "not in JobCache"},
+		},
+	}
+}
+
+func TestSlackNotification(t *testing.T) {
+	srv, requests := newTestWebhookServer(t, http.StatusOK)
+	slack, err := NewSlackNotification(srv.URL)
+	require.NoError(t, err)
+
+	err = slack.Send(context.Background(), testNotificationEvent())
+	require.NoError(t, err)
+	require.Len(t, *requests, 1)
+	require.JSONEq(t, `{
+		"text": "ze-rg/apps-dev failed (revision 6ffa5a7)",
+		"attachments": [
+			{
+				"color": "danger",
+				"text": "sourceApps error: failed to update foo\n• app/foo update failed: update foobar\n• job/bar create: not in JobCache"
+			}
+		]
+	}`, string((*requests)[0].body))
+}
+
+func TestTeamsNotification(t *testing.T) {
+	srv, requests := newTestWebhookServer(t, http.StatusOK)
+	teams, err := NewTeamsNotification(srv.URL)
+	require.NoError(t, err)
+
+	event := testNotificationEvent()
+	event.State = NotificationStateSuccess
+	event.Description = "reconcile succeeded"
+	event.TargetUrl = "https://example.com/details"
+	event.Actions = event.Actions[1:]
+	err = teams.Send(context.Background(), event)
+	require.NoError(t, err)
+	require.Len(t, *requests, 1)
+	require.JSONEq(t, `{
+		"@type": "MessageCard",
+		"@context": "http://schema.org/extensions",
+		"themeColor": "2EB886",
+		"summary": "ze-rg/apps-dev succeeded (revision 6ffa5a7)",
+		"title": "ze-rg/apps-dev succeeded (revision 6ffa5a7)",
+		"text": "reconcile succeeded",
+		"sections": [{"facts": [{"name": "job/bar", "value": "create: not in JobCache"}]}],
+		"potentialAction": [{"@type": "OpenUri", "name": "Details", "targets": [{"os": "default", "uri": "https://example.com/details"}]}]
+	}`, string((*requests)[0].body))
+}
+
+func TestWebhookNotification(t *testing.T) {
+	srv, requests := newTestWebhookServer(t, http.StatusNoContent)
+
+	t.Run("json with signature", func(t *testing.T) {
+		webhook, err := NewWebhookNotification(srv.URL, "ze-secret", false)
+		require.NoError(t, err)
+
+		err = webhook.Send(context.Background(), testNotificationEvent())
+		require.NoError(t, err)
+		req := (*requests)[len(*requests)-1]
+		require.Equal(t, "application/json", req.header.Get("Content-Type"))
+		require.Equal(t, fmt.Sprintf("sha256=%s", signWebhookBody("ze-secret", req.body)), req.header.Get(webhookSignatureHeader))
+		require.JSONEq(t, `{
+			"revision": "6ffa5a7b2da7dc37e186e2581a903e325bbd38be",
+			"state": "failure",
+			"name": "ze-rg/apps-dev",
+			"description": "sourceApps error: failed to update foo",
+			"actions": [
+				{"kind": "app", "name": "foo", "action": "update", "error": "update foobar"},
+				{"kind": "job", "name": "bar", "action": "create", "reason": "not in JobCache"}
+			]
+		}`, string(req.body))
+	})
+
+	t.Run("cloudevents without signature", func(t *testing.T) {
+		webhook, err := NewWebhookNotification(srv.URL, "", true)
+		require.NoError(t, err)
+		webhook.now = func() time.Time {
+			return time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+		}
+
+		event := testNotificationEvent()
+		event.Actions = nil
+		err = webhook.Send(context.Background(), event)
+		require.NoError(t, err)
+		req := (*requests)[len(*requests)-1]
+		require.Equal(t, "application/cloudevents+json", req.header.Get("Content-Type"))
+		require.Empty(t, req.header.Get(webhookSignatureHeader))
+
+		ce := map[string]any{}
+		err = json.Unmarshal(req.body, &ce)
+		require.NoError(t, err)
+		require.Len(t, ce["id"], 32)
+		delete(ce, "id")
+		require.Equal(t, map[string]any{
+			"specversion":     "1.0",
+			"source":          "azcagit",
+			"type":            "io.xenit.azcagit.notification",
+			"subject":         "ze-rg/apps-dev",
+			"time":            "2023-10-01T12:00:00Z",
+			"datacontenttype": "application/json",
+			"data": map[string]any{
+				"revision":    "6ffa5a7b2da7dc37e186e2581a903e325bbd38be",
+				"state":       "failure",
+				"name":        "ze-rg/apps-dev",
+				"description": "sourceApps error: failed to update foo",
+				"actions":     []any{},
+			},
+		}, ce)
+	})
+}
+
+func TestWebhookNotificationUnexpectedStatusCode(t *testing.T) {
+	srv, _ := newTestWebhookServer(t, http.StatusForbidden)
+	webhook, err := NewWebhookNotification(srv.URL, "", false)
+	require.NoError(t, err)
+
+	err = webhook.Send(context.Background(), testNotificationEvent())
+	require.ErrorContains(t, err, "unexpected status code from webhook: 403")
+}
+
+func TestCommitStatusOnlyNotification(t *testing.T) {
+	srv, requests := newTestWebhookServer(t, http.StatusOK)
+	slack, err := NewSlackNotification(srv.URL)
+	require.NoError(t, err)
+	teams, err := NewTeamsNotification(srv.URL)
+	require.NoError(t, err)
+	webhook, err := NewWebhookNotification(srv.URL, "", false)
+	require.NoError(t, err)
+
+	event := testNotificationEvent()
+	event.CommitStatusOnly = true
+	for _, notification := range []Notification{slack, teams, webhook} {
+		err := notification.Send(context.Background(), event)
+		require.NoError(t, err)
+	}
+	require.Empty(t, *requests)
+}
+
+func TestMultiNotification(t *testing.T) {
+	first := NewInMemNotification()
+	first.SendResponse(fmt.Errorf("ze-first-failure"))
+	second := NewInMemNotification()
+
+	err := NewMultiNotification(first, second).Send(context.Background(), testNotificationEvent())
+	require.ErrorContains(t, err, "ze-first-failure")
+	require.Len(t, first.GetNotifications(), 1)
+	require.Len(t, second.GetNotifications(), 1)
+}
@@ -0,0 +1,101 @@
+package preview
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v6"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v6/git"
+)
+
+type AzureDevOpsPullRequests struct {
+	project string
+	repo    string
+	branch  string
+	client  git.Client
+}
+
+var _ PullRequests = (*AzureDevOpsPullRequests)(nil)
+
+// NewAzureDevOpsPullRequests uses apiUrl as the organization (or collection for Azure DevOps Server) url if set
+func NewAzureDevOpsPullRequests(host string, id string, token string, apiUrl string, branch string) (*AzureDevOpsPullRequests, error) {
+	comp := strings.Split(id, "/")
+	if len(comp) < 3 || comp[len(comp)-2] != "_git" {
+		return nil, fmt.Errorf("invalid repository id %q", id)
+	}
+
+	orgUrl := strings.TrimSuffix(apiUrl, "/")
+	if orgUrl == "" {
+		if len(comp) != 4 {
+			return nil, fmt.Errorf("invalid repository id %q", id)
+		}
+		orgUrl = fmt.Sprintf("%s/%s", host, comp[0])
+	}
+
+	connection := azuredevops.NewPatConnection(orgUrl, token)
+	client := connection.GetClientByUrl(orgUrl)
+
+	return &AzureDevOpsPullRequests{
+		project: comp[len(comp)-3],
+		repo:    comp[len(comp)-1],
+		branch:  branch,
+		client:  &git.ClientImpl{Client: *client},
+	}, nil
+}
+
+func (a *AzureDevOpsPullRequests) List(ctx context.Context) ([]PullRequest, error) {
+	prs, err := a.client.GetPullRequests(ctx, git.GetPullRequestsArgs{
+		Project:      &a.project,
+		RepositoryId: &a.repo,
+		SearchCriteria: &git.GitPullRequestSearchCriteria{
+			Status:        &git.PullRequestStatusValues.Active,
+			TargetRefName: toPtr(fmt.Sprintf("refs/heads/%s", a.branch)),
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not list pull requests: %w", err)
+	}
+
+	pullRequests := []PullRequest{}
+	if prs == nil {
+		return pullRequests, nil
+	}
+
+	for _, pr := range *prs {
+		// pull requests from forks are skipped, as the branch can't be cloned from the repository
+		if pr.ForkSource != nil || pr.PullRequestId == nil || pr.SourceRefName == nil {
+			continue
+		}
+
+		revision := ""
+		if pr.LastMergeSourceCommit != nil && pr.LastMergeSourceCommit.CommitId != nil {
+			revision = *pr.LastMergeSourceCommit.CommitId
+		}
+
+		pullRequests = append(pullRequests, PullRequest{
+			Number:   *pr.PullRequestId,
+			Branch:   strings.TrimPrefix(*pr.SourceRefName, "refs/heads/"),
+			Revision: revision,
+		})
+	}
+
+	return pullRequests, nil
+}
+
+func (a *AzureDevOpsPullRequests) Comment(ctx context.Context, number int, body string) error {
+	_, err := a.client.CreateThread(ctx, git.CreateThreadArgs{
+		Project:       &a.project,
+		RepositoryId:  &a.repo,
+		PullRequestId: &number,
+		CommentThread: &git.GitPullRequestCommentThread{
+			Comments: &[]git.Comment{{Content: &body}},
+			Status:   &git.CommentThreadStatusValues.Closed,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("could not create comment: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package preview
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v41/github"
+	"golang.org/x/oauth2"
+)
+
+type GitHubPullRequests struct {
+	owner  string
+	repo   string
+	branch string
+	client *github.Client
+}
+
+var _ PullRequests = (*GitHubPullRequests)(nil)
+
+// NewGitHubPullRequests uses the GitHub Enterprise Server api if apiUrl is set or the host isn't github.com
+func NewGitHubPullRequests(host string, id string, token string, apiUrl string, branch string) (*GitHubPullRequests, error) {
+	comp := strings.Split(id, "/")
+	if len(comp) != 2 {
+		return nil, fmt.Errorf("invalid repository id %q", id)
+	}
+
+	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
+	tc := oauth2.NewClient(context.Background(), ts)
+	client := github.NewClient(tc)
+	if apiUrl != "" || !strings.HasSuffix(host, "://github.com") {
+		if apiUrl == "" {
+			apiUrl = host + "/api/v3"
+		}
+		var err error
+		client, err = github.NewEnterpriseClient(apiUrl, "", tc)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &GitHubPullRequests{
+		owner:  comp[0],
+		repo:   comp[1],
+		branch: branch,
+		client: client,
+	}, nil
+}
+
+func (g *GitHubPullRequests) List(ctx context.Context) ([]PullRequest, error) {
+	fullName := fmt.Sprintf("%s/%s", g.owner, g.repo)
+	opts := &github.PullRequestListOptions{
+		State:       "open",
+		Base:        g.branch,
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+
+	pullRequests := []PullRequest{}
+	for {
+		prs, res, err := g.client.PullRequests.List(ctx, g.owner, g.repo, opts)
+		if err != nil {
+			return nil, fmt.Errorf("could not list pull requests: %w", err)
+		}
+
+		for _, pr := range prs {
+			// pull requests from forks are skipped, as the branch can't be cloned from the repository
+			if !strings.EqualFold(pr.GetHead().GetRepo().GetFullName(), fullName) {
+				continue
+			}
+
+			pullRequests = append(pullRequests, PullRequest{
+				Number:   pr.GetNumber(),
+				Branch:   pr.GetHead().GetRef(),
+				Revision: pr.GetHead().GetSHA(),
+			})
+		}
+
+		if res.NextPage == 0 {
+			break
+		}
+		opts.Page = res.NextPage
+	}
+
+	return pullRequests, nil
+}
+
+func (g *GitHubPullRequests) Comment(ctx context.Context, number int, body string) error {
+	_, _, err := g.client.Issues.CreateComment(ctx, g.owner, g.repo, number, &github.IssueComment{Body: &body})
+	if err != nil {
+		return fmt.Errorf("could not create comment: %w", err)
+	}
+
+	return nil
+}
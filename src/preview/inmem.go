@@ -0,0 +1,34 @@
+package preview
+
+import "context"
+
+type InMemPullRequests struct {
+	pullRequests []PullRequest
+	comments     map[int][]string
+}
+
+var _ PullRequests = (*InMemPullRequests)(nil)
+
+func NewInMemPullRequests() *InMemPullRequests {
+	return &InMemPullRequests{
+		pullRequests: []PullRequest{},
+		comments:     make(map[int][]string),
+	}
+}
+
+func (p *InMemPullRequests) List(_ context.Context) ([]PullRequest, error) {
+	return p.pullRequests, nil
+}
+
+func (p *InMemPullRequests) ListResponse(pullRequests []PullRequest) {
+	p.pullRequests = pullRequests
+}
+
+func (p *InMemPullRequests) Comment(_ context.Context, number int, body string) error {
+	p.comments[number] = append(p.comments[number], body)
+	return nil
+}
+
+func (p *InMemPullRequests) GetComments(number int) []string {
+	return p.comments[number]
+}
@@ -0,0 +1,208 @@
+package preview
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/hashicorp/go-multierror"
+	"github.com/xenitab/azcagit/src/remote"
+)
+
+// PreviewTag is set (to the pull request number) on preview apps instead of the aca.xenit.io tag, which
+// makes them unmanaged by the reconcile of the environment
+const PreviewTag = "aca.xenit.io-preview"
+
+type PullRequest struct {
+	Number int
+	// Branch is the source branch of the pull request, without refs/heads/
+	Branch   string
+	Revision string
+}
+
+// PullRequests lists the open pull requests targeting the git branch and comments on them
+type PullRequests interface {
+	List(ctx context.Context) ([]PullRequest, error)
+	Comment(ctx context.Context, number int, body string) error
+}
+
+type Reconciler interface {
+	Run(ctx context.Context) error
+}
+
+// NewReconcilerFunc creates a reconciler for the branch of the pull request, using the remote app client
+// for the preview apps
+type NewReconcilerFunc func(ctx context.Context, pullRequest PullRequest, remoteAppClient remote.App) (Reconciler, error)
+
+// Previewer deploys the apps of each open pull request as preview apps and deletes them when the pull
+// request is closed
+type Previewer struct {
+	pullRequests    PullRequests
+	remoteAppClient remote.App
+	newReconciler   NewReconcilerFunc
+}
+
+func NewPreviewer(pullRequests PullRequests, remoteAppClient remote.App, newReconciler NewReconcilerFunc) *Previewer {
+	return &Previewer{
+		pullRequests:    pullRequests,
+		remoteAppClient: remoteAppClient,
+		newReconciler:   newReconciler,
+	}
+}
+
+func (p *Previewer) Run(ctx context.Context) error {
+	pullRequests, err := p.pullRequests.List(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to list pull requests: %w", err)
+	}
+
+	var result *multierror.Error
+	open := make(map[int]struct{})
+	for _, pullRequest := range pullRequests {
+		open[pullRequest.Number] = struct{}{}
+		err := p.runPullRequest(ctx, pullRequest)
+		if err != nil {
+			result = multierror.Append(fmt.Errorf("preview of pull request %d: %w", pullRequest.Number, err), result)
+		}
+	}
+
+	err = p.deleteClosed(ctx, open)
+	if err != nil {
+		result = multierror.Append(err, result)
+	}
+
+	return result.ErrorOrNil()
+}
+
+func (p *Previewer) runPullRequest(ctx context.Context, pullRequest PullRequest) error {
+	log := logr.FromContextOrDiscard(ctx)
+
+	appClient := NewApp(p.remoteAppClient, pullRequest.Number)
+	reconciler, err := p.newReconciler(ctx, pullRequest, appClient)
+	if err != nil {
+		return err
+	}
+
+	var result *multierror.Error
+	err = reconciler.Run(ctx)
+	if err != nil {
+		result = multierror.Append(err, result)
+	}
+
+	// only comment when apps are created, not on every update of the pull request
+	if len(appClient.Created()) == 0 {
+		return result.ErrorOrNil()
+	}
+
+	body, err := p.getComment(ctx, pullRequest, appClient)
+	if err != nil {
+		return multierror.Append(err, result).ErrorOrNil()
+	}
+
+	err = p.pullRequests.Comment(ctx, pullRequest.Number, body)
+	if err != nil {
+		return multierror.Append(fmt.Errorf("unable to comment: %w", err), result).ErrorOrNil()
+	}
+
+	log.Info("commented preview apps on pull request", "pull_request", pullRequest.Number, "created", appClient.Created())
+
+	return result.ErrorOrNil()
+}
+
+func (p *Previewer) getComment(ctx context.Context, pullRequest PullRequest, appClient *App) (string, error) {
+	previewApps, err := appClient.Get(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	sb := &strings.Builder{}
+	fmt.Fprintf(sb, "Preview of `%s` deployed by azcagit:\n\n", shortRevision(pullRequest.Revision))
+	for _, name := range previewApps.GetSortedNames() {
+		app, _ := previewApps.Get(name)
+		fqdn := getFqdn(app)
+		if fqdn == "" {
+			fmt.Fprintf(sb, "- `%s` (no ingress)\n", PreviewAppName(name, pullRequest.Number))
+			continue
+		}
+		fmt.Fprintf(sb, "- `%s`: https://%s\n", PreviewAppName(name, pullRequest.Number), fqdn)
+	}
+	sb.WriteString("\nThe preview is deleted when the pull request is closed.\n")
+
+	return sb.String(), nil
+}
+
+func (p *Previewer) deleteClosed(ctx context.Context, open map[int]struct{}) error {
+	log := logr.FromContextOrDiscard(ctx)
+
+	remoteApps, err := p.remoteAppClient.Get(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to get remote apps: %w", err)
+	}
+
+	if remoteApps == nil {
+		return nil
+	}
+
+	var result *multierror.Error
+	for _, name := range remoteApps.GetSortedNames() {
+		app, _ := remoteApps.Get(name)
+		number, ok := getPreviewNumber(app)
+		if !ok {
+			continue
+		}
+
+		if _, ok := open[number]; ok {
+			continue
+		}
+
+		err := p.remoteAppClient.Delete(ctx, name)
+		if err != nil {
+			result = multierror.Append(fmt.Errorf("unable to delete preview app %s: %w", name, err), result)
+			continue
+		}
+		log.Info("deleted preview app of closed pull request", "app", name, "pull_request", number)
+	}
+
+	return result.ErrorOrNil()
+}
+
+// PreviewAppName returns the name of the preview app, the name of the app with a pull request number suffix
+func PreviewAppName(name string, number int) string {
+	return fmt.Sprintf("%s-pr%d", name, number)
+}
+
+func getPreviewNumber(app remote.RemoteApp) (int, bool) {
+	if app.App == nil || app.App.Tags == nil {
+		return 0, false
+	}
+
+	tag, ok := app.App.Tags[PreviewTag]
+	if !ok || tag == nil {
+		return 0, false
+	}
+
+	number, err := strconv.Atoi(*tag)
+	if err != nil {
+		return 0, false
+	}
+
+	return number, true
+}
+
+func getFqdn(app remote.RemoteApp) string {
+	if app.App == nil || app.App.Properties == nil || app.App.Properties.Configuration == nil || app.App.Properties.Configuration.Ingress == nil || app.App.Properties.Configuration.Ingress.Fqdn == nil {
+		return ""
+	}
+
+	return *app.App.Properties.Configuration.Ingress.Fqdn
+}
+
+func shortRevision(revision string) string {
+	if len(revision) > 7 {
+		return revision[:7]
+	}
+
+	return revision
+}
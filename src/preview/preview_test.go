@@ -0,0 +1,178 @@
+package preview
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/appcontainers/armappcontainers/v2"
+	"github.com/stretchr/testify/require"
+	"github.com/xenitab/azcagit/src/remote"
+	"github.com/xenitab/azcagit/src/source"
+)
+
+type testReconciler struct {
+	run func(ctx context.Context) error
+}
+
+func (r *testReconciler) Run(ctx context.Context) error {
+	return r.run(ctx)
+}
+
+func testPreviewApp(number string, fqdn string) remote.RemoteApp {
+	return remote.RemoteApp{
+		App: &armappcontainers.ContainerApp{
+			Tags: map[string]*string{
+				PreviewTag: toPtr(number),
+			},
+			Properties: &armappcontainers.ContainerAppProperties{
+				Configuration: &armappcontainers.Configuration{
+					Ingress: &armappcontainers.Ingress{
+						Fqdn: toPtr(fqdn),
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestPreviewer(t *testing.T) {
+	ctx := context.Background()
+	pullRequests := NewInMemPullRequests()
+	remoteAppClient := remote.NewInMemApp()
+
+	pullRequests.ListResponse([]PullRequest{{Number: 1, Branch: "feature", Revision: "6ffa5a7b2da7dc37e186e2581a903e325bbd38be"}})
+	remoteApps := &remote.RemoteApps{
+		"foo-pr1": testPreviewApp("1", "foo-pr1.example.com"),
+		"bar-pr2": testPreviewApp("2", "bar-pr2.example.com"),
+		"baz": remote.RemoteApp{
+			App:     &armappcontainers.ContainerApp{},
+			Managed: true,
+		},
+	}
+	remoteAppClient.GetFirstResponse(remoteApps, nil)
+	remoteAppClient.GetSecondResponse(remoteApps, nil)
+
+	branches := []string{}
+	previewer := NewPreviewer(pullRequests, remoteAppClient, func(_ context.Context, pullRequest PullRequest, appClient remote.App) (Reconciler, error) {
+		branches = append(branches, pullRequest.Branch)
+		return &testReconciler{
+			run: func(ctx context.Context) error {
+				return appClient.Create(ctx, "foo", armappcontainers.ContainerApp{})
+			},
+		}, nil
+	})
+
+	err := previewer.Run(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"feature"}, branches)
+
+	actions := remoteAppClient.Actions()
+	require.Len(t, actions, 2)
+	require.Equal(t, "foo-pr1", actions[0].Name)
+	require.Equal(t, remote.InMemAppActionsCreate, actions[0].Action)
+	require.Equal(t, "bar-pr2", actions[1].Name)
+	require.Equal(t, remote.InMemAppActionsDelete, actions[1].Action)
+
+	require.Equal(t, []string{"Preview of `6ffa5a7` deployed by azcagit:\n\n- `foo-pr1`: https://foo-pr1.example.com\n\nThe preview is deleted when the pull request is closed.\n"}, pullRequests.GetComments(1))
+
+	// no comment when the apps already exist
+	remoteAppClient.ResetActions()
+	remoteAppClient.ResetGetSecond()
+	previewer = NewPreviewer(pullRequests, remoteAppClient, func(_ context.Context, _ PullRequest, appClient remote.App) (Reconciler, error) {
+		return &testReconciler{
+			run: func(ctx context.Context) error {
+				return appClient.Update(ctx, "foo", armappcontainers.ContainerApp{})
+			},
+		}, nil
+	})
+	err = previewer.Run(ctx)
+	require.NoError(t, err)
+	require.Len(t, pullRequests.GetComments(1), 1)
+}
+
+func TestApp(t *testing.T) {
+	ctx := context.Background()
+	remoteAppClient := remote.NewInMemApp()
+	remoteAppClient.GetFirstResponse(&remote.RemoteApps{
+		"foo-pr1": testPreviewApp("1", "foo-pr1.example.com"),
+		"foo-pr2": testPreviewApp("2", "foo-pr2.example.com"),
+		"foo": remote.RemoteApp{
+			App:     &armappcontainers.ContainerApp{},
+			Managed: true,
+		},
+	}, nil)
+
+	appClient := NewApp(remoteAppClient, 1)
+	previewApps, err := appClient.Get(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"foo"}, previewApps.GetSortedNames())
+	previewApp, _ := previewApps.Get("foo")
+	require.True(t, previewApp.Managed)
+	require.Equal(t, "foo-pr1.example.com", getFqdn(previewApp))
+
+	app := armappcontainers.ContainerApp{
+		Tags: map[string]*string{
+			"aca.xenit.io": toPtr("true"),
+			"team":         toPtr("foo"),
+		},
+		Properties: &armappcontainers.ContainerAppProperties{
+			Configuration: &armappcontainers.Configuration{
+				Ingress: &armappcontainers.Ingress{
+					CustomDomains: []*armappcontainers.CustomDomain{
+						{Name: toPtr("foo.example.com")},
+					},
+				},
+			},
+		},
+	}
+	err = appClient.Create(ctx, "foo", app)
+	require.NoError(t, err)
+	require.Equal(t, []string{"foo"}, appClient.Created())
+
+	actions := remoteAppClient.Actions()
+	require.Len(t, actions, 1)
+	require.Equal(t, "foo-pr1", actions[0].Name)
+	require.Equal(t, map[string]*string{"team": toPtr("foo"), PreviewTag: toPtr("1")}, actions[0].App.Tags)
+	require.Empty(t, actions[0].App.Properties.Configuration.Ingress.CustomDomains)
+	// the app of the source isn't changed
+	require.Len(t, app.Properties.Configuration.Ingress.CustomDomains, 1)
+	require.Contains(t, app.Tags, "aca.xenit.io")
+}
+
+func TestSource(t *testing.T) {
+	ctx := context.Background()
+	sourceClient := source.NewInMemSource()
+	apps := &source.SourceApps{
+		"foo":                              source.SourceApp{},
+		"a-very-long-name-of-an-app-abcde": source.SourceApp{},
+		"baz": source.SourceApp{
+			Specification: &source.SourceAppSpecification{
+				App: &armappcontainers.ContainerApp{},
+				CustomDomains: []source.CustomDomainSpecification{
+					{
+						Name:            toPtr("baz.example.com"),
+						CertificateName: toPtr("baz-example-com"),
+					},
+				},
+			},
+		},
+	}
+	sourceClient.GetResponse(&source.Sources{
+		Apps: apps,
+		Jobs: &source.SourceJobs{"bar": source.SourceJob{}},
+	}, "ze-revision", nil)
+
+	sources, revision, err := NewSource(sourceClient, 1).Get(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "ze-revision", revision)
+	require.Len(t, *sources.Apps, 3)
+	require.Equal(t, []string{"baz", "foo"}, sources.Apps.GetSortedNames())
+	require.ErrorContains(t, sources.Apps.Error(), "preview app name \"a-very-long-name-of-an-app-abcde-pr1\" is longer than 32 characters")
+	require.Empty(t, (*sources.Apps)["baz"].Specification.CustomDomains)
+	require.NotNil(t, (*sources.Apps)["baz"].Specification.App)
+	require.Len(t, (*apps)["baz"].Specification.CustomDomains, 1, "the source apps shouldn't be changed")
+	require.Empty(t, *sources.Jobs)
+	require.Empty(t, *sources.DaprComponents)
+	require.Empty(t, *sources.Certificates)
+	require.Empty(t, *sources.Storages)
+}
@@ -0,0 +1,49 @@
+package preview
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/xenitab/azcagit/src/config"
+)
+
+// NewPullRequests uses the token from the git url, the provider is detected from the hostname or set with
+// --notification-provider (and --notification-api-url) like for notifications
+func NewPullRequests(cfg config.ReconcileConfig) (PullRequests, error) {
+	gitUrl, err := url.Parse(cfg.GitUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	token, ok := gitUrl.User.Password()
+	if !ok {
+		token = gitUrl.User.Username()
+	}
+
+	if token == "" {
+		return nil, fmt.Errorf("previews require a token in the git url")
+	}
+
+	host := fmt.Sprintf("%s://%s", gitUrl.Scheme, gitUrl.Host)
+	id := strings.TrimSuffix(strings.TrimPrefix(gitUrl.Path, "/"), ".git")
+
+	provider := strings.ToLower(strings.TrimSpace(cfg.NotificationProvider))
+	if provider == "" {
+		switch gitUrl.Hostname() {
+		case "github.com":
+			provider = "github"
+		case "dev.azure.com":
+			provider = "azure-devops"
+		}
+	}
+
+	switch provider {
+	case "github":
+		return NewGitHubPullRequests(host, id, token, cfg.NotificationApiUrl, cfg.GitBranch)
+	case "azure-devops":
+		return NewAzureDevOpsPullRequests(host, id, token, cfg.NotificationApiUrl, cfg.GitBranch)
+	}
+
+	return nil, fmt.Errorf("previews are only supported for github and azure-devops, unable to find provider for hostname: %s", gitUrl.Hostname())
+}
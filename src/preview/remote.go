@@ -0,0 +1,111 @@
+package preview
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/appcontainers/armappcontainers/v2"
+	"github.com/xenitab/azcagit/src/remote"
+)
+
+// App manages the preview apps of a pull request, using the names of the source apps. The preview apps are
+// the only apps seen (as managed) by the reconciler
+type App struct {
+	app     remote.App
+	number  int
+	created []string
+}
+
+var _ remote.App = (*App)(nil)
+
+func NewApp(app remote.App, number int) *App {
+	return &App{
+		app:    app,
+		number: number,
+	}
+}
+
+func (a *App) Get(ctx context.Context) (*remote.RemoteApps, error) {
+	remoteApps, err := a.app.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if remoteApps == nil {
+		return nil, nil
+	}
+
+	suffix := PreviewAppName("", a.number)
+	previewApps := remote.RemoteApps{}
+	for name, remoteApp := range *remoteApps {
+		number, ok := getPreviewNumber(remoteApp)
+		if !ok || number != a.number || !strings.HasSuffix(name, suffix) {
+			continue
+		}
+
+		previewApps[strings.TrimSuffix(name, suffix)] = remote.RemoteApp{
+			App:     remoteApp.App,
+			Managed: true,
+		}
+	}
+
+	return &previewApps, nil
+}
+
+func (a *App) Create(ctx context.Context, name string, app armappcontainers.ContainerApp) error {
+	err := a.app.Create(ctx, PreviewAppName(name, a.number), toPreviewApp(app, a.number))
+	if err != nil {
+		return err
+	}
+
+	a.created = append(a.created, name)
+	return nil
+}
+
+func (a *App) Update(ctx context.Context, name string, app armappcontainers.ContainerApp) error {
+	return a.app.Update(ctx, PreviewAppName(name, a.number), toPreviewApp(app, a.number))
+}
+
+func (a *App) Delete(ctx context.Context, name string) error {
+	return a.app.Delete(ctx, PreviewAppName(name, a.number))
+}
+
+func (a *App) RestartRevisions(ctx context.Context, name string) ([]string, error) {
+	return a.app.RestartRevisions(ctx, PreviewAppName(name, a.number))
+}
+
+// Created returns the names of the source apps created by the reconciler
+func (a *App) Created() []string {
+	return a.created
+}
+
+// toPreviewApp replaces the aca.xenit.io tag with the preview tag and removes the custom domains, which are
+// already bound to the app of the environment
+func toPreviewApp(app armappcontainers.ContainerApp, number int) armappcontainers.ContainerApp {
+	tags := make(map[string]*string)
+	for key, value := range app.Tags {
+		if key == "aca.xenit.io" {
+			continue
+		}
+		tags[key] = value
+	}
+	tags[PreviewTag] = toPtr(strconv.Itoa(number))
+	app.Tags = tags
+
+	if app.Properties != nil && app.Properties.Configuration != nil && app.Properties.Configuration.Ingress != nil {
+		properties := *app.Properties
+		configuration := *properties.Configuration
+		ingress := *configuration.Ingress
+		ingress.CustomDomains = nil
+		configuration.Ingress = &ingress
+		properties.Configuration = &configuration
+		app.Properties = &properties
+	}
+
+	return app
+}
+
+func toPtr[T any](a T) *T {
+	return &a
+}
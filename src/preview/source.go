@@ -0,0 +1,67 @@
+package preview
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/xenitab/azcagit/src/source"
+)
+
+// Source only returns the apps of the pull request, the other resources are shared by the environment. The custom
+// domains are removed from the apps, as the hostnames and certificates belong to the apps of the environment.
+type Source struct {
+	source source.Source
+	number int
+}
+
+var _ source.Source = (*Source)(nil)
+
+// maxAppNameLength is the max length of a Container App name, which the suffix of the preview can exceed
+const maxAppNameLength = 32
+
+func NewSource(src source.Source, number int) *Source {
+	return &Source{
+		source: src,
+		number: number,
+	}
+}
+
+func (s *Source) Get(ctx context.Context) (*source.Sources, string, error) {
+	sources, revision, err := s.source.Get(ctx)
+	if err != nil {
+		return nil, revision, err
+	}
+
+	apps := &source.SourceApps{}
+	if sources != nil && sources.Apps != nil {
+		for name, app := range *sources.Apps {
+			app = withoutCustomDomains(app)
+			previewName := PreviewAppName(name, s.number)
+			if app.Err == nil && len(previewName) > maxAppNameLength {
+				app.Err = fmt.Errorf("preview app name %q is longer than %d characters", previewName, maxAppNameLength)
+			}
+			(*apps)[name] = app
+		}
+	}
+
+	return &source.Sources{
+		Apps:           apps,
+		Jobs:           &source.SourceJobs{},
+		DaprComponents: &source.SourceDaprComponents{},
+		Certificates:   &source.SourceCertificates{},
+		Storages:       &source.SourceStorages{},
+	}, revision, nil
+}
+
+// withoutCustomDomains returns a copy of the app without custom domains, the source app isn't changed
+func withoutCustomDomains(app source.SourceApp) source.SourceApp {
+	if app.Specification == nil || len(app.Specification.CustomDomains) == 0 {
+		return app
+	}
+
+	specification := *app.Specification
+	specification.CustomDomains = nil
+	app.Specification = &specification
+
+	return app
+}
@@ -0,0 +1,402 @@
+package reconcile
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/appcontainers/armappcontainers/v2"
+	"github.com/go-logr/logr"
+	"github.com/xenitab/azcagit/src/history"
+	"github.com/xenitab/azcagit/src/remote"
+	"github.com/xenitab/azcagit/src/source"
+)
+
+// certificateBindings maps certificate names to resource ids, a nil id means that the managed
+// certificate doesn't exist yet and the custom domain has to be added without a binding
+type certificateBindings map[string]*string
+
+func (bindings certificateBindings) pending() bool {
+	for _, certificateID := range bindings {
+		if certificateID == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// certificateReconciliation is the state shared between reconciling the certificates before and
+// after the apps
+type certificateReconciliation struct {
+	sourceCertificates *source.SourceCertificates
+	resources          map[string]remote.CertificateResource
+	remoteCertificates *remote.RemoteCertificates
+	bindings           certificateBindings
+	// shared is true when the managed environment is reconciled by another target, only binding to its certificates
+	shared bool
+}
+
+// runSourceCertificates uploads the certificates from KeyVault, which has to be done before the apps
+// bind custom domains to them. Managed certificates are created by finishSourceCertificates after
+// the apps, as the validation requires the custom domain to be added to an app first.
+func (r *Reconciler) runSourceCertificates(ctx context.Context, sources *source.Sources) (*certificateReconciliation, error) {
+	if r.cfg.SharedEnvironmentWith() != "" {
+		return r.runSharedCertificates(ctx, sources)
+	}
+
+	sourceCertificates, err := r.getSourceCertificates(ctx, sources)
+	if err != nil {
+		return nil, err
+	}
+
+	if sourceCertificates == nil {
+		return nil, nil
+	}
+
+	r.filterSourceCertificates(ctx, sourceCertificates)
+
+	resources, err := r.getSourceCertificateResources(ctx, sourceCertificates)
+	if err != nil {
+		return nil, err
+	}
+
+	remoteCertificates, err := r.getRemoteCertificates(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.createOrUpdateCertificatesIfNeeded(ctx, sourceCertificates, resources, remoteCertificates, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &certificateReconciliation{
+		sourceCertificates: sourceCertificates,
+		resources:          resources,
+		remoteCertificates: remoteCertificates,
+		bindings:           r.getCertificateBindings(resources, remoteCertificates),
+	}, nil
+}
+
+func (r *Reconciler) finishSourceCertificates(ctx context.Context, certificates *certificateReconciliation) (*remote.RemoteCertificates, error) {
+	if certificates == nil {
+		return nil, nil
+	}
+
+	if certificates.shared {
+		return certificates.remoteCertificates, nil
+	}
+
+	err := r.createOrUpdateCertificatesIfNeeded(ctx, certificates.sourceCertificates, certificates.resources, certificates.remoteCertificates, true)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.deleteCertificatesIfNeeded(ctx, certificates.sourceCertificates, certificates.remoteCertificates)
+	if err != nil {
+		return nil, err
+	}
+
+	newRemoteCertificates, err := r.updateCertificateCache(ctx, certificates.sourceCertificates, certificates.resources)
+	if err != nil {
+		return nil, err
+	}
+
+	return newRemoteCertificates, nil
+}
+
+// runSharedCertificates binds the custom domains to the certificates of the target reconciling the shared managed
+// environment, a certificate not created yet by that target is added without a binding until the next reconcile
+func (r *Reconciler) runSharedCertificates(ctx context.Context, sources *source.Sources) (*certificateReconciliation, error) {
+	sourceCertificates, err := r.getSourceCertificates(ctx, sources)
+	if err != nil {
+		return nil, err
+	}
+
+	if sourceCertificates != nil && len(*sourceCertificates) > 0 {
+		return nil, fmt.Errorf("certificates can't be reconciled as the managed environment is reconciled by the target for resource group %q", r.cfg.SharedEnvironmentWith())
+	}
+
+	remoteCertificates, err := r.getRemoteCertificates(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	bindings := make(certificateBindings)
+	if sources.Apps != nil {
+		for _, name := range sources.Apps.GetSortedNames() {
+			app, _ := sources.Apps.Get(name)
+			for _, customDomain := range app.GetCustomDomains() {
+				certificateName := *customDomain.CertificateName
+				remoteCertificate, ok := remoteCertificates.Get(certificateName)
+				switch {
+				case !ok:
+					bindings[certificateName] = nil
+				case remoteCertificate.IsManagedCertificate():
+					bindings[certificateName] = toPtr(fmt.Sprintf("%s/managedCertificates/%s", r.cfg.ManagedEnvironmentID, certificateName))
+				default:
+					bindings[certificateName] = toPtr(fmt.Sprintf("%s/certificates/%s", r.cfg.ManagedEnvironmentID, certificateName))
+				}
+			}
+		}
+	}
+
+	return &certificateReconciliation{
+		remoteCertificates: remoteCertificates,
+		bindings:           bindings,
+		shared:             true,
+	}, nil
+}
+
+func (r *Reconciler) getSourceCertificates(ctx context.Context, sources *source.Sources) (*source.SourceCertificates, error) {
+	if sources == nil {
+		return nil, fmt.Errorf("sources is nil")
+	}
+
+	if sources.Certificates == nil {
+		return nil, nil
+	}
+
+	sourceCertificates := sources.Certificates
+
+	if sourceCertificates.Error() != nil {
+		return nil, fmt.Errorf("sourceCertificates contains errors, stopping reconciliation: %w", sourceCertificates.Error())
+	}
+
+	return sourceCertificates, nil
+}
+
+func (r *Reconciler) filterSourceCertificates(ctx context.Context, sourceCertificates *source.SourceCertificates) {
+	log := logr.FromContextOrDiscard(ctx)
+
+	for _, name := range sourceCertificates.GetSortedNames() {
+		certificate, _ := sourceCertificates.Get(name)
+		shouldRunInLocation := certificate.ShoudRunInLocation(r.cfg.Location)
+		if !shouldRunInLocation {
+			log.V(1).Info("sourceCertificate was deleted because of location mis-match", "certificate", certificate.Name(), "currentLocation", r.cfg.Location, "locationFilter", certificate.Specification.LocationFilter)
+			sourceCertificates.Delete(name)
+		}
+	}
+}
+
+// getSourceCertificateResources reads the certificate secrets directly, as the values are decoded
+func (r *Reconciler) getSourceCertificateResources(ctx context.Context, sourceCertificates *source.SourceCertificates) (map[string]remote.CertificateResource, error) {
+	resources := make(map[string]remote.CertificateResource)
+	for _, name := range sourceCertificates.GetSortedNames() {
+		certificate, _ := sourceCertificates.Get(name)
+		tags := map[string]*string{
+			"aca.xenit.io": toPtr("true"),
+		}
+
+		if certificate.IsManagedCertificate() {
+			resources[name] = remote.CertificateResource{
+				ManagedCertificate: &armappcontainers.ManagedCertificate{
+					Location: &r.cfg.Location,
+					Tags:     tags,
+					Properties: &armappcontainers.ManagedCertificateProperties{
+						SubjectName:             certificate.Specification.ManagedCertificate.SubjectName,
+						DomainControlValidation: certificate.Specification.ManagedCertificate.DomainControlValidation,
+					},
+				},
+			}
+			continue
+		}
+
+		remoteSecrets := certificate.GetRemoteSecrets()
+		if len(remoteSecrets) != 1 {
+			return nil, fmt.Errorf("secret for certificate %q not valid", name)
+		}
+
+		secretPlaceholder, err := r.getSecretPlaceholder(remoteSecrets[0].SecretKey())
+		if err != nil {
+			return nil, fmt.Errorf("unable to get secret for certificate %q: %w", name, err)
+		}
+
+		resources[name] = remote.CertificateResource{
+			Certificate: &armappcontainers.Certificate{
+				Location: &r.cfg.Location,
+				Tags:     tags,
+				Properties: &armappcontainers.CertificateProperties{
+					Value: []byte(secretPlaceholder),
+				},
+			},
+		}
+	}
+
+	return resources, nil
+}
+
+// decodeCertificateValue handles both the base64 encoded PFX KeyVault stores for certificates and PEM
+func decodeCertificateValue(value string) []byte {
+	b, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return []byte(value)
+	}
+
+	return b
+}
+
+func (r *Reconciler) getCertificateBindings(resources map[string]remote.CertificateResource, remoteCertificates *remote.RemoteCertificates) certificateBindings {
+	bindings := make(certificateBindings)
+	for name, resource := range resources {
+		if !resource.IsManagedCertificate() {
+			bindings[name] = toPtr(fmt.Sprintf("%s/certificates/%s", r.cfg.ManagedEnvironmentID, name))
+			continue
+		}
+
+		remoteCertificate, ok := remoteCertificates.Get(name)
+		if !ok || !remoteCertificate.IsManagedCertificate() {
+			bindings[name] = nil
+			continue
+		}
+
+		bindings[name] = toPtr(fmt.Sprintf("%s/managedCertificates/%s", r.cfg.ManagedEnvironmentID, name))
+	}
+
+	return bindings
+}
+
+func (r *Reconciler) getRemoteCertificates(ctx context.Context) (*remote.RemoteCertificates, error) {
+	remoteCertificates, err := r.remoteCertificateClient.Get(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get remoteCertificates: %w", err)
+	}
+
+	if remoteCertificates == nil {
+		return nil, fmt.Errorf("remoteCertificates is nil")
+	}
+
+	return remoteCertificates, nil
+}
+
+func (r *Reconciler) createOrUpdateCertificatesIfNeeded(ctx context.Context, sourceCertificates *source.SourceCertificates, resources map[string]remote.CertificateResource, remoteCertificates *remote.RemoteCertificates, managedCertificates bool) error {
+	log := logr.FromContextOrDiscard(ctx)
+
+	for _, name := range sourceCertificates.GetSortedNames() {
+		resource := resources[name]
+		if resource.IsManagedCertificate() != managedCertificates {
+			continue
+		}
+
+		remoteCertificate, ok := remoteCertificates.Get(name)
+		needsUpdate, updateReason, err := r.certificateCache.NeedsUpdate(ctx, name, &remoteCertificate.CertificateResource, &resource)
+		if err != nil {
+			return err
+		}
+
+		if !needsUpdate {
+			log.Info("skipping update, no changes", "certificate", name)
+			continue
+		}
+
+		if ok && !remoteCertificate.Managed {
+			return fmt.Errorf("trying to update a non-managed certificate: %s", name)
+		}
+
+		// changing between a certificate and a managed certificate requires the old one to be removed
+		if ok && remoteCertificate.IsManagedCertificate() != resource.IsManagedCertificate() {
+			err := r.remoteCertificateClient.Delete(ctx, name)
+			r.addHistoryAction(history.ResourceKindCertificate, name, history.ActionTypeDelete, updateReason, err)
+			if err != nil {
+				return fmt.Errorf("failed to delete %s: %w", name, err)
+			}
+			ok = false
+		}
+
+		resource, err = r.resolveCertificateSecrets(ctx, resource)
+		if err != nil {
+			return fmt.Errorf("failed to resolve secrets for certificate %s: %w", name, err)
+		}
+
+		if ok {
+			err := r.remoteCertificateClient.Update(ctx, name, resource)
+			r.addHistoryAction(history.ResourceKindCertificate, name, history.ActionTypeUpdate, updateReason, err)
+			if err != nil {
+				return fmt.Errorf("failed to update %s: %w", name, err)
+			}
+			log.Info("updated remoteCertificate", "certificate", name, "reason", updateReason)
+			continue
+		}
+
+		err = r.remoteCertificateClient.Create(ctx, name, resource)
+		r.addHistoryAction(history.ResourceKindCertificate, name, history.ActionTypeCreate, updateReason, err)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", name, err)
+		}
+		log.Info("created remoteCertificate", "certificate", name, "reason", updateReason)
+	}
+
+	return nil
+}
+
+func (r *Reconciler) deleteCertificatesIfNeeded(ctx context.Context, sourceCertificates *source.SourceCertificates, remoteCertificates *remote.RemoteCertificates) error {
+	log := logr.FromContextOrDiscard(ctx)
+
+	for _, name := range remoteCertificates.GetSortedNames() {
+		if sourceCertificates.Error() != nil {
+			log.Error(fmt.Errorf("delete disabled"), "no remoteCertificates will be deleted while sourceCertificates contains errors")
+			break
+		}
+		remoteCertificate, _ := remoteCertificates.Get(name)
+		_, ok := sourceCertificates.Get(name)
+		if !ok {
+			if !remoteCertificate.Managed {
+				continue
+			}
+			err := r.remoteCertificateClient.Delete(ctx, name)
+			r.addHistoryAction(history.ResourceKindCertificate, name, history.ActionTypeDelete, "not in source", err)
+			if err != nil {
+				return err
+			}
+			log.Info("deleted remoteCertificate", "certificate", name)
+		}
+	}
+
+	return nil
+}
+
+func (r *Reconciler) updateCertificateCache(ctx context.Context, sourceCertificates *source.SourceCertificates, resources map[string]remote.CertificateResource) (*remote.RemoteCertificates, error) {
+	newRemoteCertificates, err := r.remoteCertificateClient.Get(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get new remoteCertificates: %w", err)
+	}
+
+	for _, name := range sourceCertificates.GetSortedNames() {
+		resource := resources[name]
+		remoteCertificate, ok := newRemoteCertificates.Get(name)
+		if !ok {
+			return nil, fmt.Errorf("unable to locate certificate %s after create or update", name)
+		}
+		err := r.certificateCache.Set(ctx, name, &remoteCertificate.CertificateResource, &resource)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return newRemoteCertificates, nil
+}
+
+func (r *Reconciler) populateSourceAppsCustomDomains(sourceApps *source.SourceApps, bindings certificateBindings) error {
+	for _, name := range sourceApps.GetSortedNames() {
+		app, _ := sourceApps.Get(name)
+		for _, customDomain := range app.GetCustomDomains() {
+			certificateID, ok := bindings[*customDomain.CertificateName]
+			if !ok {
+				return fmt.Errorf("certificate %q for custom domain %q in app %q not found", *customDomain.CertificateName, *customDomain.Name, name)
+			}
+
+			bindingType := armappcontainers.BindingTypeSniEnabled
+			if certificateID == nil {
+				bindingType = armappcontainers.BindingTypeDisabled
+			}
+
+			err := sourceApps.SetCustomDomain(name, *customDomain.Name, bindingType, certificateID)
+			if err != nil {
+				return fmt.Errorf("unable to set custom domain %q for app %q: %w", *customDomain.Name, name, err)
+			}
+		}
+	}
+
+	return nil
+}
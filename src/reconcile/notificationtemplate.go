@@ -0,0 +1,157 @@
+package reconcile
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/xenitab/azcagit/src/config"
+	"github.com/xenitab/azcagit/src/history"
+	"github.com/xenitab/azcagit/src/notification"
+)
+
+const azurePortalUrl = "https://portal.azure.com/#resource"
+
+// notificationTemplateData is available in the notification description and target url templates
+type notificationTemplateData struct {
+	Revision       string
+	Environment    string
+	ResourceGroup  string
+	SubscriptionID string
+	Name           string
+	State          string
+	// Description is the default description, the error if the reconcile failed
+	Description string
+	// PortalUrl is the resource group in the Azure portal
+	PortalUrl string
+	Resources []notificationTemplateResource
+}
+
+type notificationTemplateResource struct {
+	Kind   string
+	Name   string
+	Action string
+	Reason string
+	Error  string
+	// PortalUrl is the resource in the Azure portal, dapr components, certificates and storages link to the
+	// managed environment
+	PortalUrl string
+	// LogsUrl is only set for apps and jobs
+	LogsUrl string
+}
+
+type notificationTemplates struct {
+	description *template.Template
+	targetUrl   *template.Template
+}
+
+func newNotificationTemplates(cfg config.ReconcileConfig) (*notificationTemplates, error) {
+	templates := &notificationTemplates{}
+	if cfg.NotificationDescription != "" {
+		tmpl, err := template.New("description").Option("missingkey=error").Parse(cfg.NotificationDescription)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse notification description template: %w", err)
+		}
+		templates.description = tmpl
+	}
+
+	if cfg.NotificationTargetUrl != "" {
+		tmpl, err := template.New("targetUrl").Option("missingkey=error").Parse(cfg.NotificationTargetUrl)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse notification target url template: %w", err)
+		}
+		templates.targetUrl = tmpl
+	}
+
+	return templates, nil
+}
+
+// render sets the description and target url of the event from the templates, the event is only changed if
+// both templates succeed so the defaults are kept together if one of them fails
+func (t *notificationTemplates) render(cfg config.ReconcileConfig, event *notification.NotificationEvent) error {
+	if t == nil || (t.description == nil && t.targetUrl == nil) {
+		return nil
+	}
+
+	data := newNotificationTemplateData(cfg, *event)
+	description := event.Description
+	if t.description != nil {
+		var err error
+		description, err = executeTemplate(t.description, data)
+		if err != nil {
+			return fmt.Errorf("unable to render notification description template: %w", err)
+		}
+	}
+
+	targetUrl := event.TargetUrl
+	if t.targetUrl != nil {
+		var err error
+		targetUrl, err = executeTemplate(t.targetUrl, data)
+		if err != nil {
+			return fmt.Errorf("unable to render notification target url template: %w", err)
+		}
+	}
+
+	event.Description = description
+	event.TargetUrl = targetUrl
+
+	return nil
+}
+
+func executeTemplate(tmpl *template.Template, data notificationTemplateData) (string, error) {
+	sb := &strings.Builder{}
+	err := tmpl.Execute(sb, data)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(sb.String()), nil
+}
+
+func newNotificationTemplateData(cfg config.ReconcileConfig, event notification.NotificationEvent) notificationTemplateData {
+	resources := []notificationTemplateResource{}
+	for _, action := range event.Actions {
+		resources = append(resources, notificationTemplateResource{
+			Kind:      string(action.Kind),
+			Name:      action.Name,
+			Action:    string(action.Action),
+			Reason:    action.Reason,
+			Error:     action.Error,
+			PortalUrl: getResourcePortalUrl(cfg, action.Kind, action.Name),
+			LogsUrl:   getResourceLogsUrl(cfg, action.Kind, action.Name),
+		})
+	}
+
+	return notificationTemplateData{
+		Revision:       event.Revision,
+		Environment:    cfg.Environment,
+		ResourceGroup:  cfg.ResourceGroupName,
+		SubscriptionID: cfg.SubscriptionID,
+		Name:           event.Name,
+		State:          event.State.String(),
+		Description:    event.Description,
+		PortalUrl:      fmt.Sprintf("%s/subscriptions/%s/resourceGroups/%s/overview", azurePortalUrl, cfg.SubscriptionID, cfg.ResourceGroupName),
+		Resources:      resources,
+	}
+}
+
+func getResourcePortalUrl(cfg config.ReconcileConfig, kind history.ResourceKind, name string) string {
+	resourceGroupId := fmt.Sprintf("/subscriptions/%s/resourceGroups/%s", cfg.SubscriptionID, cfg.ResourceGroupName)
+	switch kind {
+	case history.ResourceKindApp:
+		return fmt.Sprintf("%s%s/providers/Microsoft.App/containerApps/%s", azurePortalUrl, resourceGroupId, name)
+	case history.ResourceKindJob:
+		return fmt.Sprintf("%s%s/providers/Microsoft.App/jobs/%s", azurePortalUrl, resourceGroupId, name)
+	}
+
+	return fmt.Sprintf("%s%s", azurePortalUrl, cfg.ManagedEnvironmentID)
+}
+
+func getResourceLogsUrl(cfg config.ReconcileConfig, kind history.ResourceKind, name string) string {
+	switch kind {
+	case history.ResourceKindApp, history.ResourceKindJob:
+		return getResourcePortalUrl(cfg, kind, name) + "/logs"
+	}
+
+	return ""
+}
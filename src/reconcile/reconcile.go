@@ -3,6 +3,7 @@ package reconcile
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -10,40 +11,75 @@ import (
 	"github.com/hashicorp/go-multierror"
 	"github.com/xenitab/azcagit/src/cache"
 	"github.com/xenitab/azcagit/src/config"
+	"github.com/xenitab/azcagit/src/history"
 	"github.com/xenitab/azcagit/src/metrics"
 	"github.com/xenitab/azcagit/src/notification"
+	"github.com/xenitab/azcagit/src/registry"
 	"github.com/xenitab/azcagit/src/remote"
 	"github.com/xenitab/azcagit/src/secret"
 	"github.com/xenitab/azcagit/src/source"
 )
 
 type Reconciler struct {
-	cfg                config.ReconcileConfig
-	sourceClient       source.Source
-	remoteAppClient    remote.App
-	remoteJobClient    remote.Job
-	secretClient       secret.Secret
-	notificationClient notification.Notification
-	metricsClient      metrics.Metrics
-	appCache           cache.AppCache
-	jobCache           cache.JobCache
-	secretCache        *cache.InMemSecretCache
-	notificationCache  cache.NotificationCache
-}
-
-func NewReconciler(cfg config.ReconcileConfig, sourceClient source.Source, remoteAppClient remote.App, remoteJobClient remote.Job, secretClient secret.Secret, notificationClient notification.Notification, metricsClient metrics.Metrics, appCache cache.AppCache, jobCache cache.JobCache, secretCache *cache.InMemSecretCache, notificationCache cache.NotificationCache) (*Reconciler, error) {
+	cfg                       config.ReconcileConfig
+	sourceClient              source.Source
+	remoteAppClient           remote.App
+	remoteJobClient           remote.Job
+	remoteDaprComponentClient remote.DaprComponent
+	remoteCertificateClient   remote.Certificate
+	remoteStorageClient       remote.Storage
+	secretClient              secret.Secret
+	registryClient            registry.Registry
+	notificationClient        notification.Notification
+	metricsClient             metrics.Metrics
+	appCache                  cache.AppCache
+	jobCache                  cache.JobCache
+	daprComponentCache        cache.DaprComponentCache
+	certificateCache          cache.CertificateCache
+	storageCache              cache.StorageCache
+	secretCache               *cache.InMemSecretCache
+	notificationCache         cache.NotificationCache
+	reconcileStateCache       cache.ReconcileStateCache
+	secretFingerprintCache    cache.SecretFingerprintCache
+	historyCache              cache.HistoryCache
+	notificationTemplates     *notificationTemplates
+	// currentRun records the actions of the run in progress
+	currentRun *history.Run
+	// currentSources are the sources of the run in progress, nil if they couldn't be read
+	currentSources *source.Sources
+}
+
+func NewReconciler(cfg config.ReconcileConfig, sourceClient source.Source, remoteAppClient remote.App, remoteJobClient remote.Job, remoteDaprComponentClient remote.DaprComponent, remoteCertificateClient remote.Certificate, remoteStorageClient remote.Storage, secretClient secret.Secret, registryClient registry.Registry, notificationClient notification.Notification, metricsClient metrics.Metrics, appCache cache.AppCache, jobCache cache.JobCache, daprComponentCache cache.DaprComponentCache, certificateCache cache.CertificateCache, storageCache cache.StorageCache, secretCache *cache.InMemSecretCache, notificationCache cache.NotificationCache, reconcileStateCache cache.ReconcileStateCache, secretFingerprintCache cache.SecretFingerprintCache, historyCache cache.HistoryCache) (*Reconciler, error) {
+	notificationTemplates, err := newNotificationTemplates(cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Reconciler{
 		cfg,
 		sourceClient,
 		remoteAppClient,
 		remoteJobClient,
+		remoteDaprComponentClient,
+		remoteCertificateClient,
+		remoteStorageClient,
 		secretClient,
+		registryClient,
 		notificationClient,
 		metricsClient,
 		appCache,
 		jobCache,
+		daprComponentCache,
+		certificateCache,
+		storageCache,
 		secretCache,
 		notificationCache,
+		reconcileStateCache,
+		secretFingerprintCache,
+		historyCache,
+		notificationTemplates,
+		nil,
+		nil,
 	}, nil
 }
 
@@ -51,6 +87,10 @@ func (r *Reconciler) Run(ctx context.Context) error {
 	var result *multierror.Error
 
 	startTime := time.Now()
+	r.currentRun = &history.Run{
+		StartTime: startTime,
+	}
+	r.currentSources = nil
 	revision, reconcileErr := r.run(ctx)
 	if reconcileErr != nil {
 		result = multierror.Append(reconcileErr, result)
@@ -63,9 +103,40 @@ func (r *Reconciler) Run(ctx context.Context) error {
 
 	r.reportReconcileMetrics(ctx, startTime, result)
 
+	r.addHistory(ctx, revision, reconcileErr)
+
 	return result.ErrorOrNil()
 }
 
+func (r *Reconciler) addHistory(ctx context.Context, revision string, reconcileErr error) {
+	log := logr.FromContextOrDiscard(ctx)
+
+	run := r.currentRun
+	r.currentRun = nil
+	if run == nil {
+		return
+	}
+
+	run.Revision = revision
+	run.EndTime = time.Now()
+	if reconcileErr != nil {
+		run.Error = reconcileErr.Error()
+	}
+
+	err := r.historyCache.Add(ctx, run)
+	if err != nil {
+		log.Error(err, "unable to add the run to the history")
+	}
+}
+
+func (r *Reconciler) addHistoryAction(kind history.ResourceKind, name string, action history.ActionType, reason string, err error) {
+	if r.currentRun == nil {
+		return
+	}
+
+	r.currentRun.AddAction(kind, name, action, reason, err)
+}
+
 func (r *Reconciler) reportReconcileMetrics(ctx context.Context, startTime time.Time, result *multierror.Error) {
 	log := logr.FromContextOrDiscard(ctx)
 
@@ -89,122 +160,279 @@ func (r *Reconciler) reportReconcileMetrics(ctx context.Context, startTime time.
 }
 
 func (r *Reconciler) run(ctx context.Context) (string, error) {
+	log := logr.FromContextOrDiscard(ctx)
+
 	sources, revision, err := r.getSources(ctx)
 	if err != nil {
 		return revision, err
 	}
+	r.currentSources = sources
 
-	err = r.populateSecretCache(ctx, sources)
+	r.sendPendingNotification(ctx, revision)
+
+	secretNames := append(sources.GetUniqueRemoteSecretNames(), sources.GetUniqueKeyVaultReferenceNames()...)
+	secretItems, err := secret.ListItems(ctx, r.secretClient, secretNames)
 	if err != nil {
 		return revision, err
 	}
 
+	sourcesHash, err := getSourcesHash(sources)
+	if err != nil {
+		return revision, err
+	}
+
+	secretFingerprints := getSecretFingerprints(sources, secretItems)
+	currentState := cache.ReconcileState{
+		Revision:    revision,
+		SourcesHash: sourcesHash,
+		SecretsHash: getSecretsHash(secretFingerprints),
+	}
+
+	previousState, previousStateFound, err := r.reconcileStateCache.Get(ctx)
+	if err != nil {
+		return revision, err
+	}
+
+	noop, err := r.isNoop(ctx, sources, previousState, previousStateFound, currentState)
+	if err != nil {
+		return revision, err
+	}
+
+	if noop {
+		if r.currentRun != nil {
+			r.currentRun.NoOp = true
+		}
+		log.Info("no-op, revision, secrets and remote state unchanged since last reconcile", "revision", revision)
+		return revision, nil
+	}
+
+	err = r.populateSecretCache(ctx, sources, secretItems)
+	if err != nil {
+		return revision, err
+	}
+
+	rotated, err := r.getRotatedSecrets(ctx, secretFingerprints)
+	if err != nil {
+		return revision, err
+	}
+
+	// dapr components are reconciled before apps and jobs, as they may depend on them
+	newRemoteDaprComponents, err := r.runSourceDaprComponents(ctx, sources)
+	if err != nil {
+		return revision, fmt.Errorf("sourceDaprComponents error: %w", err)
+	}
+
+	newRemoteStorages, err := r.runSourceStorages(ctx, sources)
+	if err != nil {
+		return revision, fmt.Errorf("sourceStorages error: %w", err)
+	}
+
+	certificates, err := r.runSourceCertificates(ctx, sources)
+	if err != nil {
+		return revision, fmt.Errorf("sourceCertificates error: %w", err)
+	}
+
+	var certificateBindings certificateBindings
+	if certificates != nil {
+		certificateBindings = certificates.bindings
+	}
+
+	resolveDigest := r.newImageDigestResolver()
+
 	var result *multierror.Error
-	err = r.runSourceApps(ctx, sources)
+	newRemoteApps, err := r.runSourceApps(ctx, sources, certificateBindings, newRemoteStorages, rotated, resolveDigest)
 	if err != nil {
 		result = multierror.Append(fmt.Errorf("sourceApps error: %w", err), result)
 	}
 
-	err = r.runSourceJobs(ctx, sources)
+	newRemoteJobs, err := r.runSourceJobs(ctx, sources, newRemoteStorages, rotated, resolveDigest)
 	if err != nil {
 		result = multierror.Append(fmt.Errorf("sourceJobs error: %w", err), result)
 	}
 
-	return revision, result.ErrorOrNil()
+	if result.ErrorOrNil() != nil {
+		return revision, result.ErrorOrNil()
+	}
+
+	// the fingerprints are only updated when the apps and jobs using rotated secrets have been updated and restarted
+	err = r.secretFingerprintCache.Set(ctx, secretFingerprints)
+	if err != nil {
+		return revision, err
+	}
+
+	newRemoteCertificates, err := r.finishSourceCertificates(ctx, certificates)
+	if err != nil {
+		return revision, fmt.Errorf("sourceCertificates error: %w", err)
+	}
+
+	// the state isn't saved while custom domains are waiting for their managed certificates, to bind them in the next reconcile
+	if certificates != nil && certificates.bindings.pending() {
+		log.Info("custom domains added without binding, waiting for the managed certificates", "revision", revision)
+		return revision, nil
+	}
+
+	currentState.RemoteHash = getRemoteHash(newRemoteApps, newRemoteJobs, newRemoteDaprComponents, newRemoteCertificates, newRemoteStorages)
+	err = r.reconcileStateCache.Set(ctx, currentState)
+	if err != nil {
+		return revision, err
+	}
+
+	return revision, nil
 }
 
-func (r *Reconciler) runSourceApps(ctx context.Context, sources *source.Sources) error {
+func (r *Reconciler) runSourceApps(ctx context.Context, sources *source.Sources, certificateBindings certificateBindings, remoteStorages *remote.RemoteStorages, rotated rotatedSecrets, resolveDigest source.ImageDigestResolver) (*remote.RemoteApps, error) {
 	sourceApps, err := r.getSourceApps(ctx, sources)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if sourceApps == nil {
-		return nil
+		return nil, nil
 	}
 
 	r.filterSourceApps(ctx, sourceApps)
 
 	r.reportSourceAppsMetrics(ctx, sourceApps)
 
+	err = r.validateSourceAppsStorages(sourceApps, remoteStorages)
+	if err != nil {
+		return nil, err
+	}
+
 	err = r.populateSourceAppsSecrets(ctx, sourceApps)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	err = r.populateSourceAppsRegistries(sourceApps)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	err = r.populateSourceAppsCustomDomains(sourceApps, certificateBindings)
+	if err != nil {
+		return nil, err
 	}
 
 	remoteApps, err := r.getRemoteApps(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	err = r.deleteAppsIfNeeded(ctx, sourceApps, remoteApps)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	err = r.createOrUpdateAppsIfNeeded(ctx, sourceApps, remoteApps)
+	// the apps with images that can't be resolved are kept, but not created or updated
+	appliedApps, digestErr := r.resolveSourceAppsImageDigests(ctx, sourceApps, resolveDigest)
+
+	err = r.createOrUpdateAppsIfNeeded(ctx, appliedApps, remoteApps, rotated)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	err = r.updateAppCache(ctx, sourceApps)
+	newRemoteApps, err := r.updateAppCache(ctx, appliedApps)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	return newRemoteApps, digestErr
 }
 
-func (r *Reconciler) runSourceJobs(ctx context.Context, sources *source.Sources) error {
+func (r *Reconciler) runSourceJobs(ctx context.Context, sources *source.Sources, remoteStorages *remote.RemoteStorages, rotated rotatedSecrets, resolveDigest source.ImageDigestResolver) (*remote.RemoteJobs, error) {
 	sourceJobs, err := r.getSourceJobs(ctx, sources)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if sourceJobs == nil {
-		return nil
+		return nil, nil
 	}
 
 	r.filterSourceJobs(ctx, sourceJobs)
 
 	r.reportSourceJobsMetrics(ctx, sourceJobs)
 
+	err = r.validateSourceJobsStorages(sourceJobs, remoteStorages)
+	if err != nil {
+		return nil, err
+	}
+
 	err = r.populateSourceJobsSecrets(ctx, sourceJobs)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	err = r.populateSourceJobsRegistries(sourceJobs)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	remoteJobs, err := r.getRemoteJobs(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	err = r.deleteJobsIfNeeded(ctx, sourceJobs, remoteJobs)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	err = r.createOrUpdateJobsIfNeeded(ctx, sourceJobs, remoteJobs)
+	// the jobs with images that can't be resolved are kept, but not created or updated
+	appliedJobs, digestErr := r.resolveSourceJobsImageDigests(ctx, sourceJobs, resolveDigest)
+
+	err = r.createOrUpdateJobsIfNeeded(ctx, appliedJobs, remoteJobs, rotated)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	err = r.updateJobCache(ctx, sourceJobs)
+	newRemoteJobs, err := r.updateJobCache(ctx, appliedJobs)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	return newRemoteJobs, digestErr
+}
+
+func (r *Reconciler) runSourceDaprComponents(ctx context.Context, sources *source.Sources) (*remote.RemoteDaprComponents, error) {
+	sourceDaprComponents, err := r.getSourceDaprComponents(ctx, sources)
+	if err != nil {
+		return nil, err
+	}
+
+	if sourceDaprComponents == nil {
+		return nil, nil
+	}
+
+	r.filterSourceDaprComponents(ctx, sourceDaprComponents)
+
+	err = r.populateSourceDaprComponentsSecrets(ctx, sourceDaprComponents)
+	if err != nil {
+		return nil, err
+	}
+
+	remoteDaprComponents, err := r.getRemoteDaprComponents(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.deleteDaprComponentsIfNeeded(ctx, sourceDaprComponents, remoteDaprComponents)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.createOrUpdateDaprComponentsIfNeeded(ctx, sourceDaprComponents, remoteDaprComponents)
+	if err != nil {
+		return nil, err
+	}
+
+	newRemoteDaprComponents, err := r.updateDaprComponentCache(ctx, sourceDaprComponents)
+	if err != nil {
+		return nil, err
+	}
+
+	return newRemoteDaprComponents, nil
 }
 
 func (r *Reconciler) reportSourceAppsMetrics(ctx context.Context, sourceApps *source.SourceApps) {
@@ -232,6 +460,68 @@ func (r *Reconciler) getSources(ctx context.Context) (*source.Sources, string, e
 	return sources, revision, nil
 }
 
+// newImageDigestResolver returns the resolver for the images of a reconcile, nil if digests aren't resolved. The
+// digests are resolved after the no-op check, so a moved tag is applied by the next reconcile that isn't a no-op.
+func (r *Reconciler) newImageDigestResolver() source.ImageDigestResolver {
+	if !r.cfg.ResolveImageDigests {
+		return nil
+	}
+
+	return source.NewCachedImageDigestResolver(r.registryClient.GetDigest)
+}
+
+// resolveSourceAppsImageDigests returns the apps to create or update with their images pinned to digests, the apps
+// with images that can't be resolved are left out and returned as the error
+func (r *Reconciler) resolveSourceAppsImageDigests(ctx context.Context, sourceApps *source.SourceApps, resolveDigest source.ImageDigestResolver) (*source.SourceApps, error) {
+	if resolveDigest == nil {
+		return sourceApps, nil
+	}
+
+	log := logr.FromContextOrDiscard(ctx)
+
+	failed := sourceApps.ResolveImageDigests(ctx, resolveDigest)
+	appliedApps := make(source.SourceApps)
+	var result *multierror.Error
+	for _, name := range sourceApps.GetSortedNames() {
+		app, _ := sourceApps.Get(name)
+		err, ok := failed[name]
+		if ok {
+			log.Error(err, "skipping app, failed to resolve image digests", "app", name)
+			result = multierror.Append(fmt.Errorf("failed to resolve image digests of app %s: %w", name, err), result)
+			continue
+		}
+		appliedApps[name] = app
+	}
+
+	return &appliedApps, result.ErrorOrNil()
+}
+
+// resolveSourceJobsImageDigests returns the jobs to create or update with their images pinned to digests, the jobs
+// with images that can't be resolved are left out and returned as the error
+func (r *Reconciler) resolveSourceJobsImageDigests(ctx context.Context, sourceJobs *source.SourceJobs, resolveDigest source.ImageDigestResolver) (*source.SourceJobs, error) {
+	if resolveDigest == nil {
+		return sourceJobs, nil
+	}
+
+	log := logr.FromContextOrDiscard(ctx)
+
+	failed := sourceJobs.ResolveImageDigests(ctx, resolveDigest)
+	appliedJobs := make(source.SourceJobs)
+	var result *multierror.Error
+	for _, name := range sourceJobs.GetSortedNames() {
+		job, _ := sourceJobs.Get(name)
+		err, ok := failed[name]
+		if ok {
+			log.Error(err, "skipping job, failed to resolve image digests", "job", name)
+			result = multierror.Append(fmt.Errorf("failed to resolve image digests of job %s: %w", name, err), result)
+			continue
+		}
+		appliedJobs[name] = job
+	}
+
+	return &appliedJobs, result.ErrorOrNil()
+}
+
 func (r *Reconciler) getSourceApps(ctx context.Context, sources *source.Sources) (*source.SourceApps, error) {
 	if sources == nil {
 		return nil, fmt.Errorf("sources is nil")
@@ -268,6 +558,24 @@ func (r *Reconciler) getSourceJobs(ctx context.Context, sources *source.Sources)
 	return sourceJobs, nil
 }
 
+func (r *Reconciler) getSourceDaprComponents(ctx context.Context, sources *source.Sources) (*source.SourceDaprComponents, error) {
+	if sources == nil {
+		return nil, fmt.Errorf("sources is nil")
+	}
+
+	if sources.DaprComponents == nil {
+		return nil, nil
+	}
+
+	sourceDaprComponents := sources.DaprComponents
+
+	if sourceDaprComponents.Error() != nil {
+		return nil, fmt.Errorf("sourceDaprComponents contains errors, stopping reconciliation: %w", sourceDaprComponents.Error())
+	}
+
+	return sourceDaprComponents, nil
+}
+
 func (r *Reconciler) getRemoteApps(ctx context.Context) (*remote.RemoteApps, error) {
 	remoteApps, err := r.remoteAppClient.Get(ctx)
 	if err != nil {
@@ -294,6 +602,19 @@ func (r *Reconciler) getRemoteJobs(ctx context.Context) (*remote.RemoteJobs, err
 	return remoteJobs, nil
 }
 
+func (r *Reconciler) getRemoteDaprComponents(ctx context.Context) (*remote.RemoteDaprComponents, error) {
+	remoteDaprComponents, err := r.remoteDaprComponentClient.Get(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get remoteDaprComponents: %w", err)
+	}
+
+	if remoteDaprComponents == nil {
+		return nil, fmt.Errorf("remoteDaprComponents is nil")
+	}
+
+	return remoteDaprComponents, nil
+}
+
 func (r *Reconciler) deleteAppsIfNeeded(ctx context.Context, sourceApps *source.SourceApps, remoteApps *remote.RemoteApps) error {
 	log := logr.FromContextOrDiscard(ctx)
 
@@ -309,6 +630,7 @@ func (r *Reconciler) deleteAppsIfNeeded(ctx context.Context, sourceApps *source.
 				continue
 			}
 			err := r.remoteAppClient.Delete(ctx, name)
+			r.addHistoryAction(history.ResourceKindApp, name, history.ActionTypeDelete, "not in source", err)
 			if err != nil {
 				return err
 			}
@@ -334,6 +656,7 @@ func (r *Reconciler) deleteJobsIfNeeded(ctx context.Context, sourceJobs *source.
 				continue
 			}
 			err := r.remoteJobClient.Delete(ctx, name)
+			r.addHistoryAction(history.ResourceKindJob, name, history.ActionTypeDelete, "not in source", err)
 			if err != nil {
 				return err
 			}
@@ -344,7 +667,33 @@ func (r *Reconciler) deleteJobsIfNeeded(ctx context.Context, sourceJobs *source.
 	return nil
 }
 
-func (r *Reconciler) createOrUpdateAppsIfNeeded(ctx context.Context, sourceApps *source.SourceApps, remoteApps *remote.RemoteApps) error {
+func (r *Reconciler) deleteDaprComponentsIfNeeded(ctx context.Context, sourceDaprComponents *source.SourceDaprComponents, remoteDaprComponents *remote.RemoteDaprComponents) error {
+	log := logr.FromContextOrDiscard(ctx)
+
+	for _, name := range remoteDaprComponents.GetSortedNames() {
+		if sourceDaprComponents.Error() != nil {
+			log.Error(fmt.Errorf("delete disabled"), "no remoteDaprComponents will be deleted while sourceDaprComponents contains errors")
+			break
+		}
+		remoteDaprComponent, _ := remoteDaprComponents.Get(name)
+		_, ok := sourceDaprComponents.Get(name)
+		if !ok {
+			if !remoteDaprComponent.Managed {
+				continue
+			}
+			err := r.remoteDaprComponentClient.Delete(ctx, name)
+			r.addHistoryAction(history.ResourceKindDaprComponent, name, history.ActionTypeDelete, "not in source", err)
+			if err != nil {
+				return err
+			}
+			log.Info("deleted remoteDaprComponent", "component", name)
+		}
+	}
+
+	return nil
+}
+
+func (r *Reconciler) createOrUpdateAppsIfNeeded(ctx context.Context, sourceApps *source.SourceApps, remoteApps *remote.RemoteApps, rotated rotatedSecrets) error {
 	log := logr.FromContextOrDiscard(ctx)
 
 	for _, name := range sourceApps.GetSortedNames() {
@@ -354,24 +703,46 @@ func (r *Reconciler) createOrUpdateAppsIfNeeded(ctx context.Context, sourceApps
 		if err != nil {
 			return err
 		}
+
+		rotatedSecretNames := rotated.referencedBy(sourceApp.GetRemoteSecrets())
+		if ok && len(rotatedSecretNames) > 0 {
+			needsUpdate = true
+			updateReason = getSecretRotatedReason(rotatedSecretNames)
+		}
 		if !needsUpdate {
 			log.Info("skipping update, no changes", "app", name)
 			continue
 		}
+		app, err := r.resolveAppSecrets(ctx, *sourceApp.Specification.App)
+		if err != nil {
+			return err
+		}
+
 		if ok {
 			if !remoteApp.Managed {
 				return fmt.Errorf("trying to update a non-managed app: %s", name)
 			}
 
-			err := r.remoteAppClient.Update(ctx, name, *sourceApp.Specification.App)
+			err := r.remoteAppClient.Update(ctx, name, app)
+			r.addHistoryAction(history.ResourceKindApp, name, history.ActionTypeUpdate, updateReason, err)
 			if err != nil {
 				return fmt.Errorf("failed to update %s: %w", name, err)
 			}
 			log.Info("updated remoteApp", "app", name, "reason", updateReason)
+
+			if len(rotatedSecretNames) > 0 && r.cfg.SecretRotationRestart {
+				revisionNames, err := r.remoteAppClient.RestartRevisions(ctx, name)
+				r.addHistoryAction(history.ResourceKindApp, name, history.ActionTypeRestart, updateReason, err)
+				if err != nil {
+					return fmt.Errorf("failed to restart revisions of %s: %w", name, err)
+				}
+				log.Info("restarted remoteApp revisions", "app", name, "revisions", revisionNames, "reason", updateReason)
+			}
 			continue
 		}
 
-		err = r.remoteAppClient.Create(ctx, name, *sourceApp.Specification.App)
+		err = r.remoteAppClient.Create(ctx, name, app)
+		r.addHistoryAction(history.ResourceKindApp, name, history.ActionTypeCreate, updateReason, err)
 		if err != nil {
 			return fmt.Errorf("failed to create %s: %w", name, err)
 		}
@@ -381,7 +752,9 @@ func (r *Reconciler) createOrUpdateAppsIfNeeded(ctx context.Context, sourceApps
 	return nil
 }
 
-func (r *Reconciler) createOrUpdateJobsIfNeeded(ctx context.Context, sourceJobs *source.SourceJobs, remoteJobs *remote.RemoteJobs) error {
+// createOrUpdateJobsIfNeeded updates jobs using rotated secrets, there are no revisions to restart as
+// every job execution reads the secrets when started
+func (r *Reconciler) createOrUpdateJobsIfNeeded(ctx context.Context, sourceJobs *source.SourceJobs, remoteJobs *remote.RemoteJobs, rotated rotatedSecrets) error {
 	log := logr.FromContextOrDiscard(ctx)
 
 	for _, name := range sourceJobs.GetSortedNames() {
@@ -392,16 +765,28 @@ func (r *Reconciler) createOrUpdateJobsIfNeeded(ctx context.Context, sourceJobs
 			return err
 		}
 
+		rotatedSecretNames := rotated.referencedBy(sourceJob.GetRemoteSecrets())
+		if ok && len(rotatedSecretNames) > 0 {
+			needsUpdate = true
+			updateReason = getSecretRotatedReason(rotatedSecretNames)
+		}
+
 		if !needsUpdate {
 			log.Info("skipping update, no changes", "job", name)
 			continue
 		}
+		job, err := r.resolveJobSecrets(ctx, *sourceJob.Specification.Job)
+		if err != nil {
+			return err
+		}
+
 		if ok {
 			if !remoteJob.Managed {
 				return fmt.Errorf("trying to update a non-managed job: %s", name)
 			}
 
-			err := r.remoteJobClient.Update(ctx, name, *sourceJob.Specification.Job)
+			err := r.remoteJobClient.Update(ctx, name, job)
+			r.addHistoryAction(history.ResourceKindJob, name, history.ActionTypeUpdate, updateReason, err)
 			if err != nil {
 				return fmt.Errorf("failed to update %s: %w", name, err)
 			}
@@ -409,7 +794,8 @@ func (r *Reconciler) createOrUpdateJobsIfNeeded(ctx context.Context, sourceJobs
 			continue
 		}
 
-		err = r.remoteJobClient.Create(ctx, name, *sourceJob.Specification.Job)
+		err = r.remoteJobClient.Create(ctx, name, job)
+		r.addHistoryAction(history.ResourceKindJob, name, history.ActionTypeCreate, updateReason, err)
 		if err != nil {
 			return fmt.Errorf("failed to create %s: %w", name, err)
 		}
@@ -419,46 +805,112 @@ func (r *Reconciler) createOrUpdateJobsIfNeeded(ctx context.Context, sourceJobs
 	return nil
 }
 
-func (r *Reconciler) updateAppCache(ctx context.Context, sourceApps *source.SourceApps) error {
+func (r *Reconciler) createOrUpdateDaprComponentsIfNeeded(ctx context.Context, sourceDaprComponents *source.SourceDaprComponents, remoteDaprComponents *remote.RemoteDaprComponents) error {
+	log := logr.FromContextOrDiscard(ctx)
+
+	for _, name := range sourceDaprComponents.GetSortedNames() {
+		sourceDaprComponent, _ := sourceDaprComponents.Get(name)
+		remoteDaprComponent, ok := remoteDaprComponents.Get(name)
+		needsUpdate, updateReason, err := r.daprComponentCache.NeedsUpdate(ctx, name, remoteDaprComponent.DaprComponent, sourceDaprComponent.Specification.Component)
+		if err != nil {
+			return err
+		}
+
+		if !needsUpdate {
+			log.Info("skipping update, no changes", "component", name)
+			continue
+		}
+		component, err := r.resolveDaprComponentSecrets(ctx, *sourceDaprComponent.Specification.Component)
+		if err != nil {
+			return err
+		}
+
+		if ok {
+			if !remoteDaprComponent.Managed {
+				return fmt.Errorf("trying to update a non-managed dapr component: %s", name)
+			}
+
+			err := r.remoteDaprComponentClient.Update(ctx, name, component)
+			r.addHistoryAction(history.ResourceKindDaprComponent, name, history.ActionTypeUpdate, updateReason, err)
+			if err != nil {
+				return fmt.Errorf("failed to update %s: %w", name, err)
+			}
+			log.Info("updated remoteDaprComponent", "component", name, "reason", updateReason)
+			continue
+		}
+
+		err = r.remoteDaprComponentClient.Create(ctx, name, component)
+		r.addHistoryAction(history.ResourceKindDaprComponent, name, history.ActionTypeCreate, updateReason, err)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", name, err)
+		}
+		log.Info("created remoteDaprComponent", "component", name, "reason", updateReason)
+	}
+
+	return nil
+}
+
+func (r *Reconciler) updateAppCache(ctx context.Context, sourceApps *source.SourceApps) (*remote.RemoteApps, error) {
 	newRemoteApps, err := r.remoteAppClient.Get(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get new remoteApps: %w", err)
+		return nil, fmt.Errorf("failed to get new remoteApps: %w", err)
 	}
 
 	for _, name := range sourceApps.GetSortedNames() {
 		sourceApp, _ := sourceApps.Get(name)
 		remoteApp, ok := newRemoteApps.Get(name)
 		if !ok {
-			return fmt.Errorf("unable to locate app %s after create or update", name)
+			return nil, fmt.Errorf("unable to locate app %s after create or update", name)
 		}
 		err := r.appCache.Set(ctx, name, remoteApp.App, sourceApp.Specification.App)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
-	return nil
+	return newRemoteApps, nil
 }
 
-func (r *Reconciler) updateJobCache(ctx context.Context, sourceJobs *source.SourceJobs) error {
+func (r *Reconciler) updateJobCache(ctx context.Context, sourceJobs *source.SourceJobs) (*remote.RemoteJobs, error) {
 	newRemoteJobs, err := r.remoteJobClient.Get(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get new remoteJobs: %w", err)
+		return nil, fmt.Errorf("failed to get new remoteJobs: %w", err)
 	}
 
 	for _, name := range sourceJobs.GetSortedNames() {
 		sourceJob, _ := sourceJobs.Get(name)
 		remoteJob, ok := newRemoteJobs.Get(name)
 		if !ok {
-			return fmt.Errorf("unable to locate job %s after create or update", name)
+			return nil, fmt.Errorf("unable to locate job %s after create or update", name)
 		}
 		err := r.jobCache.Set(ctx, name, remoteJob.Job, sourceJob.Specification.Job)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
-	return nil
+	return newRemoteJobs, nil
+}
+
+func (r *Reconciler) updateDaprComponentCache(ctx context.Context, sourceDaprComponents *source.SourceDaprComponents) (*remote.RemoteDaprComponents, error) {
+	newRemoteDaprComponents, err := r.remoteDaprComponentClient.Get(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get new remoteDaprComponents: %w", err)
+	}
+
+	for _, name := range sourceDaprComponents.GetSortedNames() {
+		sourceDaprComponent, _ := sourceDaprComponents.Get(name)
+		remoteDaprComponent, ok := newRemoteDaprComponents.Get(name)
+		if !ok {
+			return nil, fmt.Errorf("unable to locate dapr component %s after create or update", name)
+		}
+		err := r.daprComponentCache.Set(ctx, name, remoteDaprComponent.DaprComponent, sourceDaprComponent.Specification.Component)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return newRemoteDaprComponents, nil
 }
 
 func (r *Reconciler) filterSourceApps(ctx context.Context, sourceApps *source.SourceApps) {
@@ -487,24 +939,29 @@ func (r *Reconciler) filterSourceJobs(ctx context.Context, sourceJobs *source.So
 	}
 }
 
-func (r *Reconciler) populateSecretCache(ctx context.Context, sources *source.Sources) error {
-	secretItems, err := r.secretClient.ListItems(ctx)
-	if err != nil {
-		return err
+func (r *Reconciler) filterSourceDaprComponents(ctx context.Context, sourceDaprComponents *source.SourceDaprComponents) {
+	log := logr.FromContextOrDiscard(ctx)
+
+	for _, name := range sourceDaprComponents.GetSortedNames() {
+		component, _ := sourceDaprComponents.Get(name)
+		shouldRunInLocation := component.ShoudRunInLocation(r.cfg.Location)
+		if !shouldRunInLocation {
+			log.V(1).Info("sourceDaprComponent was deleted because of location mis-match", "component", component.Name(), "currentLocation", r.cfg.Location, "locationFilter", component.Specification.LocationFilter)
+			sourceDaprComponents.Delete(name)
+		}
 	}
+}
 
+// populateSecretCache only caches the last change of the secrets from ListItems, the values are read when
+// a resource using them is created or updated
+func (r *Reconciler) populateSecretCache(ctx context.Context, sources *source.Sources, secretItems *secret.Items) error {
 	for _, secretName := range sources.GetUniqueRemoteSecretNames() {
-		_, ok := secretItems.Get(secretName)
+		item, ok := secretItems.Get(secretName)
 		if !ok {
 			return fmt.Errorf("secret not found %q", secretName)
 		}
 
-		secretValue, changedAt, err := r.secretClient.Get(ctx, secretName)
-		if err != nil {
-			return err
-		}
-
-		r.secretCache.Set(secretName, secretValue, changedAt)
+		r.secretCache.SetFingerprint(secretName, item.LastChange().UTC())
 	}
 
 	return nil
@@ -518,12 +975,25 @@ func (r *Reconciler) populateSourceAppsSecrets(ctx context.Context, sourceApps *
 				return fmt.Errorf("secret %d for app %q not valid", i, name)
 			}
 
-			secretValue, ok := r.secretCache.Get(*remoteSecret.RemoteSecretName)
-			if !ok {
-				return fmt.Errorf("unable to get secret %d for app %q from cache", i, name)
+			if remoteSecret.IsKeyVaultReference() {
+				keyVaultSecretURL, err := r.getKeyVaultSecretURL(remoteSecret)
+				if err != nil {
+					return fmt.Errorf("unable to get secret reference %q for app %q: %w", *remoteSecret.SecretName, name, err)
+				}
+
+				err = sourceApps.SetSecretReference(name, *remoteSecret.SecretName, keyVaultSecretURL, *remoteSecret.Identity)
+				if err != nil {
+					return fmt.Errorf("unable to set secret reference %q for app %q", *remoteSecret.SecretName, name)
+				}
+				continue
 			}
 
-			err := sourceApps.SetSecret(name, *remoteSecret.SecretName, secretValue)
+			secretValue, err := r.getSecretPlaceholder(remoteSecret.SecretKey())
+			if err != nil {
+				return fmt.Errorf("unable to get secret %d for app %q from cache: %w", i, name, err)
+			}
+
+			err = sourceApps.SetSecret(name, *remoteSecret.SecretName, secretValue)
 			if err != nil {
 				return fmt.Errorf("unable to set secret %q for app %q", *remoteSecret.SecretName, name)
 			}
@@ -533,6 +1003,25 @@ func (r *Reconciler) populateSourceAppsSecrets(ctx context.Context, sourceApps *
 	return nil
 }
 
+// getKeyVaultSecretURL returns the versionless secret URL, making Container Apps use the latest version
+func (r *Reconciler) getKeyVaultSecretURL(remoteSecret source.RemoteSecretSpecification) (string, error) {
+	keyVaultName := r.cfg.KeyVaultName
+	if remoteSecret.GetVault() != "" {
+		secretVault, err := r.cfg.GetSecretVault(remoteSecret.GetVault())
+		if err != nil {
+			return "", err
+		}
+
+		if secretVault.Type != config.SecretVaultTypeKeyVault {
+			return "", fmt.Errorf("secret vault %q is of type %q, only %q supports references", secretVault.Name, secretVault.Type, config.SecretVaultTypeKeyVault)
+		}
+
+		keyVaultName = secretVault.KeyVaultName
+	}
+
+	return fmt.Sprintf("https://%s.vault.azure.net/secrets/%s", keyVaultName, *remoteSecret.RemoteSecretName), nil
+}
+
 func (r *Reconciler) populateSourceJobsSecrets(ctx context.Context, sourceJobs *source.SourceJobs) error {
 	for _, name := range sourceJobs.GetSortedNames() {
 		job, _ := sourceJobs.Get(name)
@@ -541,12 +1030,25 @@ func (r *Reconciler) populateSourceJobsSecrets(ctx context.Context, sourceJobs *
 				return fmt.Errorf("secret %d for job %q not valid", i, name)
 			}
 
-			secretValue, ok := r.secretCache.Get(*remoteSecret.RemoteSecretName)
-			if !ok {
-				return fmt.Errorf("unable to get secret %d for job %q from cache", i, name)
+			if remoteSecret.IsKeyVaultReference() {
+				keyVaultSecretURL, err := r.getKeyVaultSecretURL(remoteSecret)
+				if err != nil {
+					return fmt.Errorf("unable to get secret reference %q for job %q: %w", *remoteSecret.SecretName, name, err)
+				}
+
+				err = sourceJobs.SetSecretReference(name, *remoteSecret.SecretName, keyVaultSecretURL, *remoteSecret.Identity)
+				if err != nil {
+					return fmt.Errorf("unable to set secret reference %q for job %q", *remoteSecret.SecretName, name)
+				}
+				continue
+			}
+
+			secretValue, err := r.getSecretPlaceholder(remoteSecret.SecretKey())
+			if err != nil {
+				return fmt.Errorf("unable to get secret %d for job %q from cache: %w", i, name, err)
 			}
 
-			err := sourceJobs.SetSecret(name, *remoteSecret.SecretName, secretValue)
+			err = sourceJobs.SetSecret(name, *remoteSecret.SecretName, secretValue)
 			if err != nil {
 				return fmt.Errorf("unable to set secret %q for job %q", *remoteSecret.SecretName, name)
 			}
@@ -556,19 +1058,54 @@ func (r *Reconciler) populateSourceJobsSecrets(ctx context.Context, sourceJobs *
 	return nil
 }
 
-func (r *Reconciler) populateSourceAppsRegistries(sourceApps *source.SourceApps) error {
-	if r.cfg.ContainerRegistryServer == "" && r.cfg.ContainerRegistryUsername == "" && r.cfg.ContainerRegistryPassword == "" {
-		return nil
+func (r *Reconciler) populateSourceDaprComponentsSecrets(ctx context.Context, sourceDaprComponents *source.SourceDaprComponents) error {
+	for _, name := range sourceDaprComponents.GetSortedNames() {
+		component, _ := sourceDaprComponents.Get(name)
+		for i, remoteSecret := range component.GetRemoteSecrets() {
+			if !remoteSecret.Valid() {
+				return fmt.Errorf("secret %d for dapr component %q not valid", i, name)
+			}
+
+			secretValue, err := r.getSecretPlaceholder(remoteSecret.SecretKey())
+			if err != nil {
+				return fmt.Errorf("unable to get secret %d for dapr component %q from cache: %w", i, name, err)
+			}
+
+			err = sourceDaprComponents.SetSecret(name, *remoteSecret.SecretName, secretValue)
+			if err != nil {
+				return fmt.Errorf("unable to set secret %q for dapr component %q", *remoteSecret.SecretName, name)
+			}
+		}
 	}
 
-	if r.cfg.ContainerRegistryServer != "" && (r.cfg.ContainerRegistryUsername == "" || r.cfg.ContainerRegistryPassword == "") {
-		return fmt.Errorf("all of container registry server, username and password needs to be set")
+	return nil
+}
+
+func (r *Reconciler) populateSourceAppsRegistries(sourceApps *source.SourceApps) error {
+	registries, err := r.cfg.GetContainerRegistries()
+	if err != nil {
+		return err
 	}
 
 	for _, name := range sourceApps.GetSortedNames() {
-		err := sourceApps.SetRegistry(name, r.cfg.ContainerRegistryServer, r.cfg.ContainerRegistryUsername, r.cfg.ContainerRegistryPassword)
-		if err != nil {
-			return err
+		app, _ := sourceApps.Get(name)
+		if app.Specification != nil && app.Specification.DisableRegistries {
+			continue
+		}
+
+		for _, containerRegistry := range getRegistriesForImages(registries, app.GetImageRegistryServers()) {
+			if containerRegistry.UsesIdentity() {
+				err := sourceApps.SetRegistryIdentity(name, containerRegistry.Server, containerRegistry.Identity)
+				if err != nil {
+					return fmt.Errorf("unable to set registry for app %q: %w", name, err)
+				}
+				continue
+			}
+
+			err := sourceApps.SetRegistry(name, containerRegistry.Server, containerRegistry.Username, containerRegistry.Password)
+			if err != nil {
+				return err
+			}
 		}
 	}
 
@@ -576,24 +1113,56 @@ func (r *Reconciler) populateSourceAppsRegistries(sourceApps *source.SourceApps)
 }
 
 func (r *Reconciler) populateSourceJobsRegistries(sourceJobs *source.SourceJobs) error {
-	if r.cfg.ContainerRegistryServer == "" && r.cfg.ContainerRegistryUsername == "" && r.cfg.ContainerRegistryPassword == "" {
-		return nil
-	}
-
-	if r.cfg.ContainerRegistryServer != "" && (r.cfg.ContainerRegistryUsername == "" || r.cfg.ContainerRegistryPassword == "") {
-		return fmt.Errorf("all of container registry server, username and password needs to be set")
+	registries, err := r.cfg.GetContainerRegistries()
+	if err != nil {
+		return err
 	}
 
 	for _, name := range sourceJobs.GetSortedNames() {
-		err := sourceJobs.SetRegistry(name, r.cfg.ContainerRegistryServer, r.cfg.ContainerRegistryUsername, r.cfg.ContainerRegistryPassword)
-		if err != nil {
-			return err
+		job, _ := sourceJobs.Get(name)
+		if job.Specification != nil && job.Specification.DisableRegistries {
+			continue
+		}
+
+		for _, containerRegistry := range getRegistriesForImages(registries, job.GetImageRegistryServers()) {
+			if containerRegistry.UsesIdentity() {
+				err := sourceJobs.SetRegistryIdentity(name, containerRegistry.Server, containerRegistry.Identity)
+				if err != nil {
+					return fmt.Errorf("unable to set registry for job %q: %w", name, err)
+				}
+				continue
+			}
+
+			err := sourceJobs.SetRegistry(name, containerRegistry.Server, containerRegistry.Username, containerRegistry.Password)
+			if err != nil {
+				return err
+			}
 		}
 	}
 
 	return nil
 }
 
+// getRegistriesForImages returns the default containerRegistry and the additional registries used by the images
+func getRegistriesForImages(registries []config.ContainerRegistry, imageServers []string) []config.ContainerRegistry {
+	result := []config.ContainerRegistry{}
+	for _, containerRegistry := range registries {
+		if containerRegistry.Default {
+			result = append(result, containerRegistry)
+			continue
+		}
+
+		for _, server := range imageServers {
+			if strings.EqualFold(server, containerRegistry.Server) {
+				result = append(result, containerRegistry)
+				break
+			}
+		}
+	}
+
+	return result
+}
+
 func (r *Reconciler) sendNotification(ctx context.Context, revision string, reconcileErr error) error {
 	log := logr.FromContextOrDiscard(ctx)
 	log.V(1).Info("sendNotification invoked", "revision", revision, "reconcileErr", reconcileErr)
@@ -610,15 +1179,89 @@ func (r *Reconciler) sendNotification(ctx context.Context, revision string, reco
 		state = notification.NotificationStateFailure
 	}
 
-	name := strings.ToLower(fmt.Sprintf("%s/%s-%s", r.cfg.ResourceGroupName, r.cfg.NotificationGroup, r.cfg.Environment))
-	event := notification.NotificationEvent{
+	var actions []history.Action
+	if r.currentRun != nil {
+		actions = r.currentRun.Actions
+	}
+
+	events := []notification.NotificationEvent{
+		{
+			Revision:    revision,
+			State:       state,
+			Name:        r.getNotificationName(),
+			Description: description,
+			Actions:     actions,
+		},
+	}
+
+	if r.cfg.NotificationPerResource {
+		events = append(events, r.getResourceNotificationEvents(revision, r.currentSources, actions, reconcileErr)...)
+	}
+
+	var result *multierror.Error
+	for i, event := range events {
+		// resources without actions keep the status already sent for the revision, a later no-op or failed run
+		// shouldn't replace the status of the run that deployed them
+		if i > 0 && len(event.Actions) == 0 {
+			previousNotificationEvent, found, err := r.notificationCache.Get(ctx, event.Name)
+			if err == nil && found && previousNotificationEvent.Revision == revision {
+				continue
+			}
+		}
+
+		err := r.sendNotificationEvent(ctx, event)
+		if err != nil {
+			result = multierror.Append(err, result)
+		}
+	}
+
+	return result.ErrorOrNil()
+}
+
+// sendPendingNotification notifies that the reconcile of a revision has started, only done once per revision to
+// not flip the status of a revision that has already been reconciled back to pending on every run
+func (r *Reconciler) sendPendingNotification(ctx context.Context, revision string) {
+	log := logr.FromContextOrDiscard(ctx)
+
+	if revision == "" {
+		return
+	}
+
+	name := r.getNotificationName()
+	previousNotificationEvent, found, err := r.notificationCache.Get(ctx, name)
+	if err != nil {
+		log.Error(err, "unable to get previous notification event from cache")
+		return
+	}
+
+	if found && previousNotificationEvent.Revision == revision {
+		log.V(1).Info("skipping pending notification, revision already notified", "revision", revision)
+		return
+	}
+
+	err = r.sendNotificationEvent(ctx, notification.NotificationEvent{
 		Revision:    revision,
-		State:       state,
+		State:       notification.NotificationStatePending,
 		Name:        name,
-		Description: description,
+		Description: "reconcile in progress",
+		// chat and webhook notifications only get the result, not a message for every new revision
+		CommitStatusOnly: true,
+	})
+	if err != nil {
+		log.Error(err, "unable to send pending notification")
 	}
+}
 
-	previousNotificationEvent, found, err := r.notificationCache.Get(ctx)
+func (r *Reconciler) getNotificationName() string {
+	return strings.ToLower(fmt.Sprintf("%s/%s-%s", r.cfg.ResourceGroupName, r.cfg.NotificationGroup, r.cfg.Environment))
+}
+
+func (r *Reconciler) sendNotificationEvent(ctx context.Context, event notification.NotificationEvent) error {
+	log := logr.FromContextOrDiscard(ctx)
+
+	// the events are compared and cached before rendering, as the templates may render the same text for
+	// different events or include values that change between runs
+	previousNotificationEvent, found, err := r.notificationCache.Get(ctx, event.Name)
 	if err != nil {
 		log.V(1).Error(err, "unable to get previous notification event from cache, received error", "event", event)
 		return err
@@ -635,6 +1278,11 @@ func (r *Reconciler) sendNotification(ctx context.Context, revision string, reco
 		return err
 	}
 
+	err = r.notificationTemplates.render(r.cfg, &event)
+	if err != nil {
+		log.Error(err, "unable to render notification templates, using the defaults", "event", event)
+	}
+
 	err = r.notificationClient.Send(ctx, event)
 	if err != nil {
 		log.V(1).Error(err, "unable to send event, received error", "event", event)
@@ -645,3 +1293,98 @@ func (r *Reconciler) sendNotification(ctx context.Context, revision string, reco
 
 	return nil
 }
+
+// getResourceNotificationEvents returns an event per source app and job and per other changed resource, failed if
+// any of its actions failed. Apps and jobs without actions are unchanged, or failed if the reconcile failed as
+// they may not have been reconciled.
+func (r *Reconciler) getResourceNotificationEvents(revision string, sources *source.Sources, actions []history.Action, reconcileErr error) []notification.NotificationEvent {
+	events := []notification.NotificationEvent{}
+	indexes := make(map[string]int)
+	getEvent := func(kind history.ResourceKind, resourceName string) *notification.NotificationEvent {
+		name := strings.ToLower(fmt.Sprintf("%s/%s/%s/%s", r.cfg.ResourceGroupName, r.cfg.Environment, kind, resourceName))
+		i, ok := indexes[name]
+		if !ok {
+			i = len(events)
+			indexes[name] = i
+			events = append(events, notification.NotificationEvent{
+				Revision: revision,
+				State:    notification.NotificationStateSuccess,
+				Name:     name,
+				// the resources get a commit status each, other notifications only get the reconcile event
+				CommitStatusOnly: true,
+			})
+		}
+
+		return &events[i]
+	}
+
+	setSourceError := func(kind history.ResourceKind, name string, err error) {
+		event := getEvent(kind, name)
+		if err != nil {
+			event.State = notification.NotificationStateFailure
+			event.Description = fmt.Sprintf("invalid manifest: %s", err.Error())
+		}
+	}
+
+	if sources != nil && sources.Apps != nil {
+		for _, name := range getSortedKeys(*sources.Apps) {
+			setSourceError(history.ResourceKindApp, name, (*sources.Apps)[name].Err)
+		}
+	}
+
+	if sources != nil && sources.Jobs != nil {
+		for _, name := range getSortedKeys(*sources.Jobs) {
+			setSourceError(history.ResourceKindJob, name, (*sources.Jobs)[name].Err)
+		}
+	}
+
+	for _, action := range actions {
+		event := getEvent(action.Kind, action.Name)
+		event.Actions = append(event.Actions, action)
+		if event.State == notification.NotificationStateFailure {
+			continue
+		}
+
+		if action.Error != "" {
+			event.State = notification.NotificationStateFailure
+			event.Description = fmt.Sprintf("%s failed: %s", action.Action, action.Error)
+			continue
+		}
+
+		description := fmt.Sprintf("%s succeeded", action.Action)
+		if action.Reason != "" {
+			description = fmt.Sprintf("%s: %s", description, action.Reason)
+		}
+		if event.Description != "" {
+			description = fmt.Sprintf("%s, %s", event.Description, description)
+		}
+		event.Description = description
+	}
+
+	for i := range events {
+		event := &events[i]
+		if len(event.Actions) > 0 || event.State == notification.NotificationStateFailure {
+			continue
+		}
+
+		if reconcileErr != nil {
+			event.State = notification.NotificationStateFailure
+			event.Description = "not reconciled, the reconcile failed"
+			continue
+		}
+
+		event.Description = "unchanged"
+	}
+
+	return events
+}
+
+func getSortedKeys[T any](m map[string]T) []string {
+	keys := []string{}
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -2,6 +2,7 @@ package reconcile
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
 	"testing"
 	"time"
@@ -10,8 +11,10 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/xenitab/azcagit/src/cache"
 	"github.com/xenitab/azcagit/src/config"
+	"github.com/xenitab/azcagit/src/history"
 	"github.com/xenitab/azcagit/src/metrics"
 	"github.com/xenitab/azcagit/src/notification"
+	"github.com/xenitab/azcagit/src/registry"
 	"github.com/xenitab/azcagit/src/remote"
 	"github.com/xenitab/azcagit/src/secret"
 	"github.com/xenitab/azcagit/src/source"
@@ -23,17 +26,27 @@ func TestReconciler(t *testing.T) {
 	sourceClient := source.NewInMemSource()
 	remoteAppClient := remote.NewInMemApp()
 	remoteJobClient := remote.NewInMemJob()
+	remoteDaprComponentClient := remote.NewInMemDaprComponent()
+	remoteCertificateClient := remote.NewInMemCertificate()
+	remoteStorageClient := remote.NewInMemStorage()
 	secretClient := secret.NewInMemSecret()
+	registryClient := registry.NewInMemRegistry()
 	notificationClient := notification.NewInMemNotification()
 	metricsClient := metrics.NewInMemMetrics()
 	appCache := cache.NewInMemAppCache()
 	jobCache := cache.NewInMemJobCache()
+	daprComponentCache := cache.NewInMemDaprComponentCache()
+	certificateCache := cache.NewInMemCertificateCache()
+	storageCache := cache.NewInMemStorageCache()
 	secretCache := cache.NewInMemSecretCache()
 	notificationCache := cache.NewInMemNotificationCache()
+	reconcileStateCache := cache.NewInMemReconcileStateCache()
+	secretFingerprintCache := cache.NewInMemSecretFingerprintCache()
+	historyCache := cache.NewInMemHistoryCache()
 
 	ctx := context.Background()
 
-	reconciler, err := NewReconciler(config.ReconcileConfig{}, sourceClient, remoteAppClient, remoteJobClient, secretClient, notificationClient, metricsClient, appCache, jobCache, secretCache, notificationCache)
+	reconciler, err := NewReconciler(config.ReconcileConfig{}, sourceClient, remoteAppClient, remoteJobClient, remoteDaprComponentClient, remoteCertificateClient, remoteStorageClient, secretClient, registryClient, notificationClient, metricsClient, appCache, jobCache, daprComponentCache, certificateCache, storageCache, secretCache, notificationCache, reconcileStateCache, secretFingerprintCache, historyCache)
 	require.NoError(t, err)
 
 	resetClients := func() {
@@ -44,6 +57,7 @@ func TestReconciler(t *testing.T) {
 		remoteAppClient.CreateResponse(nil)
 		remoteAppClient.UpdateResponse(nil)
 		remoteAppClient.DeleteResponse(nil)
+		remoteAppClient.RestartRevisionsResponse(nil, nil)
 		remoteAppClient.ResetActions()
 		remoteJobClient.GetFirstResponse(nil, nil)
 		remoteJobClient.GetSecondResponse(nil, nil)
@@ -52,11 +66,36 @@ func TestReconciler(t *testing.T) {
 		remoteJobClient.UpdateResponse(nil)
 		remoteJobClient.DeleteResponse(nil)
 		remoteJobClient.ResetActions()
+		remoteDaprComponentClient.GetFirstResponse(nil, nil)
+		remoteDaprComponentClient.GetSecondResponse(nil, nil)
+		remoteDaprComponentClient.ResetGetSecond()
+		remoteDaprComponentClient.CreateResponse(nil)
+		remoteDaprComponentClient.UpdateResponse(nil)
+		remoteDaprComponentClient.DeleteResponse(nil)
+		remoteDaprComponentClient.ResetActions()
+		remoteCertificateClient.GetFirstResponse(nil, nil)
+		remoteCertificateClient.GetSecondResponse(nil, nil)
+		remoteCertificateClient.ResetGetSecond()
+		remoteCertificateClient.CreateResponse(nil)
+		remoteCertificateClient.UpdateResponse(nil)
+		remoteCertificateClient.DeleteResponse(nil)
+		remoteCertificateClient.ResetActions()
+		remoteStorageClient.GetFirstResponse(nil, nil)
+		remoteStorageClient.GetSecondResponse(nil, nil)
+		remoteStorageClient.ResetGetSecond()
+		remoteStorageClient.CreateResponse(nil)
+		remoteStorageClient.UpdateResponse(nil)
+		remoteStorageClient.ResetActions()
 		secretClient.Reset()
+		registryClient.Reset()
 		notificationClient.SendResponse(nil)
 		notificationClient.ResetNotifications()
 		metricsClient.Reset()
 		notificationCache.Reset()
+		reconcileStateCache.Reset()
+		secretFingerprintCache.Reset()
+		secretCache.Reset()
+		historyCache.Reset()
 	}
 
 	t.Run("everything is nil", func(t *testing.T) {
@@ -990,6 +1029,119 @@ func TestReconciler(t *testing.T) {
 		require.Equal(t, "foobar", cacheValue)
 	})
 
+	t.Run("test remote secret keyvault reference", func(t *testing.T) {
+		defer resetClients()
+		sourceClient.GetResponse(&source.Sources{
+			Apps: &source.SourceApps{
+				"foo": source.SourceApp{
+					Kind:       "AzureContainerApp",
+					APIVersion: "aca.xenit.io/v1alpha2",
+					Metadata: map[string]string{
+						"name": "foo",
+					},
+					Specification: &source.SourceAppSpecification{
+						App: &armappcontainers.ContainerApp{},
+						RemoteSecrets: []source.RemoteSecretSpecification{
+							{
+								SecretName:       toPtr("ze-app-secret"),
+								RemoteSecretName: toPtr("ze-remote-secret-reference"),
+								Identity:         toPtr("System"),
+							},
+						},
+					},
+				},
+			},
+		}, defaultFakeRevision, nil)
+		remoteAppClient.GetFirstResponse(&remote.RemoteApps{}, nil)
+		remoteAppClient.GetSecondResponse(&remote.RemoteApps{
+			"foo": remote.RemoteApp{
+				App:     &armappcontainers.ContainerApp{},
+				Managed: true,
+			},
+		}, nil)
+		err := reconciler.Run(ctx)
+		require.NoError(t, err)
+		actions := remoteAppClient.Actions()
+		require.Len(t, actions, 1)
+		secret := actions[0].App.Properties.Configuration.Secrets[0]
+		require.Equal(t, "ze-app-secret", *secret.Name)
+		require.Nil(t, secret.Value)
+		require.Equal(t, "https://.vault.azure.net/secrets/ze-remote-secret-reference", *secret.KeyVaultURL)
+		require.Equal(t, "System", *secret.Identity)
+		_, ok := secretCache.Get("ze-remote-secret-reference")
+		require.False(t, ok)
+	})
+
+	t.Run("test remote secret from vault", func(t *testing.T) {
+		defer resetClients()
+		secretClient.Set("other/ze-remote-secret", "foobaz", time.Now())
+		sourceClient.GetResponse(&source.Sources{
+			Apps: &source.SourceApps{
+				"foo": source.SourceApp{
+					Kind:       "AzureContainerApp",
+					APIVersion: "aca.xenit.io/v1alpha2",
+					Metadata: map[string]string{
+						"name": "foo",
+					},
+					Specification: &source.SourceAppSpecification{
+						App: &armappcontainers.ContainerApp{},
+						RemoteSecrets: []source.RemoteSecretSpecification{
+							{
+								SecretName:       toPtr("ze-app-secret"),
+								RemoteSecretName: toPtr("ze-remote-secret"),
+								Vault:            toPtr("other"),
+							},
+						},
+					},
+				},
+			},
+		}, defaultFakeRevision, nil)
+		remoteAppClient.GetFirstResponse(&remote.RemoteApps{}, nil)
+		remoteAppClient.GetSecondResponse(&remote.RemoteApps{
+			"foo": remote.RemoteApp{
+				App:     &armappcontainers.ContainerApp{},
+				Managed: true,
+			},
+		}, nil)
+		err := reconciler.Run(ctx)
+		require.NoError(t, err)
+		actions := remoteAppClient.Actions()
+		require.Len(t, actions, 1)
+		require.Equal(t, "foobaz", *actions[0].App.Properties.Configuration.Secrets[0].Value)
+		cacheValue, ok := secretCache.Get("other/ze-remote-secret")
+		require.True(t, ok)
+		require.Equal(t, "foobaz", cacheValue)
+	})
+
+	t.Run("test remote secret keyvault reference from unconfigured vault", func(t *testing.T) {
+		defer resetClients()
+		sourceClient.GetResponse(&source.Sources{
+			Apps: &source.SourceApps{
+				"foo": source.SourceApp{
+					Kind:       "AzureContainerApp",
+					APIVersion: "aca.xenit.io/v1alpha2",
+					Metadata: map[string]string{
+						"name": "foo",
+					},
+					Specification: &source.SourceAppSpecification{
+						App: &armappcontainers.ContainerApp{},
+						RemoteSecrets: []source.RemoteSecretSpecification{
+							{
+								SecretName:       toPtr("ze-app-secret"),
+								RemoteSecretName: toPtr("ze-remote-secret-reference"),
+								Identity:         toPtr("System"),
+								Vault:            toPtr("other"),
+							},
+						},
+					},
+				},
+			},
+		}, defaultFakeRevision, nil)
+		remoteAppClient.GetFirstResponse(&remote.RemoteApps{}, nil)
+		err := reconciler.Run(ctx)
+		require.ErrorContains(t, err, "secret vault \"other\" is not configured")
+	})
+
 	t.Run("test remote secret failure", func(t *testing.T) {
 		defer resetClients()
 		sourceClient.GetResponse(&source.Sources{
@@ -1033,7 +1185,7 @@ func TestReconciler(t *testing.T) {
 			ContainerRegistryUsername: "foo",
 			ContainerRegistryPassword: "bar",
 		}
-		reconciler, err := NewReconciler(cfg, sourceClient, remoteAppClient, remoteJobClient, secretClient, notificationClient, metricsClient, appCache, jobCache, secretCache, notificationCache)
+		reconciler, err := NewReconciler(cfg, sourceClient, remoteAppClient, remoteJobClient, remoteDaprComponentClient, remoteCertificateClient, remoteStorageClient, secretClient, registryClient, notificationClient, metricsClient, appCache, jobCache, daprComponentCache, certificateCache, storageCache, secretCache, notificationCache, reconcileStateCache, secretFingerprintCache, historyCache)
 		require.NoError(t, err)
 		sourceClient.GetResponse(&source.Sources{
 			Apps: &source.SourceApps{
@@ -1071,6 +1223,79 @@ func TestReconciler(t *testing.T) {
 		require.Equal(t, "azcagit-reg-cred", *actions[0].App.Properties.Configuration.Registries[0].PasswordSecretRef)
 	})
 
+	t.Run("test populate registry with identity and additional registries", func(t *testing.T) {
+		defer resetClients()
+
+		cfg := config.ReconcileConfig{
+			ContainerRegistryServer:   "foobar.azurecr.io",
+			ContainerRegistryIdentity: "system",
+			ContainerRegistries: []string{
+				"server=ghcr.io;username=foo;password=bar",
+				"server=unused.io;username=foo;password=bar",
+			},
+		}
+		reconciler, err := NewReconciler(cfg, sourceClient, remoteAppClient, remoteJobClient, remoteDaprComponentClient, remoteCertificateClient, remoteStorageClient, secretClient, registryClient, notificationClient, metricsClient, appCache, jobCache, daprComponentCache, certificateCache, storageCache, secretCache, notificationCache, reconcileStateCache, secretFingerprintCache, historyCache)
+		require.NoError(t, err)
+		newApp := func(name string, disableRegistries bool) source.SourceApp {
+			return source.SourceApp{
+				Kind:       "AzureContainerApp",
+				APIVersion: "aca.xenit.io/v1alpha2",
+				Metadata: map[string]string{
+					"name": name,
+				},
+				Specification: &source.SourceAppSpecification{
+					App: &armappcontainers.ContainerApp{
+						Identity: &armappcontainers.ManagedServiceIdentity{
+							Type: toPtr(armappcontainers.ManagedServiceIdentityTypeSystemAssigned),
+						},
+						Properties: &armappcontainers.ContainerAppProperties{
+							Template: &armappcontainers.Template{
+								Containers: []*armappcontainers.Container{
+									{
+										Image: toPtr("ghcr.io/foo/bar:v1"),
+									},
+								},
+							},
+						},
+					},
+					DisableRegistries: disableRegistries,
+				},
+			}
+		}
+		sourceClient.GetResponse(&source.Sources{
+			Apps: &source.SourceApps{
+				"bar": newApp("bar", true),
+				"foo": newApp("foo", false),
+			},
+		}, defaultFakeRevision, nil)
+		remoteAppClient.GetFirstResponse(&remote.RemoteApps{}, nil)
+		remoteAppClient.GetSecondResponse(&remote.RemoteApps{
+			"bar": remote.RemoteApp{
+				App:     &armappcontainers.ContainerApp{},
+				Managed: true,
+			},
+			"foo": remote.RemoteApp{
+				App:     &armappcontainers.ContainerApp{},
+				Managed: true,
+			},
+		}, nil)
+		err = reconciler.Run(ctx)
+		require.NoError(t, err)
+		actions := remoteAppClient.Actions()
+		require.Len(t, actions, 2)
+		require.Equal(t, "bar", actions[0].Name)
+		require.Nil(t, actions[0].App.Properties.Configuration)
+		require.Equal(t, "foo", actions[1].Name)
+		registries := actions[1].App.Properties.Configuration.Registries
+		require.Len(t, registries, 2)
+		require.Equal(t, "foobar.azurecr.io", *registries[0].Server)
+		require.Equal(t, "system", *registries[0].Identity)
+		require.Nil(t, registries[0].PasswordSecretRef)
+		require.Equal(t, "ghcr.io", *registries[1].Server)
+		require.Equal(t, "azcagit-reg-cred", *registries[1].PasswordSecretRef)
+		require.Len(t, actions[1].App.Properties.Configuration.Secrets, 1)
+	})
+
 	t.Run("test notification success event", func(t *testing.T) {
 		defer resetClients()
 		sourceClient.GetResponse(&source.Sources{
@@ -1101,9 +1326,46 @@ func TestReconciler(t *testing.T) {
 		require.Equal(t, actions[0].Name, "foo")
 		require.Equal(t, actions[0].Action, remote.InMemAppActionsCreate)
 		notifications := notificationClient.GetNotifications()
-		require.Len(t, notifications, 1)
-		require.Equal(t, notification.NotificationStateSuccess, notifications[0].State)
+		require.Len(t, notifications, 2)
+		require.Equal(t, notification.NotificationStatePending, notifications[0].State)
 		require.Equal(t, defaultFakeRevision, notifications[0].Revision)
+		require.Equal(t, notification.NotificationStateSuccess, notifications[1].State)
+		require.Equal(t, defaultFakeRevision, notifications[1].Revision)
+	})
+
+	t.Run("test pending notification only sent once per revision", func(t *testing.T) {
+		defer resetClients()
+		for i := 0; i < 2; i++ {
+			sourceClient.GetResponse(&source.Sources{
+				Apps: &source.SourceApps{
+					"foo": source.SourceApp{
+						Kind:       "AzureContainerApp",
+						APIVersion: "aca.xenit.io/v1alpha2",
+						Metadata: map[string]string{
+							"name": "foo",
+						},
+						Specification: &source.SourceAppSpecification{
+							App: &armappcontainers.ContainerApp{},
+						},
+					},
+				},
+			}, defaultFakeRevision, nil)
+			remoteApps := &remote.RemoteApps{
+				"foo": remote.RemoteApp{
+					App:     &armappcontainers.ContainerApp{},
+					Managed: true,
+				},
+			}
+			remoteAppClient.GetFirstResponse(remoteApps, nil)
+			remoteAppClient.GetSecondResponse(remoteApps, nil)
+			err := reconciler.Run(ctx)
+			require.NoError(t, err)
+		}
+
+		notifications := notificationClient.GetNotifications()
+		require.Len(t, notifications, 2)
+		require.Equal(t, notification.NotificationStatePending, notifications[0].State)
+		require.Equal(t, notification.NotificationStateSuccess, notifications[1].State)
 	})
 
 	t.Run("test notification failure event", func(t *testing.T) {
@@ -1193,9 +1455,15 @@ func TestReconciler(t *testing.T) {
 		})
 
 		notifications := notificationClient.GetNotifications()
-		require.Len(t, notifications, 2)
+		require.Len(t, notifications, 4)
 		require.Equal(t, "first-revision", notifications[0].Revision)
-		require.Equal(t, "second-revision", notifications[1].Revision)
+		require.Equal(t, notification.NotificationStatePending, notifications[0].State)
+		require.Equal(t, "first-revision", notifications[1].Revision)
+		require.Equal(t, notification.NotificationStateSuccess, notifications[1].State)
+		require.Equal(t, "second-revision", notifications[2].Revision)
+		require.Equal(t, notification.NotificationStatePending, notifications[2].State)
+		require.Equal(t, "second-revision", notifications[3].Revision)
+		require.Equal(t, notification.NotificationStateSuccess, notifications[3].State)
 	})
 
 	t.Run("test notification deduplication", func(t *testing.T) {
@@ -1217,9 +1485,18 @@ func TestReconciler(t *testing.T) {
 		require.Contains(t, notifications[0].Description, "ze-failure")
 	})
 
-	t.Run("test notification error", func(t *testing.T) {
+	t.Run("test notification per resource", func(t *testing.T) {
 		defer resetClients()
 
+		cfg := config.ReconcileConfig{
+			ResourceGroupName:       "ze-rg",
+			Environment:             "dev",
+			NotificationGroup:       "apps",
+			NotificationPerResource: true,
+		}
+		reconciler, err := NewReconciler(cfg, sourceClient, remoteAppClient, remoteJobClient, remoteDaprComponentClient, remoteCertificateClient, remoteStorageClient, secretClient, registryClient, notificationClient, metricsClient, appCache, jobCache, daprComponentCache, certificateCache, storageCache, secretCache, notificationCache, reconcileStateCache, secretFingerprintCache, historyCache)
+		require.NoError(t, err)
+
 		sourceClient.GetResponse(&source.Sources{
 			Apps: &source.SourceApps{
 				"foo": source.SourceApp{
@@ -1235,53 +1512,175 @@ func TestReconciler(t *testing.T) {
 			},
 		}, defaultFakeRevision, nil)
 		remoteAppClient.GetFirstResponse(&remote.RemoteApps{}, nil)
+		remoteAppClient.CreateResponse(fmt.Errorf("ze-create-failure"))
+		err = reconciler.Run(ctx)
+		require.ErrorContains(t, err, "ze-create-failure")
+
+		notifications := notificationClient.GetNotifications()
+		require.Len(t, notifications, 3)
+		require.Equal(t, "ze-rg/apps-dev", notifications[0].Name)
+		require.Equal(t, notification.NotificationStatePending, notifications[0].State)
+		require.Equal(t, "ze-rg/apps-dev", notifications[1].Name)
+		require.Equal(t, notification.NotificationStateFailure, notifications[1].State)
+		require.Equal(t, "ze-rg/dev/app/foo", notifications[2].Name)
+		require.Equal(t, notification.NotificationStateFailure, notifications[2].State)
+		require.Equal(t, "create failed: ze-create-failure", notifications[2].Description)
+		require.Len(t, notifications[1].Actions, 1)
+		require.Equal(t, notifications[1].Actions, notifications[2].Actions)
+		require.True(t, notifications[0].CommitStatusOnly, "pending events are only sent as commit status")
+		require.False(t, notifications[1].CommitStatusOnly)
+		require.True(t, notifications[2].CommitStatusOnly, "resource events are only sent as commit status")
+
+		notificationClient.ResetNotifications()
+		remoteAppClient.CreateResponse(nil)
+		remoteAppClient.ResetGetSecond()
+		remoteAppClient.GetFirstResponse(&remote.RemoteApps{}, nil)
 		remoteAppClient.GetSecondResponse(&remote.RemoteApps{
 			"foo": remote.RemoteApp{
 				App:     &armappcontainers.ContainerApp{},
 				Managed: true,
 			},
 		}, nil)
-		notificationClient.SendResponse(fmt.Errorf("fake notification error"))
-		err := reconciler.Run(ctx)
-		require.ErrorContains(t, err, "fake notification error")
+		err = reconciler.Run(ctx)
+		require.NoError(t, err)
+
+		notifications = notificationClient.GetNotifications()
+		require.Len(t, notifications, 2)
+		require.Equal(t, "ze-rg/apps-dev", notifications[0].Name)
+		require.Equal(t, notification.NotificationStateSuccess, notifications[0].State)
+		require.Equal(t, "ze-rg/dev/app/foo", notifications[1].Name)
+		require.Equal(t, notification.NotificationStateSuccess, notifications[1].State)
+		require.Equal(t, "create succeeded: not in AppCache", notifications[1].Description)
 	})
 
-	t.Run("test locationFilter", func(t *testing.T) {
+	t.Run("test notification per resource for unchanged resources", func(t *testing.T) {
 		defer resetClients()
 
 		cfg := config.ReconcileConfig{
-			Location: "foobar",
+			ResourceGroupName:       "ze-rg",
+			Environment:             "dev",
+			NotificationGroup:       "apps",
+			NotificationPerResource: true,
 		}
-		reconciler, err := NewReconciler(cfg, sourceClient, remoteAppClient, remoteJobClient, secretClient, notificationClient, metricsClient, appCache, jobCache, secretCache, notificationCache)
+		reconciler, err := NewReconciler(cfg, sourceClient, remoteAppClient, remoteJobClient, remoteDaprComponentClient, remoteCertificateClient, remoteStorageClient, secretClient, registryClient, notificationClient, metricsClient, appCache, jobCache, daprComponentCache, certificateCache, storageCache, secretCache, notificationCache, reconcileStateCache, secretFingerprintCache, historyCache)
 		require.NoError(t, err)
 
-		sourceClient.GetResponse(&source.Sources{
-			Apps: &source.SourceApps{
-				"foo": source.SourceApp{
-					Kind:       "AzureContainerApp",
-					APIVersion: "aca.xenit.io/v1alpha2",
-					Metadata: map[string]string{
-						"name": "foo",
+		newSources := func() *source.Sources {
+			return &source.Sources{
+				Apps: &source.SourceApps{
+					"foo": source.SourceApp{
+						Kind:       "AzureContainerApp",
+						APIVersion: "aca.xenit.io/v1alpha2",
+						Metadata: map[string]string{
+							"name": "foo",
+						},
+						Specification: &source.SourceAppSpecification{
+							App: &armappcontainers.ContainerApp{},
+						},
 					},
-					Specification: &source.SourceAppSpecification{
-						LocationFilter: []source.LocationFilterSpecification{
-							"zefakeregion",
+				},
+				Jobs: &source.SourceJobs{
+					"qux": source.SourceJob{
+						Kind:       "AzureContainerJob",
+						APIVersion: "aca.xenit.io/v1alpha2",
+						Metadata: map[string]string{
+							"name": "qux",
 						},
-						App: &armappcontainers.ContainerApp{},
+						Specification: &source.SourceJobSpecification{
+							Job: &armappcontainers.Job{},
+						},
+					},
+				},
+			}
+		}
+		now := time.Now()
+		remoteApps := &remote.RemoteApps{
+			"foo": remote.RemoteApp{
+				App: &armappcontainers.ContainerApp{
+					SystemData: &armappcontainers.SystemData{
+						LastModifiedAt: &now,
 					},
 				},
+				Managed: true,
 			},
-		}, defaultFakeRevision, nil)
-		remoteAppClient.GetFirstResponse(&remote.RemoteApps{}, nil)
-		remoteAppClient.GetSecondResponse(&remote.RemoteApps{}, nil)
-		err = reconciler.Run(ctx)
+		}
+		remoteJobs := &remote.RemoteJobs{
+			"qux": remote.RemoteJob{
+				Job: &armappcontainers.Job{
+					SystemData: &armappcontainers.SystemData{
+						LastModifiedAt: &now,
+					},
+				},
+				Managed: true,
+			},
+		}
+		run := func(sources *source.Sources, revision string) ([]notification.NotificationEvent, error) {
+			notificationClient.ResetNotifications()
+			sourceClient.GetResponse(sources, revision, nil)
+			remoteAppClient.ResetGetSecond()
+			remoteAppClient.GetFirstResponse(remoteApps, nil)
+			remoteAppClient.GetSecondResponse(remoteApps, nil)
+			remoteJobClient.ResetGetSecond()
+			remoteJobClient.GetFirstResponse(remoteJobs, nil)
+			remoteJobClient.GetSecondResponse(remoteJobs, nil)
+			err := reconciler.Run(ctx)
+			return notificationClient.GetNotifications(), err
+		}
+
+		notifications, err := run(newSources(), "first-revision")
 		require.NoError(t, err)
-		actions := remoteAppClient.Actions()
-		require.Len(t, actions, 0)
+		require.Len(t, notifications, 4)
+		require.Equal(t, "ze-rg/dev/app/foo", notifications[2].Name)
+		require.Equal(t, "update succeeded: not in AppCache", notifications[2].Description)
+		require.Equal(t, "ze-rg/dev/job/qux", notifications[3].Name)
+		require.Equal(t, "update succeeded: not in JobCache", notifications[3].Description)
+
+		notifications, err = run(newSources(), "second-revision")
+		require.NoError(t, err)
+		require.Len(t, notifications, 4)
+		require.Equal(t, "ze-rg/dev/app/foo", notifications[2].Name)
+		require.Equal(t, notification.NotificationStateSuccess, notifications[2].State)
+		require.Equal(t, "unchanged", notifications[2].Description)
+		require.Equal(t, "ze-rg/dev/job/qux", notifications[3].Name)
+		require.Equal(t, "unchanged", notifications[3].Description)
+
+		notifications, err = run(newSources(), "second-revision")
+		require.NoError(t, err)
+		require.Empty(t, notifications, "unchanged resources are only notified once per revision")
+
+		invalidSources := newSources()
+		(*invalidSources.Apps)["bar"] = source.SourceApp{
+			Err: fmt.Errorf("ze-invalid"),
+		}
+		notifications, err = run(invalidSources, "third-revision")
+		require.ErrorContains(t, err, "ze-invalid")
+		require.Len(t, notifications, 5)
+		require.Equal(t, notification.NotificationStateFailure, notifications[1].State)
+		require.Equal(t, "ze-rg/dev/app/bar", notifications[2].Name)
+		require.Equal(t, notification.NotificationStateFailure, notifications[2].State)
+		require.Equal(t, "invalid manifest: ze-invalid", notifications[2].Description)
+		require.Equal(t, "ze-rg/dev/app/foo", notifications[3].Name)
+		require.Equal(t, notification.NotificationStateFailure, notifications[3].State)
+		require.Equal(t, "not reconciled, the reconcile failed", notifications[3].Description)
+		require.Equal(t, "ze-rg/dev/job/qux", notifications[4].Name)
+		require.Equal(t, notification.NotificationStateFailure, notifications[4].State)
 	})
 
-	t.Run("verify that metrics work", func(t *testing.T) {
+	t.Run("test notification templates", func(t *testing.T) {
 		defer resetClients()
+
+		cfg := config.ReconcileConfig{
+			ResourceGroupName:       "ze-rg",
+			Environment:             "dev",
+			SubscriptionID:          "ze-sub",
+			NotificationGroup:       "apps",
+			NotificationPerResource: true,
+			NotificationDescription: `{{ .State }} in {{ .Environment }}{{ range .Resources }}, {{ .Action }} {{ .Kind }}/{{ .Name }}{{ end }}`,
+			NotificationTargetUrl:   `{{ if eq (len .Resources) 1 }}{{ (index .Resources 0).LogsUrl }}{{ else }}{{ .PortalUrl }}{{ end }}`,
+		}
+		reconciler, err := NewReconciler(cfg, sourceClient, remoteAppClient, remoteJobClient, remoteDaprComponentClient, remoteCertificateClient, remoteStorageClient, secretClient, registryClient, notificationClient, metricsClient, appCache, jobCache, daprComponentCache, certificateCache, storageCache, secretCache, notificationCache, reconcileStateCache, secretFingerprintCache, historyCache)
+		require.NoError(t, err)
+
 		sourceClient.GetResponse(&source.Sources{
 			Apps: &source.SourceApps{
 				"foo": source.SourceApp{
@@ -1303,20 +1702,1151 @@ func TestReconciler(t *testing.T) {
 				Managed: true,
 			},
 		}, nil)
-		err := reconciler.Run(ctx)
+		err = reconciler.Run(ctx)
 		require.NoError(t, err)
-		actions := remoteAppClient.Actions()
-		require.Len(t, actions, 1)
-		require.Equal(t, actions[0].Name, "foo")
-		require.Equal(t, actions[0].Action, remote.InMemAppActionsCreate)
-		intStats := metricsClient.IntStats()
-		require.Len(t, intStats, 1)
-		require.Equal(t, 1, intStats[0])
-		durationStats := metricsClient.DurationStats()
-		require.Len(t, durationStats, 1)
-		require.Greater(t, durationStats[0].Nanoseconds(), int64(100))
-		successStats := metricsClient.SuccessStats()
-		require.Len(t, successStats, 1)
+
+		notifications := notificationClient.GetNotifications()
+		require.Len(t, notifications, 3)
+		require.Equal(t, "pending in dev", notifications[0].Description)
+		require.Equal(t, "https://portal.azure.com/#resource/subscriptions/ze-sub/resourceGroups/ze-rg/overview", notifications[0].TargetUrl)
+		require.Equal(t, "success in dev, create app/foo", notifications[1].Description)
+		require.Equal(t, "https://portal.azure.com/#resource/subscriptions/ze-sub/resourceGroups/ze-rg/providers/Microsoft.App/containerApps/foo/logs", notifications[1].TargetUrl)
+		require.Equal(t, "ze-rg/dev/app/foo", notifications[2].Name)
+		require.Equal(t, "success in dev, create app/foo", notifications[2].Description)
+
+		cachedEvent, found, err := notificationCache.Get(ctx, "ze-rg/apps-dev")
+		require.NoError(t, err)
+		require.True(t, found)
+		require.Equal(t, "reconcile succeeded", cachedEvent.Description, "events are cached before rendering")
+		require.Empty(t, cachedEvent.TargetUrl)
+
+		t.Run("failing template keeps both defaults", func(t *testing.T) {
+			failingCfg := cfg
+			failingCfg.NotificationTargetUrl = `{{ (index .Resources 5).LogsUrl }}`
+			reconciler, err := NewReconciler(failingCfg, sourceClient, remoteAppClient, remoteJobClient, remoteDaprComponentClient, remoteCertificateClient, remoteStorageClient, secretClient, registryClient, notificationClient, metricsClient, appCache, jobCache, daprComponentCache, certificateCache, storageCache, secretCache, notificationCache, reconcileStateCache, secretFingerprintCache, historyCache)
+			require.NoError(t, err)
+
+			notificationClient.ResetNotifications()
+			sourceClient.GetResponse(&source.Sources{Apps: &source.SourceApps{}}, "new-revision", nil)
+			remoteAppClient.ResetGetSecond()
+			remoteAppClient.GetFirstResponse(&remote.RemoteApps{}, nil)
+			remoteAppClient.GetSecondResponse(&remote.RemoteApps{}, nil)
+			err = reconciler.Run(ctx)
+			require.NoError(t, err)
+
+			notifications := notificationClient.GetNotifications()
+			require.Len(t, notifications, 2)
+			require.Equal(t, "reconcile succeeded", notifications[1].Description)
+			require.Empty(t, notifications[1].TargetUrl)
+		})
+
+		cfg.NotificationTargetUrl = "{{ .PortalUrl"
+		_, err = NewReconciler(cfg, sourceClient, remoteAppClient, remoteJobClient, remoteDaprComponentClient, remoteCertificateClient, remoteStorageClient, secretClient, registryClient, notificationClient, metricsClient, appCache, jobCache, daprComponentCache, certificateCache, storageCache, secretCache, notificationCache, reconcileStateCache, secretFingerprintCache, historyCache)
+		require.ErrorContains(t, err, "unable to parse notification target url template")
+	})
+
+	t.Run("test notification error", func(t *testing.T) {
+		defer resetClients()
+
+		sourceClient.GetResponse(&source.Sources{
+			Apps: &source.SourceApps{
+				"foo": source.SourceApp{
+					Kind:       "AzureContainerApp",
+					APIVersion: "aca.xenit.io/v1alpha2",
+					Metadata: map[string]string{
+						"name": "foo",
+					},
+					Specification: &source.SourceAppSpecification{
+						App: &armappcontainers.ContainerApp{},
+					},
+				},
+			},
+		}, defaultFakeRevision, nil)
+		remoteAppClient.GetFirstResponse(&remote.RemoteApps{}, nil)
+		remoteAppClient.GetSecondResponse(&remote.RemoteApps{
+			"foo": remote.RemoteApp{
+				App:     &armappcontainers.ContainerApp{},
+				Managed: true,
+			},
+		}, nil)
+		notificationClient.SendResponse(fmt.Errorf("fake notification error"))
+		err := reconciler.Run(ctx)
+		require.ErrorContains(t, err, "fake notification error")
+	})
+
+	t.Run("test locationFilter", func(t *testing.T) {
+		defer resetClients()
+
+		cfg := config.ReconcileConfig{
+			Location: "foobar",
+		}
+		reconciler, err := NewReconciler(cfg, sourceClient, remoteAppClient, remoteJobClient, remoteDaprComponentClient, remoteCertificateClient, remoteStorageClient, secretClient, registryClient, notificationClient, metricsClient, appCache, jobCache, daprComponentCache, certificateCache, storageCache, secretCache, notificationCache, reconcileStateCache, secretFingerprintCache, historyCache)
+		require.NoError(t, err)
+
+		sourceClient.GetResponse(&source.Sources{
+			Apps: &source.SourceApps{
+				"foo": source.SourceApp{
+					Kind:       "AzureContainerApp",
+					APIVersion: "aca.xenit.io/v1alpha2",
+					Metadata: map[string]string{
+						"name": "foo",
+					},
+					Specification: &source.SourceAppSpecification{
+						LocationFilter: []source.LocationFilterSpecification{
+							"zefakeregion",
+						},
+						App: &armappcontainers.ContainerApp{},
+					},
+				},
+			},
+		}, defaultFakeRevision, nil)
+		remoteAppClient.GetFirstResponse(&remote.RemoteApps{}, nil)
+		remoteAppClient.GetSecondResponse(&remote.RemoteApps{}, nil)
+		err = reconciler.Run(ctx)
+		require.NoError(t, err)
+		actions := remoteAppClient.Actions()
+		require.Len(t, actions, 0)
+	})
+
+	t.Run("verify that metrics work", func(t *testing.T) {
+		defer resetClients()
+		sourceClient.GetResponse(&source.Sources{
+			Apps: &source.SourceApps{
+				"foo": source.SourceApp{
+					Kind:       "AzureContainerApp",
+					APIVersion: "aca.xenit.io/v1alpha2",
+					Metadata: map[string]string{
+						"name": "foo",
+					},
+					Specification: &source.SourceAppSpecification{
+						App: &armappcontainers.ContainerApp{},
+					},
+				},
+			},
+		}, defaultFakeRevision, nil)
+		remoteAppClient.GetFirstResponse(&remote.RemoteApps{}, nil)
+		remoteAppClient.GetSecondResponse(&remote.RemoteApps{
+			"foo": remote.RemoteApp{
+				App:     &armappcontainers.ContainerApp{},
+				Managed: true,
+			},
+		}, nil)
+		err := reconciler.Run(ctx)
+		require.NoError(t, err)
+		actions := remoteAppClient.Actions()
+		require.Len(t, actions, 1)
+		require.Equal(t, actions[0].Name, "foo")
+		require.Equal(t, actions[0].Action, remote.InMemAppActionsCreate)
+		intStats := metricsClient.IntStats()
+		require.Len(t, intStats, 1)
+		require.Equal(t, 1, intStats[0])
+		durationStats := metricsClient.DurationStats()
+		require.Len(t, durationStats, 1)
+		require.Greater(t, durationStats[0].Nanoseconds(), int64(100))
+		successStats := metricsClient.SuccessStats()
+		require.Len(t, successStats, 1)
 		require.True(t, successStats[0])
 	})
+
+	t.Run("test no-op fast path", func(t *testing.T) {
+		defer resetClients()
+		now := time.Now()
+		later := now.Add(1 * time.Minute)
+		remoteApps := &remote.RemoteApps{
+			"foo": remote.RemoteApp{
+				App: &armappcontainers.ContainerApp{
+					SystemData: &armappcontainers.SystemData{
+						LastModifiedAt: &now,
+					},
+				},
+				Managed: true,
+			},
+		}
+		remoteAppsLater := &remote.RemoteApps{
+			"foo": remote.RemoteApp{
+				App: &armappcontainers.ContainerApp{
+					SystemData: &armappcontainers.SystemData{
+						LastModifiedAt: &later,
+					},
+				},
+				Managed: true,
+			},
+		}
+		newSources := func() *source.Sources {
+			return &source.Sources{
+				Apps: &source.SourceApps{
+					"foo": source.SourceApp{
+						Kind:       "AzureContainerApp",
+						APIVersion: "aca.xenit.io/v1alpha2",
+						Metadata: map[string]string{
+							"name": "foo",
+						},
+						Specification: &source.SourceAppSpecification{
+							App: &armappcontainers.ContainerApp{},
+							RemoteSecrets: []source.RemoteSecretSpecification{
+								{
+									SecretName:       toPtr("ze-secret"),
+									RemoteSecretName: toPtr("ze-remote-secret"),
+								},
+							},
+						},
+					},
+				},
+			}
+		}
+		secretClient.Set("ze-remote-secret", "foobar", now)
+		remoteAppClient.GetFirstResponse(remoteApps, nil)
+		remoteAppClient.GetSecondResponse(remoteApps, nil)
+
+		t.Run("first run reconciles", func(t *testing.T) {
+			sourceClient.GetResponse(newSources(), defaultFakeRevision, nil)
+			err := reconciler.Run(ctx)
+			require.NoError(t, err)
+			actions := remoteAppClient.Actions()
+			require.Len(t, actions, 1)
+			require.Equal(t, remote.InMemAppActionsUpdate, actions[0].Action)
+			remoteAppClient.ResetActions()
+			state, found, err := reconcileStateCache.Get(ctx)
+			require.NoError(t, err)
+			require.True(t, found)
+			require.Equal(t, defaultFakeRevision, state.Revision)
+			fingerprints, found, err := secretFingerprintCache.Get(ctx)
+			require.NoError(t, err)
+			require.True(t, found)
+			require.Equal(t, map[string]time.Time{"ze-remote-secret": now.UTC()}, fingerprints)
+			require.Equal(t, []string{"ze-remote-secret"}, secretClient.Gets())
+			secretClient.ResetGets()
+		})
+
+		t.Run("second run is a no-op", func(t *testing.T) {
+			sourceClient.GetResponse(newSources(), defaultFakeRevision, nil)
+			err := reconciler.Run(ctx)
+			require.NoError(t, err)
+			require.Len(t, remoteAppClient.Actions(), 0)
+			remoteAppClient.ResetGetSecond()
+		})
+
+		t.Run("changed secret triggers full reconcile", func(t *testing.T) {
+			sourceClient.GetResponse(newSources(), defaultFakeRevision, nil)
+			secretClient.Set("ze-remote-secret", "foobaz", later)
+			err := reconciler.Run(ctx)
+			require.NoError(t, err)
+			actions := remoteAppClient.Actions()
+			require.Len(t, actions, 1)
+			require.Equal(t, remote.InMemAppActionsUpdate, actions[0].Action)
+			remoteAppClient.ResetActions()
+			require.Equal(t, []string{"ze-remote-secret"}, secretClient.Gets())
+			secretClient.ResetGets()
+		})
+
+		t.Run("changed remote triggers full reconcile", func(t *testing.T) {
+			secretCache.Reset()
+			sourceClient.GetResponse(newSources(), defaultFakeRevision, nil)
+			remoteAppClient.GetFirstResponse(remoteAppsLater, nil)
+			remoteAppClient.GetSecondResponse(remoteAppsLater, nil)
+			err := reconciler.Run(ctx)
+			require.NoError(t, err)
+			actions := remoteAppClient.Actions()
+			require.Len(t, actions, 1)
+			require.Equal(t, remote.InMemAppActionsUpdate, actions[0].Action)
+			require.Equal(t, "foobaz", *actions[0].App.Properties.Configuration.Secrets[0].Value)
+			remoteAppClient.ResetActions()
+			secretClient.ResetGets()
+		})
+
+		t.Run("unchanged secret isn't read when only another app changes", func(t *testing.T) {
+			secretCache.Reset()
+			sources := newSources()
+			(*sources.Apps)["bar"] = source.SourceApp{
+				Kind:       "AzureContainerApp",
+				APIVersion: "aca.xenit.io/v1alpha2",
+				Metadata: map[string]string{
+					"name": "bar",
+				},
+				Specification: &source.SourceAppSpecification{
+					App: &armappcontainers.ContainerApp{},
+				},
+			}
+			sourceClient.GetResponse(sources, defaultFakeRevision, nil)
+			remoteAppsWithBar := &remote.RemoteApps{
+				"foo": (*remoteAppsLater)["foo"],
+				"bar": remote.RemoteApp{
+					App: &armappcontainers.ContainerApp{
+						SystemData: &armappcontainers.SystemData{
+							LastModifiedAt: &later,
+						},
+					},
+					Managed: true,
+				},
+			}
+			remoteAppClient.ResetGetSecond()
+			remoteAppClient.GetFirstResponse(remoteAppsLater, nil)
+			remoteAppClient.GetSecondResponse(remoteAppsWithBar, nil)
+			err := reconciler.Run(ctx)
+			require.NoError(t, err)
+			actions := remoteAppClient.Actions()
+			require.Len(t, actions, 1)
+			require.Equal(t, "bar", actions[0].Name)
+			require.Equal(t, remote.InMemAppActionsCreate, actions[0].Action)
+			remoteAppClient.ResetActions()
+			require.Empty(t, secretClient.Gets(), "unchanged secrets shouldn't be read")
+		})
+
+		t.Run("failed reconcile does not update state", func(t *testing.T) {
+			previousState, _, err := reconcileStateCache.Get(ctx)
+			require.NoError(t, err)
+			sourceClient.GetResponse(&source.Sources{Apps: &source.SourceApps{}}, "new-revision", nil)
+			remoteAppClient.ResetGetSecond()
+			remoteAppClient.GetFirstResponse(nil, fmt.Errorf("foobar"))
+			err = reconciler.Run(ctx)
+			require.ErrorContains(t, err, "failed to get remoteApps: foobar")
+			state, _, err := reconcileStateCache.Get(ctx)
+			require.NoError(t, err)
+			require.Equal(t, previousState, state)
+		})
+	})
+
+	t.Run("test history", func(t *testing.T) {
+		defer resetClients()
+		now := time.Now()
+		newRemoteApp := func() remote.RemoteApp {
+			return remote.RemoteApp{
+				App: &armappcontainers.ContainerApp{
+					SystemData: &armappcontainers.SystemData{
+						LastModifiedAt: &now,
+					},
+				},
+				Managed: true,
+			}
+		}
+		newSources := func() *source.Sources {
+			return &source.Sources{
+				Apps: &source.SourceApps{
+					"foo": source.SourceApp{
+						Kind:       "AzureContainerApp",
+						APIVersion: "aca.xenit.io/v1alpha2",
+						Metadata: map[string]string{
+							"name": "foo",
+						},
+						Specification: &source.SourceAppSpecification{
+							App: &armappcontainers.ContainerApp{},
+						},
+					},
+				},
+			}
+		}
+
+		t.Run("first run deletes and creates", func(t *testing.T) {
+			sourceClient.GetResponse(newSources(), defaultFakeRevision, nil)
+			remoteAppClient.GetFirstResponse(&remote.RemoteApps{"bar": newRemoteApp()}, nil)
+			remoteAppClient.GetSecondResponse(&remote.RemoteApps{"foo": newRemoteApp()}, nil)
+			err := reconciler.Run(ctx)
+			require.NoError(t, err)
+		})
+
+		t.Run("second run is a no-op", func(t *testing.T) {
+			sourceClient.GetResponse(newSources(), defaultFakeRevision, nil)
+			remoteAppClient.ResetGetSecond()
+			remoteAppClient.GetFirstResponse(&remote.RemoteApps{"foo": newRemoteApp()}, nil)
+			err := reconciler.Run(ctx)
+			require.NoError(t, err)
+			require.Len(t, remoteAppClient.Actions(), 2)
+		})
+
+		t.Run("another no-op run is collapsed", func(t *testing.T) {
+			sourceClient.GetResponse(newSources(), defaultFakeRevision, nil)
+			remoteAppClient.ResetGetSecond()
+			remoteAppClient.GetFirstResponse(&remote.RemoteApps{"foo": newRemoteApp()}, nil)
+			err := reconciler.Run(ctx)
+			require.NoError(t, err)
+		})
+
+		t.Run("third run fails to delete", func(t *testing.T) {
+			sourceClient.GetResponse(newSources(), "new-revision", nil)
+			remoteAppClient.ResetGetSecond()
+			remoteAppClient.GetFirstResponse(&remote.RemoteApps{"foo": newRemoteApp(), "baz": newRemoteApp()}, nil)
+			remoteAppClient.DeleteResponse(fmt.Errorf("delete foobar"))
+			err := reconciler.Run(ctx)
+			require.ErrorContains(t, err, "delete foobar")
+		})
+
+		runs, err := historyCache.List(ctx, 0)
+		require.NoError(t, err)
+		require.Len(t, runs, 3)
+
+		require.Equal(t, 3, runs[0].Number)
+		require.Equal(t, "new-revision", runs[0].Revision)
+		require.Contains(t, runs[0].Error, "delete foobar")
+		require.Equal(t, []history.Action{
+			{Kind: history.ResourceKindApp, Name: "baz", Action: history.ActionTypeDelete, Reason: "not in source", Error: "delete foobar"},
+		}, runs[0].Actions)
+
+		require.Equal(t, 2, runs[1].Number)
+		require.True(t, runs[1].NoOp)
+		require.Equal(t, 2, runs[1].NoOpRuns)
+		require.False(t, runs[1].LastEndTime.Before(runs[1].EndTime))
+		require.Empty(t, runs[1].Actions)
+
+		require.Equal(t, 1, runs[2].Number)
+		require.Equal(t, defaultFakeRevision, runs[2].Revision)
+		require.Empty(t, runs[2].Error)
+		require.False(t, runs[2].NoOp)
+		require.False(t, runs[2].EndTime.Before(runs[2].StartTime))
+		require.Equal(t, []history.Action{
+			{Kind: history.ResourceKindApp, Name: "bar", Action: history.ActionTypeDelete, Reason: "not in source"},
+			{Kind: history.ResourceKindApp, Name: "foo", Action: history.ActionTypeCreate, Reason: "remoteApp nil"},
+		}, runs[2].Actions)
+
+		fooRuns := history.FilterRuns(runs, "foo", 0)
+		require.Len(t, fooRuns, 1)
+		require.Equal(t, 1, fooRuns[0].Number)
+	})
+
+	t.Run("test secret rotation", func(t *testing.T) {
+		defer resetClients()
+
+		cfg := config.ReconcileConfig{
+			SecretRotationRestart: true,
+		}
+		reconciler, err := NewReconciler(cfg, sourceClient, remoteAppClient, remoteJobClient, remoteDaprComponentClient, remoteCertificateClient, remoteStorageClient, secretClient, registryClient, notificationClient, metricsClient, appCache, jobCache, daprComponentCache, certificateCache, storageCache, secretCache, notificationCache, reconcileStateCache, secretFingerprintCache, historyCache)
+		require.NoError(t, err)
+
+		now := time.Now()
+		later := now.Add(1 * time.Minute)
+		remoteApps := &remote.RemoteApps{
+			"foo": remote.RemoteApp{
+				App: &armappcontainers.ContainerApp{
+					SystemData: &armappcontainers.SystemData{
+						LastModifiedAt: &now,
+					},
+				},
+				Managed: true,
+			},
+		}
+		newSources := func() *source.Sources {
+			return &source.Sources{
+				Apps: &source.SourceApps{
+					"foo": source.SourceApp{
+						Kind:       "AzureContainerApp",
+						APIVersion: "aca.xenit.io/v1alpha2",
+						Metadata: map[string]string{
+							"name": "foo",
+						},
+						Specification: &source.SourceAppSpecification{
+							App: &armappcontainers.ContainerApp{},
+							RemoteSecrets: []source.RemoteSecretSpecification{
+								{
+									SecretName:       toPtr("ze-secret"),
+									RemoteSecretName: toPtr("ze-remote-secret-reference"),
+									Identity:         toPtr("System"),
+								},
+							},
+						},
+					},
+				},
+			}
+		}
+		secretClient.Set("ze-remote-secret-reference", "foobar", now)
+		remoteAppClient.GetFirstResponse(remoteApps, nil)
+		remoteAppClient.GetSecondResponse(remoteApps, nil)
+		remoteAppClient.RestartRevisionsResponse([]string{"foo--abc123"}, nil)
+
+		t.Run("first run tracks the keyvault reference", func(t *testing.T) {
+			sourceClient.GetResponse(newSources(), defaultFakeRevision, nil)
+			err := reconciler.Run(ctx)
+			require.NoError(t, err)
+			actions := remoteAppClient.Actions()
+			require.Len(t, actions, 1)
+			require.Equal(t, remote.InMemAppActionsUpdate, actions[0].Action)
+			remoteAppClient.ResetActions()
+			remoteAppClient.ResetGetSecond()
+			fingerprints, found, err := secretFingerprintCache.Get(ctx)
+			require.NoError(t, err)
+			require.True(t, found)
+			require.Equal(t, map[string]time.Time{"ze-remote-secret-reference": now.UTC()}, fingerprints)
+		})
+
+		t.Run("unchanged app isn't updated", func(t *testing.T) {
+			sourceClient.GetResponse(newSources(), "new-revision", nil)
+			err := reconciler.Run(ctx)
+			require.NoError(t, err)
+			require.Len(t, remoteAppClient.Actions(), 0)
+			remoteAppClient.ResetGetSecond()
+		})
+
+		t.Run("rotated secret updates and restarts the app", func(t *testing.T) {
+			sourceClient.GetResponse(newSources(), "new-revision", nil)
+			secretClient.Set("ze-remote-secret-reference", "foobaz", later)
+			err := reconciler.Run(ctx)
+			require.NoError(t, err)
+			actions := remoteAppClient.Actions()
+			require.Len(t, actions, 2)
+			require.Equal(t, remote.InMemAppActionsUpdate, actions[0].Action)
+			require.Equal(t, "foo", actions[1].Name)
+			require.Equal(t, remote.InMemAppActionsRestartRevisions, actions[1].Action)
+			require.Empty(t, secretClient.Gets(), "keyvault references shouldn't be read")
+			remoteAppClient.ResetActions()
+			remoteAppClient.ResetGetSecond()
+		})
+
+		t.Run("expired state still detects rotation", func(t *testing.T) {
+			reconcileStateCache.Reset()
+			sourceClient.GetResponse(newSources(), "new-revision", nil)
+			secretClient.Set("ze-remote-secret-reference", "foobar", later.Add(1*time.Minute))
+			err := reconciler.Run(ctx)
+			require.NoError(t, err)
+			actions := remoteAppClient.Actions()
+			require.Len(t, actions, 2)
+			require.Equal(t, remote.InMemAppActionsRestartRevisions, actions[1].Action)
+			remoteAppClient.ResetActions()
+			remoteAppClient.ResetGetSecond()
+		})
+
+		t.Run("missing fingerprints are recorded without restart", func(t *testing.T) {
+			secretFingerprintCache.Reset()
+			sourceClient.GetResponse(newSources(), "new-revision", nil)
+			secretClient.Set("ze-remote-secret-reference", "foobaz", later.Add(2*time.Minute))
+			err := reconciler.Run(ctx)
+			require.NoError(t, err)
+			for _, action := range remoteAppClient.Actions() {
+				require.NotEqual(t, remote.InMemAppActionsRestartRevisions, action.Action)
+			}
+			fingerprints, found, err := secretFingerprintCache.Get(ctx)
+			require.NoError(t, err)
+			require.True(t, found)
+			require.Equal(t, map[string]time.Time{"ze-remote-secret-reference": later.Add(2 * time.Minute).UTC()}, fingerprints)
+			remoteAppClient.ResetActions()
+			remoteAppClient.ResetGetSecond()
+		})
+
+		t.Run("restart failure", func(t *testing.T) {
+			sourceClient.GetResponse(newSources(), "new-revision", nil)
+			secretClient.Set("ze-remote-secret-reference", "foobar", later.Add(3*time.Minute))
+			remoteAppClient.RestartRevisionsResponse(nil, fmt.Errorf("foobar"))
+			err := reconciler.Run(ctx)
+			require.ErrorContains(t, err, "failed to restart revisions of foo: foobar")
+			fingerprints, _, err := secretFingerprintCache.Get(ctx)
+			require.NoError(t, err)
+			require.Equal(t, map[string]time.Time{"ze-remote-secret-reference": later.Add(2 * time.Minute).UTC()}, fingerprints, "fingerprints shouldn't be updated before the restart succeeds")
+		})
+	})
+
+	t.Run("test resolve image digests", func(t *testing.T) {
+		defer resetClients()
+
+		cfg := config.ReconcileConfig{
+			ResolveImageDigests: true,
+		}
+		reconciler, err := NewReconciler(cfg, sourceClient, remoteAppClient, remoteJobClient, remoteDaprComponentClient, remoteCertificateClient, remoteStorageClient, secretClient, registryClient, notificationClient, metricsClient, appCache, jobCache, daprComponentCache, certificateCache, storageCache, secretCache, notificationCache, reconcileStateCache, secretFingerprintCache, historyCache)
+		require.NoError(t, err)
+
+		newSources := func() *source.Sources {
+			return &source.Sources{
+				Apps: &source.SourceApps{
+					"foo": source.SourceApp{
+						Kind:       "AzureContainerApp",
+						APIVersion: "aca.xenit.io/v1alpha2",
+						Metadata: map[string]string{
+							"name": "foo",
+						},
+						Specification: &source.SourceAppSpecification{
+							App: &armappcontainers.ContainerApp{
+								Properties: &armappcontainers.ContainerAppProperties{
+									Template: &armappcontainers.Template{
+										Containers: []*armappcontainers.Container{
+											{
+												Name:  toPtr("foo"),
+												Image: toPtr("foo.azurecr.io/foo:latest"),
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			}
+		}
+		remoteApps := &remote.RemoteApps{
+			"foo": remote.RemoteApp{
+				App:     &armappcontainers.ContainerApp{},
+				Managed: true,
+			},
+		}
+		remoteAppClient.GetFirstResponse(remoteApps, nil)
+		remoteAppClient.GetSecondResponse(remoteApps, nil)
+
+		t.Run("unknown image only skips the app", func(t *testing.T) {
+			registryClient.SetTag("foo.azurecr.io/bar", "v1", "sha256:bar", time.Now())
+			sources := newSources()
+			(*sources.Apps)["bar"] = source.SourceApp{
+				Kind:       "AzureContainerApp",
+				APIVersion: "aca.xenit.io/v1alpha2",
+				Metadata: map[string]string{
+					"name": "bar",
+				},
+				Specification: &source.SourceAppSpecification{
+					App: &armappcontainers.ContainerApp{
+						Properties: &armappcontainers.ContainerAppProperties{
+							Template: &armappcontainers.Template{
+								Containers: []*armappcontainers.Container{
+									{
+										Name:  toPtr("bar"),
+										Image: toPtr("foo.azurecr.io/bar:v1"),
+									},
+								},
+							},
+						},
+					},
+				},
+			}
+			sourceClient.GetResponse(sources, defaultFakeRevision, nil)
+			remoteAppClient.GetSecondResponse(&remote.RemoteApps{
+				"foo": remote.RemoteApp{
+					App:     &armappcontainers.ContainerApp{},
+					Managed: true,
+				},
+				"bar": remote.RemoteApp{
+					App:     &armappcontainers.ContainerApp{},
+					Managed: true,
+				},
+			}, nil)
+			err := reconciler.Run(ctx)
+			require.ErrorContains(t, err, "failed to resolve image digests of app foo")
+			actions := remoteAppClient.Actions()
+			require.Len(t, actions, 1)
+			require.Equal(t, "bar", actions[0].Name)
+			require.Equal(t, remote.InMemAppActionsCreate, actions[0].Action)
+			require.Equal(t, "foo.azurecr.io/bar:v1@sha256:bar", *actions[0].App.Properties.Template.Containers[0].Image)
+			registryClient.Reset()
+			remoteAppClient.GetSecondResponse(remoteApps, nil)
+			remoteAppClient.ResetActions()
+			remoteAppClient.ResetGetSecond()
+		})
+
+		t.Run("first digest", func(t *testing.T) {
+			registryClient.SetTag("foo.azurecr.io/foo", "latest", "sha256:first", time.Now())
+			sourceClient.GetResponse(newSources(), defaultFakeRevision, nil)
+			err := reconciler.Run(ctx)
+			require.NoError(t, err)
+			actions := remoteAppClient.Actions()
+			require.Len(t, actions, 1)
+			require.Equal(t, "foo.azurecr.io/foo:latest@sha256:first", *actions[0].App.Properties.Template.Containers[0].Image)
+			remoteAppClient.ResetActions()
+			remoteAppClient.ResetGetSecond()
+		})
+
+		t.Run("no-op doesn't resolve digests", func(t *testing.T) {
+			registryClient.Reset()
+			sourceClient.GetResponse(newSources(), defaultFakeRevision, nil)
+			err := reconciler.Run(ctx)
+			require.NoError(t, err, "the registry isn't used by a no-op")
+			require.Len(t, remoteAppClient.Actions(), 0)
+			remoteAppClient.ResetGetSecond()
+		})
+
+		t.Run("moved tag is applied when the state expires", func(t *testing.T) {
+			registryClient.SetTag("foo.azurecr.io/foo", "latest", "sha256:second", time.Now())
+			reconcileStateCache.Reset()
+			sourceClient.GetResponse(newSources(), defaultFakeRevision, nil)
+			err := reconciler.Run(ctx)
+			require.NoError(t, err)
+			actions := remoteAppClient.Actions()
+			require.Len(t, actions, 1)
+			require.Equal(t, remote.InMemAppActionsUpdate, actions[0].Action)
+			require.Equal(t, "foo.azurecr.io/foo:latest@sha256:second", *actions[0].App.Properties.Template.Containers[0].Image)
+		})
+	})
+
+	t.Run("test dapr components", func(t *testing.T) {
+		defer resetClients()
+		secretClient.Set("ze-remote-secret", "foobar", time.Now())
+		newSources := func() *source.Sources {
+			return &source.Sources{
+				Apps: &source.SourceApps{
+					"foo": source.SourceApp{
+						Kind:       "AzureContainerApp",
+						APIVersion: "aca.xenit.io/v1alpha2",
+						Metadata: map[string]string{
+							"name": "foo",
+						},
+						Specification: &source.SourceAppSpecification{
+							App: &armappcontainers.ContainerApp{},
+						},
+					},
+				},
+				DaprComponents: &source.SourceDaprComponents{
+					"statestore": source.SourceDaprComponent{
+						Kind:       "AzureContainerDaprComponent",
+						APIVersion: "aca.xenit.io/v1alpha2",
+						Metadata: map[string]string{
+							"name": "statestore",
+						},
+						Specification: &source.SourceDaprComponentSpecification{
+							Component: &armappcontainers.DaprComponent{
+								Properties: &armappcontainers.DaprComponentProperties{
+									ComponentType: toPtr("state.azure.blobstorage"),
+								},
+							},
+							RemoteSecrets: []source.RemoteSecretSpecification{
+								{
+									SecretName:       toPtr("ze-component-secret"),
+									RemoteSecretName: toPtr("ze-remote-secret"),
+								},
+							},
+						},
+					},
+				},
+			}
+		}
+
+		t.Run("failed dapr component stops app reconciliation", func(t *testing.T) {
+			sourceClient.GetResponse(newSources(), defaultFakeRevision, nil)
+			remoteDaprComponentClient.GetFirstResponse(&remote.RemoteDaprComponents{}, nil)
+			remoteDaprComponentClient.CreateResponse(fmt.Errorf("foobar"))
+			remoteAppClient.GetFirstResponse(&remote.RemoteApps{}, nil)
+			err := reconciler.Run(ctx)
+			require.ErrorContains(t, err, "sourceDaprComponents error: failed to create statestore: foobar")
+			require.Len(t, remoteDaprComponentClient.Actions(), 1)
+			require.Len(t, remoteAppClient.Actions(), 0)
+			remoteDaprComponentClient.CreateResponse(nil)
+			remoteDaprComponentClient.ResetGetSecond()
+			remoteDaprComponentClient.ResetActions()
+		})
+
+		t.Run("creates dapr component with remote secret and deletes managed leftovers", func(t *testing.T) {
+			sourceClient.GetResponse(newSources(), defaultFakeRevision, nil)
+			remoteDaprComponentClient.GetFirstResponse(&remote.RemoteDaprComponents{
+				"old": remote.RemoteDaprComponent{
+					DaprComponent: &armappcontainers.DaprComponent{},
+					Managed:       true,
+				},
+				"unmanaged": remote.RemoteDaprComponent{
+					DaprComponent: &armappcontainers.DaprComponent{},
+					Managed:       false,
+				},
+			}, nil)
+			remoteDaprComponentClient.GetSecondResponse(&remote.RemoteDaprComponents{
+				"statestore": remote.RemoteDaprComponent{
+					DaprComponent: &armappcontainers.DaprComponent{},
+					Managed:       true,
+				},
+				"unmanaged": remote.RemoteDaprComponent{
+					DaprComponent: &armappcontainers.DaprComponent{},
+					Managed:       false,
+				},
+			}, nil)
+			remoteAppClient.GetFirstResponse(&remote.RemoteApps{}, nil)
+			remoteAppClient.GetSecondResponse(&remote.RemoteApps{
+				"foo": remote.RemoteApp{
+					App:     &armappcontainers.ContainerApp{},
+					Managed: true,
+				},
+			}, nil)
+			err := reconciler.Run(ctx)
+			require.NoError(t, err)
+			actions := remoteDaprComponentClient.Actions()
+			require.Len(t, actions, 2)
+			require.Equal(t, "old", actions[0].Name)
+			require.Equal(t, remote.InMemDaprComponentActionsDelete, actions[0].Action)
+			require.Equal(t, "statestore", actions[1].Name)
+			require.Equal(t, remote.InMemDaprComponentActionsCreate, actions[1].Action)
+			require.Equal(t, "ze-component-secret", *actions[1].DaprComponent.Properties.Secrets[0].Name)
+			require.Equal(t, "foobar", *actions[1].DaprComponent.Properties.Secrets[0].Value)
+			require.Len(t, remoteAppClient.Actions(), 1)
+		})
+	})
+
+	t.Run("test certificates and custom domains", func(t *testing.T) {
+		defer resetClients()
+		secretClient.Set("foo-cert", base64.StdEncoding.EncodeToString([]byte("ze-pfx")), time.Now())
+		certificateModified := time.Now()
+		newSources := func() *source.Sources {
+			return &source.Sources{
+				Apps: &source.SourceApps{
+					"foo": source.SourceApp{
+						Kind:       "AzureContainerApp",
+						APIVersion: "aca.xenit.io/v1alpha2",
+						Metadata: map[string]string{
+							"name": "foo",
+						},
+						Specification: &source.SourceAppSpecification{
+							App: &armappcontainers.ContainerApp{
+								Properties: &armappcontainers.ContainerAppProperties{
+									Configuration: &armappcontainers.Configuration{
+										Ingress: &armappcontainers.Ingress{
+											External: toPtr(true),
+										},
+									},
+								},
+							},
+							CustomDomains: []source.CustomDomainSpecification{
+								{
+									Name:            toPtr("foo.example.com"),
+									CertificateName: toPtr("foo"),
+								},
+								{
+									Name:            toPtr("bar.example.com"),
+									CertificateName: toPtr("bar"),
+								},
+							},
+						},
+					},
+				},
+				Certificates: &source.SourceCertificates{
+					"foo": source.SourceCertificate{
+						Kind:       "AzureContainerCertificate",
+						APIVersion: "aca.xenit.io/v1alpha2",
+						Metadata: map[string]string{
+							"name": "foo",
+						},
+						Specification: &source.SourceCertificateSpecification{
+							KeyVaultCertificate: &source.KeyVaultCertificateSpecification{
+								RemoteSecretName: toPtr("foo-cert"),
+							},
+						},
+					},
+					"bar": source.SourceCertificate{
+						Kind:       "AzureContainerCertificate",
+						APIVersion: "aca.xenit.io/v1alpha2",
+						Metadata: map[string]string{
+							"name": "bar",
+						},
+						Specification: &source.SourceCertificateSpecification{
+							ManagedCertificate: &source.ManagedCertificateSpecification{
+								SubjectName:             toPtr("bar.example.com"),
+								DomainControlValidation: toPtr(armappcontainers.ManagedCertificateDomainControlValidationCNAME),
+							},
+						},
+					},
+				},
+			}
+		}
+
+		t.Run("failed app doesn't create managed certificate", func(t *testing.T) {
+			sourceClient.GetResponse(newSources(), defaultFakeRevision, nil)
+			remoteCertificateClient.GetFirstResponse(&remote.RemoteCertificates{}, nil)
+			remoteAppClient.GetFirstResponse(&remote.RemoteApps{}, nil)
+			remoteAppClient.CreateResponse(fmt.Errorf("foobar"))
+			err := reconciler.Run(ctx)
+			require.ErrorContains(t, err, "failed to create foo: foobar")
+			actions := remoteCertificateClient.Actions()
+			require.Len(t, actions, 1)
+			require.Equal(t, "foo", actions[0].Name)
+			require.Equal(t, remote.InMemCertificateActionsCreate, actions[0].Action)
+			require.Equal(t, []byte("ze-pfx"), actions[0].Certificate.Certificate.Properties.Value)
+			remoteAppClient.CreateResponse(nil)
+			remoteAppClient.ResetGetSecond()
+			remoteAppClient.ResetActions()
+			remoteCertificateClient.ResetGetSecond()
+			remoteCertificateClient.ResetActions()
+		})
+
+		t.Run("managed certificate is created after the domain is added", func(t *testing.T) {
+			sourceClient.GetResponse(newSources(), defaultFakeRevision, nil)
+			remoteCertificateClient.GetFirstResponse(&remote.RemoteCertificates{}, nil)
+			remoteCertificateClient.GetSecondResponse(&remote.RemoteCertificates{
+				"foo": remote.RemoteCertificate{
+					CertificateResource: remote.CertificateResource{Certificate: &armappcontainers.Certificate{SystemData: &armappcontainers.SystemData{LastModifiedAt: &certificateModified}}},
+					Managed:             true,
+				},
+				"bar": remote.RemoteCertificate{
+					CertificateResource: remote.CertificateResource{ManagedCertificate: &armappcontainers.ManagedCertificate{SystemData: &armappcontainers.SystemData{LastModifiedAt: &certificateModified}}},
+					Managed:             true,
+				},
+			}, nil)
+			remoteAppClient.GetFirstResponse(&remote.RemoteApps{}, nil)
+			remoteAppClient.GetSecondResponse(&remote.RemoteApps{
+				"foo": remote.RemoteApp{
+					App:     &armappcontainers.ContainerApp{},
+					Managed: true,
+				},
+			}, nil)
+			err := reconciler.Run(ctx)
+			require.NoError(t, err)
+
+			certificateActions := remoteCertificateClient.Actions()
+			require.Len(t, certificateActions, 2)
+			require.Equal(t, "foo", certificateActions[0].Name)
+			require.Equal(t, "bar", certificateActions[1].Name)
+			require.Equal(t, remote.InMemCertificateActionsCreate, certificateActions[1].Action)
+			require.Equal(t, "bar.example.com", *certificateActions[1].Certificate.ManagedCertificate.Properties.SubjectName)
+
+			appActions := remoteAppClient.Actions()
+			require.Len(t, appActions, 1)
+			customDomains := appActions[0].App.Properties.Configuration.Ingress.CustomDomains
+			require.Len(t, customDomains, 2)
+			require.Equal(t, "foo.example.com", *customDomains[0].Name)
+			require.Equal(t, armappcontainers.BindingTypeSniEnabled, *customDomains[0].BindingType)
+			require.Equal(t, "/certificates/foo", *customDomains[0].CertificateID)
+			require.Equal(t, "bar.example.com", *customDomains[1].Name)
+			require.Equal(t, armappcontainers.BindingTypeDisabled, *customDomains[1].BindingType)
+			require.Nil(t, customDomains[1].CertificateID)
+
+			_, found, err := reconcileStateCache.Get(ctx)
+			require.NoError(t, err)
+			require.False(t, found, "the state shouldn't be saved while a binding is pending")
+			remoteAppClient.ResetActions()
+			remoteCertificateClient.ResetActions()
+		})
+
+		t.Run("custom domain is bound when the managed certificate exists", func(t *testing.T) {
+			sourceClient.GetResponse(newSources(), defaultFakeRevision, nil)
+			remoteCertificates := &remote.RemoteCertificates{
+				"foo": remote.RemoteCertificate{
+					CertificateResource: remote.CertificateResource{Certificate: &armappcontainers.Certificate{SystemData: &armappcontainers.SystemData{LastModifiedAt: &certificateModified}}},
+					Managed:             true,
+				},
+				"bar": remote.RemoteCertificate{
+					CertificateResource: remote.CertificateResource{ManagedCertificate: &armappcontainers.ManagedCertificate{SystemData: &armappcontainers.SystemData{LastModifiedAt: &certificateModified}}},
+					Managed:             true,
+				},
+				"old": remote.RemoteCertificate{
+					CertificateResource: remote.CertificateResource{Certificate: &armappcontainers.Certificate{SystemData: &armappcontainers.SystemData{LastModifiedAt: &certificateModified}}},
+					Managed:             true,
+				},
+			}
+			remoteCertificateClient.GetFirstResponse(remoteCertificates, nil)
+			remoteCertificateClient.GetSecondResponse(remoteCertificates, nil)
+			remoteAppClient.GetFirstResponse(&remote.RemoteApps{
+				"foo": remote.RemoteApp{
+					App:     &armappcontainers.ContainerApp{},
+					Managed: true,
+				},
+			}, nil)
+			secretCache.Reset()
+			secretClient.ResetGets()
+			err := reconciler.Run(ctx)
+			require.NoError(t, err)
+
+			certificateActions := remoteCertificateClient.Actions()
+			require.Len(t, certificateActions, 1)
+			require.Equal(t, "old", certificateActions[0].Name)
+			require.Equal(t, remote.InMemCertificateActionsDelete, certificateActions[0].Action)
+			require.Empty(t, secretClient.Gets(), "the secret of an unchanged certificate shouldn't be read")
+
+			appActions := remoteAppClient.Actions()
+			require.Len(t, appActions, 1)
+			require.Equal(t, remote.InMemAppActionsUpdate, appActions[0].Action)
+			customDomains := appActions[0].App.Properties.Configuration.Ingress.CustomDomains
+			require.Equal(t, armappcontainers.BindingTypeSniEnabled, *customDomains[1].BindingType)
+			require.Equal(t, "/managedCertificates/bar", *customDomains[1].CertificateID)
+
+			_, found, err := reconcileStateCache.Get(ctx)
+			require.NoError(t, err)
+			require.True(t, found)
+		})
+
+		t.Run("unknown certificate fails", func(t *testing.T) {
+			sources := newSources()
+			sources.Certificates = &source.SourceCertificates{}
+			sourceClient.GetResponse(sources, defaultFakeRevision, nil)
+			remoteCertificateClient.GetFirstResponse(&remote.RemoteCertificates{}, nil)
+			remoteAppClient.GetFirstResponse(&remote.RemoteApps{}, nil)
+			err := reconciler.Run(ctx)
+			require.ErrorContains(t, err, "certificate \"foo\" for custom domain \"foo.example.com\" in app \"foo\" not found")
+		})
+
+		t.Run("shared environment binds to the existing certificates", func(t *testing.T) {
+			cfg := config.ReconcileConfig{
+				ResourceGroupName:    "rg-owner",
+				ManagedEnvironmentID: "ze-me",
+				Targets:              []string{"resourceGroup=rg-shared"},
+			}
+			targets, err := cfg.GetTargets()
+			require.NoError(t, err)
+			sharedReconciler, err := NewReconciler(cfg.ForTarget(targets[1]), sourceClient, remoteAppClient, remoteJobClient, remoteDaprComponentClient, remote.NewReadOnlyCertificate(remoteCertificateClient), remoteStorageClient, secretClient, registryClient, notificationClient, metricsClient, appCache, jobCache, daprComponentCache, certificateCache, storageCache, secretCache, notificationCache, reconcileStateCache, secretFingerprintCache, historyCache)
+			require.NoError(t, err)
+
+			remoteAppClient.ResetGetSecond()
+			remoteAppClient.ResetActions()
+			remoteCertificateClient.ResetGetSecond()
+			remoteCertificateClient.ResetActions()
+			sources := newSources()
+			sources.Certificates = &source.SourceCertificates{}
+			sourceClient.GetResponse(sources, defaultFakeRevision, nil)
+			remoteCertificates := &remote.RemoteCertificates{
+				"foo": remote.RemoteCertificate{
+					CertificateResource: remote.CertificateResource{Certificate: &armappcontainers.Certificate{SystemData: &armappcontainers.SystemData{LastModifiedAt: &certificateModified}}},
+					Managed:             true,
+				},
+			}
+			remoteCertificateClient.GetFirstResponse(remoteCertificates, nil)
+			remoteCertificateClient.GetSecondResponse(remoteCertificates, nil)
+			remoteAppClient.GetFirstResponse(&remote.RemoteApps{}, nil)
+			reconcileStateCache.Reset()
+			err = sharedReconciler.Run(ctx)
+			require.NoError(t, err)
+			require.Empty(t, remoteCertificateClient.Actions())
+
+			appActions := remoteAppClient.Actions()
+			require.Len(t, appActions, 1)
+			customDomains := appActions[0].App.Properties.Configuration.Ingress.CustomDomains
+			require.Len(t, customDomains, 2)
+			require.Equal(t, armappcontainers.BindingTypeSniEnabled, *customDomains[0].BindingType)
+			require.Equal(t, "ze-me/certificates/foo", *customDomains[0].CertificateID)
+			require.Equal(t, armappcontainers.BindingTypeDisabled, *customDomains[1].BindingType)
+			require.Nil(t, customDomains[1].CertificateID)
+
+			_, found, err := reconcileStateCache.Get(ctx)
+			require.NoError(t, err)
+			require.False(t, found, "the state shouldn't be saved while the certificate of the shared environment is missing")
+
+			sourceClient.GetResponse(newSources(), defaultFakeRevision, nil)
+			remoteAppClient.ResetActions()
+			remoteCertificateClient.ResetGetSecond()
+			err = sharedReconciler.Run(ctx)
+			require.ErrorContains(t, err, "certificates can't be reconciled as the managed environment is reconciled by the target for resource group \"rg-owner\"")
+			require.Empty(t, remoteCertificateClient.Actions())
+		})
+	})
+
+	t.Run("test storages", func(t *testing.T) {
+		defer resetClients()
+		secretClient.Set("storage-key", "ze-key", time.Now())
+		storageModified := time.Now()
+		newSources := func() *source.Sources {
+			return &source.Sources{
+				Apps: &source.SourceApps{
+					"foo": source.SourceApp{
+						Kind:       "AzureContainerApp",
+						APIVersion: "aca.xenit.io/v1alpha2",
+						Metadata: map[string]string{
+							"name": "foo",
+						},
+						Specification: &source.SourceAppSpecification{
+							App: &armappcontainers.ContainerApp{
+								Properties: &armappcontainers.ContainerAppProperties{
+									Template: &armappcontainers.Template{
+										Volumes: []*armappcontainers.Volume{
+											{
+												Name:        toPtr("data"),
+												StorageName: toPtr("data"),
+												StorageType: toPtr(armappcontainers.StorageTypeAzureFile),
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+				Storages: &source.SourceStorages{
+					"data": source.SourceStorage{
+						Kind:       "AzureContainerStorage",
+						APIVersion: "aca.xenit.io/v1alpha2",
+						Metadata: map[string]string{
+							"name": "data",
+						},
+						Specification: &source.SourceStorageSpecification{
+							Storage: &armappcontainers.ManagedEnvironmentStorage{
+								Properties: &armappcontainers.ManagedEnvironmentStorageProperties{
+									AzureFile: &armappcontainers.AzureFileProperties{
+										AccountName: toPtr("foo"),
+										ShareName:   toPtr("data"),
+										AccessMode:  toPtr(armappcontainers.AccessModeReadWrite),
+									},
+								},
+							},
+							RemoteSecrets: []source.RemoteSecretSpecification{
+								{
+									SecretName:       toPtr("accountKey"),
+									RemoteSecretName: toPtr("storage-key"),
+								},
+							},
+						},
+					},
+				},
+			}
+		}
+
+		t.Run("storage is created before the app", func(t *testing.T) {
+			sourceClient.GetResponse(newSources(), defaultFakeRevision, nil)
+			remoteStorageClient.GetFirstResponse(&remote.RemoteStorages{}, nil)
+			remoteStorageClient.GetSecondResponse(&remote.RemoteStorages{
+				"data": remote.RemoteStorage{
+					Storage: &armappcontainers.ManagedEnvironmentStorage{
+						SystemData: &armappcontainers.SystemData{LastModifiedAt: &storageModified},
+					},
+					Managed: true,
+				},
+			}, nil)
+			remoteAppClient.GetFirstResponse(&remote.RemoteApps{}, nil)
+			remoteAppClient.GetSecondResponse(&remote.RemoteApps{
+				"foo": remote.RemoteApp{
+					App:     &armappcontainers.ContainerApp{},
+					Managed: true,
+				},
+			}, nil)
+			err := reconciler.Run(ctx)
+			require.NoError(t, err)
+
+			storageActions := remoteStorageClient.Actions()
+			require.Len(t, storageActions, 1)
+			require.Equal(t, "data", storageActions[0].Name)
+			require.Equal(t, remote.InMemStorageActionsCreate, storageActions[0].Action)
+			require.Equal(t, "ze-key", *storageActions[0].Storage.Properties.AzureFile.AccountKey)
+
+			appActions := remoteAppClient.Actions()
+			require.Len(t, appActions, 1)
+			require.Equal(t, remote.InMemAppActionsCreate, appActions[0].Action)
+			remoteAppClient.ResetGetSecond()
+			remoteAppClient.ResetActions()
+			remoteStorageClient.ResetActions()
+		})
+
+		t.Run("storage removed from source isn't deleted", func(t *testing.T) {
+			sources := newSources()
+			sources.Apps = &source.SourceApps{}
+			sources.Storages = &source.SourceStorages{}
+			sourceClient.GetResponse(sources, defaultFakeRevision, nil)
+			remoteStorages := &remote.RemoteStorages{
+				"data": remote.RemoteStorage{
+					Storage: &armappcontainers.ManagedEnvironmentStorage{
+						SystemData: &armappcontainers.SystemData{LastModifiedAt: &storageModified},
+					},
+					Managed: true,
+				},
+			}
+			remoteStorageClient.GetFirstResponse(remoteStorages, nil)
+			remoteStorageClient.GetSecondResponse(remoteStorages, nil)
+			remoteAppClient.GetFirstResponse(&remote.RemoteApps{}, nil)
+			remoteAppClient.GetSecondResponse(&remote.RemoteApps{}, nil)
+			err := reconciler.Run(ctx)
+			require.NoError(t, err)
+			require.Len(t, remoteStorageClient.Actions(), 0)
+			remoteAppClient.ResetGetSecond()
+			remoteAppClient.ResetActions()
+		})
+
+		t.Run("unmanaged storage isn't updated", func(t *testing.T) {
+			sourceClient.GetResponse(newSources(), defaultFakeRevision, nil)
+			remoteStorages := &remote.RemoteStorages{
+				"data": remote.RemoteStorage{
+					Storage: &armappcontainers.ManagedEnvironmentStorage{
+						SystemData: &armappcontainers.SystemData{LastModifiedAt: toPtr(storageModified.Add(time.Minute))},
+					},
+				},
+			}
+			remoteStorageClient.GetFirstResponse(remoteStorages, nil)
+			remoteAppClient.GetFirstResponse(&remote.RemoteApps{}, nil)
+			err := reconciler.Run(ctx)
+			require.ErrorContains(t, err, "trying to update a non-managed storage: data")
+			require.Len(t, remoteStorageClient.Actions(), 0)
+			require.Len(t, remoteAppClient.Actions(), 0)
+			remoteStorageClient.ResetGetSecond()
+		})
+
+		t.Run("unknown storage fails", func(t *testing.T) {
+			sources := newSources()
+			sources.Storages = &source.SourceStorages{}
+			sourceClient.GetResponse(sources, defaultFakeRevision, nil)
+			remoteStorageClient.GetFirstResponse(&remote.RemoteStorages{}, nil)
+			remoteStorageClient.GetSecondResponse(&remote.RemoteStorages{}, nil)
+			remoteAppClient.GetFirstResponse(&remote.RemoteApps{}, nil)
+			err := reconciler.Run(ctx)
+			require.ErrorContains(t, err, "app \"foo\" has a volume with the storage \"data\", which doesn't exist in the managed environment")
+			require.Len(t, remoteAppClient.Actions(), 0)
+		})
+	})
 }
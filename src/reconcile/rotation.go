@@ -0,0 +1,72 @@
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/xenitab/azcagit/src/source"
+)
+
+// rotatedSecrets contains the remote secrets (including KeyVault references) that have changed since
+// the last successful reconcile
+type rotatedSecrets map[string]struct{}
+
+// getRotatedSecrets compares the fingerprints with the stored ones, nothing is rotated if no fingerprints
+// are stored yet as they are recorded after the first successful reconcile
+func (r *Reconciler) getRotatedSecrets(ctx context.Context, secretFingerprints map[string]time.Time) (rotatedSecrets, error) {
+	log := logr.FromContextOrDiscard(ctx)
+
+	previousSecretFingerprints, found, err := r.secretFingerprintCache.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rotated := make(rotatedSecrets)
+	if !found {
+		log.Info("no secret fingerprints stored, recording them without rotating secrets")
+		return rotated, nil
+	}
+
+	for secretName, lastChange := range secretFingerprints {
+		previousChange, ok := previousSecretFingerprints[secretName]
+		if !ok || previousChange.Equal(lastChange) {
+			continue
+		}
+
+		log.Info("secret rotated", "secret", secretName, "previousChange", previousChange, "lastChange", lastChange)
+		rotated[secretName] = struct{}{}
+	}
+
+	return rotated, nil
+}
+
+// referencedBy returns the sorted names of the rotated secrets used by the remote secrets
+func (rotated rotatedSecrets) referencedBy(remoteSecrets []source.RemoteSecretSpecification) []string {
+	secretsMap := make(map[string]struct{})
+	for _, remoteSecret := range remoteSecrets {
+		if !remoteSecret.Valid() {
+			continue
+		}
+
+		_, ok := rotated[remoteSecret.SecretKey()]
+		if ok {
+			secretsMap[remoteSecret.SecretKey()] = struct{}{}
+		}
+	}
+
+	secretNames := []string{}
+	for secretName := range secretsMap {
+		secretNames = append(secretNames, secretName)
+	}
+	sort.Strings(secretNames)
+
+	return secretNames
+}
+
+func getSecretRotatedReason(secretNames []string) string {
+	return fmt.Sprintf("secret rotated: %s", strings.Join(secretNames, ", "))
+}
@@ -0,0 +1,191 @@
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/appcontainers/armappcontainers/v2"
+	"github.com/xenitab/azcagit/src/remote"
+)
+
+// secretPlaceholderPrefix marks a secret value that hasn't been read yet. The placeholder contains the
+// name and last change of the secret, so the caches (hashing the source resources) see a new version of
+// a secret without azcagit reading it. The placeholders are replaced with the values just before a
+// resource is created or updated.
+const secretPlaceholderPrefix = "azcagit-secret-placeholder:"
+
+func (r *Reconciler) getSecretPlaceholder(secretName string) (string, error) {
+	changedAt, ok := r.secretCache.GetFingerprint(secretName)
+	if !ok {
+		return "", fmt.Errorf("secret %q not found in cache", secretName)
+	}
+
+	return fmt.Sprintf("%s%s@%s", secretPlaceholderPrefix, secretName, changedAt.UTC().Format(time.RFC3339Nano)), nil
+}
+
+// getSecretValue reads the secret once per reconcile
+func (r *Reconciler) getSecretValue(ctx context.Context, secretName string) (string, error) {
+	value, ok := r.secretCache.Get(secretName)
+	if ok {
+		return value, nil
+	}
+
+	value, changedAt, err := r.secretClient.Get(ctx, secretName)
+	if err != nil {
+		return "", err
+	}
+
+	r.secretCache.Set(secretName, value, changedAt)
+
+	return value, nil
+}
+
+func (r *Reconciler) resolveSecretPlaceholder(ctx context.Context, value *string) (*string, error) {
+	if value == nil || !strings.HasPrefix(*value, secretPlaceholderPrefix) {
+		return value, nil
+	}
+
+	placeholder := strings.TrimPrefix(*value, secretPlaceholderPrefix)
+	separator := strings.LastIndex(placeholder, "@")
+	if separator < 0 {
+		return nil, fmt.Errorf("invalid secret placeholder")
+	}
+
+	secretValue, err := r.getSecretValue(ctx, placeholder[:separator])
+	if err != nil {
+		return nil, err
+	}
+
+	return &secretValue, nil
+}
+
+// resolveSecrets returns a copy of the secrets with the placeholders replaced, the source resources keep
+// the placeholders for the caches
+func (r *Reconciler) resolveSecrets(ctx context.Context, secrets []*armappcontainers.Secret) ([]*armappcontainers.Secret, error) {
+	if secrets == nil {
+		return nil, nil
+	}
+
+	resolvedSecrets := make([]*armappcontainers.Secret, 0, len(secrets))
+	for _, s := range secrets {
+		if s == nil {
+			resolvedSecrets = append(resolvedSecrets, s)
+			continue
+		}
+
+		value, err := r.resolveSecretPlaceholder(ctx, s.Value)
+		if err != nil {
+			secretName := ""
+			if s.Name != nil {
+				secretName = *s.Name
+			}
+			return nil, fmt.Errorf("unable to read secret %q: %w", secretName, err)
+		}
+
+		resolvedSecret := *s
+		resolvedSecret.Value = value
+		resolvedSecrets = append(resolvedSecrets, &resolvedSecret)
+	}
+
+	return resolvedSecrets, nil
+}
+
+func (r *Reconciler) resolveAppSecrets(ctx context.Context, app armappcontainers.ContainerApp) (armappcontainers.ContainerApp, error) {
+	if app.Properties == nil || app.Properties.Configuration == nil {
+		return app, nil
+	}
+
+	secrets, err := r.resolveSecrets(ctx, app.Properties.Configuration.Secrets)
+	if err != nil {
+		return armappcontainers.ContainerApp{}, err
+	}
+
+	properties := *app.Properties
+	configuration := *properties.Configuration
+	configuration.Secrets = secrets
+	properties.Configuration = &configuration
+	app.Properties = &properties
+
+	return app, nil
+}
+
+func (r *Reconciler) resolveJobSecrets(ctx context.Context, job armappcontainers.Job) (armappcontainers.Job, error) {
+	if job.Properties == nil || job.Properties.Configuration == nil {
+		return job, nil
+	}
+
+	secrets, err := r.resolveSecrets(ctx, job.Properties.Configuration.Secrets)
+	if err != nil {
+		return armappcontainers.Job{}, err
+	}
+
+	properties := *job.Properties
+	configuration := *properties.Configuration
+	configuration.Secrets = secrets
+	properties.Configuration = &configuration
+	job.Properties = &properties
+
+	return job, nil
+}
+
+func (r *Reconciler) resolveDaprComponentSecrets(ctx context.Context, component armappcontainers.DaprComponent) (armappcontainers.DaprComponent, error) {
+	if component.Properties == nil {
+		return component, nil
+	}
+
+	secrets, err := r.resolveSecrets(ctx, component.Properties.Secrets)
+	if err != nil {
+		return armappcontainers.DaprComponent{}, err
+	}
+
+	properties := *component.Properties
+	properties.Secrets = secrets
+	component.Properties = &properties
+
+	return component, nil
+}
+
+func (r *Reconciler) resolveStorageSecrets(ctx context.Context, storage armappcontainers.ManagedEnvironmentStorage) (armappcontainers.ManagedEnvironmentStorage, error) {
+	if storage.Properties == nil || storage.Properties.AzureFile == nil {
+		return storage, nil
+	}
+
+	accountKey, err := r.resolveSecretPlaceholder(ctx, storage.Properties.AzureFile.AccountKey)
+	if err != nil {
+		return armappcontainers.ManagedEnvironmentStorage{}, fmt.Errorf("unable to read secret for the account key: %w", err)
+	}
+
+	properties := *storage.Properties
+	azureFile := *properties.AzureFile
+	azureFile.AccountKey = accountKey
+	properties.AzureFile = &azureFile
+	storage.Properties = &properties
+
+	return storage, nil
+}
+
+func (r *Reconciler) resolveCertificateSecrets(ctx context.Context, resource remote.CertificateResource) (remote.CertificateResource, error) {
+	if resource.Certificate == nil || resource.Certificate.Properties == nil {
+		return resource, nil
+	}
+
+	value := string(resource.Certificate.Properties.Value)
+	if !strings.HasPrefix(value, secretPlaceholderPrefix) {
+		return resource, nil
+	}
+
+	secretValue, err := r.resolveSecretPlaceholder(ctx, &value)
+	if err != nil {
+		return remote.CertificateResource{}, fmt.Errorf("unable to read secret for the certificate value: %w", err)
+	}
+
+	properties := *resource.Certificate.Properties
+	properties.Value = decodeCertificateValue(*secretValue)
+	certificate := *resource.Certificate
+	certificate.Properties = &properties
+	resource.Certificate = &certificate
+
+	return resource, nil
+}
@@ -0,0 +1,152 @@
+package reconcile
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/xenitab/azcagit/src/cache"
+	"github.com/xenitab/azcagit/src/remote"
+	"github.com/xenitab/azcagit/src/secret"
+	"github.com/xenitab/azcagit/src/source"
+)
+
+func (r *Reconciler) isNoop(ctx context.Context, sources *source.Sources, previousState cache.ReconcileState, previousStateFound bool, currentState cache.ReconcileState) (bool, error) {
+	if sources == nil || !previousStateFound {
+		return false, nil
+	}
+
+	if previousState.Revision != currentState.Revision || previousState.SourcesHash != currentState.SourcesHash || previousState.SecretsHash != currentState.SecretsHash {
+		return false, nil
+	}
+
+	var err error
+	var remoteApps *remote.RemoteApps
+	if sources.Apps != nil {
+		remoteApps, err = r.getRemoteApps(ctx)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	var remoteJobs *remote.RemoteJobs
+	if sources.Jobs != nil {
+		remoteJobs, err = r.getRemoteJobs(ctx)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	var remoteDaprComponents *remote.RemoteDaprComponents
+	if sources.DaprComponents != nil {
+		remoteDaprComponents, err = r.getRemoteDaprComponents(ctx)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	var remoteCertificates *remote.RemoteCertificates
+	if sources.Certificates != nil {
+		remoteCertificates, err = r.getRemoteCertificates(ctx)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	var remoteStorages *remote.RemoteStorages
+	if sources.Storages != nil {
+		remoteStorages, err = r.getRemoteStorages(ctx)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return previousState.RemoteHash == getRemoteHash(remoteApps, remoteJobs, remoteDaprComponents, remoteCertificates, remoteStorages), nil
+}
+
+func getSourcesHash(sources *source.Sources) (string, error) {
+	if sources == nil {
+		return "", nil
+	}
+
+	b, err := json.Marshal(sources)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", md5.Sum(b)), nil
+}
+
+// getSecretFingerprints returns the last change of the referenced secrets (including KeyVault references)
+// found in the secret items
+func getSecretFingerprints(sources *source.Sources, secretItems *secret.Items) map[string]time.Time {
+	fingerprints := make(map[string]time.Time)
+	secretNames := append(sources.GetUniqueRemoteSecretNames(), sources.GetUniqueKeyVaultReferenceNames()...)
+	for _, secretName := range secretNames {
+		item, ok := secretItems.Get(secretName)
+		if !ok {
+			continue
+		}
+		fingerprints[secretName] = item.LastChange().UTC()
+	}
+
+	return fingerprints
+}
+
+func getSecretsHash(secretFingerprints map[string]time.Time) string {
+	secretNames := []string{}
+	for secretName := range secretFingerprints {
+		secretNames = append(secretNames, secretName)
+	}
+	sort.Strings(secretNames)
+
+	h := md5.New()
+	for _, secretName := range secretNames {
+		fmt.Fprintf(h, "%s/%s\n", secretName, secretFingerprints[secretName].Format(time.RFC3339Nano))
+	}
+
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+func getRemoteHash(remoteApps *remote.RemoteApps, remoteJobs *remote.RemoteJobs, remoteDaprComponents *remote.RemoteDaprComponents, remoteCertificates *remote.RemoteCertificates, remoteStorages *remote.RemoteStorages) string {
+	h := md5.New()
+	if remoteApps != nil {
+		for _, name := range remoteApps.GetSortedNames() {
+			remoteApp, _ := remoteApps.Get(name)
+			fmt.Fprintf(h, "app/%s/%t/%s\n", name, remoteApp.Managed, remoteApp.LastModified().UTC().Format(time.RFC3339Nano))
+		}
+	}
+
+	if remoteJobs != nil {
+		for _, name := range remoteJobs.GetSortedNames() {
+			remoteJob, _ := remoteJobs.Get(name)
+			fmt.Fprintf(h, "job/%s/%t/%s\n", name, remoteJob.Managed, remoteJob.LastModified().UTC().Format(time.RFC3339Nano))
+		}
+	}
+
+	if remoteDaprComponents != nil {
+		for _, name := range remoteDaprComponents.GetSortedNames() {
+			remoteDaprComponent, _ := remoteDaprComponents.Get(name)
+			fmt.Fprintf(h, "daprcomponent/%s/%t/%s\n", name, remoteDaprComponent.Managed, remoteDaprComponent.LastModified().UTC().Format(time.RFC3339Nano))
+		}
+	}
+
+	if remoteCertificates != nil {
+		for _, name := range remoteCertificates.GetSortedNames() {
+			remoteCertificate, _ := remoteCertificates.Get(name)
+			fmt.Fprintf(h, "certificate/%s/%t/%s\n", name, remoteCertificate.Managed, remoteCertificate.LastModified().UTC().Format(time.RFC3339Nano))
+		}
+	}
+
+	if remoteStorages != nil {
+		for _, name := range remoteStorages.GetSortedNames() {
+			remoteStorage, _ := remoteStorages.Get(name)
+			fmt.Fprintf(h, "storage/%s/%s\n", name, remoteStorage.LastModified().UTC().Format(time.RFC3339Nano))
+		}
+	}
+
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
@@ -0,0 +1,223 @@
+package reconcile
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	"github.com/xenitab/azcagit/src/history"
+	"github.com/xenitab/azcagit/src/remote"
+	"github.com/xenitab/azcagit/src/source"
+)
+
+// runSourceStorages creates or updates the storages before the apps and jobs mounting them. Storages
+// are never deleted, only created and updated.
+func (r *Reconciler) runSourceStorages(ctx context.Context, sources *source.Sources) (*remote.RemoteStorages, error) {
+	sourceStorages, err := r.getSourceStorages(ctx, sources)
+	if err != nil {
+		return nil, err
+	}
+
+	if sourceStorages == nil {
+		return nil, nil
+	}
+
+	r.filterSourceStorages(ctx, sourceStorages)
+
+	err = r.populateSourceStoragesSecrets(ctx, sourceStorages)
+	if err != nil {
+		return nil, err
+	}
+
+	remoteStorages, err := r.getRemoteStorages(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.createOrUpdateStoragesIfNeeded(ctx, sourceStorages, remoteStorages)
+	if err != nil {
+		return nil, err
+	}
+
+	newRemoteStorages, err := r.updateStorageCache(ctx, sourceStorages)
+	if err != nil {
+		return nil, err
+	}
+
+	return newRemoteStorages, nil
+}
+
+func (r *Reconciler) getSourceStorages(ctx context.Context, sources *source.Sources) (*source.SourceStorages, error) {
+	if sources == nil {
+		return nil, fmt.Errorf("sources is nil")
+	}
+
+	if sources.Storages == nil {
+		return nil, nil
+	}
+
+	sourceStorages := sources.Storages
+
+	if sourceStorages.Error() != nil {
+		return nil, fmt.Errorf("sourceStorages contains errors, stopping reconciliation: %w", sourceStorages.Error())
+	}
+
+	return sourceStorages, nil
+}
+
+func (r *Reconciler) filterSourceStorages(ctx context.Context, sourceStorages *source.SourceStorages) {
+	log := logr.FromContextOrDiscard(ctx)
+
+	for _, name := range sourceStorages.GetSortedNames() {
+		storage, _ := sourceStorages.Get(name)
+		shouldRunInLocation := storage.ShoudRunInLocation(r.cfg.Location)
+		if !shouldRunInLocation {
+			log.V(1).Info("sourceStorage was deleted because of location mis-match", "storage", storage.Name(), "currentLocation", r.cfg.Location, "locationFilter", storage.Specification.LocationFilter)
+			sourceStorages.Delete(name)
+		}
+	}
+}
+
+func (r *Reconciler) populateSourceStoragesSecrets(ctx context.Context, sourceStorages *source.SourceStorages) error {
+	for _, name := range sourceStorages.GetSortedNames() {
+		storage, _ := sourceStorages.Get(name)
+		for i, remoteSecret := range storage.GetRemoteSecrets() {
+			if !remoteSecret.Valid() {
+				return fmt.Errorf("secret %d for storage %q not valid", i, name)
+			}
+
+			secretValue, err := r.getSecretPlaceholder(remoteSecret.SecretKey())
+			if err != nil {
+				return fmt.Errorf("unable to get secret %d for storage %q from cache: %w", i, name, err)
+			}
+
+			err = sourceStorages.SetSecret(name, *remoteSecret.SecretName, secretValue)
+			if err != nil {
+				return fmt.Errorf("unable to set secret %q for storage %q", *remoteSecret.SecretName, name)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (r *Reconciler) getRemoteStorages(ctx context.Context) (*remote.RemoteStorages, error) {
+	remoteStorages, err := r.remoteStorageClient.Get(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get remoteStorages: %w", err)
+	}
+
+	if remoteStorages == nil {
+		return nil, fmt.Errorf("remoteStorages is nil")
+	}
+
+	return remoteStorages, nil
+}
+
+func (r *Reconciler) createOrUpdateStoragesIfNeeded(ctx context.Context, sourceStorages *source.SourceStorages, remoteStorages *remote.RemoteStorages) error {
+	log := logr.FromContextOrDiscard(ctx)
+
+	for _, name := range sourceStorages.GetSortedNames() {
+		sourceStorage, _ := sourceStorages.Get(name)
+		remoteStorage, ok := remoteStorages.Get(name)
+		needsUpdate, updateReason, err := r.storageCache.NeedsUpdate(ctx, name, remoteStorage.Storage, sourceStorage.Specification.Storage)
+		if err != nil {
+			return err
+		}
+
+		if !needsUpdate {
+			log.Info("skipping update, no changes", "storage", name)
+			continue
+		}
+
+		if ok && !remoteStorage.Managed {
+			return fmt.Errorf("trying to update a non-managed storage: %s", name)
+		}
+
+		storage, err := r.resolveStorageSecrets(ctx, *sourceStorage.Specification.Storage)
+		if err != nil {
+			return err
+		}
+
+		if ok {
+			err := r.remoteStorageClient.Update(ctx, name, storage)
+			r.addHistoryAction(history.ResourceKindStorage, name, history.ActionTypeUpdate, updateReason, err)
+			if err != nil {
+				return fmt.Errorf("failed to update %s: %w", name, err)
+			}
+			log.Info("updated remoteStorage", "storage", name, "reason", updateReason)
+			continue
+		}
+
+		err = r.remoteStorageClient.Create(ctx, name, storage)
+		r.addHistoryAction(history.ResourceKindStorage, name, history.ActionTypeCreate, updateReason, err)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", name, err)
+		}
+		log.Info("created remoteStorage", "storage", name, "reason", updateReason)
+	}
+
+	return nil
+}
+
+func (r *Reconciler) updateStorageCache(ctx context.Context, sourceStorages *source.SourceStorages) (*remote.RemoteStorages, error) {
+	newRemoteStorages, err := r.remoteStorageClient.Get(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get new remoteStorages: %w", err)
+	}
+
+	for _, name := range sourceStorages.GetSortedNames() {
+		sourceStorage, _ := sourceStorages.Get(name)
+		remoteStorage, ok := newRemoteStorages.Get(name)
+		if !ok {
+			return nil, fmt.Errorf("unable to locate storage %s after create or update", name)
+		}
+		err := r.storageCache.Set(ctx, name, remoteStorage.Storage, sourceStorage.Specification.Storage)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return newRemoteStorages, nil
+}
+
+// validateVolumeStorages fails early with a clear error if an app or job mounts a storage that
+// doesn't exist, instead of the error returned by ARM when applying it.
+func validateVolumeStorages(kind string, name string, storageNames []string, remoteStorages *remote.RemoteStorages) error {
+	if remoteStorages == nil {
+		return nil
+	}
+
+	for _, storageName := range storageNames {
+		_, ok := remoteStorages.Get(storageName)
+		if !ok {
+			return fmt.Errorf("%s %q has a volume with the storage %q, which doesn't exist in the managed environment", kind, name, storageName)
+		}
+	}
+
+	return nil
+}
+
+func (r *Reconciler) validateSourceAppsStorages(sourceApps *source.SourceApps, remoteStorages *remote.RemoteStorages) error {
+	for _, name := range sourceApps.GetSortedNames() {
+		app, _ := sourceApps.Get(name)
+		err := validateVolumeStorages("app", name, app.GetVolumeStorageNames(), remoteStorages)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *Reconciler) validateSourceJobsStorages(sourceJobs *source.SourceJobs, remoteStorages *remote.RemoteStorages) error {
+	for _, name := range sourceJobs.GetSortedNames() {
+		job, _ := sourceJobs.Get(name)
+		err := validateVolumeStorages("job", name, job.GetVolumeStorageNames(), remoteStorages)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,463 @@
+package registry
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+)
+
+const (
+	mediaTypeDockerManifest     = "application/vnd.docker.distribution.manifest.v2+json"
+	mediaTypeDockerManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+	mediaTypeOCIManifest        = "application/vnd.oci.image.manifest.v1+json"
+	mediaTypeOCIIndex           = "application/vnd.oci.image.index.v1+json"
+)
+
+var manifestAcceptHeader = strings.Join([]string{
+	mediaTypeOCIIndex,
+	mediaTypeDockerManifestList,
+	mediaTypeOCIManifest,
+	mediaTypeDockerManifest,
+}, ", ")
+
+// HTTPRegistry talks to container registries using the Docker Registry HTTP API V2,
+// authenticating with basic credentials or the bearer token challenge flow.
+type HTTPRegistry struct {
+	httpClient      *http.Client
+	credentials     map[string]Credential
+	tokenCredential azcore.TokenCredential
+	tokens          map[string]string
+	mu              sync.Mutex
+}
+
+var _ Registry = (*HTTPRegistry)(nil)
+
+// NewHTTPRegistry creates a registry client using the credentials for the matching servers. Azure
+// Container Registries without credentials are accessed using tokenCredential (managed identity), if not nil.
+func NewHTTPRegistry(credentials []Credential, tokenCredential azcore.TokenCredential) *HTTPRegistry {
+	reg := newHTTPRegistry(credentials, &http.Client{Timeout: 30 * time.Second})
+	reg.tokenCredential = tokenCredential
+	return reg
+}
+
+func newHTTPRegistry(credentials []Credential, httpClient *http.Client) *HTTPRegistry {
+	credentialsByHost := make(map[string]Credential)
+	for _, credential := range credentials {
+		if credential.Server == "" {
+			continue
+		}
+		// registry servers are case insensitive, like the host of the images
+		credentialsByHost[strings.ToLower(credential.Server)] = credential
+	}
+
+	return &HTTPRegistry{
+		httpClient:  httpClient,
+		credentials: credentialsByHost,
+		tokens:      make(map[string]string),
+	}
+}
+
+type tagList struct {
+	Tags []string `json:"tags"`
+}
+
+func (r *HTTPRegistry) ListTags(ctx context.Context, imageName string) ([]string, error) {
+	host, repository, err := parseImageName(imageName)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := []string{}
+	next := fmt.Sprintf("/v2/%s/tags/list?n=1000", repository)
+	for next != "" {
+		res, err := r.do(ctx, http.MethodGet, host, repository, next, "")
+		if err != nil {
+			return nil, err
+		}
+
+		var list tagList
+		err = json.NewDecoder(res.Body).Decode(&list)
+		res.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode tags for %s: %w", imageName, err)
+		}
+
+		tags = append(tags, list.Tags...)
+		next = nextLink(res.Header.Get("Link"))
+	}
+
+	return tags, nil
+}
+
+func (r *HTTPRegistry) GetDigest(ctx context.Context, imageName string, reference string) (string, error) {
+	host, repository, err := parseImageName(imageName)
+	if err != nil {
+		return "", err
+	}
+
+	res, err := r.do(ctx, http.MethodHead, host, repository, fmt.Sprintf("/v2/%s/manifests/%s", repository, reference), manifestAcceptHeader)
+	if err != nil {
+		return "", err
+	}
+	res.Body.Close()
+
+	digest := res.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return "", fmt.Errorf("no digest returned for %s:%s", imageName, reference)
+	}
+
+	return digest, nil
+}
+
+type manifest struct {
+	MediaType string `json:"mediaType"`
+	Config    struct {
+		Digest string `json:"digest"`
+	} `json:"config"`
+	Manifests []struct {
+		Digest   string `json:"digest"`
+		Platform struct {
+			Architecture string `json:"architecture"`
+			OS           string `json:"os"`
+		} `json:"platform"`
+	} `json:"manifests"`
+}
+
+type imageConfig struct {
+	Created time.Time `json:"created"`
+}
+
+func (r *HTTPRegistry) GetCreated(ctx context.Context, imageName string, reference string) (time.Time, error) {
+	host, repository, err := parseImageName(imageName)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	m, err := r.getManifest(ctx, host, repository, reference)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if len(m.Manifests) != 0 {
+		m, err = r.getManifest(ctx, host, repository, selectPlatformDigest(m))
+		if err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	if m.Config.Digest == "" {
+		return time.Time{}, fmt.Errorf("no config found for %s:%s", imageName, reference)
+	}
+
+	res, err := r.do(ctx, http.MethodGet, host, repository, fmt.Sprintf("/v2/%s/blobs/%s", repository, m.Config.Digest), "")
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer res.Body.Close()
+
+	var cfg imageConfig
+	err = json.NewDecoder(res.Body).Decode(&cfg)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unable to decode image config for %s:%s: %w", imageName, reference, err)
+	}
+
+	return cfg.Created, nil
+}
+
+func (r *HTTPRegistry) getManifest(ctx context.Context, host string, repository string, reference string) (manifest, error) {
+	res, err := r.do(ctx, http.MethodGet, host, repository, fmt.Sprintf("/v2/%s/manifests/%s", repository, reference), manifestAcceptHeader)
+	if err != nil {
+		return manifest{}, err
+	}
+	defer res.Body.Close()
+
+	var m manifest
+	err = json.NewDecoder(res.Body).Decode(&m)
+	if err != nil {
+		return manifest{}, fmt.Errorf("unable to decode manifest for %s/%s:%s: %w", host, repository, reference, err)
+	}
+
+	return m, nil
+}
+
+func selectPlatformDigest(m manifest) string {
+	for _, platformManifest := range m.Manifests {
+		if platformManifest.Platform.OS == "linux" && platformManifest.Platform.Architecture == "amd64" {
+			return platformManifest.Digest
+		}
+	}
+
+	return m.Manifests[0].Digest
+}
+
+func (r *HTTPRegistry) do(ctx context.Context, method string, host string, repository string, path string, accept string) (*http.Response, error) {
+	scope := fmt.Sprintf("repository:%s:pull", repository)
+	res, err := r.send(ctx, method, host, path, accept, r.getToken(host, scope))
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode == http.StatusUnauthorized {
+		challenge := res.Header.Get("WWW-Authenticate")
+		res.Body.Close()
+
+		authorization, err := r.authorize(ctx, host, scope, challenge)
+		if err != nil {
+			return nil, err
+		}
+
+		res, err = r.send(ctx, method, host, path, accept, authorization)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		res.Body.Close()
+		return nil, fmt.Errorf("registry %s returned status %d for %s %s", host, res.StatusCode, method, path)
+	}
+
+	return res, nil
+}
+
+func (r *HTTPRegistry) send(ctx context.Context, method string, host string, path string, accept string, authorization string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("https://%s%s", host, path), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	return r.httpClient.Do(req)
+}
+
+type tokenResponse struct {
+	Token       string `json:"token"`
+	AccessToken string `json:"access_token"`
+}
+
+func (r *HTTPRegistry) authorize(ctx context.Context, host string, scope string, challenge string) (string, error) {
+	credential, hasCredential := r.credentials[strings.ToLower(host)]
+
+	scheme, params := parseChallenge(challenge)
+	switch scheme {
+	case "basic":
+		if !hasCredential {
+			return "", fmt.Errorf("registry %s requires credentials", host)
+		}
+		authorization := fmt.Sprintf("Basic %s", basicAuth(credential.Username, credential.Password))
+		r.setToken(host, scope, authorization)
+		return authorization, nil
+	case "bearer":
+	default:
+		return "", fmt.Errorf("registry %s returned an unsupported authentication challenge: %q", host, challenge)
+	}
+
+	realm, ok := params["realm"]
+	if !ok {
+		return "", fmt.Errorf("registry %s returned a bearer challenge without realm", host)
+	}
+
+	tokenUrl, err := url.Parse(realm)
+	if err != nil {
+		return "", err
+	}
+
+	query := tokenUrl.Query()
+	if service, ok := params["service"]; ok {
+		query.Set("service", service)
+	}
+	query.Set("scope", scope)
+	tokenUrl.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenUrl.String(), nil)
+	if err != nil {
+		return "", err
+	}
+
+	if !hasCredential && r.tokenCredential != nil && strings.HasSuffix(host, azureContainerRegistrySuffix) {
+		credential, err = r.exchangeAzureToken(ctx, host)
+		if err != nil {
+			return "", err
+		}
+		hasCredential = true
+	}
+
+	if hasCredential {
+		req.SetBasicAuth(credential.Username, credential.Password)
+	}
+
+	res, err := r.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token request for %s returned status %d", host, res.StatusCode)
+	}
+
+	var token tokenResponse
+	err = json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(&token)
+	if err != nil {
+		return "", err
+	}
+
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+
+	if token.Token == "" {
+		return "", fmt.Errorf("token request for %s returned an empty token", host)
+	}
+
+	authorization := fmt.Sprintf("Bearer %s", token.Token)
+	r.setToken(host, scope, authorization)
+	return authorization, nil
+}
+
+const (
+	azureContainerRegistrySuffix = ".azurecr.io"
+	azureContainerRegistryScope  = "https://containerregistry.azure.net/.default"
+	// azureContainerRegistryUsername is the username used together with a refresh token from the token exchange
+	azureContainerRegistryUsername = "00000000-0000-0000-0000-000000000000"
+)
+
+type exchangeResponse struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// exchangeAzureToken exchanges an Azure AD access token for an Azure Container Registry refresh token,
+// that can be used as password in the token flow.
+func (r *HTTPRegistry) exchangeAzureToken(ctx context.Context, host string) (Credential, error) {
+	accessToken, err := r.tokenCredential.GetToken(ctx, policy.TokenRequestOptions{
+		Scopes: []string{azureContainerRegistryScope},
+	})
+	if err != nil {
+		return Credential{}, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "access_token")
+	form.Set("service", host)
+	form.Set("access_token", accessToken.Token)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("https://%s/oauth2/exchange", host), strings.NewReader(form.Encode()))
+	if err != nil {
+		return Credential{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	res, err := r.httpClient.Do(req)
+	if err != nil {
+		return Credential{}, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return Credential{}, fmt.Errorf("token exchange for %s returned status %d", host, res.StatusCode)
+	}
+
+	var exchange exchangeResponse
+	err = json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(&exchange)
+	if err != nil {
+		return Credential{}, err
+	}
+
+	if exchange.RefreshToken == "" {
+		return Credential{}, fmt.Errorf("token exchange for %s returned an empty refresh token", host)
+	}
+
+	return Credential{
+		Server:   host,
+		Username: azureContainerRegistryUsername,
+		Password: exchange.RefreshToken,
+	}, nil
+}
+
+func (r *HTTPRegistry) getToken(host string, scope string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.tokens[fmt.Sprintf("%s/%s", host, scope)]
+}
+
+func (r *HTTPRegistry) setToken(host string, scope string, authorization string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tokens[fmt.Sprintf("%s/%s", host, scope)] = authorization
+}
+
+func basicAuth(username string, password string) string {
+	return base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", username, password)))
+}
+
+// parseChallenge parses a WWW-Authenticate header like: Bearer realm="https://foo/token",service="foo"
+func parseChallenge(challenge string) (string, map[string]string) {
+	params := make(map[string]string)
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(challenge), " ")
+	for _, param := range splitChallengeParams(rest) {
+		key, value, ok := strings.Cut(param, "=")
+		if !ok {
+			continue
+		}
+		params[strings.ToLower(strings.TrimSpace(key))] = strings.Trim(strings.TrimSpace(value), `"`)
+	}
+
+	return strings.ToLower(scheme), params
+}
+
+func splitChallengeParams(s string) []string {
+	params := []string{}
+	inQuotes := false
+	start := 0
+	for i, c := range s {
+		switch {
+		case c == '"':
+			inQuotes = !inQuotes
+		case c == ',' && !inQuotes:
+			params = append(params, s[start:i])
+			start = i + 1
+		}
+	}
+	if strings.TrimSpace(s[start:]) != "" {
+		params = append(params, s[start:])
+	}
+
+	return params
+}
+
+// nextLink returns the path from a Link header like: </v2/foo/tags/list?last=bar&n=1000>; rel="next"
+func nextLink(link string) string {
+	if link == "" || !strings.Contains(link, `rel="next"`) {
+		return ""
+	}
+
+	start := strings.Index(link, "<")
+	end := strings.Index(link, ">")
+	if start == -1 || end <= start {
+		return ""
+	}
+
+	next, err := url.Parse(link[start+1 : end])
+	if err != nil {
+		return ""
+	}
+
+	return next.RequestURI()
+}
@@ -0,0 +1,144 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseImageName(t *testing.T) {
+	cases := []struct {
+		testDescription    string
+		imageName          string
+		expectedHost       string
+		expectedRepository string
+	}{
+		{
+			testDescription:    "docker hub library image",
+			imageName:          "nginx",
+			expectedHost:       "registry-1.docker.io",
+			expectedRepository: "library/nginx",
+		},
+		{
+			testDescription:    "docker hub image",
+			imageName:          "foo/bar",
+			expectedHost:       "registry-1.docker.io",
+			expectedRepository: "foo/bar",
+		},
+		{
+			testDescription:    "explicit docker hub",
+			imageName:          "docker.io/nginx",
+			expectedHost:       "registry-1.docker.io",
+			expectedRepository: "library/nginx",
+		},
+		{
+			testDescription:    "registry with port",
+			imageName:          "myregistry:5000/foo/bar",
+			expectedHost:       "myregistry:5000",
+			expectedRepository: "foo/bar",
+		},
+		{
+			testDescription:    "azure container registry",
+			imageName:          "foo.azurecr.io/bar",
+			expectedHost:       "foo.azurecr.io",
+			expectedRepository: "bar",
+		},
+	}
+
+	for i, c := range cases {
+		t.Logf("Test #%d: %s", i, c.testDescription)
+		host, repository, err := parseImageName(c.imageName)
+		require.NoError(t, err)
+		require.Equal(t, c.expectedHost, host)
+		require.Equal(t, c.expectedRepository, repository)
+	}
+}
+
+func TestHTTPRegistry(t *testing.T) {
+	var serverUrl string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok || username != "foo" || password != "bar" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		require.Equal(t, "repository:foo/bar:pull", r.URL.Query().Get("scope"))
+		fmt.Fprint(w, `{"token": "ze-token"}`)
+	})
+	mux.HandleFunc("/v2/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer ze-token" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="ze-registry"`, serverUrl))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch {
+		case r.URL.Path == "/v2/foo/bar/tags/list" && r.URL.Query().Get("last") == "":
+			w.Header().Set("Link", `</v2/foo/bar/tags/list?last=v2&n=1000>; rel="next"`)
+			fmt.Fprint(w, `{"tags": ["v1", "v2"]}`)
+		case r.URL.Path == "/v2/foo/bar/tags/list":
+			fmt.Fprint(w, `{"tags": ["v3"]}`)
+		case r.URL.Path == "/v2/foo/bar/manifests/v1":
+			w.Header().Set("Docker-Content-Digest", "sha256:index")
+			fmt.Fprint(w, `{"mediaType": "application/vnd.oci.image.index.v1+json", "manifests": [{"digest": "sha256:arm", "platform": {"os": "linux", "architecture": "arm64"}}, {"digest": "sha256:amd", "platform": {"os": "linux", "architecture": "amd64"}}]}`)
+		case r.URL.Path == "/v2/foo/bar/manifests/sha256:amd":
+			fmt.Fprint(w, `{"mediaType": "application/vnd.oci.image.manifest.v1+json", "config": {"digest": "sha256:config"}}`)
+		case r.URL.Path == "/v2/foo/bar/blobs/sha256:config":
+			fmt.Fprint(w, `{"created": "2023-01-02T03:04:05Z"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	server := httptest.NewTLSServer(mux)
+	defer server.Close()
+	serverUrl = server.URL
+	host := strings.TrimPrefix(server.URL, "https://")
+
+	ctx := context.Background()
+	reg := newHTTPRegistry([]Credential{{Server: host, Username: "foo", Password: "bar"}}, server.Client())
+	imageName := fmt.Sprintf("%s/foo/bar", host)
+
+	tags, err := reg.ListTags(ctx, imageName)
+	require.NoError(t, err)
+	require.Equal(t, []string{"v1", "v2", "v3"}, tags)
+
+	digest, err := reg.GetDigest(ctx, imageName, "v1")
+	require.NoError(t, err)
+	require.Equal(t, "sha256:index", digest)
+
+	created, err := reg.GetCreated(ctx, imageName, "v1")
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC), created)
+
+	_, err = reg.GetDigest(ctx, imageName, "v4")
+	require.ErrorContains(t, err, "returned status 404")
+
+	anonymous := newHTTPRegistry(nil, server.Client())
+	_, err = anonymous.ListTags(ctx, imageName)
+	require.ErrorContains(t, err, "token request")
+}
+
+func TestHTTPRegistryCredentialServerCase(t *testing.T) {
+	reg := newHTTPRegistry([]Credential{{Server: "MyRegistry.azurecr.io", Username: "foo", Password: "bar"}}, http.DefaultClient)
+	authorization, err := reg.authorize(context.Background(), "myregistry.azurecr.io", "repository:foo:pull", `Basic realm="ze-registry"`)
+	require.NoError(t, err)
+	require.Equal(t, fmt.Sprintf("Basic %s", basicAuth("foo", "bar")), authorization)
+}
+
+func TestParseChallenge(t *testing.T) {
+	scheme, params := parseChallenge(`Bearer realm="https://foo.azurecr.io/oauth2/token",service="foo.azurecr.io",scope="repository:a,b:pull"`)
+	require.Equal(t, "bearer", scheme)
+	require.Equal(t, map[string]string{
+		"realm":   "https://foo.azurecr.io/oauth2/token",
+		"service": "foo.azurecr.io",
+		"scope":   "repository:a,b:pull",
+	}, params)
+}
@@ -0,0 +1,63 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type InMemRegistry struct {
+	tags    map[string][]string
+	digests map[string]string
+	created map[string]time.Time
+}
+
+var _ Registry = (*InMemRegistry)(nil)
+
+func NewInMemRegistry() *InMemRegistry {
+	return &InMemRegistry{
+		tags:    make(map[string][]string),
+		digests: make(map[string]string),
+		created: make(map[string]time.Time),
+	}
+}
+
+func (r *InMemRegistry) ListTags(ctx context.Context, imageName string) ([]string, error) {
+	tags, ok := r.tags[imageName]
+	if !ok {
+		return nil, fmt.Errorf("image %q not found", imageName)
+	}
+
+	return tags, nil
+}
+
+func (r *InMemRegistry) GetDigest(ctx context.Context, imageName string, reference string) (string, error) {
+	digest, ok := r.digests[fmt.Sprintf("%s:%s", imageName, reference)]
+	if !ok {
+		return "", fmt.Errorf("digest for %s:%s not found", imageName, reference)
+	}
+
+	return digest, nil
+}
+
+func (r *InMemRegistry) GetCreated(ctx context.Context, imageName string, reference string) (time.Time, error) {
+	created, ok := r.created[fmt.Sprintf("%s:%s", imageName, reference)]
+	if !ok {
+		return time.Time{}, fmt.Errorf("created for %s:%s not found", imageName, reference)
+	}
+
+	return created, nil
+}
+
+func (r *InMemRegistry) Reset() {
+	r.tags = make(map[string][]string)
+	r.digests = make(map[string]string)
+	r.created = make(map[string]time.Time)
+}
+
+// SetTag adds a tag to the image together with the digest and creation time of the tagged image.
+func (r *InMemRegistry) SetTag(imageName string, tag string, digest string, created time.Time) {
+	r.tags[imageName] = append(r.tags[imageName], tag)
+	r.digests[fmt.Sprintf("%s:%s", imageName, tag)] = digest
+	r.created[fmt.Sprintf("%s:%s", imageName, tag)] = created
+}
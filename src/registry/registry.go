@@ -0,0 +1,49 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type Registry interface {
+	ListTags(ctx context.Context, imageName string) ([]string, error)
+	GetDigest(ctx context.Context, imageName string, reference string) (string, error)
+	GetCreated(ctx context.Context, imageName string, reference string) (time.Time, error)
+}
+
+type Credential struct {
+	Server   string
+	Username string
+	Password string
+}
+
+const (
+	dockerHubHost    = "docker.io"
+	dockerHubAPIHost = "registry-1.docker.io"
+)
+
+// parseImageName splits an image name (without tag or digest) into registry host and repository,
+// following the same defaults as docker for images without a registry.
+func parseImageName(imageName string) (string, string, error) {
+	if imageName == "" {
+		return "", "", fmt.Errorf("image name is empty")
+	}
+
+	parts := strings.SplitN(imageName, "/", 2)
+	if len(parts) == 1 {
+		return dockerHubAPIHost, fmt.Sprintf("library/%s", imageName), nil
+	}
+
+	host := parts[0]
+	if !strings.ContainsAny(host, ".:") && host != "localhost" {
+		return dockerHubAPIHost, imageName, nil
+	}
+
+	if host == dockerHubHost {
+		return parseImageName(parts[1])
+	}
+
+	return host, parts[1], nil
+}
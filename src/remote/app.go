@@ -2,6 +2,7 @@ package remote
 
 import (
 	"sort"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/appcontainers/armappcontainers/v2"
 )
@@ -11,6 +12,22 @@ type RemoteApp struct {
 	Managed bool
 }
 
+func (app *RemoteApp) LastModified() time.Time {
+	if app.App == nil || app.App.SystemData == nil {
+		return time.Time{}
+	}
+
+	if app.App.SystemData.LastModifiedAt != nil {
+		return *app.App.SystemData.LastModifiedAt
+	}
+
+	if app.App.SystemData.CreatedAt != nil {
+		return *app.App.SystemData.CreatedAt
+	}
+
+	return time.Time{}
+}
+
 type RemoteApps map[string]RemoteApp
 
 func (apps *RemoteApps) GetSortedNames() []string {
@@ -12,8 +12,9 @@ import (
 )
 
 type AzureApp struct {
-	resourceGroup string
-	client        *armappcontainers.ContainerAppsClient
+	resourceGroup   string
+	client          *armappcontainers.ContainerAppsClient
+	revisionsClient *armappcontainers.ContainerAppsRevisionsClient
 }
 
 var _ App = (*AzureApp)(nil)
@@ -24,9 +25,15 @@ func NewAzureApp(cfg config.ReconcileConfig, cred azcore.TokenCredential) (*Azur
 		return nil, err
 	}
 
+	revisionsClient, err := armappcontainers.NewContainerAppsRevisionsClient(cfg.SubscriptionID, cred, nil)
+	if err != nil {
+		return nil, err
+	}
+
 	return &AzureApp{
-		resourceGroup: cfg.ResourceGroupName,
-		client:        client,
+		resourceGroup:   cfg.ResourceGroupName,
+		client:          client,
+		revisionsClient: revisionsClient,
 	}, nil
 }
 
@@ -92,3 +99,30 @@ func (r *AzureApp) Delete(ctx context.Context, name string) error {
 
 	return nil
 }
+
+func (r *AzureApp) RestartRevisions(ctx context.Context, name string) ([]string, error) {
+	revisionNames := []string{}
+	pager := r.revisionsClient.NewListRevisionsPager(r.resourceGroup, name, nil)
+	for pager.More() {
+		nextResult, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list revisions: %w", err)
+		}
+
+		for _, revision := range nextResult.Value {
+			if revision == nil || revision.Name == nil || revision.Properties == nil || revision.Properties.Active == nil || !*revision.Properties.Active {
+				continue
+			}
+			revisionNames = append(revisionNames, *revision.Name)
+		}
+	}
+
+	for _, revisionName := range revisionNames {
+		_, err := r.revisionsClient.RestartRevision(ctx, r.resourceGroup, name, revisionName, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to restart revision %s: %w", revisionName, err)
+		}
+	}
+
+	return revisionNames, nil
+}
@@ -0,0 +1,140 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/appcontainers/armappcontainers/v2"
+	"github.com/xenitab/azcagit/src/config"
+)
+
+type AzureCertificate struct {
+	resourceGroup            string
+	environmentName          string
+	certificateClient        *armappcontainers.CertificatesClient
+	managedCertificateClient *armappcontainers.ManagedCertificatesClient
+}
+
+var _ Certificate = (*AzureCertificate)(nil)
+
+func NewAzureCertificate(cfg config.ReconcileConfig, cred azcore.TokenCredential) (*AzureCertificate, error) {
+	environmentID, err := arm.ParseResourceID(cfg.ManagedEnvironmentID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse managed environment id: %w", err)
+	}
+
+	certificateClient, err := armappcontainers.NewCertificatesClient(environmentID.SubscriptionID, cred, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	managedCertificateClient, err := armappcontainers.NewManagedCertificatesClient(environmentID.SubscriptionID, cred, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AzureCertificate{
+		resourceGroup:            environmentID.ResourceGroupName,
+		environmentName:          environmentID.Name,
+		certificateClient:        certificateClient,
+		managedCertificateClient: managedCertificateClient,
+	}, nil
+}
+
+func (r *AzureCertificate) Get(ctx context.Context) (*RemoteCertificates, error) {
+	certificates := make(RemoteCertificates)
+	certificatePager := r.certificateClient.NewListPager(r.resourceGroup, r.environmentName, nil)
+	for certificatePager.More() {
+		nextResult, err := certificatePager.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, certificate := range nextResult.Value {
+			certificates[*certificate.Name] = RemoteCertificate{
+				CertificateResource{Certificate: certificate},
+				isManagedByTags(certificate.Tags),
+			}
+		}
+	}
+
+	managedCertificatePager := r.managedCertificateClient.NewListPager(r.resourceGroup, r.environmentName, nil)
+	for managedCertificatePager.More() {
+		nextResult, err := managedCertificatePager.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, managedCertificate := range nextResult.Value {
+			certificates[*managedCertificate.Name] = RemoteCertificate{
+				CertificateResource{ManagedCertificate: managedCertificate},
+				isManagedByTags(managedCertificate.Tags),
+			}
+		}
+	}
+
+	return &certificates, nil
+}
+
+func (r *AzureCertificate) Create(ctx context.Context, name string, certificate CertificateResource) error {
+	if certificate.IsManagedCertificate() {
+		res, err := r.managedCertificateClient.BeginCreateOrUpdate(ctx, r.resourceGroup, r.environmentName, name, &armappcontainers.ManagedCertificatesClientBeginCreateOrUpdateOptions{
+			ManagedCertificateEnvelope: certificate.ManagedCertificate,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create: %w", err)
+		}
+
+		_, err = res.PollUntilDone(ctx, &runtime.PollUntilDoneOptions{
+			Frequency: 5 * time.Second,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create: %w", err)
+		}
+
+		return nil
+	}
+
+	_, err := r.certificateClient.CreateOrUpdate(ctx, r.resourceGroup, r.environmentName, name, &armappcontainers.CertificatesClientCreateOrUpdateOptions{
+		CertificateEnvelope: certificate.Certificate,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create: %w", err)
+	}
+
+	return nil
+}
+
+func (r *AzureCertificate) Update(ctx context.Context, name string, certificate CertificateResource) error {
+	return r.Create(ctx, name, certificate)
+}
+
+// Delete removes both the certificate and the managed certificate with the name, as the names are
+// reconciled as one namespace
+func (r *AzureCertificate) Delete(ctx context.Context, name string) error {
+	_, err := r.certificateClient.Delete(ctx, r.resourceGroup, r.environmentName, name, &armappcontainers.CertificatesClientDeleteOptions{})
+	if err != nil && !isNotFound(err) {
+		return fmt.Errorf("failed to delete: %w", err)
+	}
+
+	_, err = r.managedCertificateClient.Delete(ctx, r.resourceGroup, r.environmentName, name, &armappcontainers.ManagedCertificatesClientDeleteOptions{})
+	if err != nil && !isNotFound(err) {
+		return fmt.Errorf("failed to delete: %w", err)
+	}
+
+	return nil
+}
+
+func isManagedByTags(tags map[string]*string) bool {
+	tag, ok := tags["aca.xenit.io"]
+	return ok && tag != nil && *tag == "true"
+}
+
+func isNotFound(err error) bool {
+	var responseErr *azcore.ResponseError
+	return errors.As(err, &responseErr) && responseErr.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,91 @@
+package remote
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/appcontainers/armappcontainers/v2"
+	"github.com/xenitab/azcagit/src/config"
+)
+
+// DaprComponentManagedSecretName is the secret azcagit adds to the Dapr components it manages,
+// as Dapr components can't be tagged.
+const DaprComponentManagedSecretName = "azcagit-managed"
+
+type AzureDaprComponent struct {
+	resourceGroup   string
+	environmentName string
+	client          *armappcontainers.DaprComponentsClient
+}
+
+var _ DaprComponent = (*AzureDaprComponent)(nil)
+
+func NewAzureDaprComponent(cfg config.ReconcileConfig, cred azcore.TokenCredential) (*AzureDaprComponent, error) {
+	environmentID, err := arm.ParseResourceID(cfg.ManagedEnvironmentID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse managed environment id: %w", err)
+	}
+
+	client, err := armappcontainers.NewDaprComponentsClient(environmentID.SubscriptionID, cred, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AzureDaprComponent{
+		resourceGroup:   environmentID.ResourceGroupName,
+		environmentName: environmentID.Name,
+		client:          client,
+	}, nil
+}
+
+func (r *AzureDaprComponent) Get(ctx context.Context) (*RemoteDaprComponents, error) {
+	components := make(RemoteDaprComponents)
+	pager := r.client.NewListPager(r.resourceGroup, r.environmentName, nil)
+	for pager.More() {
+		nextResult, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, component := range nextResult.Value {
+			managed := false
+			if component.Properties != nil {
+				for _, secret := range component.Properties.Secrets {
+					if secret != nil && secret.Name != nil && *secret.Name == DaprComponentManagedSecretName {
+						managed = true
+					}
+				}
+			}
+
+			components[*component.Name] = RemoteDaprComponent{
+				component,
+				managed,
+			}
+		}
+	}
+
+	return &components, nil
+}
+
+func (r *AzureDaprComponent) Create(ctx context.Context, name string, component armappcontainers.DaprComponent) error {
+	_, err := r.client.CreateOrUpdate(ctx, r.resourceGroup, r.environmentName, name, component, &armappcontainers.DaprComponentsClientCreateOrUpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to create: %w", err)
+	}
+
+	return nil
+}
+
+func (r *AzureDaprComponent) Update(ctx context.Context, name string, component armappcontainers.DaprComponent) error {
+	return r.Create(ctx, name, component)
+}
+
+func (r *AzureDaprComponent) Delete(ctx context.Context, name string) error {
+	_, err := r.client.Delete(ctx, r.resourceGroup, r.environmentName, name, &armappcontainers.DaprComponentsClientDeleteOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to delete: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,107 @@
+package remote
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/appcontainers/armappcontainers/v2"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
+	"github.com/xenitab/azcagit/src/config"
+)
+
+// StorageManagedTagPrefix is the prefix of the tag azcagit adds to the managed environment for every
+// storage it manages, as storages can't be tagged.
+const StorageManagedTagPrefix = "aca.xenit.io-storage-"
+
+type AzureStorage struct {
+	resourceGroup   string
+	environmentName string
+	environmentID   string
+	client          *armappcontainers.ManagedEnvironmentsStoragesClient
+	tagsClient      *armresources.TagsClient
+}
+
+var _ Storage = (*AzureStorage)(nil)
+
+func NewAzureStorage(cfg config.ReconcileConfig, cred azcore.TokenCredential) (*AzureStorage, error) {
+	environmentID, err := arm.ParseResourceID(cfg.ManagedEnvironmentID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse managed environment id: %w", err)
+	}
+
+	client, err := armappcontainers.NewManagedEnvironmentsStoragesClient(environmentID.SubscriptionID, cred, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	tagsClient, err := armresources.NewTagsClient(environmentID.SubscriptionID, cred, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AzureStorage{
+		resourceGroup:   environmentID.ResourceGroupName,
+		environmentName: environmentID.Name,
+		environmentID:   strings.TrimPrefix(cfg.ManagedEnvironmentID, "/"),
+		client:          client,
+		tagsClient:      tagsClient,
+	}, nil
+}
+
+func (r *AzureStorage) Get(ctx context.Context) (*RemoteStorages, error) {
+	tags, err := r.tagsClient.GetAtScope(ctx, r.environmentID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get managed environment tags: %w", err)
+	}
+
+	storages := make(RemoteStorages)
+	res, err := r.client.List(ctx, r.resourceGroup, r.environmentName, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, storage := range res.Value {
+		managed := false
+		if tags.Properties != nil {
+			value, ok := tags.Properties.Tags[StorageManagedTagPrefix+*storage.Name]
+			managed = ok && value != nil && *value == "true"
+		}
+
+		storages[*storage.Name] = RemoteStorage{
+			storage,
+			managed,
+		}
+	}
+
+	return &storages, nil
+}
+
+func (r *AzureStorage) Create(ctx context.Context, name string, storage armappcontainers.ManagedEnvironmentStorage) error {
+	_, err := r.client.CreateOrUpdate(ctx, r.resourceGroup, r.environmentName, name, storage, &armappcontainers.ManagedEnvironmentsStoragesClientCreateOrUpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to create: %w", err)
+	}
+
+	operation := armresources.TagsPatchOperationMerge
+	managed := "true"
+	_, err = r.tagsClient.UpdateAtScope(ctx, r.environmentID, armresources.TagsPatchResource{
+		Operation: &operation,
+		Properties: &armresources.Tags{
+			Tags: map[string]*string{
+				StorageManagedTagPrefix + name: &managed,
+			},
+		},
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to tag the managed environment: %w", err)
+	}
+
+	return nil
+}
+
+func (r *AzureStorage) Update(ctx context.Context, name string, storage armappcontainers.ManagedEnvironmentStorage) error {
+	return r.Create(ctx, name, storage)
+}
@@ -0,0 +1,86 @@
+package remote
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/appcontainers/armappcontainers/v2"
+)
+
+// CertificateResource is either a certificate uploaded to the managed environment or a managed certificate
+type CertificateResource struct {
+	Certificate        *armappcontainers.Certificate        `json:"certificate,omitempty"`
+	ManagedCertificate *armappcontainers.ManagedCertificate `json:"managedCertificate,omitempty"`
+}
+
+func (c *CertificateResource) IsManagedCertificate() bool {
+	return c.ManagedCertificate != nil
+}
+
+func (c *CertificateResource) ID() *string {
+	if c.ManagedCertificate != nil {
+		return c.ManagedCertificate.ID
+	}
+
+	if c.Certificate != nil {
+		return c.Certificate.ID
+	}
+
+	return nil
+}
+
+func (c *CertificateResource) SystemData() *armappcontainers.SystemData {
+	if c.ManagedCertificate != nil {
+		return c.ManagedCertificate.SystemData
+	}
+
+	if c.Certificate != nil {
+		return c.Certificate.SystemData
+	}
+
+	return nil
+}
+
+func (c *CertificateResource) MarshalJSON() ([]byte, error) {
+	type certificateResource CertificateResource
+	return json.Marshal((*certificateResource)(c))
+}
+
+type RemoteCertificate struct {
+	CertificateResource
+	Managed bool
+}
+
+func (certificate *RemoteCertificate) LastModified() time.Time {
+	systemData := certificate.SystemData()
+	if systemData == nil {
+		return time.Time{}
+	}
+
+	if systemData.LastModifiedAt != nil {
+		return *systemData.LastModifiedAt
+	}
+
+	if systemData.CreatedAt != nil {
+		return *systemData.CreatedAt
+	}
+
+	return time.Time{}
+}
+
+type RemoteCertificates map[string]RemoteCertificate
+
+func (certificates *RemoteCertificates) GetSortedNames() []string {
+	names := []string{}
+	for name := range *certificates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (certificates *RemoteCertificates) Get(name string) (RemoteCertificate, bool) {
+	certificate, ok := (*certificates)[name]
+	return certificate, ok
+}
@@ -0,0 +1,45 @@
+package remote
+
+import (
+	"sort"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/appcontainers/armappcontainers/v2"
+)
+
+type RemoteDaprComponent struct {
+	DaprComponent *armappcontainers.DaprComponent
+	Managed       bool
+}
+
+func (component *RemoteDaprComponent) LastModified() time.Time {
+	if component.DaprComponent == nil || component.DaprComponent.SystemData == nil {
+		return time.Time{}
+	}
+
+	if component.DaprComponent.SystemData.LastModifiedAt != nil {
+		return *component.DaprComponent.SystemData.LastModifiedAt
+	}
+
+	if component.DaprComponent.SystemData.CreatedAt != nil {
+		return *component.DaprComponent.SystemData.CreatedAt
+	}
+
+	return time.Time{}
+}
+
+type RemoteDaprComponents map[string]RemoteDaprComponent
+
+func (components *RemoteDaprComponents) GetSortedNames() []string {
+	names := []string{}
+	for name := range *components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (components *RemoteDaprComponents) Get(name string) (RemoteDaprComponent, bool) {
+	component, ok := (*components)[name]
+	return component, ok
+}
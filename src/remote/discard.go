@@ -0,0 +1,112 @@
+package remote
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/appcontainers/armappcontainers/v2"
+)
+
+// discardJob, discardDaprComponent and discardCertificate are used when the resources are reconciled by
+// another reconciler, like the dapr components and certificates of a managed environment shared by targets
+type discardJob struct{}
+
+var _ Job = (*discardJob)(nil)
+
+func NewDiscardJob() Job {
+	return &discardJob{}
+}
+
+func (*discardJob) Get(_ context.Context) (*RemoteJobs, error) {
+	return &RemoteJobs{}, nil
+}
+
+func (*discardJob) Create(_ context.Context, _ string, _ armappcontainers.Job) error {
+	return nil
+}
+
+func (*discardJob) Update(_ context.Context, _ string, _ armappcontainers.Job) error {
+	return nil
+}
+
+func (*discardJob) Delete(_ context.Context, _ string) error {
+	return nil
+}
+
+type discardDaprComponent struct{}
+
+var _ DaprComponent = (*discardDaprComponent)(nil)
+
+func NewDiscardDaprComponent() DaprComponent {
+	return &discardDaprComponent{}
+}
+
+func (*discardDaprComponent) Get(_ context.Context) (*RemoteDaprComponents, error) {
+	return &RemoteDaprComponents{}, nil
+}
+
+func (*discardDaprComponent) Create(_ context.Context, _ string, _ armappcontainers.DaprComponent) error {
+	return nil
+}
+
+func (*discardDaprComponent) Update(_ context.Context, _ string, _ armappcontainers.DaprComponent) error {
+	return nil
+}
+
+func (*discardDaprComponent) Delete(_ context.Context, _ string) error {
+	return nil
+}
+
+type discardCertificate struct{}
+
+var _ Certificate = (*discardCertificate)(nil)
+
+func NewDiscardCertificate() Certificate {
+	return &discardCertificate{}
+}
+
+func (*discardCertificate) Get(_ context.Context) (*RemoteCertificates, error) {
+	return &RemoteCertificates{}, nil
+}
+
+func (*discardCertificate) Create(_ context.Context, _ string, _ CertificateResource) error {
+	return nil
+}
+
+func (*discardCertificate) Update(_ context.Context, _ string, _ CertificateResource) error {
+	return nil
+}
+
+func (*discardCertificate) Delete(_ context.Context, _ string) error {
+	return nil
+}
+
+// readOnlyCertificate returns the certificates of a managed environment shared by targets, making it possible for
+// the other targets to bind custom domains to the certificates of the target reconciling the environment
+type readOnlyCertificate struct {
+	client Certificate
+}
+
+var _ Certificate = (*readOnlyCertificate)(nil)
+
+func NewReadOnlyCertificate(client Certificate) Certificate {
+	return &readOnlyCertificate{
+		client,
+	}
+}
+
+func (c *readOnlyCertificate) Get(ctx context.Context) (*RemoteCertificates, error) {
+	return c.client.Get(ctx)
+}
+
+func (*readOnlyCertificate) Create(_ context.Context, name string, _ CertificateResource) error {
+	return fmt.Errorf("unable to create certificate %q, the certificates of a shared managed environment are read-only", name)
+}
+
+func (*readOnlyCertificate) Update(_ context.Context, name string, _ CertificateResource) error {
+	return fmt.Errorf("unable to update certificate %q, the certificates of a shared managed environment are read-only", name)
+}
+
+func (*readOnlyCertificate) Delete(_ context.Context, name string) error {
+	return fmt.Errorf("unable to delete certificate %q, the certificates of a shared managed environment are read-only", name)
+}
@@ -12,6 +12,7 @@ const (
 	InMemAppActionsCreate InMemAppActions = iota
 	InMemAppActionsUpdate
 	InMemAppActionsDelete
+	InMemAppActionsRestartRevisions
 )
 
 type InMemAppAction struct {
@@ -37,6 +38,10 @@ type InMemApp struct {
 	deleteResponse struct {
 		err error
 	}
+	restartRevisionsResponse struct {
+		revisionNames []string
+		err           error
+	}
 	actions []InMemAppAction
 }
 
@@ -97,6 +102,16 @@ func (r *InMemApp) DeleteResponse(err error) {
 	r.deleteResponse.err = err
 }
 
+func (r *InMemApp) RestartRevisions(ctx context.Context, name string) ([]string, error) {
+	r.actions = append(r.actions, InMemAppAction{Name: name, Action: InMemAppActionsRestartRevisions, App: armappcontainers.ContainerApp{}})
+	return r.restartRevisionsResponse.revisionNames, r.restartRevisionsResponse.err
+}
+
+func (r *InMemApp) RestartRevisionsResponse(revisionNames []string, err error) {
+	r.restartRevisionsResponse.revisionNames = revisionNames
+	r.restartRevisionsResponse.err = err
+}
+
 func (r *InMemApp) Actions() []InMemAppAction {
 	return r.actions
 }
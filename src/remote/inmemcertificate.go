@@ -0,0 +1,104 @@
+package remote
+
+import (
+	"context"
+)
+
+type InMemCertificateActions int
+
+const (
+	InMemCertificateActionsCreate InMemCertificateActions = iota
+	InMemCertificateActionsUpdate
+	InMemCertificateActionsDelete
+)
+
+type InMemCertificateAction struct {
+	Name        string
+	Action      InMemCertificateActions
+	Certificate CertificateResource
+}
+
+type InMemCertificate struct {
+	getResponse struct {
+		firstRemoteCertificates  *RemoteCertificates
+		secondRemoteCertificates *RemoteCertificates
+		firstErr                 error
+		secondErr                error
+		second                   bool
+	}
+	createResponse struct {
+		err error
+	}
+	updateResponse struct {
+		err error
+	}
+	deleteResponse struct {
+		err error
+	}
+	actions []InMemCertificateAction
+}
+
+var _ Certificate = (*InMemCertificate)(nil)
+
+func NewInMemCertificate() *InMemCertificate {
+	return &InMemCertificate{}
+}
+
+func (r *InMemCertificate) Get(ctx context.Context) (*RemoteCertificates, error) {
+	if !r.getResponse.second {
+		r.getResponse.second = true
+		return r.getResponse.firstRemoteCertificates, r.getResponse.firstErr
+	} else {
+		r.getResponse.second = false
+		return r.getResponse.secondRemoteCertificates, r.getResponse.secondErr
+	}
+}
+
+func (r *InMemCertificate) GetFirstResponse(remoteCertificates *RemoteCertificates, err error) {
+	r.getResponse.firstRemoteCertificates = remoteCertificates
+	r.getResponse.firstErr = err
+}
+
+func (r *InMemCertificate) GetSecondResponse(remoteCertificates *RemoteCertificates, err error) {
+	r.getResponse.secondRemoteCertificates = remoteCertificates
+	r.getResponse.secondErr = err
+}
+
+func (r *InMemCertificate) ResetGetSecond() {
+	r.getResponse.second = false
+}
+
+func (r *InMemCertificate) Create(ctx context.Context, name string, certificate CertificateResource) error {
+	r.actions = append(r.actions, InMemCertificateAction{Name: name, Action: InMemCertificateActionsCreate, Certificate: certificate})
+	return r.createResponse.err
+}
+
+func (r *InMemCertificate) CreateResponse(err error) {
+	r.createResponse.err = err
+}
+
+func (r *InMemCertificate) Update(ctx context.Context, name string, certificate CertificateResource) error {
+	r.actions = append(r.actions, InMemCertificateAction{Name: name, Action: InMemCertificateActionsUpdate, Certificate: certificate})
+	return r.updateResponse.err
+}
+
+func (r *InMemCertificate) UpdateResponse(err error) {
+	r.updateResponse.err = err
+}
+
+func (r *InMemCertificate) Delete(ctx context.Context, name string) error {
+	r.actions = append(r.actions, InMemCertificateAction{Name: name, Action: InMemCertificateActionsDelete, Certificate: CertificateResource{}})
+	return r.deleteResponse.err
+}
+
+func (r *InMemCertificate) DeleteResponse(err error) {
+	r.deleteResponse.err = err
+}
+
+func (r *InMemCertificate) Actions() []InMemCertificateAction {
+	return r.actions
+}
+
+func (r *InMemCertificate) ResetActions() {
+	r.actions = []InMemCertificateAction{}
+}
@@ -0,0 +1,106 @@
+package remote
+
+import (
+	"context"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/appcontainers/armappcontainers/v2"
+)
+
+type InMemDaprComponentActions int
+
+const (
+	InMemDaprComponentActionsCreate InMemDaprComponentActions = iota
+	InMemDaprComponentActionsUpdate
+	InMemDaprComponentActionsDelete
+)
+
+type InMemDaprComponentAction struct {
+	Name          string
+	Action        InMemDaprComponentActions
+	DaprComponent armappcontainers.DaprComponent
+}
+
+type InMemDaprComponent struct {
+	getResponse struct {
+		firstRemoteDaprComponents  *RemoteDaprComponents
+		secondRemoteDaprComponents *RemoteDaprComponents
+		firstErr                   error
+		secondErr                  error
+		second                     bool
+	}
+	createResponse struct {
+		err error
+	}
+	updateResponse struct {
+		err error
+	}
+	deleteResponse struct {
+		err error
+	}
+	actions []InMemDaprComponentAction
+}
+
+var _ DaprComponent = (*InMemDaprComponent)(nil)
+
+func NewInMemDaprComponent() *InMemDaprComponent {
+	return &InMemDaprComponent{}
+}
+
+func (r *InMemDaprComponent) Get(ctx context.Context) (*RemoteDaprComponents, error) {
+	if !r.getResponse.second {
+		r.getResponse.second = true
+		return r.getResponse.firstRemoteDaprComponents, r.getResponse.firstErr
+	} else {
+		r.getResponse.second = false
+		return r.getResponse.secondRemoteDaprComponents, r.getResponse.secondErr
+	}
+}
+
+func (r *InMemDaprComponent) GetFirstResponse(remoteComponents *RemoteDaprComponents, err error) {
+	r.getResponse.firstRemoteDaprComponents = remoteComponents
+	r.getResponse.firstErr = err
+}
+
+func (r *InMemDaprComponent) GetSecondResponse(remoteComponents *RemoteDaprComponents, err error) {
+	r.getResponse.secondRemoteDaprComponents = remoteComponents
+	r.getResponse.secondErr = err
+}
+
+func (r *InMemDaprComponent) ResetGetSecond() {
+	r.getResponse.second = false
+}
+
+func (r *InMemDaprComponent) Create(ctx context.Context, name string, component armappcontainers.DaprComponent) error {
+	r.actions = append(r.actions, InMemDaprComponentAction{Name: name, Action: InMemDaprComponentActionsCreate, DaprComponent: component})
+	return r.createResponse.err
+}
+
+func (r *InMemDaprComponent) CreateResponse(err error) {
+	r.createResponse.err = err
+}
+
+func (r *InMemDaprComponent) Update(ctx context.Context, name string, component armappcontainers.DaprComponent) error {
+	r.actions = append(r.actions, InMemDaprComponentAction{Name: name, Action: InMemDaprComponentActionsUpdate, DaprComponent: component})
+	return r.updateResponse.err
+}
+
+func (r *InMemDaprComponent) UpdateResponse(err error) {
+	r.updateResponse.err = err
+}
+
+func (r *InMemDaprComponent) Delete(ctx context.Context, name string) error {
+	r.actions = append(r.actions, InMemDaprComponentAction{Name: name, Action: InMemDaprComponentActionsDelete, DaprComponent: armappcontainers.DaprComponent{}})
+	return r.deleteResponse.err
+}
+
+func (r *InMemDaprComponent) DeleteResponse(err error) {
+	r.deleteResponse.err = err
+}
+
+func (r *InMemDaprComponent) Actions() []InMemDaprComponentAction {
+	return r.actions
+}
+
+func (r *InMemDaprComponent) ResetActions() {
+	r.actions = []InMemDaprComponentAction{}
+}
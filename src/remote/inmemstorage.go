@@ -0,0 +1,93 @@
+package remote
+
+import (
+	"context"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/appcontainers/armappcontainers/v2"
+)
+
+type InMemStorageActions int
+
+const (
+	InMemStorageActionsCreate InMemStorageActions = iota
+	InMemStorageActionsUpdate
+)
+
+type InMemStorageAction struct {
+	Name    string
+	Action  InMemStorageActions
+	Storage armappcontainers.ManagedEnvironmentStorage
+}
+
+type InMemStorage struct {
+	getResponse struct {
+		firstRemoteStorages  *RemoteStorages
+		secondRemoteStorages *RemoteStorages
+		firstErr             error
+		secondErr            error
+		second               bool
+	}
+	createResponse struct {
+		err error
+	}
+	updateResponse struct {
+		err error
+	}
+	actions []InMemStorageAction
+}
+
+var _ Storage = (*InMemStorage)(nil)
+
+func NewInMemStorage() *InMemStorage {
+	return &InMemStorage{}
+}
+
+func (r *InMemStorage) Get(ctx context.Context) (*RemoteStorages, error) {
+	if !r.getResponse.second {
+		r.getResponse.second = true
+		return r.getResponse.firstRemoteStorages, r.getResponse.firstErr
+	} else {
+		r.getResponse.second = false
+		return r.getResponse.secondRemoteStorages, r.getResponse.secondErr
+	}
+}
+
+func (r *InMemStorage) GetFirstResponse(remoteStorages *RemoteStorages, err error) {
+	r.getResponse.firstRemoteStorages = remoteStorages
+	r.getResponse.firstErr = err
+}
+
+func (r *InMemStorage) GetSecondResponse(remoteStorages *RemoteStorages, err error) {
+	r.getResponse.secondRemoteStorages = remoteStorages
+	r.getResponse.secondErr = err
+}
+
+func (r *InMemStorage) ResetGetSecond() {
+	r.getResponse.second = false
+}
+
+func (r *InMemStorage) Create(ctx context.Context, name string, storage armappcontainers.ManagedEnvironmentStorage) error {
+	r.actions = append(r.actions, InMemStorageAction{Name: name, Action: InMemStorageActionsCreate, Storage: storage})
+	return r.createResponse.err
+}
+
+func (r *InMemStorage) CreateResponse(err error) {
+	r.createResponse.err = err
+}
+
+func (r *InMemStorage) Update(ctx context.Context, name string, storage armappcontainers.ManagedEnvironmentStorage) error {
+	r.actions = append(r.actions, InMemStorageAction{Name: name, Action: InMemStorageActionsUpdate, Storage: storage})
+	return r.updateResponse.err
+}
+
+func (r *InMemStorage) UpdateResponse(err error) {
+	r.updateResponse.err = err
+}
+
+func (r *InMemStorage) Actions() []InMemStorageAction {
+	return r.actions
+}
+
+func (r *InMemStorage) ResetActions() {
+	r.actions = []InMemStorageAction{}
+}
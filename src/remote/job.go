@@ -2,6 +2,7 @@ package remote
 
 import (
 	"sort"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/appcontainers/armappcontainers/v2"
 )
@@ -11,6 +12,22 @@ type RemoteJob struct {
 	Managed bool
 }
 
+func (job *RemoteJob) LastModified() time.Time {
+	if job.Job == nil || job.Job.SystemData == nil {
+		return time.Time{}
+	}
+
+	if job.Job.SystemData.LastModifiedAt != nil {
+		return *job.Job.SystemData.LastModifiedAt
+	}
+
+	if job.Job.SystemData.CreatedAt != nil {
+		return *job.Job.SystemData.CreatedAt
+	}
+
+	return time.Time{}
+}
+
 type RemoteJobs map[string]RemoteJob
 
 func (jobs *RemoteJobs) GetSortedNames() []string {
@@ -11,6 +11,8 @@ type App interface {
 	Create(ctx context.Context, name string, app armappcontainers.ContainerApp) error
 	Update(ctx context.Context, name string, app armappcontainers.ContainerApp) error
 	Delete(ctx context.Context, name string) error
+	// RestartRevisions restarts the active revisions of the app and returns their names
+	RestartRevisions(ctx context.Context, name string) ([]string, error)
 }
 
 type Job interface {
@@ -19,3 +21,23 @@ type Job interface {
 	Update(ctx context.Context, name string, app armappcontainers.Job) error
 	Delete(ctx context.Context, name string) error
 }
+
+type DaprComponent interface {
+	Get(ctx context.Context) (*RemoteDaprComponents, error)
+	Create(ctx context.Context, name string, component armappcontainers.DaprComponent) error
+	Update(ctx context.Context, name string, component armappcontainers.DaprComponent) error
+	Delete(ctx context.Context, name string) error
+}
+
+type Certificate interface {
+	Get(ctx context.Context) (*RemoteCertificates, error)
+	Create(ctx context.Context, name string, certificate CertificateResource) error
+	Update(ctx context.Context, name string, certificate CertificateResource) error
+	Delete(ctx context.Context, name string) error
+}
+
+type Storage interface {
+	Get(ctx context.Context) (*RemoteStorages, error)
+	Create(ctx context.Context, name string, storage armappcontainers.ManagedEnvironmentStorage) error
+	Update(ctx context.Context, name string, storage armappcontainers.ManagedEnvironmentStorage) error
+}
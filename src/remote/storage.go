@@ -0,0 +1,47 @@
+package remote
+
+import (
+	"sort"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/appcontainers/armappcontainers/v2"
+)
+
+// RemoteStorage is managed by azcagit if the managed environment has the tag StorageManagedTagPrefix
+// with the name of the storage, as storages can't be tagged.
+type RemoteStorage struct {
+	Storage *armappcontainers.ManagedEnvironmentStorage
+	Managed bool
+}
+
+func (storage *RemoteStorage) LastModified() time.Time {
+	if storage.Storage == nil || storage.Storage.SystemData == nil {
+		return time.Time{}
+	}
+
+	if storage.Storage.SystemData.LastModifiedAt != nil {
+		return *storage.Storage.SystemData.LastModifiedAt
+	}
+
+	if storage.Storage.SystemData.CreatedAt != nil {
+		return *storage.Storage.SystemData.CreatedAt
+	}
+
+	return time.Time{}
+}
+
+type RemoteStorages map[string]RemoteStorage
+
+func (storages *RemoteStorages) GetSortedNames() []string {
+	names := []string{}
+	for name := range *storages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (storages *RemoteStorages) Get(name string) (RemoteStorage, bool) {
+	storage, ok := (*storages)[name]
+	return storage, ok
+}
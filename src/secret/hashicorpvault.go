@@ -0,0 +1,186 @@
+package secret
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// HashicorpVaultSecret reads secrets from a HashiCorp Vault KV v2 secrets engine, using the HTTP API.
+// The secret names are the keys in path of the mount and the value is read from field in the secret data.
+type HashicorpVaultSecret struct {
+	httpClient *http.Client
+	address    string
+	mount      string
+	path       string
+	token      string
+	field      string
+}
+
+var _ Secret = (*HashicorpVaultSecret)(nil)
+var _ NamedItemsLister = (*HashicorpVaultSecret)(nil)
+
+func NewHashicorpVaultSecret(address string, mount string, path string, token string, field string) *HashicorpVaultSecret {
+	return newHashicorpVaultSecret(address, mount, path, token, field, &http.Client{Timeout: 30 * time.Second})
+}
+
+func newHashicorpVaultSecret(address string, mount string, path string, token string, field string, httpClient *http.Client) *HashicorpVaultSecret {
+	return &HashicorpVaultSecret{
+		httpClient: httpClient,
+		address:    address,
+		mount:      mount,
+		path:       path,
+		token:      token,
+		field:      field,
+	}
+}
+
+type hashicorpVaultListResponse struct {
+	Data struct {
+		Keys []string `json:"keys"`
+	} `json:"data"`
+}
+
+type hashicorpVaultMetadataResponse struct {
+	Data struct {
+		UpdatedTime time.Time `json:"updated_time"`
+	} `json:"data"`
+}
+
+type hashicorpVaultDataResponse struct {
+	Data struct {
+		Data     map[string]interface{} `json:"data"`
+		Metadata struct {
+			CreatedTime time.Time `json:"created_time"`
+		} `json:"metadata"`
+	} `json:"data"`
+}
+
+// ListItems lists the secrets in path, the metadata of every secret is read to get the last change.
+// Use ListNamedItems to only read the metadata of the secrets in use.
+func (s *HashicorpVaultSecret) ListItems(ctx context.Context) (*Items, error) {
+	var list hashicorpVaultListResponse
+	found, err := s.do(ctx, fmt.Sprintf("metadata/%s?list=true", escapePath(s.secretPath(""))), &list)
+	if err != nil {
+		return nil, err
+	}
+
+	if !found {
+		items := make(Items)
+		return &items, nil
+	}
+
+	names := []string{}
+	for _, key := range list.Data.Keys {
+		// the secrets in sub paths can't be referenced, as the secret names can't contain /
+		if strings.HasSuffix(key, "/") {
+			continue
+		}
+		names = append(names, key)
+	}
+
+	return s.ListNamedItems(ctx, names)
+}
+
+// ListNamedItems reads the metadata of the secrets with the names, secrets that don't exist are left out
+func (s *HashicorpVaultSecret) ListNamedItems(ctx context.Context, names []string) (*Items, error) {
+	items := make(Items)
+	for _, name := range names {
+		if _, ok := items[name]; ok {
+			continue
+		}
+
+		var metadata hashicorpVaultMetadataResponse
+		found, err := s.do(ctx, fmt.Sprintf("metadata/%s", escapePath(s.secretPath(name))), &metadata)
+		if err != nil {
+			return nil, err
+		}
+
+		if !found {
+			continue
+		}
+
+		items[name] = Item{
+			name:      name,
+			changedAt: metadata.Data.UpdatedTime,
+		}
+	}
+
+	return &items, nil
+}
+
+func (s *HashicorpVaultSecret) Get(ctx context.Context, name string) (string, time.Time, error) {
+	var data hashicorpVaultDataResponse
+	found, err := s.do(ctx, fmt.Sprintf("data/%s", escapePath(s.secretPath(name))), &data)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	if !found {
+		return "", time.Time{}, fmt.Errorf("secret %q not found", name)
+	}
+
+	value, ok := data.Data.Data[s.field]
+	if !ok {
+		return "", time.Time{}, fmt.Errorf("field %q not found in secret %q", s.field, name)
+	}
+
+	stringValue, ok := value.(string)
+	if !ok {
+		return "", time.Time{}, fmt.Errorf("field %q in secret %q isn't a string", s.field, name)
+	}
+
+	return stringValue, data.Data.Metadata.CreatedTime, nil
+}
+
+// do sends a GET request for the path in the mount and returns false if the path doesn't exist
+func (s *HashicorpVaultSecret) do(ctx context.Context, path string, v interface{}) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/v1/%s/%s", s.address, escapePath(s.mount), path), nil)
+	if err != nil {
+		return false, err
+	}
+
+	req.Header.Set("X-Vault-Token", s.token)
+
+	res, err := s.httpClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("unexpected status code from vault: %d", res.StatusCode)
+	}
+
+	err = json.NewDecoder(res.Body).Decode(v)
+	if err != nil {
+		return false, fmt.Errorf("unable to decode response from vault: %w", err)
+	}
+
+	return true, nil
+}
+
+func (s *HashicorpVaultSecret) secretPath(name string) string {
+	if s.path == "" {
+		return name
+	}
+
+	return fmt.Sprintf("%s/%s", s.path, name)
+}
+
+func escapePath(path string) string {
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		parts[i] = url.PathEscape(part)
+	}
+
+	return strings.Join(parts, "/")
+}
@@ -0,0 +1,75 @@
+package secret
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashicorpVaultSecret(t *testing.T) {
+	requests := []string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "ze-token", r.Header.Get("X-Vault-Token"))
+
+		request := fmt.Sprintf("%s?%s", r.URL.Path, r.URL.RawQuery)
+		requests = append(requests, request)
+		switch request {
+		case "/v1/secret/metadata/?list=true":
+			fmt.Fprint(w, `{"data":{"keys":["foo","team/"]}}`)
+		case "/v1/secret/metadata/team/?list=true":
+			fmt.Fprint(w, `{"data":{"keys":["bar","baz"]}}`)
+		case "/v1/secret/metadata/foo?":
+			fmt.Fprint(w, `{"data":{"updated_time":"2023-11-01T10:00:00Z"}}`)
+		case "/v1/secret/metadata/team/bar?":
+			fmt.Fprint(w, `{"data":{"updated_time":"2023-11-02T10:00:00Z"}}`)
+		case "/v1/secret/metadata/team/baz?":
+			fmt.Fprint(w, `{"data":{"updated_time":"2023-11-03T10:00:00Z"}}`)
+		case "/v1/secret/data/team/bar?":
+			fmt.Fprint(w, `{"data":{"data":{"value":"baz","number":1},"metadata":{"created_time":"2023-11-02T10:00:00Z"}}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	rootSecret := newHashicorpVaultSecret(srv.URL, "secret", "", "ze-token", "value", srv.Client())
+	items, err := rootSecret.ListItems(context.Background())
+	require.NoError(t, err)
+	require.Len(t, *items, 1)
+	_, ok := items.Get("foo")
+	require.True(t, ok)
+
+	vaultSecret := newHashicorpVaultSecret(srv.URL, "secret", "team", "ze-token", "value", srv.Client())
+
+	items, err = vaultSecret.ListItems(context.Background())
+	require.NoError(t, err)
+	require.Len(t, *items, 2)
+	item, ok := items.Get("bar")
+	require.True(t, ok)
+	require.Equal(t, time.Date(2023, 11, 2, 10, 0, 0, 0, time.UTC), item.LastChange())
+
+	requests = []string{}
+	items, err = vaultSecret.ListNamedItems(context.Background(), []string{"bar", "missing", "bar"})
+	require.NoError(t, err)
+	require.Len(t, *items, 1)
+	_, ok = items.Get("bar")
+	require.True(t, ok)
+	require.Equal(t, []string{"/v1/secret/metadata/team/bar?", "/v1/secret/metadata/team/missing?"}, requests)
+
+	value, changedAt, err := vaultSecret.Get(context.Background(), "bar")
+	require.NoError(t, err)
+	require.Equal(t, "baz", value)
+	require.Equal(t, time.Date(2023, 11, 2, 10, 0, 0, 0, time.UTC), changedAt)
+
+	_, _, err = vaultSecret.Get(context.Background(), "missing")
+	require.ErrorContains(t, err, "secret \"missing\" not found")
+
+	numberSecret := newHashicorpVaultSecret(srv.URL, "secret", "team", "ze-token", "number", srv.Client())
+	_, _, err = numberSecret.Get(context.Background(), "bar")
+	require.ErrorContains(t, err, "field \"number\" in secret \"bar\" isn't a string")
+}
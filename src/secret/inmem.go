@@ -9,6 +9,7 @@ import (
 type InMemSecret struct {
 	items  *Items
 	values map[string]string
+	gets   []string
 }
 
 var _ Secret = (*InMemSecret)(nil)
@@ -27,6 +28,7 @@ func (s *InMemSecret) ListItems(ctx context.Context) (*Items, error) {
 }
 
 func (s *InMemSecret) Get(ctx context.Context, name string) (string, time.Time, error) {
+	s.gets = append(s.gets, name)
 	item, ok := s.items.Get(name)
 	if !ok {
 		return "", time.Time{}, fmt.Errorf("item for %q not found", name)
@@ -45,12 +47,32 @@ func (s *InMemSecret) Reset() {
 	values := make(map[string]string)
 	s.items = &items
 	s.values = values
+	s.gets = nil
+}
+
+// Gets returns the names of the secrets that have been fetched using Get
+func (s *InMemSecret) Gets() []string {
+	return s.gets
+}
+
+func (s *InMemSecret) ResetGets() {
+	s.gets = nil
 }
 
 func (s *InMemSecret) Set(name string, value string, changedAt time.Time) {
 	s.values[name] = value
 	(*s.items)[name] = Item{
-		name,
-		changedAt,
+		name:      name,
+		changedAt: changedAt,
 	}
 }
+
+func (s *InMemSecret) SetTags(name string, tags map[string]string) {
+	item, ok := (*s.items)[name]
+	if !ok {
+		return
+	}
+
+	item.tags = tags
+	(*s.items)[name] = item
+}
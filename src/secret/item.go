@@ -7,6 +7,7 @@ import (
 type Item struct {
 	name      string
 	changedAt time.Time
+	tags      map[string]string
 }
 
 func (i *Item) LastChange() time.Time {
@@ -17,6 +18,15 @@ func (i *Item) Name() string {
 	return i.name
 }
 
+func (i *Item) Tag(key string) (string, bool) {
+	if i.tags == nil {
+		return "", false
+	}
+
+	value, ok := i.tags[key]
+	return value, ok
+}
+
 type Items map[string]Item
 
 func (i *Items) Get(name string) (Item, bool) {
@@ -17,7 +17,11 @@ type KeyVaultSecret struct {
 var _ Secret = (*KeyVaultSecret)(nil)
 
 func NewKeyVaultSecret(cfg config.ReconcileConfig, cred azcore.TokenCredential) (*KeyVaultSecret, error) {
-	vaultUrl := fmt.Sprintf("https://%s.vault.azure.net", cfg.KeyVaultName)
+	return newKeyVaultSecret(cfg.KeyVaultName, cred)
+}
+
+func newKeyVaultSecret(keyVaultName string, cred azcore.TokenCredential) (*KeyVaultSecret, error) {
+	vaultUrl := fmt.Sprintf("https://%s.vault.azure.net", keyVaultName)
 	client, err := azsecrets.NewClient(vaultUrl, cred, nil)
 	if err != nil {
 		return nil, err
@@ -46,9 +50,18 @@ func (s *KeyVaultSecret) ListItems(ctx context.Context) (*Items, error) {
 				changedAt = item.Attributes.Created
 			}
 
+			tags := make(map[string]string)
+			for key, value := range item.Tags {
+				if value == nil {
+					continue
+				}
+				tags[key] = *value
+			}
+
 			items[item.ID.Name()] = Item{
 				name:      item.ID.Name(),
 				changedAt: *changedAt,
+				tags:      tags,
 			}
 		}
 	}
@@ -0,0 +1,186 @@
+package secret
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/xenitab/azcagit/src/config"
+)
+
+// MultiSecret reads secrets without a vault from the default secret and secrets named
+// <vault>/<name> from the vault
+type MultiSecret struct {
+	defaultSecret Secret
+	vaults        map[string]Secret
+}
+
+var _ Secret = (*MultiSecret)(nil)
+var _ NamedItemsLister = (*MultiSecret)(nil)
+var _ FileLoader = (*MultiSecret)(nil)
+
+func NewMultiSecret(defaultSecret Secret, vaults map[string]Secret) *MultiSecret {
+	return &MultiSecret{
+		defaultSecret: defaultSecret,
+		vaults:        vaults,
+	}
+}
+
+// NewSecretClient creates the default KeyVault secret and the configured secret vaults
+func NewSecretClient(cfg config.ReconcileConfig, cred azcore.TokenCredential) (*MultiSecret, error) {
+	defaultSecret, err := NewKeyVaultSecret(cfg, cred)
+	if err != nil {
+		return nil, err
+	}
+
+	secretVaults, err := cfg.GetSecretVaults()
+	if err != nil {
+		return nil, err
+	}
+
+	vaults := make(map[string]Secret)
+	for _, secretVault := range secretVaults {
+		switch secretVault.Type {
+		case config.SecretVaultTypeKeyVault:
+			vault, err := newKeyVaultSecret(secretVault.KeyVaultName, cred)
+			if err != nil {
+				return nil, err
+			}
+			vaults[secretVault.Name] = vault
+		case config.SecretVaultTypeHashicorpVault:
+			vaults[secretVault.Name] = NewHashicorpVaultSecret(secretVault.Address, secretVault.Mount, secretVault.Path, secretVault.Token, secretVault.Field)
+		case config.SecretVaultTypeSops:
+			vaults[secretVault.Name] = NewSopsSecret(secretVault.Path, secretVault.AgeKey, cred)
+		default:
+			return nil, fmt.Errorf("secret vault %q has unknown type %q", secretVault.Name, secretVault.Type)
+		}
+	}
+
+	return NewMultiSecret(defaultSecret, vaults), nil
+}
+
+// JoinName returns the name of a secret in a vault, an empty vault is the default secret
+func JoinName(vault string, name string) string {
+	if vault == "" {
+		return name
+	}
+
+	return fmt.Sprintf("%s/%s", vault, name)
+}
+
+func splitName(name string) (string, string) {
+	vault, secretName, ok := strings.Cut(name, "/")
+	if !ok {
+		return "", name
+	}
+
+	return vault, secretName
+}
+
+func (s *MultiSecret) ListItems(ctx context.Context) (*Items, error) {
+	return s.listItems(ctx, func(vault Secret, _ string) (*Items, error) {
+		return vault.ListItems(ctx)
+	})
+}
+
+// ListNamedItems only lists the items with the names in the vaults implementing NamedItemsLister, a
+// vault without any of the names isn't listed at all
+func (s *MultiSecret) ListNamedItems(ctx context.Context, names []string) (*Items, error) {
+	vaultSecretNames := make(map[string][]string)
+	for _, name := range names {
+		vaultName, secretName := splitName(name)
+		vaultSecretNames[vaultName] = append(vaultSecretNames[vaultName], secretName)
+	}
+
+	return s.listItems(ctx, func(vault Secret, vaultName string) (*Items, error) {
+		secretNames, ok := vaultSecretNames[vaultName]
+		if !ok {
+			items := make(Items)
+			return &items, nil
+		}
+
+		return ListItems(ctx, vault, secretNames)
+	})
+}
+
+func (s *MultiSecret) listItems(ctx context.Context, listVaultItems func(vault Secret, vaultName string) (*Items, error)) (*Items, error) {
+	items, err := listVaultItems(s.defaultSecret, "")
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(Items)
+	for name, item := range *items {
+		result[name] = item
+	}
+
+	for _, vaultName := range s.getSortedVaultNames() {
+		vaultItems, err := listVaultItems(s.vaults[vaultName], vaultName)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list secrets in vault %q: %w", vaultName, err)
+		}
+
+		for name, item := range *vaultItems {
+			item.name = JoinName(vaultName, name)
+			result[item.name] = item
+		}
+	}
+
+	return &result, nil
+}
+
+func (s *MultiSecret) Get(ctx context.Context, name string) (string, time.Time, error) {
+	vaultName, secretName := splitName(name)
+	if vaultName == "" {
+		return s.defaultSecret.Get(ctx, secretName)
+	}
+
+	vault, ok := s.vaults[vaultName]
+	if !ok {
+		return "", time.Time{}, fmt.Errorf("secret vault %q is not configured", vaultName)
+	}
+
+	return vault.Get(ctx, secretName)
+}
+
+func (s *MultiSecret) FilePaths() []string {
+	paths := []string{}
+	for _, vaultName := range s.getSortedVaultNames() {
+		loader, ok := s.vaults[vaultName].(FileLoader)
+		if !ok {
+			continue
+		}
+		paths = append(paths, loader.FilePaths()...)
+	}
+
+	return paths
+}
+
+func (s *MultiSecret) LoadFiles(files map[string][]byte) error {
+	for _, vaultName := range s.getSortedVaultNames() {
+		loader, ok := s.vaults[vaultName].(FileLoader)
+		if !ok {
+			continue
+		}
+
+		err := loader.LoadFiles(files)
+		if err != nil {
+			return fmt.Errorf("unable to load files for vault %q: %w", vaultName, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *MultiSecret) getSortedVaultNames() []string {
+	names := []string{}
+	for name := range s.vaults {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,53 @@
+package secret
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiSecret(t *testing.T) {
+	now := time.Now()
+	defaultSecret := NewInMemSecret()
+	defaultSecret.Set("foo", "default-foo", now)
+	otherSecret := NewInMemSecret()
+	otherSecret.Set("foo", "other-foo", now.Add(time.Minute))
+	otherSecret.Set("bar", "other-bar", now)
+
+	unusedSecret := NewInMemSecret()
+	unusedSecret.Set("baz", "unused-baz", now)
+
+	multiSecret := NewMultiSecret(defaultSecret, map[string]Secret{"other": otherSecret, "unused": unusedSecret})
+
+	items, err := multiSecret.ListItems(context.Background())
+	require.NoError(t, err)
+	require.Len(t, *items, 4)
+	item, ok := items.Get("other/foo")
+	require.True(t, ok)
+	require.Equal(t, now.Add(time.Minute), item.LastChange())
+
+	items, err = multiSecret.ListNamedItems(context.Background(), []string{"foo", "other/bar", "missing/foo"})
+	require.NoError(t, err)
+	require.Len(t, *items, 3)
+	_, ok = items.Get("other/bar")
+	require.True(t, ok)
+	_, ok = items.Get("unused/baz")
+	require.False(t, ok, "vaults without referenced secrets shouldn't be listed")
+
+	value, _, err := multiSecret.Get(context.Background(), "foo")
+	require.NoError(t, err)
+	require.Equal(t, "default-foo", value)
+
+	value, changedAt, err := multiSecret.Get(context.Background(), JoinName("other", "foo"))
+	require.NoError(t, err)
+	require.Equal(t, "other-foo", value)
+	require.Equal(t, now.Add(time.Minute), changedAt)
+
+	_, _, err = multiSecret.Get(context.Background(), "missing/foo")
+	require.ErrorContains(t, err, "secret vault \"missing\" is not configured")
+
+	require.Empty(t, multiSecret.FilePaths())
+	require.Equal(t, "foo", JoinName("", "foo"))
+}
@@ -9,3 +9,26 @@ type Secret interface {
 	ListItems(ctx context.Context) (*Items, error)
 	Get(ctx context.Context, name string) (string, time.Time, error)
 }
+
+// FileLoader is implemented by secrets stored in files in the git repository, the files are loaded at
+// every checkout
+type FileLoader interface {
+	FilePaths() []string
+	LoadFiles(files map[string][]byte) error
+}
+
+// NamedItemsLister is implemented by secrets where listing every item is expensive, only the items
+// with the names are listed
+type NamedItemsLister interface {
+	ListNamedItems(ctx context.Context, names []string) (*Items, error)
+}
+
+// ListItems lists the items with the names, secrets that don't implement NamedItemsLister list all items
+func ListItems(ctx context.Context, s Secret, names []string) (*Items, error) {
+	lister, ok := s.(NamedItemsLister)
+	if !ok {
+		return s.ListItems(ctx)
+	}
+
+	return lister.ListNamedItems(ctx, names)
+}
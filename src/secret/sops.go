@@ -0,0 +1,293 @@
+package secret
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/security/keyvault/azkeys"
+	"github.com/getsops/sops/v3"
+	"github.com/getsops/sops/v3/aes"
+	"github.com/getsops/sops/v3/keyservice"
+	sopsyaml "github.com/getsops/sops/v3/stores/yaml"
+	"google.golang.org/grpc"
+)
+
+// SopsSecret reads secrets from a SOPS encrypted YAML file in the git repository. Every top level key
+// is a secret and the data key is decrypted using either an age identity or an Azure KeyVault key.
+// The file is decrypted and its MAC verified the same way as the SOPS decrypt package does, but with
+// the configured age identity and the credential of azcagit instead of the environment.
+type SopsSecret struct {
+	path          string
+	ageKey        string
+	cred          azcore.TokenCredential
+	clientOptions *azkeys.ClientOptions
+
+	mu     sync.Mutex
+	file   *sopsFile
+	values map[string]interface{}
+}
+
+var _ Secret = (*SopsSecret)(nil)
+var _ FileLoader = (*SopsSecret)(nil)
+
+func NewSopsSecret(path string, ageKey string, cred azcore.TokenCredential) *SopsSecret {
+	return newSopsSecret(path, ageKey, cred, nil)
+}
+
+func newSopsSecret(path string, ageKey string, cred azcore.TokenCredential, clientOptions *azkeys.ClientOptions) *SopsSecret {
+	return &SopsSecret{
+		path:          path,
+		ageKey:        ageKey,
+		cred:          cred,
+		clientOptions: clientOptions,
+	}
+}
+
+// sopsFile contains the encrypted top level values, the content is kept to decrypt the file on the first Get
+type sopsFile struct {
+	content      []byte
+	encrypted    map[string]interface{}
+	lastModified time.Time
+}
+
+func (s *SopsSecret) FilePaths() []string {
+	return []string{s.path}
+}
+
+func (s *SopsSecret) LoadFiles(files map[string][]byte) error {
+	content, ok := files[s.path]
+	if !ok {
+		return fmt.Errorf("sops file %q not found", s.path)
+	}
+
+	file, err := parseSopsFile(content)
+	if err != nil {
+		return fmt.Errorf("unable to parse sops file %q: %w", s.path, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.file = file
+	s.values = nil
+
+	return nil
+}
+
+func parseSopsFile(content []byte) (*sopsFile, error) {
+	tree, err := loadSopsTree(content)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sopsFile{
+		content:      content,
+		encrypted:    getSopsTopLevelValues(tree),
+		lastModified: tree.Metadata.LastModified.UTC(),
+	}, nil
+}
+
+func loadSopsTree(content []byte) (sops.Tree, error) {
+	store := &sopsyaml.Store{}
+	tree, err := store.LoadEncryptedFile(content)
+	if err != nil {
+		return sops.Tree{}, err
+	}
+
+	if len(tree.Branches) == 0 {
+		return sops.Tree{}, fmt.Errorf("sops file is empty")
+	}
+
+	return tree, nil
+}
+
+func getSopsTopLevelValues(tree sops.Tree) map[string]interface{} {
+	values := make(map[string]interface{})
+	for _, item := range tree.Branches[0] {
+		key, ok := item.Key.(string)
+		if !ok {
+			continue
+		}
+		values[key] = item.Value
+	}
+
+	return values
+}
+
+func (s *SopsSecret) ListItems(ctx context.Context) (*Items, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return nil, fmt.Errorf("sops file %q not loaded", s.path)
+	}
+
+	items := make(Items)
+	for name := range s.file.encrypted {
+		items[name] = Item{
+			name:      name,
+			changedAt: s.file.lastModified,
+		}
+	}
+
+	return &items, nil
+}
+
+func (s *SopsSecret) Get(ctx context.Context, name string) (string, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return "", time.Time{}, fmt.Errorf("sops file %q not loaded", s.path)
+	}
+
+	value, ok := s.file.encrypted[name]
+	if !ok {
+		return "", time.Time{}, fmt.Errorf("secret %q not found in sops file %q", name, s.path)
+	}
+
+	stringValue, ok := value.(string)
+	if !ok {
+		return "", time.Time{}, fmt.Errorf("secret %q in sops file %q isn't a string", name, s.path)
+	}
+
+	if !strings.HasPrefix(stringValue, "ENC[") {
+		return "", time.Time{}, fmt.Errorf("secret %q in sops file %q isn't encrypted", name, s.path)
+	}
+
+	if s.values == nil {
+		values, err := s.decrypt(ctx)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		s.values = values
+	}
+
+	return fmt.Sprint(s.values[name]), s.file.lastModified, nil
+}
+
+// decrypt decrypts the whole file and verifies the MAC, the same way as decrypt.DataWithFormat
+func (s *SopsSecret) decrypt(ctx context.Context) (map[string]interface{}, error) {
+	tree, err := loadSopsTree(s.file.content)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse sops file %q: %w", s.path, err)
+	}
+
+	ks := &sopsKeyService{ctx: ctx, secret: s}
+	dataKey, err := tree.Metadata.GetDataKeyWithKeyServices([]keyservice.KeyServiceClient{ks})
+	if err != nil {
+		if len(ks.errs) == 0 {
+			return nil, fmt.Errorf("unable to decrypt the data key of sops file %q: %w", s.path, err)
+		}
+		return nil, fmt.Errorf("unable to decrypt the data key of sops file %q: %s", s.path, strings.Join(ks.errs, ", "))
+	}
+
+	cipher := aes.NewCipher()
+	mac, err := tree.Decrypt(dataKey, cipher)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decrypt sops file %q: %w", s.path, err)
+	}
+
+	originalMac, err := cipher.Decrypt(tree.Metadata.MessageAuthenticationCode, dataKey, tree.Metadata.LastModified.Format(time.RFC3339))
+	if err != nil {
+		return nil, fmt.Errorf("unable to decrypt the MAC of sops file %q: %w", s.path, err)
+	}
+
+	if originalMac != mac {
+		return nil, fmt.Errorf("unable to verify the integrity of sops file %q, the MAC doesn't match", s.path)
+	}
+
+	return getSopsTopLevelValues(tree), nil
+}
+
+// sopsKeyService decrypts the data key using the age identity and credential of the SopsSecret, the
+// errors are kept as SOPS only returns the number of key groups that failed
+type sopsKeyService struct {
+	ctx    context.Context
+	secret *SopsSecret
+	errs   []string
+}
+
+var _ keyservice.KeyServiceClient = (*sopsKeyService)(nil)
+
+func (ks *sopsKeyService) Encrypt(ctx context.Context, in *keyservice.EncryptRequest, opts ...grpc.CallOption) (*keyservice.EncryptResponse, error) {
+	return nil, fmt.Errorf("encrypt isn't supported")
+}
+
+func (ks *sopsKeyService) Decrypt(ctx context.Context, in *keyservice.DecryptRequest, opts ...grpc.CallOption) (*keyservice.DecryptResponse, error) {
+	var plaintext []byte
+	var err error
+	switch key := in.Key.KeyType.(type) {
+	case *keyservice.Key_AgeKey:
+		plaintext, err = ks.secret.decryptAgeKey(in.Ciphertext)
+		if err != nil {
+			err = fmt.Errorf("age recipient %q: %w", key.AgeKey.Recipient, err)
+		}
+	case *keyservice.Key_AzureKeyvaultKey:
+		plaintext, err = ks.secret.unwrapAzureKeyVaultKey(ks.ctx, key.AzureKeyvaultKey, in.Ciphertext)
+		if err != nil {
+			err = fmt.Errorf("azure keyvault key %q: %w", key.AzureKeyvaultKey.Name, err)
+		}
+	default:
+		err = fmt.Errorf("unsupported key type %T", key)
+	}
+	if err != nil {
+		ks.errs = append(ks.errs, err.Error())
+		return nil, err
+	}
+
+	return &keyservice.DecryptResponse{Plaintext: plaintext}, nil
+}
+
+func (s *SopsSecret) decryptAgeKey(encryptedKey []byte) ([]byte, error) {
+	if s.ageKey == "" {
+		return nil, fmt.Errorf("no age key configured")
+	}
+
+	identity, err := age.ParseX25519Identity(s.ageKey)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse age key: %w", err)
+	}
+
+	r, err := age.Decrypt(armor.NewReader(bytes.NewReader(encryptedKey)), identity)
+	if err != nil {
+		return nil, err
+	}
+
+	return io.ReadAll(r)
+}
+
+func (s *SopsSecret) unwrapAzureKeyVaultKey(ctx context.Context, key *keyservice.AzureKeyVaultKey, encryptedKey []byte) ([]byte, error) {
+	if s.cred == nil {
+		return nil, fmt.Errorf("no azure credential configured")
+	}
+
+	value, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(string(encryptedKey), "="))
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode the encrypted key: %w", err)
+	}
+
+	client, err := azkeys.NewClient(key.VaultUrl, s.cred, s.clientOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	algorithm := azkeys.EncryptionAlgorithmRSAOAEP256
+	res, err := client.UnwrapKey(ctx, key.Name, key.Version, azkeys.KeyOperationParameters{
+		Algorithm: &algorithm,
+		Value:     value,
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return res.Result, nil
+}
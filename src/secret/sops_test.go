@@ -0,0 +1,204 @@
+package secret
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"filippo.io/age"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/security/keyvault/azkeys"
+	"github.com/getsops/sops/v3"
+	"github.com/getsops/sops/v3/aes"
+	sopsage "github.com/getsops/sops/v3/age"
+	"github.com/getsops/sops/v3/azkv"
+	"github.com/getsops/sops/v3/keys"
+	sopsyaml "github.com/getsops/sops/v3/stores/yaml"
+	"github.com/stretchr/testify/require"
+)
+
+const testSopsPlainYAML = `foo: foobar
+bar_unencrypted: baz
+nested:
+    foo: bar
+`
+
+func TestSopsSecretAge(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	dataKey := testRandomBytes(t, 32)
+	ageKey, err := sopsage.MasterKeyFromRecipient(identity.Recipient().String())
+	require.NoError(t, err)
+	err = ageKey.Encrypt(dataKey)
+	require.NoError(t, err)
+	content := testSopsEncrypt(t, testSopsPlainYAML, dataKey, ageKey)
+
+	sopsSecret := newSopsSecret("secrets/secrets.enc.yaml", identity.String(), nil, nil)
+	require.Equal(t, []string{"secrets/secrets.enc.yaml"}, sopsSecret.FilePaths())
+
+	_, err = sopsSecret.ListItems(context.Background())
+	require.ErrorContains(t, err, "sops file \"secrets/secrets.enc.yaml\" not loaded")
+
+	err = sopsSecret.LoadFiles(map[string][]byte{})
+	require.ErrorContains(t, err, "sops file \"secrets/secrets.enc.yaml\" not found")
+
+	err = sopsSecret.LoadFiles(map[string][]byte{"secrets/secrets.enc.yaml": []byte("foo: bar")})
+	require.ErrorContains(t, err, "unable to parse sops file \"secrets/secrets.enc.yaml\"")
+
+	err = sopsSecret.LoadFiles(map[string][]byte{"secrets/secrets.enc.yaml": content})
+	require.NoError(t, err)
+
+	lastModified := time.Date(2023, 11, 1, 10, 0, 0, 0, time.UTC)
+	items, err := sopsSecret.ListItems(context.Background())
+	require.NoError(t, err)
+	require.Len(t, *items, 3)
+	item, ok := items.Get("foo")
+	require.True(t, ok)
+	require.Equal(t, lastModified, item.LastChange())
+
+	value, changedAt, err := sopsSecret.Get(context.Background(), "foo")
+	require.NoError(t, err)
+	require.Equal(t, "foobar", value)
+	require.Equal(t, lastModified, changedAt)
+
+	_, _, err = sopsSecret.Get(context.Background(), "bar_unencrypted")
+	require.ErrorContains(t, err, "secret \"bar_unencrypted\" in sops file \"secrets/secrets.enc.yaml\" isn't encrypted")
+
+	_, _, err = sopsSecret.Get(context.Background(), "nested")
+	require.ErrorContains(t, err, "secret \"nested\" in sops file \"secrets/secrets.enc.yaml\" isn't a string")
+
+	_, _, err = sopsSecret.Get(context.Background(), "missing")
+	require.ErrorContains(t, err, "secret \"missing\" not found in sops file \"secrets/secrets.enc.yaml\"")
+
+	otherIdentity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	otherSopsSecret := newSopsSecret("secrets/secrets.enc.yaml", otherIdentity.String(), nil, nil)
+	err = otherSopsSecret.LoadFiles(map[string][]byte{"secrets/secrets.enc.yaml": content})
+	require.NoError(t, err)
+	_, _, err = otherSopsSecret.Get(context.Background(), "foo")
+	require.ErrorContains(t, err, "unable to decrypt the data key of sops file \"secrets/secrets.enc.yaml\"")
+	require.ErrorContains(t, err, "no identity matched any of the recipients")
+}
+
+func TestSopsSecretTampered(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	dataKey := testRandomBytes(t, 32)
+	ageKey, err := sopsage.MasterKeyFromRecipient(identity.Recipient().String())
+	require.NoError(t, err)
+	err = ageKey.Encrypt(dataKey)
+	require.NoError(t, err)
+	content := testSopsEncrypt(t, testSopsPlainYAML, dataKey, ageKey)
+	tampered := strings.Replace(string(content), "bar_unencrypted: baz", "bar_unencrypted: qux", 1)
+	require.NotEqual(t, string(content), tampered)
+
+	sopsSecret := newSopsSecret("secrets.enc.yaml", identity.String(), nil, nil)
+	err = sopsSecret.LoadFiles(map[string][]byte{"secrets.enc.yaml": []byte(tampered)})
+	require.NoError(t, err)
+
+	_, _, err = sopsSecret.Get(context.Background(), "foo")
+	require.ErrorContains(t, err, "unable to verify the integrity of sops file \"secrets.enc.yaml\", the MAC doesn't match")
+}
+
+func TestSopsSecretAzureKeyVault(t *testing.T) {
+	dataKey := testRandomBytes(t, 32)
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer authorization="https://login.microsoftonline.com/ze-tenant", resource="https://vault.azure.net"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		require.Equal(t, "/keys/sops-key/ze-version/unwrapkey", r.URL.Path)
+		require.Equal(t, "Bearer ze-token", r.Header.Get("Authorization"))
+
+		var body map[string]string
+		err := json.NewDecoder(r.Body).Decode(&body)
+		require.NoError(t, err)
+		require.Equal(t, "RSA-OAEP-256", body["alg"])
+		require.Equal(t, base64.RawURLEncoding.EncodeToString([]byte("ze-wrapped-key")), body["value"])
+
+		err = json.NewEncoder(w).Encode(map[string]string{"value": base64.RawURLEncoding.EncodeToString(dataKey)})
+		require.NoError(t, err)
+	}))
+	defer srv.Close()
+
+	azureKey := azkv.NewMasterKey(srv.URL, "sops-key", "ze-version")
+	azureKey.EncryptedKey = base64.RawURLEncoding.EncodeToString([]byte("ze-wrapped-key"))
+	content := testSopsEncrypt(t, "foo: foobar\n", dataKey, azureKey)
+
+	clientOptions := &azkeys.ClientOptions{
+		ClientOptions: azcore.ClientOptions{
+			Transport: srv.Client(),
+			Retry:     policy.RetryOptions{MaxRetries: -1},
+		},
+		DisableChallengeResourceVerification: true,
+	}
+
+	sopsSecret := newSopsSecret("secrets.enc.yaml", "", &testTokenCredential{}, clientOptions)
+	err := sopsSecret.LoadFiles(map[string][]byte{"secrets.enc.yaml": content})
+	require.NoError(t, err)
+
+	value, _, err := sopsSecret.Get(context.Background(), "foo")
+	require.NoError(t, err)
+	require.Equal(t, "foobar", value)
+
+	withoutCredential := newSopsSecret("secrets.enc.yaml", "", nil, clientOptions)
+	err = withoutCredential.LoadFiles(map[string][]byte{"secrets.enc.yaml": content})
+	require.NoError(t, err)
+	_, _, err = withoutCredential.Get(context.Background(), "foo")
+	require.ErrorContains(t, err, "no azure credential configured")
+}
+
+type testTokenCredential struct{}
+
+func (c *testTokenCredential) GetToken(ctx context.Context, options policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	return azcore.AccessToken{Token: "ze-token", ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
+
+func testRandomBytes(t *testing.T, n int) []byte {
+	t.Helper()
+
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	require.NoError(t, err)
+
+	return b
+}
+
+// testSopsEncrypt encrypts the yaml like sops does, with master keys that already contain the encrypted data key
+func testSopsEncrypt(t *testing.T, plain string, dataKey []byte, masterKeys ...keys.MasterKey) []byte {
+	t.Helper()
+
+	store := &sopsyaml.Store{}
+	branches, err := store.LoadPlainFile([]byte(plain))
+	require.NoError(t, err)
+
+	tree := sops.Tree{
+		Branches: branches,
+		Metadata: sops.Metadata{
+			KeyGroups:         []sops.KeyGroup{masterKeys},
+			UnencryptedSuffix: "_unencrypted",
+			LastModified:      time.Date(2023, 11, 1, 10, 0, 0, 0, time.UTC),
+			Version:           "3.8.1",
+		},
+	}
+
+	cipher := aes.NewCipher()
+	mac, err := tree.Encrypt(dataKey, cipher)
+	require.NoError(t, err)
+	tree.Metadata.MessageAuthenticationCode, err = cipher.Encrypt(mac, dataKey, tree.Metadata.LastModified.Format(time.RFC3339))
+	require.NoError(t, err)
+
+	content, err := store.EmitEncryptedFile(tree)
+	require.NoError(t, err)
+
+	return content
+}
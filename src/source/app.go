@@ -23,6 +23,15 @@ type SourceAppSpecification struct {
 	RemoteSecrets  []RemoteSecretSpecification    `json:"remoteSecrets,omitempty" yaml:"remoteSecrets,omitempty"`
 	LocationFilter []LocationFilterSpecification  `json:"locationFilter,omitempty" yaml:"locationFilter,omitempty"`
 	Replacements   *ReplacementsSpecification     `json:"replacements,omitempty" yaml:"replacements,omitempty"`
+	CustomDomains  []CustomDomainSpecification    `json:"customDomains,omitempty" yaml:"customDomains,omitempty"`
+	// DisableRegistries opts out of the container registries added by azcagit
+	DisableRegistries bool `json:"disableRegistries,omitempty" yaml:"disableRegistries,omitempty"`
+}
+
+// CustomDomainSpecification binds a hostname to the AzureContainerCertificate with the name CertificateName
+type CustomDomainSpecification struct {
+	Name            *string `json:"name,omitempty" yaml:"name,omitempty"`
+	CertificateName *string `json:"certificateName,omitempty" yaml:"certificateName,omitempty"`
 }
 
 type SourceApp struct {
@@ -31,6 +40,8 @@ type SourceApp struct {
 	Metadata      map[string]string       `json:"metadata,omitempty" yaml:"metadata,omitempty"`
 	Specification *SourceAppSpecification `json:"spec,omitempty" yaml:"spec,omitempty"`
 	Err           error
+	// tenant is the policy of the tenant with the path of the manifest, nil if it isn't in the path of a tenant
+	tenant *TenantPolicy
 }
 
 func (app *SourceApp) Error() error {
@@ -51,6 +62,22 @@ func (app *SourceApp) Name() string {
 }
 
 func (app *SourceApp) SetSecret(name string, value string) error {
+	return app.addSecret(&armappcontainers.Secret{
+		Name:  &name,
+		Value: &value,
+	})
+}
+
+// SetSecretReference adds a secret referencing KeyVault, the value is read by Container Apps using the identity
+func (app *SourceApp) SetSecretReference(name string, keyVaultURL string, identity string) error {
+	return app.addSecret(&armappcontainers.Secret{
+		Name:        &name,
+		KeyVaultURL: &keyVaultURL,
+		Identity:    &identity,
+	})
+}
+
+func (app *SourceApp) addSecret(secret *armappcontainers.Secret) error {
 	if app == nil || app.Specification == nil || app.Specification.App == nil {
 		return fmt.Errorf("app is nil")
 	}
@@ -72,19 +99,74 @@ func (app *SourceApp) SetSecret(name string, value string) error {
 			continue
 		}
 
-		if *v.Name == name {
-			return fmt.Errorf("a secret with name %q already exists", name)
+		if *v.Name == *secret.Name {
+			return fmt.Errorf("a secret with name %q already exists", *secret.Name)
 		}
 	}
 
-	app.Specification.App.Properties.Configuration.Secrets = append(app.Specification.App.Properties.Configuration.Secrets, &armappcontainers.Secret{
-		Name:  &name,
-		Value: &value,
+	app.Specification.App.Properties.Configuration.Secrets = append(app.Specification.App.Properties.Configuration.Secrets, secret)
+
+	return nil
+}
+
+func (app *SourceApp) GetCustomDomains() []CustomDomainSpecification {
+	if app == nil || app.Specification == nil {
+		return []CustomDomainSpecification{}
+	}
+
+	return app.Specification.CustomDomains
+}
+
+// SetCustomDomain adds the custom domain to the app ingress, certificateID should be nil when bindingType is Disabled
+func (app *SourceApp) SetCustomDomain(name string, bindingType armappcontainers.BindingType, certificateID *string) error {
+	if app == nil || app.Specification == nil || app.Specification.App == nil {
+		return fmt.Errorf("app is nil")
+	}
+
+	if app.Specification.App.Properties == nil || app.Specification.App.Properties.Configuration == nil || app.Specification.App.Properties.Configuration.Ingress == nil {
+		return fmt.Errorf("custom domain %q requires ingress to be configured", name)
+	}
+
+	ingress := app.Specification.App.Properties.Configuration.Ingress
+	for _, v := range ingress.CustomDomains {
+		if v == nil || v.Name == nil {
+			continue
+		}
+
+		if strings.EqualFold(*v.Name, name) {
+			return fmt.Errorf("the custom domain %q already exists", name)
+		}
+	}
+
+	ingress.CustomDomains = append(ingress.CustomDomains, &armappcontainers.CustomDomain{
+		Name:          &name,
+		BindingType:   &bindingType,
+		CertificateID: certificateID,
 	})
 
 	return nil
 }
 
+// GetVolumeStorageNames returns the managed environment storages referenced by the volumes
+func (app *SourceApp) GetVolumeStorageNames() []string {
+	if app == nil || app.Specification == nil || app.Specification.App == nil || app.Specification.App.Properties == nil || app.Specification.App.Properties.Template == nil {
+		return []string{}
+	}
+
+	names := []string{}
+	for _, volume := range app.Specification.App.Properties.Template.Volumes {
+		if volume == nil || volume.StorageName == nil || *volume.StorageName == "" {
+			continue
+		}
+		if volume.StorageType != nil && *volume.StorageType != armappcontainers.StorageTypeAzureFile {
+			continue
+		}
+		names = append(names, *volume.StorageName)
+	}
+
+	return names
+}
+
 func (app *SourceApp) SetRegistry(server string, username string, password string) error {
 	if app == nil || app.Specification == nil || app.Specification.App == nil {
 		return fmt.Errorf("app is nil")
@@ -102,22 +184,13 @@ func (app *SourceApp) SetRegistry(server string, username string, password strin
 		app.Specification.App.Properties.Configuration.Registries = []*armappcontainers.RegistryCredentials{}
 	}
 
-	for _, v := range app.Specification.App.Properties.Configuration.Registries {
-		if v == nil || v.Server == nil || v.Username == nil {
-			continue
-		}
-
-		if v.Identity == nil && v.PasswordSecretRef == nil {
-			continue
-		}
-
-		if *v.Server == server {
-			return fmt.Errorf("the server %q already exists", server)
-		}
+	err := validateRegistryNotConfigured(app.Specification.App.Properties.Configuration.Registries, server)
+	if err != nil {
+		return err
 	}
 
-	passwordSecretRef := "azcagit-reg-cred"
-	err := app.SetSecret(passwordSecretRef, password)
+	passwordSecretRef := getRegistryPasswordSecretName(app.Specification.App.Properties.Configuration.Secrets)
+	err = app.SetSecret(passwordSecretRef, password)
 	if err != nil {
 		return err
 	}
@@ -132,6 +205,46 @@ func (app *SourceApp) SetRegistry(server string, username string, password strin
 	return nil
 }
 
+// SetRegistryIdentity adds a registry using a managed identity, which has to be assigned to the app
+func (app *SourceApp) SetRegistryIdentity(server string, identity string) error {
+	if app == nil || app.Specification == nil || app.Specification.App == nil {
+		return fmt.Errorf("app is nil")
+	}
+
+	if !isIdentityAssigned(identity, app.Specification.App.Identity) {
+		return fmt.Errorf("identity %q for the server %q is not assigned", identity, server)
+	}
+
+	if app.Specification.App.Properties == nil {
+		app.Specification.App.Properties = &armappcontainers.ContainerAppProperties{}
+	}
+
+	if app.Specification.App.Properties.Configuration == nil {
+		app.Specification.App.Properties.Configuration = &armappcontainers.Configuration{}
+	}
+
+	err := validateRegistryNotConfigured(app.Specification.App.Properties.Configuration.Registries, server)
+	if err != nil {
+		return err
+	}
+
+	app.Specification.App.Properties.Configuration.Registries = append(app.Specification.App.Properties.Configuration.Registries, &armappcontainers.RegistryCredentials{
+		Server:   &server,
+		Identity: &identity,
+	})
+
+	return nil
+}
+
+// GetImageRegistryServers returns the registry servers used by the container images
+func (app *SourceApp) GetImageRegistryServers() []string {
+	if app == nil || app.Specification == nil || app.Specification.App == nil || app.Specification.App.Properties == nil || app.Specification.App.Properties.Template == nil {
+		return []string{}
+	}
+
+	return getImageRegistryServers(app.Specification.App.Properties.Template.Containers, app.Specification.App.Properties.Template.InitContainers)
+}
+
 func (app *SourceApp) GetRemoteSecrets() []RemoteSecretSpecification {
 	secretsMap := make(map[string]struct{})
 	if app == nil || app.Specification == nil || app.Specification.RemoteSecrets == nil || len(app.Specification.RemoteSecrets) == 0 {
@@ -191,12 +304,67 @@ func (app *SourceApp) ValidateFields() error {
 		result = multierror.Append(fmt.Errorf("location is disabled and set through azcagit"), result)
 	}
 
+	if app.Specification != nil && app.Specification.App != nil {
+		err := validateRemoteSecretIdentities(app.Specification.RemoteSecrets, app.Specification.App.Identity)
+		if err != nil {
+			result = multierror.Append(err, result)
+		}
+	}
+
+	if app.Specification != nil {
+		err := validateRemoteSecretNames(app.Specification.RemoteSecrets)
+		if err != nil {
+			result = multierror.Append(err, result)
+		}
+	}
+
+	err := app.validatePolicy()
+	if err != nil {
+		result = multierror.Append(err, result)
+	}
+
+	if app.Specification != nil {
+		for i, customDomain := range app.Specification.CustomDomains {
+			if customDomain.Name == nil || *customDomain.Name == "" {
+				result = multierror.Append(fmt.Errorf("name missing from custom domain %d", i), result)
+			}
+			if customDomain.CertificateName == nil || *customDomain.CertificateName == "" {
+				result = multierror.Append(fmt.Errorf("certificateName missing from custom domain %d", i), result)
+			}
+		}
+	}
+
 	return result.ErrorOrNil()
 }
 
+func (app *SourceApp) validatePolicy() error {
+	if app.tenant == nil || app.Specification == nil || app.Specification.App == nil || app.Specification.App.Properties == nil {
+		return nil
+	}
+
+	properties := app.Specification.App.Properties
+	var ingress *armappcontainers.Ingress
+	var secrets []*armappcontainers.Secret
+	if properties.Configuration != nil {
+		ingress = properties.Configuration.Ingress
+		secrets = properties.Configuration.Secrets
+	}
+
+	var containers []*armappcontainers.Container
+	var initContainers []*armappcontainers.InitContainer
+	if properties.Template != nil {
+		containers = properties.Template.Containers
+		initContainers = properties.Template.InitContainers
+	}
+
+	return app.tenant.validate(containers, initContainers, ingress, secrets, app.Specification.RemoteSecrets)
+}
+
 func validateJsonIsAppKind(j []byte) (bool, error) {
 	dec := json.NewDecoder(bytes.NewReader(j))
-	var newapp SourceApp
+	var newapp struct {
+		Kind string `json:"kind,omitempty"`
+	}
 	err := dec.Decode(&newapp)
 	if err != nil {
 		return false, err
@@ -236,11 +404,23 @@ func (app *SourceApp) Unmarshal(y []byte, cfg config.ReconcileConfig) (bool, err
 		return true, err
 	}
 
+	newapp.tenant = app.tenant
 	err = newapp.ValidateFields()
 	if err != nil {
 		return true, err
 	}
 
+	err = newapp.applyReplacements()
+	if err != nil {
+		return true, err
+	}
+
+	// the replacements can change the fields constrained by the policy
+	err = newapp.validatePolicy()
+	if err != nil {
+		return true, err
+	}
+
 	if cfg.ManagedEnvironmentID == "" {
 		return true, fmt.Errorf("cfg.ManagedEnvironmentID is not set")
 	}
@@ -269,34 +449,54 @@ func (app *SourceApp) Unmarshal(y []byte, cfg config.ReconcileConfig) (bool, err
 
 	newapp.Specification.App.Tags["aca.xenit.io"] = toPtr("true")
 
-	err = newapp.applyReplacements()
-	if err != nil {
-		return true, err
-	}
-
 	*app = newapp
 	return true, nil
 }
 
 func (app *SourceApp) applyReplacements() error {
-	if app.Specification.Replacements != nil && app.Specification.Replacements.Images != nil && len(app.Specification.Replacements.Images) != 0 {
-		if app.Specification.App.Properties.Template == nil || app.Specification.App.Properties.Template.Containers == nil || len(app.Specification.App.Properties.Template.Containers) == 0 {
-			return fmt.Errorf("no containers found")
+	replacements := app.Specification.Replacements
+	if replacements.isEmpty() {
+		return nil
+	}
+
+	err := replacements.validate()
+	if err != nil {
+		return err
+	}
+
+	if replacements.hasContainerReplacements() {
+		containers := []replacementContainer{}
+		if app.Specification.App.Properties != nil && app.Specification.App.Properties.Template != nil {
+			template := app.Specification.App.Properties.Template
+			containers = toReplacementContainers(template.Containers, template.InitContainers)
 		}
-		for i, container := range app.Specification.App.Properties.Template.Containers {
-			if container.Image == nil || *container.Image == "" {
-				return fmt.Errorf("no image found for container %d", i)
-			}
-			oldImage := *container.Image
-			imageParts := strings.Split(oldImage, ":")
-			imageName := imageParts[0]
-			for _, replacementImage := range app.Specification.Replacements.Images {
-				if imageName == *replacementImage.ImageName {
-					*app.Specification.App.Properties.Template.Containers[i].Image = fmt.Sprintf("%s:%s", imageName, *replacementImage.NewImageTag)
-				}
-			}
+		err := replacements.applyContainers(containers)
+		if err != nil {
+			return err
 		}
 	}
+
+	if replacements.Scale != nil {
+		if app.Specification.App.Properties == nil || app.Specification.App.Properties.Template == nil {
+			return fmt.Errorf("no template found for scale replacement")
+		}
+		if app.Specification.App.Properties.Template.Scale == nil {
+			app.Specification.App.Properties.Template.Scale = &armappcontainers.Scale{}
+		}
+		if replacements.Scale.MinReplicas != nil {
+			app.Specification.App.Properties.Template.Scale.MinReplicas = toPtr(*replacements.Scale.MinReplicas)
+		}
+		if replacements.Scale.MaxReplicas != nil {
+			app.Specification.App.Properties.Template.Scale.MaxReplicas = toPtr(*replacements.Scale.MaxReplicas)
+		}
+	}
+
+	newApp, err := applyJSONPathReplacements(app.Specification.App, replacements.JSONPaths)
+	if err != nil {
+		return err
+	}
+	app.Specification.App = newApp
+
 	return nil
 }
 
@@ -317,13 +517,15 @@ func (app *SourceApp) ShoudRunInLocation(currentLocation string) bool {
 
 type SourceApps map[string]SourceApp
 
-func (apps *SourceApps) Unmarshal(path string, y []byte, cfg config.ReconcileConfig) {
+func (apps *SourceApps) Unmarshal(path string, y []byte, cfg config.ReconcileConfig, policy *Policy) {
 	if apps == nil {
 		apps = toPtr(make(SourceApps))
 	}
 	parts := strings.Split(string(y), "---")
 	for i, part := range parts {
-		var app SourceApp
+		app := SourceApp{
+			tenant: policy.GetTenant(path),
+		}
 		isContainerApp, err := app.Unmarshal([]byte(part), cfg)
 		if err != nil {
 			app.Err = fmt.Errorf("unable to unmarshal SourceApp from %s (document %d): %w", path, i, err)
@@ -383,6 +585,38 @@ func (apps *SourceApps) SetSecret(name string, secretName string, secretValue st
 	return nil
 }
 
+func (apps *SourceApps) SetSecretReference(name string, secretName string, keyVaultURL string, identity string) error {
+	app, ok := (*apps)[name]
+	if !ok {
+		return fmt.Errorf("no sourceApp with name %q", name)
+	}
+
+	err := app.SetSecretReference(secretName, keyVaultURL, identity)
+	if err != nil {
+		return err
+	}
+
+	(*apps)[name] = app
+
+	return nil
+}
+
+func (apps *SourceApps) SetCustomDomain(name string, domainName string, bindingType armappcontainers.BindingType, certificateID *string) error {
+	app, ok := (*apps)[name]
+	if !ok {
+		return fmt.Errorf("no sourceApp with name %q", name)
+	}
+
+	err := app.SetCustomDomain(domainName, bindingType, certificateID)
+	if err != nil {
+		return err
+	}
+
+	(*apps)[name] = app
+
+	return nil
+}
+
 func (apps *SourceApps) SetRegistry(name string, server string, username string, password string) error {
 	app, ok := (*apps)[name]
 	if !ok {
@@ -399,13 +633,55 @@ func (apps *SourceApps) SetRegistry(name string, server string, username string,
 	return nil
 }
 
+func (apps *SourceApps) SetRegistryIdentity(name string, server string, identity string) error {
+	app, ok := (*apps)[name]
+	if !ok {
+		return fmt.Errorf("no sourceApp with name %q", name)
+	}
+
+	err := app.SetRegistryIdentity(server, identity)
+	if err != nil {
+		return err
+	}
+
+	(*apps)[name] = app
+
+	return nil
+}
+
+// GetUniqueKeyVaultReferenceNames returns the remote secrets used as KeyVault references, they aren't read
+// by azcagit but their changes are tracked
+func (apps *SourceApps) GetUniqueKeyVaultReferenceNames() []string {
+	secretsMap := make(map[string]struct{})
+	for _, appName := range apps.GetSortedNames() {
+		app, _ := apps.Get(appName)
+		for _, remoteSecret := range app.GetRemoteSecrets() {
+			if !remoteSecret.IsKeyVaultReference() {
+				continue
+			}
+			secretsMap[remoteSecret.SecretKey()] = struct{}{}
+		}
+	}
+
+	secrets := []string{}
+	for secret := range secretsMap {
+		secrets = append(secrets, secret)
+	}
+	sort.Strings(secrets)
+
+	return secrets
+}
+
 func (apps *SourceApps) GetUniqueRemoteSecretNames() []string {
 	secretsMap := make(map[string]struct{})
 	for _, appName := range apps.GetSortedNames() {
 		app, _ := apps.Get(appName)
 		appSecrets := app.GetRemoteSecrets()
 		for _, remoteSecret := range appSecrets {
-			secretsMap[*remoteSecret.RemoteSecretName] = struct{}{}
+			if remoteSecret.IsKeyVaultReference() {
+				continue
+			}
+			secretsMap[remoteSecret.SecretKey()] = struct{}{}
 		}
 	}
 
@@ -286,6 +286,46 @@ spec:
 			expectedError:  "",
 			isContainerApp: true,
 		},
+		{
+			testDescription: "keyvault reference with unassigned user-assigned identity",
+			rawYaml: `
+kind: AzureContainerApp
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+  name: foo
+spec:
+  remoteSecrets:
+    - secretName: foo
+      remoteSecretName: bar
+      identity: /subscriptions/ze-sub/resourceGroups/ze-rg/providers/Microsoft.ManagedIdentity/userAssignedIdentities/bar
+  app:
+    identity:
+      type: UserAssigned
+      userAssignedIdentities:
+        /subscriptions/ze-sub/resourceGroups/ze-rg/providers/Microsoft.ManagedIdentity/userAssignedIdentities/foo: {}
+`,
+			expectedResult: SourceApp{},
+			expectedError:  "identity \"/subscriptions/ze-sub/resourceGroups/ze-rg/providers/Microsoft.ManagedIdentity/userAssignedIdentities/bar\" for remote secret \"foo\" is not assigned",
+			isContainerApp: true,
+		},
+		{
+			testDescription: "keyvault reference with system-assigned identity missing",
+			rawYaml: `
+kind: AzureContainerApp
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+  name: foo
+spec:
+  remoteSecrets:
+    - secretName: foo
+      remoteSecretName: bar
+      identity: System
+  app: {}
+`,
+			expectedResult: SourceApp{},
+			expectedError:  "identity \"System\" for remote secret \"foo\" is not assigned",
+			isContainerApp: true,
+		},
 	}
 
 	for i, c := range cases {
@@ -480,7 +520,7 @@ spec:
 		apps.Unmarshal("foobar/baz.yaml", []byte(c.rawYaml), config.ReconcileConfig{
 			Location:             "ze-location",
 			ManagedEnvironmentID: "ze-managedEnvironmentID",
-		})
+		}, nil)
 		require.Len(t, apps, c.expectedLenght)
 		if c.expectedError != "" {
 			require.ErrorContains(t, apps.Error(), c.expectedError)
@@ -636,6 +676,29 @@ func TestSourceAppSetRegistry(t *testing.T) {
 		require.ErrorContains(t, err, "the server \"ze-server\" already exists")
 	}
 
+	// fails with server already exists in another case
+	{
+		app := SourceApp{
+			Specification: &SourceAppSpecification{
+				App: &armappcontainers.ContainerApp{
+					Properties: &armappcontainers.ContainerAppProperties{
+						Configuration: &armappcontainers.Configuration{
+							Registries: []*armappcontainers.RegistryCredentials{
+								{
+									Server:            toPtr("Ze-Server"),
+									Username:          toPtr(""),
+									PasswordSecretRef: toPtr(""),
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+		err := app.SetRegistry("ze-server", "foo", "bar")
+		require.ErrorContains(t, err, "the server \"ze-server\" already exists")
+	}
+
 	// working with no secrets
 	{
 		app := SourceApp{
@@ -712,6 +775,171 @@ func TestSourceAppSetRegistry(t *testing.T) {
 	}
 }
 
+func TestSourceAppSetRegistryIdentity(t *testing.T) {
+	// fails with identity not assigned
+	{
+		app := SourceApp{
+			Specification: &SourceAppSpecification{
+				App: &armappcontainers.ContainerApp{},
+			},
+		}
+		err := app.SetRegistryIdentity("ze-server", "system")
+		require.ErrorContains(t, err, "identity \"system\" for the server \"ze-server\" is not assigned")
+	}
+
+	// fails with server already exists
+	{
+		app := SourceApp{
+			Specification: &SourceAppSpecification{
+				App: &armappcontainers.ContainerApp{
+					Identity: &armappcontainers.ManagedServiceIdentity{
+						Type: toPtr(armappcontainers.ManagedServiceIdentityTypeSystemAssigned),
+					},
+					Properties: &armappcontainers.ContainerAppProperties{
+						Configuration: &armappcontainers.Configuration{
+							Registries: []*armappcontainers.RegistryCredentials{
+								{
+									Server:   toPtr("ze-server"),
+									Identity: toPtr("system"),
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+		err := app.SetRegistryIdentity("ze-server", "system")
+		require.ErrorContains(t, err, "the server \"ze-server\" already exists")
+	}
+
+	// working with user-assigned identity and a registry with password
+	{
+		identity := "/subscriptions/ze-sub/resourceGroups/ze-rg/providers/Microsoft.ManagedIdentity/userAssignedIdentities/foo"
+		app := SourceApp{
+			Specification: &SourceAppSpecification{
+				App: &armappcontainers.ContainerApp{
+					Identity: &armappcontainers.ManagedServiceIdentity{
+						Type: toPtr(armappcontainers.ManagedServiceIdentityTypeUserAssigned),
+						UserAssignedIdentities: map[string]*armappcontainers.UserAssignedIdentity{
+							identity: {},
+						},
+					},
+				},
+			},
+		}
+		err := app.SetRegistry("ze-server", "foo", "bar")
+		require.NoError(t, err)
+		err = app.SetRegistry("ze-other-server", "foo", "baz")
+		require.NoError(t, err)
+		err = app.SetRegistryIdentity("ze-identity-server", identity)
+		require.NoError(t, err)
+		registries := app.Specification.App.Properties.Configuration.Registries
+		require.Len(t, registries, 3)
+		require.Equal(t, "azcagit-reg-cred", *registries[0].PasswordSecretRef)
+		require.Equal(t, "azcagit-reg-cred-2", *registries[1].PasswordSecretRef)
+		require.Equal(t, "ze-identity-server", *registries[2].Server)
+		require.Equal(t, identity, *registries[2].Identity)
+		require.Nil(t, registries[2].PasswordSecretRef)
+		require.Len(t, app.Specification.App.Properties.Configuration.Secrets, 2)
+	}
+}
+
+func TestSourceAppGetImageRegistryServers(t *testing.T) {
+	app := SourceApp{
+		Specification: &SourceAppSpecification{
+			App: &armappcontainers.ContainerApp{
+				Properties: &armappcontainers.ContainerAppProperties{
+					Template: &armappcontainers.Template{
+						Containers: []*armappcontainers.Container{
+							{Image: toPtr("foobar.azurecr.io/foo:v1")},
+							{Image: toPtr("nginx:latest")},
+							{Image: toPtr("library/nginx:latest")},
+							{Image: toPtr("localhost:5000/foo")},
+						},
+						InitContainers: []*armappcontainers.InitContainer{
+							{Image: toPtr("foobar.azurecr.io/bar:v1")},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	require.Equal(t, []string{"docker.io", "foobar.azurecr.io", "localhost:5000"}, app.GetImageRegistryServers())
+	require.Equal(t, []string{}, (&SourceApp{}).GetImageRegistryServers())
+}
+
+func TestSourceAppSetCustomDomain(t *testing.T) {
+	// fails with app is nil
+	{
+		app := SourceApp{}
+		err := app.SetCustomDomain("foo.example.com", armappcontainers.BindingTypeDisabled, nil)
+		require.ErrorContains(t, err, "app is nil")
+	}
+
+	// fails without ingress
+	{
+		app := SourceApp{
+			Specification: &SourceAppSpecification{
+				App: &armappcontainers.ContainerApp{},
+			},
+		}
+		err := app.SetCustomDomain("foo.example.com", armappcontainers.BindingTypeDisabled, nil)
+		require.ErrorContains(t, err, "custom domain \"foo.example.com\" requires ingress to be configured")
+	}
+
+	// fails with custom domain already exists
+	{
+		app := SourceApp{
+			Specification: &SourceAppSpecification{
+				App: &armappcontainers.ContainerApp{
+					Properties: &armappcontainers.ContainerAppProperties{
+						Configuration: &armappcontainers.Configuration{
+							Ingress: &armappcontainers.Ingress{
+								CustomDomains: []*armappcontainers.CustomDomain{
+									{
+										Name: toPtr("FOO.example.com"),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+		err := app.SetCustomDomain("foo.example.com", armappcontainers.BindingTypeDisabled, nil)
+		require.ErrorContains(t, err, "the custom domain \"foo.example.com\" already exists")
+	}
+
+	// working with SourceApps
+	{
+		app := SourceApp{
+			Specification: &SourceAppSpecification{
+				App: &armappcontainers.ContainerApp{
+					Properties: &armappcontainers.ContainerAppProperties{
+						Configuration: &armappcontainers.Configuration{
+							Ingress: &armappcontainers.Ingress{},
+						},
+					},
+				},
+			},
+		}
+		apps := make(SourceApps)
+		apps["foo"] = app
+
+		err := apps.SetCustomDomain("foo", "foo.example.com", armappcontainers.BindingTypeSniEnabled, toPtr("ze-certificate-id"))
+		require.NoError(t, err)
+
+		updatedApp, ok := apps["foo"]
+		require.True(t, ok)
+		customDomains := updatedApp.Specification.App.Properties.Configuration.Ingress.CustomDomains
+		require.Len(t, customDomains, 1)
+		require.Equal(t, "foo.example.com", *customDomains[0].Name)
+		require.Equal(t, armappcontainers.BindingTypeSniEnabled, *customDomains[0].BindingType)
+		require.Equal(t, "ze-certificate-id", *customDomains[0].CertificateID)
+	}
+}
+
 func TestSourceAppsGetRemoteSecret(t *testing.T) {
 	cases := []struct {
 		testDescription string
@@ -775,6 +1003,75 @@ func TestSourceAppsGetRemoteSecret(t *testing.T) {
 				"bar",
 			},
 		},
+		{
+			testDescription: "keyvault references aren't fetched",
+			input: &SourceApps{
+				"foo": {
+					Specification: &SourceAppSpecification{
+						RemoteSecrets: []RemoteSecretSpecification{
+							{
+								SecretName:       toPtr("foo"),
+								RemoteSecretName: toPtr("bar"),
+							},
+							{
+								SecretName:       toPtr("baz"),
+								RemoteSecretName: toPtr("foobar"),
+								Identity:         toPtr("System"),
+							},
+						},
+					},
+				},
+			},
+			expectedOutput: []string{
+				"bar",
+			},
+		},
+		{
+			testDescription: "secrets from vaults",
+			input: &SourceApps{
+				"foo": {
+					Specification: &SourceAppSpecification{
+						RemoteSecrets: []RemoteSecretSpecification{
+							{
+								SecretName:       toPtr("foo"),
+								RemoteSecretName: toPtr("bar"),
+							},
+							{
+								SecretName:       toPtr("baz"),
+								RemoteSecretName: toPtr("bar"),
+								Vault:            toPtr("other"),
+							},
+						},
+					},
+				},
+			},
+			expectedOutput: []string{
+				"bar",
+				"other/bar",
+			},
+		},
+		{
+			testDescription: "vault can't be selected with the remote secret name",
+			input: &SourceApps{
+				"foo": {
+					Specification: &SourceAppSpecification{
+						RemoteSecrets: []RemoteSecretSpecification{
+							{
+								SecretName:       toPtr("foo"),
+								RemoteSecretName: toPtr("bar"),
+							},
+							{
+								SecretName:       toPtr("baz"),
+								RemoteSecretName: toPtr("other/bar"),
+							},
+						},
+					},
+				},
+			},
+			expectedOutput: []string{
+				"bar",
+			},
+		},
 	}
 
 	for i, c := range cases {
@@ -784,6 +1081,41 @@ func TestSourceAppsGetRemoteSecret(t *testing.T) {
 	}
 }
 
+func TestSourceAppsGetUniqueKeyVaultReferenceNames(t *testing.T) {
+	apps := &SourceApps{
+		"foo": {
+			Specification: &SourceAppSpecification{
+				RemoteSecrets: []RemoteSecretSpecification{
+					{
+						SecretName:       toPtr("foo"),
+						RemoteSecretName: toPtr("bar"),
+					},
+					{
+						SecretName:       toPtr("baz"),
+						RemoteSecretName: toPtr("foobar"),
+						Identity:         toPtr("System"),
+					},
+				},
+			},
+		},
+		"bar": {
+			Specification: &SourceAppSpecification{
+				RemoteSecrets: []RemoteSecretSpecification{
+					{
+						SecretName:       toPtr("baz"),
+						RemoteSecretName: toPtr("foobar"),
+						Identity:         toPtr("System"),
+						Vault:            toPtr("other"),
+					},
+				},
+			},
+		},
+	}
+
+	require.Equal(t, []string{"foobar", "other/foobar"}, apps.GetUniqueKeyVaultReferenceNames())
+	require.Equal(t, []string{"bar"}, apps.GetUniqueRemoteSecretNames())
+}
+
 func TestSourceAppParseLocationFilterSpecification(t *testing.T) {
 	cases := []struct {
 		testDescription        string
@@ -0,0 +1,310 @@
+package source
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/appcontainers/armappcontainers/v2"
+	"github.com/hashicorp/go-multierror"
+	"github.com/xenitab/azcagit/src/config"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	AzureContainerCertificateVersion = "aca.xenit.io/v1alpha2"
+	AzureContainerCertificateKind    = "AzureContainerCertificate"
+)
+
+type KeyVaultCertificateSpecification struct {
+	// RemoteSecretName is the KeyVault secret containing the certificate (PFX or PEM), for KeyVault
+	// certificates it's the same as the certificate name
+	RemoteSecretName *string `json:"remoteSecretName,omitempty" yaml:"remoteSecretName,omitempty"`
+}
+
+type ManagedCertificateSpecification struct {
+	SubjectName             *string                                                     `json:"subjectName,omitempty" yaml:"subjectName,omitempty"`
+	DomainControlValidation *armappcontainers.ManagedCertificateDomainControlValidation `json:"domainControlValidation,omitempty" yaml:"domainControlValidation,omitempty"`
+}
+
+type SourceCertificateSpecification struct {
+	KeyVaultCertificate *KeyVaultCertificateSpecification `json:"keyVaultCertificate,omitempty" yaml:"keyVaultCertificate,omitempty"`
+	ManagedCertificate  *ManagedCertificateSpecification  `json:"managedCertificate,omitempty" yaml:"managedCertificate,omitempty"`
+	LocationFilter      []LocationFilterSpecification     `json:"locationFilter,omitempty" yaml:"locationFilter,omitempty"`
+}
+
+type SourceCertificate struct {
+	Kind          string                          `json:"kind,omitempty" yaml:"kind,omitempty"`
+	APIVersion    string                          `json:"apiVersion,omitempty" yaml:"apiVersion,omitempty"`
+	Metadata      map[string]string               `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+	Specification *SourceCertificateSpecification `json:"spec,omitempty" yaml:"spec,omitempty"`
+	Err           error
+	// tenant is the policy of the tenant with the path of the manifest, nil if it isn't in the path of a tenant
+	tenant *TenantPolicy
+}
+
+func (certificate *SourceCertificate) Error() error {
+	return certificate.Err
+}
+
+func (certificate *SourceCertificate) Name() string {
+	if certificate.Metadata == nil {
+		return ""
+	}
+
+	name, ok := certificate.Metadata["name"]
+	if !ok {
+		return ""
+	}
+
+	return name
+}
+
+func (certificate *SourceCertificate) IsManagedCertificate() bool {
+	return certificate != nil && certificate.Specification != nil && certificate.Specification.ManagedCertificate != nil
+}
+
+func (certificate *SourceCertificate) GetRemoteSecrets() []RemoteSecretSpecification {
+	if certificate == nil || certificate.Specification == nil || certificate.Specification.KeyVaultCertificate == nil {
+		return []RemoteSecretSpecification{}
+	}
+
+	secret := RemoteSecretSpecification{
+		SecretName:       toPtr(certificate.Name()),
+		RemoteSecretName: certificate.Specification.KeyVaultCertificate.RemoteSecretName,
+	}
+	if !secret.Valid() {
+		return []RemoteSecretSpecification{}
+	}
+
+	return []RemoteSecretSpecification{secret}
+}
+
+func (certificate *SourceCertificate) ValidateFields() error {
+	var result *multierror.Error
+	if certificate.Kind == "" {
+		return fmt.Errorf("kind is missing")
+	}
+	if certificate.Kind != "" && certificate.Kind != AzureContainerCertificateKind {
+		return fmt.Errorf("kind not AzureContainerCertificate")
+	}
+	requiredVersion := AzureContainerCertificateVersion
+	if certificate.APIVersion != "" && certificate.APIVersion != requiredVersion {
+		result = multierror.Append(fmt.Errorf("apiVersion for %s should be %s", certificate.Kind, requiredVersion), result)
+	}
+
+	if certificate.Specification == nil {
+		result = multierror.Append(fmt.Errorf("spec is missing"), result)
+	}
+
+	if certificate.Specification != nil {
+		spec := certificate.Specification
+		if (spec.KeyVaultCertificate == nil) == (spec.ManagedCertificate == nil) {
+			result = multierror.Append(fmt.Errorf("exactly one of keyVaultCertificate or managedCertificate is required"), result)
+		}
+
+		if spec.KeyVaultCertificate != nil && (spec.KeyVaultCertificate.RemoteSecretName == nil || *spec.KeyVaultCertificate.RemoteSecretName == "") {
+			result = multierror.Append(fmt.Errorf("keyVaultCertificate.remoteSecretName is missing"), result)
+		}
+
+		if spec.KeyVaultCertificate != nil && spec.KeyVaultCertificate.RemoteSecretName != nil && strings.Contains(*spec.KeyVaultCertificate.RemoteSecretName, "/") {
+			result = multierror.Append(fmt.Errorf("keyVaultCertificate.remoteSecretName %q can't contain /", *spec.KeyVaultCertificate.RemoteSecretName), result)
+		}
+
+		if spec.ManagedCertificate != nil && (spec.ManagedCertificate.SubjectName == nil || *spec.ManagedCertificate.SubjectName == "") {
+			result = multierror.Append(fmt.Errorf("managedCertificate.subjectName is missing"), result)
+		}
+	}
+
+	err := certificate.validatePolicy()
+	if err != nil {
+		result = multierror.Append(err, result)
+	}
+
+	if certificate.Metadata == nil {
+		result = multierror.Append(fmt.Errorf("metadata is missing"), result)
+	}
+
+	if certificate.Metadata != nil {
+		_, ok := certificate.Metadata["name"]
+		if !ok {
+			result = multierror.Append(fmt.Errorf("name missing from metadata"), result)
+		}
+	}
+
+	return result.ErrorOrNil()
+}
+
+func (certificate *SourceCertificate) validatePolicy() error {
+	if certificate.tenant == nil {
+		return nil
+	}
+
+	return certificate.tenant.validate(nil, nil, nil, nil, certificate.GetRemoteSecrets())
+}
+
+func validateJsonIsCertificateKind(j []byte) (bool, error) {
+	dec := json.NewDecoder(bytes.NewReader(j))
+	var newcertificate struct {
+		Kind string `json:"kind,omitempty"`
+	}
+	err := dec.Decode(&newcertificate)
+	if err != nil {
+		return false, err
+	}
+
+	if newcertificate.Kind == "" {
+		return false, fmt.Errorf("kind is missing")
+	}
+
+	if newcertificate.Kind != "" && newcertificate.Kind != AzureContainerCertificateKind {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+func (certificate *SourceCertificate) Unmarshal(y []byte, cfg config.ReconcileConfig) (bool, error) {
+	j, err := yaml.YAMLToJSON(y)
+	if err != nil {
+		return true, err
+	}
+
+	isCertificate, err := validateJsonIsCertificateKind(j)
+	if err != nil {
+		return isCertificate, err
+	}
+
+	if !isCertificate {
+		return false, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(j))
+	dec.DisallowUnknownFields()
+	var newcertificate SourceCertificate
+	err = dec.Decode(&newcertificate)
+	if err != nil {
+		return true, err
+	}
+
+	newcertificate.tenant = certificate.tenant
+	err = newcertificate.ValidateFields()
+	if err != nil {
+		return true, err
+	}
+
+	if newcertificate.Specification.ManagedCertificate != nil && newcertificate.Specification.ManagedCertificate.DomainControlValidation == nil {
+		newcertificate.Specification.ManagedCertificate.DomainControlValidation = toPtr(armappcontainers.ManagedCertificateDomainControlValidationCNAME)
+	}
+
+	if len(newcertificate.Specification.LocationFilter) != 0 {
+		sanitizedLocationFilters := []LocationFilterSpecification{}
+		for _, filter := range newcertificate.Specification.LocationFilter {
+			sanitizedLocationFilters = append(sanitizedLocationFilters, LocationFilterSpecification(sanitizeAzureLocation(filter)))
+		}
+		newcertificate.Specification.LocationFilter = sanitizedLocationFilters
+	}
+
+	*certificate = newcertificate
+	return true, nil
+}
+
+func (certificate *SourceCertificate) ShoudRunInLocation(currentLocation string) bool {
+	if certificate == nil || certificate.Specification == nil || len(certificate.Specification.LocationFilter) == 0 {
+		return true
+	}
+
+	fixedCurrentLocation := sanitizeAzureLocation(LocationFilterSpecification(currentLocation))
+	for _, filter := range certificate.Specification.LocationFilter {
+		if fixedCurrentLocation == filter {
+			return true
+		}
+	}
+
+	return false
+}
+
+type SourceCertificates map[string]SourceCertificate
+
+func (certificates *SourceCertificates) Unmarshal(path string, y []byte, cfg config.ReconcileConfig, policy *Policy) {
+	if certificates == nil {
+		certificates = toPtr(make(SourceCertificates))
+	}
+	parts := strings.Split(string(y), "---")
+	for i, part := range parts {
+		certificate := SourceCertificate{
+			tenant: policy.GetTenant(path),
+		}
+		isCertificate, err := certificate.Unmarshal([]byte(part), cfg)
+		if err != nil {
+			certificate.Err = fmt.Errorf("unable to unmarshal SourceCertificate from %s (document %d): %w", path, i, err)
+			(*certificates)[fmt.Sprintf("%s-%d", path, i)] = certificate
+			continue
+		}
+		if !isCertificate {
+			continue
+		}
+		_, ok := (*certificates)[certificate.Name()]
+		if ok {
+			certificate.Err = fmt.Errorf("unable to add %s (document %d) with name %s as name is a duplicate", path, i, certificate.Name())
+			(*certificates)[fmt.Sprintf("%s-%d-%s", path, i, certificate.Name())] = certificate
+			continue
+		}
+		(*certificates)[certificate.Name()] = certificate
+	}
+}
+
+func (certificates *SourceCertificates) GetSortedNames() []string {
+	names := []string{}
+	for name, certificate := range *certificates {
+		if certificate.Error() != nil {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (certificates *SourceCertificates) Get(name string) (SourceCertificate, bool) {
+	certificate, ok := (*certificates)[name]
+	if certificate.Error() != nil {
+		return SourceCertificate{}, false
+	}
+	return certificate, ok
+}
+
+func (certificates *SourceCertificates) Delete(name string) {
+	delete(*certificates, name)
+}
+
+func (certificates *SourceCertificates) GetUniqueRemoteSecretNames() []string {
+	secretsMap := make(map[string]struct{})
+	for _, certificateName := range certificates.GetSortedNames() {
+		certificate, _ := certificates.Get(certificateName)
+		for _, remoteSecret := range certificate.GetRemoteSecrets() {
+			secretsMap[remoteSecret.SecretKey()] = struct{}{}
+		}
+	}
+
+	secrets := []string{}
+	for secret := range secretsMap {
+		secrets = append(secrets, secret)
+	}
+	sort.Strings(secrets)
+
+	return secrets
+}
+
+func (certificates *SourceCertificates) Error() error {
+	var result *multierror.Error
+	for _, certificate := range *certificates {
+		if certificate.Error() != nil {
+			result = multierror.Append(certificate.Error(), result)
+		}
+	}
+
+	return result.ErrorOrNil()
+}
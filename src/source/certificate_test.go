@@ -0,0 +1,149 @@
+package source
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/appcontainers/armappcontainers/v2"
+	"github.com/stretchr/testify/require"
+	"github.com/xenitab/azcagit/src/config"
+)
+
+func TestSourceCertificates(t *testing.T) {
+	cases := []struct {
+		testDescription string
+		rawYaml         string
+		expectedResult  SourceCertificates
+		expectedLenght  int
+		expectedError   string
+	}{
+		{
+			testDescription: "keyvault and managed certificates",
+			rawYaml: `
+kind: AzureContainerCertificate
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+ name: foo
+spec:
+  keyVaultCertificate:
+    remoteSecretName: foo-example-com
+---
+kind: AzureContainerCertificate
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+ name: bar
+spec:
+  managedCertificate:
+    subjectName: bar.example.com
+`,
+			expectedResult: SourceCertificates{
+				"foo": {
+					Kind:       "AzureContainerCertificate",
+					APIVersion: "aca.xenit.io/v1alpha2",
+					Metadata: map[string]string{
+						"name": "foo",
+					},
+					Specification: &SourceCertificateSpecification{
+						KeyVaultCertificate: &KeyVaultCertificateSpecification{
+							RemoteSecretName: toPtr("foo-example-com"),
+						},
+					},
+				},
+				"bar": {
+					Kind:       "AzureContainerCertificate",
+					APIVersion: "aca.xenit.io/v1alpha2",
+					Metadata: map[string]string{
+						"name": "bar",
+					},
+					Specification: &SourceCertificateSpecification{
+						ManagedCertificate: &ManagedCertificateSpecification{
+							SubjectName:             toPtr("bar.example.com"),
+							DomainControlValidation: toPtr(armappcontainers.ManagedCertificateDomainControlValidationCNAME),
+						},
+					},
+				},
+			},
+			expectedLenght: 2,
+			expectedError:  "",
+		},
+		{
+			testDescription: "both keyvault and managed certificate",
+			rawYaml: `
+kind: AzureContainerCertificate
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+ name: foo
+spec:
+  keyVaultCertificate:
+    remoteSecretName: foo-example-com
+  managedCertificate:
+    subjectName: foo.example.com
+`,
+			expectedResult: SourceCertificates{},
+			expectedLenght: 1,
+			expectedError:  "exactly one of keyVaultCertificate or managedCertificate is required",
+		},
+		{
+			testDescription: "subjectName missing",
+			rawYaml: `
+kind: AzureContainerCertificate
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+ name: foo
+spec:
+  managedCertificate:
+    domainControlValidation: TXT
+`,
+			expectedResult: SourceCertificates{},
+			expectedLenght: 1,
+			expectedError:  "managedCertificate.subjectName is missing",
+		},
+	}
+
+	for i, c := range cases {
+		t.Logf("Test #%d: %s", i, c.testDescription)
+		certificates := SourceCertificates{}
+		certificates.Unmarshal("foobar/baz.yaml", []byte(c.rawYaml), config.ReconcileConfig{}, nil)
+		require.Len(t, certificates, c.expectedLenght)
+		if c.expectedError != "" {
+			require.ErrorContains(t, certificates.Error(), c.expectedError)
+		} else {
+			require.NoError(t, certificates.Error())
+		}
+
+		certificatesWithoutErrors := SourceCertificates{}
+		for name, certificate := range certificates {
+			if certificate.Error() != nil {
+				continue
+			}
+			certificatesWithoutErrors[name] = certificate
+		}
+		require.Equal(t, c.expectedResult, certificatesWithoutErrors)
+	}
+}
+
+func TestSourceCertificatesGetRemoteSecret(t *testing.T) {
+	certificates := SourceCertificates{
+		"foo": {
+			Metadata: map[string]string{
+				"name": "foo",
+			},
+			Specification: &SourceCertificateSpecification{
+				KeyVaultCertificate: &KeyVaultCertificateSpecification{
+					RemoteSecretName: toPtr("remote-foo"),
+				},
+			},
+		},
+		"bar": {
+			Metadata: map[string]string{
+				"name": "bar",
+			},
+			Specification: &SourceCertificateSpecification{
+				ManagedCertificate: &ManagedCertificateSpecification{
+					SubjectName: toPtr("bar.example.com"),
+				},
+			},
+		},
+	}
+
+	require.Equal(t, []string{"remote-foo"}, certificates.GetUniqueRemoteSecretNames())
+}
@@ -1,14 +1,41 @@
 package source
 
 import (
+	"fmt"
 	"strings"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/appcontainers/armappcontainers/v2"
 	"github.com/xenitab/azcagit/src/config"
+	"github.com/xenitab/azcagit/src/secret"
 )
 
+// KeyVaultReferenceSystemIdentity is used as identity for KeyVault references using the system-assigned identity
+const KeyVaultReferenceSystemIdentity = "System"
+
 type RemoteSecretSpecification struct {
 	SecretName       *string `json:"secretName,omitempty" yaml:"secretName,omitempty"`
 	RemoteSecretName *string `json:"remoteSecretName,omitempty" yaml:"remoteSecretName,omitempty"`
+	// Identity makes the secret a KeyVault reference read by Container Apps with the identity (a resource
+	// id of a user-assigned identity or System), instead of azcagit copying the value
+	Identity *string `json:"identity,omitempty" yaml:"identity,omitempty"`
+	// Vault is the name of a configured secret vault to read the secret from, the default KeyVault is used if empty
+	Vault *string `json:"vault,omitempty" yaml:"vault,omitempty"`
+}
+
+func (r *RemoteSecretSpecification) GetVault() string {
+	if r.Vault == nil {
+		return ""
+	}
+	return *r.Vault
+}
+
+// SecretKey is the name of the remote secret in the secret client and cache
+func (r *RemoteSecretSpecification) SecretKey() string {
+	return secret.JoinName(r.GetVault(), *r.RemoteSecretName)
+}
+
+func (r *RemoteSecretSpecification) IsKeyVaultReference() bool {
+	return r.Identity != nil && *r.Identity != ""
 }
 
 func (r *RemoteSecretSpecification) Valid() bool {
@@ -18,16 +45,82 @@ func (r *RemoteSecretSpecification) Valid() bool {
 	if *r.SecretName == "" || *r.RemoteSecretName == "" {
 		return false
 	}
+	// the vault is only selected with vault, a / would make the name ambiguous with another vault
+	if strings.Contains(r.GetVault(), "/") || strings.Contains(*r.RemoteSecretName, "/") {
+		return false
+	}
 	return true
 }
 
+// validateRemoteSecretIdentities verifies that the identities used by KeyVault references are assigned
+func validateRemoteSecretIdentities(remoteSecrets []RemoteSecretSpecification, identity *armappcontainers.ManagedServiceIdentity) error {
+	for _, remoteSecret := range remoteSecrets {
+		if !remoteSecret.IsKeyVaultReference() {
+			continue
+		}
+
+		if !isIdentityAssigned(*remoteSecret.Identity, identity) {
+			secretName := ""
+			if remoteSecret.SecretName != nil {
+				secretName = *remoteSecret.SecretName
+			}
+			return fmt.Errorf("identity %q for remote secret %q is not assigned", *remoteSecret.Identity, secretName)
+		}
+	}
+
+	return nil
+}
+
+func isIdentityAssigned(identityName string, identity *armappcontainers.ManagedServiceIdentity) bool {
+	if identity == nil || identity.Type == nil {
+		return false
+	}
+
+	if strings.EqualFold(identityName, KeyVaultReferenceSystemIdentity) {
+		return *identity.Type == armappcontainers.ManagedServiceIdentityTypeSystemAssigned || *identity.Type == armappcontainers.ManagedServiceIdentityTypeSystemAssignedUserAssigned
+	}
+
+	for userAssignedIdentity := range identity.UserAssignedIdentities {
+		if strings.EqualFold(identityName, userAssignedIdentity) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validateRemoteSecretsNotKeyVaultReferences is used by kinds only supporting secret values
+func validateRemoteSecretsNotKeyVaultReferences(remoteSecrets []RemoteSecretSpecification) error {
+	for _, remoteSecret := range remoteSecrets {
+		if remoteSecret.IsKeyVaultReference() {
+			return fmt.Errorf("identity is only supported for remoteSecrets of apps and jobs")
+		}
+	}
+
+	return nil
+}
+
+// validateRemoteSecretNames rejects remote secret names with a /, the vault is only selected with vault
+func validateRemoteSecretNames(remoteSecrets []RemoteSecretSpecification) error {
+	for _, remoteSecret := range remoteSecrets {
+		if remoteSecret.RemoteSecretName != nil && strings.Contains(*remoteSecret.RemoteSecretName, "/") {
+			return fmt.Errorf("remoteSecretName %q can't contain /, use vault to read it from another vault", *remoteSecret.RemoteSecretName)
+		}
+	}
+
+	return nil
+}
+
 type LocationFilterSpecification string
-type ImageReplacementSpecification struct {
-	ImageName   *string `json:"imageName,omitempty" yaml:"imageName,omitempty"`
-	NewImageTag *string `json:"newImageTag,omitempty" yaml:"newImageTag,omitempty"`
+
+type documentHeader struct {
+	Kind     string            `json:"kind,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
 }
-type ReplacementsSpecification struct {
-	Images []ImageReplacementSpecification `json:"images,omitempty" yaml:"image,omitempty"`
+
+func isEmptyDocument(j []byte) bool {
+	trimmed := strings.TrimSpace(string(j))
+	return trimmed == "" || trimmed == "null"
 }
 
 func sanitizeAzureLocation(filter LocationFilterSpecification) LocationFilterSpecification {
@@ -36,19 +129,37 @@ func sanitizeAzureLocation(filter LocationFilterSpecification) LocationFilterSpe
 	return LocationFilterSpecification(lowercaseFilter)
 }
 
-func getSourcesFromFiles(yamlFiles *map[string][]byte, cfg config.ReconcileConfig) *Sources {
+func getSourcesFromFiles(yamlFiles *map[string][]byte, cfg config.ReconcileConfig, policy *Policy) *Sources {
 	apps := &SourceApps{}
 	for path := range *yamlFiles {
 		content := (*yamlFiles)[path]
-		apps.Unmarshal(path, content, cfg)
+		apps.Unmarshal(path, content, cfg, policy)
 	}
 	jobs := &SourceJobs{}
 	for path := range *yamlFiles {
 		content := (*yamlFiles)[path]
-		jobs.Unmarshal(path, content, cfg)
+		jobs.Unmarshal(path, content, cfg, policy)
+	}
+	daprComponents := &SourceDaprComponents{}
+	for path := range *yamlFiles {
+		content := (*yamlFiles)[path]
+		daprComponents.Unmarshal(path, content, cfg, policy)
+	}
+	certificates := &SourceCertificates{}
+	for path := range *yamlFiles {
+		content := (*yamlFiles)[path]
+		certificates.Unmarshal(path, content, cfg, policy)
+	}
+	storages := &SourceStorages{}
+	for path := range *yamlFiles {
+		content := (*yamlFiles)[path]
+		storages.Unmarshal(path, content, cfg, policy)
 	}
 	return &Sources{
-		Apps: apps,
-		Jobs: jobs,
+		Apps:           apps,
+		Jobs:           jobs,
+		DaprComponents: daprComponents,
+		Certificates:   certificates,
+		Storages:       storages,
 	}
 }
@@ -0,0 +1,358 @@
+package source
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/appcontainers/armappcontainers/v2"
+	"github.com/hashicorp/go-multierror"
+	"github.com/xenitab/azcagit/src/config"
+	"github.com/xenitab/azcagit/src/remote"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	AzureContainerDaprComponentVersion = "aca.xenit.io/v1alpha2"
+	AzureContainerDaprComponentKind    = "AzureContainerDaprComponent"
+)
+
+type SourceDaprComponentSpecification struct {
+	Component      *armappcontainers.DaprComponent `json:"component,omitempty" yaml:"component,omitempty"`
+	RemoteSecrets  []RemoteSecretSpecification     `json:"remoteSecrets,omitempty" yaml:"remoteSecrets,omitempty"`
+	LocationFilter []LocationFilterSpecification   `json:"locationFilter,omitempty" yaml:"locationFilter,omitempty"`
+}
+
+type SourceDaprComponent struct {
+	Kind          string                            `json:"kind,omitempty" yaml:"kind,omitempty"`
+	APIVersion    string                            `json:"apiVersion,omitempty" yaml:"apiVersion,omitempty"`
+	Metadata      map[string]string                 `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+	Specification *SourceDaprComponentSpecification `json:"spec,omitempty" yaml:"spec,omitempty"`
+	Err           error
+	// tenant is the policy of the tenant with the path of the manifest, nil if it isn't in the path of a tenant
+	tenant *TenantPolicy
+}
+
+func (component *SourceDaprComponent) Error() error {
+	return component.Err
+}
+
+func (component *SourceDaprComponent) Name() string {
+	if component.Metadata == nil {
+		return ""
+	}
+
+	name, ok := component.Metadata["name"]
+	if !ok {
+		return ""
+	}
+
+	return name
+}
+
+func (component *SourceDaprComponent) SetSecret(name string, value string) error {
+	if component == nil || component.Specification == nil || component.Specification.Component == nil {
+		return fmt.Errorf("component is nil")
+	}
+
+	if component.Specification.Component.Properties == nil {
+		component.Specification.Component.Properties = &armappcontainers.DaprComponentProperties{}
+	}
+
+	if component.Specification.Component.Properties.Secrets == nil {
+		component.Specification.Component.Properties.Secrets = []*armappcontainers.Secret{}
+	}
+
+	for _, v := range component.Specification.Component.Properties.Secrets {
+		if v == nil || v.Name == nil {
+			continue
+		}
+
+		if *v.Name == name {
+			return fmt.Errorf("a secret with name %q already exists", name)
+		}
+	}
+
+	component.Specification.Component.Properties.Secrets = append(component.Specification.Component.Properties.Secrets, &armappcontainers.Secret{
+		Name:  &name,
+		Value: &value,
+	})
+
+	return nil
+}
+
+func (component *SourceDaprComponent) GetRemoteSecrets() []RemoteSecretSpecification {
+	if component == nil || component.Specification == nil || len(component.Specification.RemoteSecrets) == 0 {
+		return []RemoteSecretSpecification{}
+	}
+
+	secrets := []RemoteSecretSpecification{}
+	for _, secret := range component.Specification.RemoteSecrets {
+		if !secret.Valid() {
+			continue
+		}
+		secrets = append(secrets, secret)
+	}
+
+	return secrets
+}
+
+func (component *SourceDaprComponent) ValidateFields() error {
+	var result *multierror.Error
+	if component.Kind == "" {
+		return fmt.Errorf("kind is missing")
+	}
+	if component.Kind != "" && component.Kind != AzureContainerDaprComponentKind {
+		return fmt.Errorf("kind not AzureContainerDaprComponent")
+	}
+	requiredVersion := AzureContainerDaprComponentVersion
+	if component.APIVersion != "" && component.APIVersion != requiredVersion {
+		result = multierror.Append(fmt.Errorf("apiVersion for %s should be %s", component.Kind, requiredVersion), result)
+	}
+
+	if component.Specification == nil {
+		result = multierror.Append(fmt.Errorf("spec is missing"), result)
+	}
+
+	if component.Specification != nil {
+		err := validateRemoteSecretsNotKeyVaultReferences(component.Specification.RemoteSecrets)
+		if err != nil {
+			result = multierror.Append(err, result)
+		}
+
+		err = validateRemoteSecretNames(component.Specification.RemoteSecrets)
+		if err != nil {
+			result = multierror.Append(err, result)
+		}
+	}
+
+	if component.Specification != nil && component.Specification.Component == nil {
+		result = multierror.Append(fmt.Errorf("component is missing"), result)
+	}
+
+	if component.Specification != nil && component.Specification.Component != nil && (component.Specification.Component.Properties == nil || component.Specification.Component.Properties.ComponentType == nil) {
+		result = multierror.Append(fmt.Errorf("componentType is missing"), result)
+	}
+
+	if component.Specification != nil && component.Specification.Component != nil && component.Specification.Component.Properties != nil {
+		for _, secret := range component.Specification.Component.Properties.Secrets {
+			if secret != nil && secret.Name != nil && *secret.Name == remote.DaprComponentManagedSecretName {
+				result = multierror.Append(fmt.Errorf("secret name %s is reserved by azcagit", remote.DaprComponentManagedSecretName), result)
+			}
+		}
+	}
+
+	err := component.validatePolicy()
+	if err != nil {
+		result = multierror.Append(err, result)
+	}
+
+	if component.Metadata == nil {
+		result = multierror.Append(fmt.Errorf("metadata is missing"), result)
+	}
+
+	if component.Metadata != nil {
+		_, ok := component.Metadata["name"]
+		if !ok {
+			result = multierror.Append(fmt.Errorf("name missing from metadata"), result)
+		}
+	}
+
+	return result.ErrorOrNil()
+}
+
+func (component *SourceDaprComponent) validatePolicy() error {
+	if component.tenant == nil || component.Specification == nil {
+		return nil
+	}
+
+	var secrets []*armappcontainers.Secret
+	if component.Specification.Component != nil && component.Specification.Component.Properties != nil {
+		secrets = component.Specification.Component.Properties.Secrets
+	}
+
+	return component.tenant.validate(nil, nil, nil, secrets, component.Specification.RemoteSecrets)
+}
+
+func validateJsonIsDaprComponentKind(j []byte) (bool, error) {
+	dec := json.NewDecoder(bytes.NewReader(j))
+	var newcomponent struct {
+		Kind string `json:"kind,omitempty"`
+	}
+	err := dec.Decode(&newcomponent)
+	if err != nil {
+		return false, err
+	}
+
+	if newcomponent.Kind == "" {
+		return false, fmt.Errorf("kind is missing")
+	}
+
+	if newcomponent.Kind != "" && newcomponent.Kind != AzureContainerDaprComponentKind {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+func (component *SourceDaprComponent) Unmarshal(y []byte, cfg config.ReconcileConfig) (bool, error) {
+	j, err := yaml.YAMLToJSON(y)
+	if err != nil {
+		return true, err
+	}
+
+	isDaprComponent, err := validateJsonIsDaprComponentKind(j)
+	if err != nil {
+		return isDaprComponent, err
+	}
+
+	if !isDaprComponent {
+		return false, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(j))
+	dec.DisallowUnknownFields()
+	var newcomponent SourceDaprComponent
+	err = dec.Decode(&newcomponent)
+	if err != nil {
+		return true, err
+	}
+
+	newcomponent.tenant = component.tenant
+	err = newcomponent.ValidateFields()
+	if err != nil {
+		return true, err
+	}
+
+	if len(newcomponent.Specification.LocationFilter) != 0 {
+		sanitizedLocationFilters := []LocationFilterSpecification{}
+		for _, filter := range newcomponent.Specification.LocationFilter {
+			sanitizedLocationFilters = append(sanitizedLocationFilters, LocationFilterSpecification(sanitizeAzureLocation(filter)))
+		}
+		newcomponent.Specification.LocationFilter = sanitizedLocationFilters
+	}
+
+	err = newcomponent.SetSecret(remote.DaprComponentManagedSecretName, "true")
+	if err != nil {
+		return true, err
+	}
+
+	*component = newcomponent
+	return true, nil
+}
+
+func (component *SourceDaprComponent) ShoudRunInLocation(currentLocation string) bool {
+	if component == nil || component.Specification == nil || len(component.Specification.LocationFilter) == 0 {
+		return true
+	}
+
+	fixedCurrentLocation := sanitizeAzureLocation(LocationFilterSpecification(currentLocation))
+	for _, filter := range component.Specification.LocationFilter {
+		if fixedCurrentLocation == filter {
+			return true
+		}
+	}
+
+	return false
+}
+
+type SourceDaprComponents map[string]SourceDaprComponent
+
+func (components *SourceDaprComponents) Unmarshal(path string, y []byte, cfg config.ReconcileConfig, policy *Policy) {
+	if components == nil {
+		components = toPtr(make(SourceDaprComponents))
+	}
+	parts := strings.Split(string(y), "---")
+	for i, part := range parts {
+		component := SourceDaprComponent{
+			tenant: policy.GetTenant(path),
+		}
+		isDaprComponent, err := component.Unmarshal([]byte(part), cfg)
+		if err != nil {
+			component.Err = fmt.Errorf("unable to unmarshal SourceDaprComponent from %s (document %d): %w", path, i, err)
+			(*components)[fmt.Sprintf("%s-%d", path, i)] = component
+			continue
+		}
+		if !isDaprComponent {
+			continue
+		}
+		_, ok := (*components)[component.Name()]
+		if ok {
+			component.Err = fmt.Errorf("unable to add %s (document %d) with name %s as name is a duplicate", path, i, component.Name())
+			(*components)[fmt.Sprintf("%s-%d-%s", path, i, component.Name())] = component
+			continue
+		}
+		(*components)[component.Name()] = component
+	}
+}
+
+func (components *SourceDaprComponents) GetSortedNames() []string {
+	names := []string{}
+	for name, component := range *components {
+		if component.Error() != nil {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (components *SourceDaprComponents) Get(name string) (SourceDaprComponent, bool) {
+	component, ok := (*components)[name]
+	if component.Error() != nil {
+		return SourceDaprComponent{}, false
+	}
+	return component, ok
+}
+
+func (components *SourceDaprComponents) Delete(name string) {
+	delete(*components, name)
+}
+
+func (components *SourceDaprComponents) SetSecret(name string, secretName string, secretValue string) error {
+	component, ok := (*components)[name]
+	if !ok {
+		return fmt.Errorf("no SourceDaprComponent with name %q", name)
+	}
+
+	err := component.SetSecret(secretName, secretValue)
+	if err != nil {
+		return err
+	}
+
+	(*components)[name] = component
+
+	return nil
+}
+
+func (components *SourceDaprComponents) GetUniqueRemoteSecretNames() []string {
+	secretsMap := make(map[string]struct{})
+	for _, componentName := range components.GetSortedNames() {
+		component, _ := components.Get(componentName)
+		for _, remoteSecret := range component.GetRemoteSecrets() {
+			secretsMap[remoteSecret.SecretKey()] = struct{}{}
+		}
+	}
+
+	secrets := []string{}
+	for secret := range secretsMap {
+		secrets = append(secrets, secret)
+	}
+	sort.Strings(secrets)
+
+	return secrets
+}
+
+func (components *SourceDaprComponents) Error() error {
+	var result *multierror.Error
+	for _, component := range *components {
+		if component.Error() != nil {
+			result = multierror.Append(component.Error(), result)
+		}
+	}
+
+	return result.ErrorOrNil()
+}
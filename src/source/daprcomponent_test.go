@@ -0,0 +1,269 @@
+package source
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/appcontainers/armappcontainers/v2"
+	"github.com/stretchr/testify/require"
+	"github.com/xenitab/azcagit/src/config"
+)
+
+func TestSourceDaprComponents(t *testing.T) {
+	cases := []struct {
+		testDescription string
+		rawYaml         string
+		expectedResult  SourceDaprComponents
+		expectedLenght  int
+		expectedError   string
+	}{
+		{
+			testDescription: "plain working, single document",
+			rawYaml: `
+kind: AzureContainerDaprComponent
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+ name: statestore
+spec:
+  component:
+    properties:
+      componentType: state.azure.blobstorage
+      version: v1
+      scopes:
+        - foo
+`,
+			expectedResult: SourceDaprComponents{
+				"statestore": {
+					Kind:       "AzureContainerDaprComponent",
+					APIVersion: "aca.xenit.io/v1alpha2",
+					Metadata: map[string]string{
+						"name": "statestore",
+					},
+					Specification: &SourceDaprComponentSpecification{
+						Component: &armappcontainers.DaprComponent{
+							Properties: &armappcontainers.DaprComponentProperties{
+								ComponentType: toPtr("state.azure.blobstorage"),
+								Version:       toPtr("v1"),
+								Scopes:        []*string{toPtr("foo")},
+								Secrets: []*armappcontainers.Secret{
+									{
+										Name:  toPtr("azcagit-managed"),
+										Value: toPtr("true"),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedLenght: 1,
+			expectedError:  "",
+		},
+		{
+			testDescription: "other kinds are ignored",
+			rawYaml: `
+kind: AzureContainerDaprComponent
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+ name: statestore
+spec:
+  component:
+    properties:
+      componentType: state.azure.blobstorage
+---
+kind: AzureContainerApp
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+ name: foo
+spec:
+  app: {}
+`,
+			expectedResult: SourceDaprComponents{
+				"statestore": {
+					Kind:       "AzureContainerDaprComponent",
+					APIVersion: "aca.xenit.io/v1alpha2",
+					Metadata: map[string]string{
+						"name": "statestore",
+					},
+					Specification: &SourceDaprComponentSpecification{
+						Component: &armappcontainers.DaprComponent{
+							Properties: &armappcontainers.DaprComponentProperties{
+								ComponentType: toPtr("state.azure.blobstorage"),
+								Secrets: []*armappcontainers.Secret{
+									{
+										Name:  toPtr("azcagit-managed"),
+										Value: toPtr("true"),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedLenght: 1,
+			expectedError:  "",
+		},
+		{
+			testDescription: "componentType missing",
+			rawYaml: `
+kind: AzureContainerDaprComponent
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+ name: statestore
+spec:
+  component:
+    properties:
+      version: v1
+`,
+			expectedResult: SourceDaprComponents{},
+			expectedLenght: 1,
+			expectedError:  "componentType is missing",
+		},
+		{
+			testDescription: "reserved secret name",
+			rawYaml: `
+kind: AzureContainerDaprComponent
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+ name: statestore
+spec:
+  component:
+    properties:
+      componentType: state.azure.blobstorage
+      secrets:
+        - name: azcagit-managed
+          value: "false"
+`,
+			expectedResult: SourceDaprComponents{},
+			expectedLenght: 1,
+			expectedError:  "secret name azcagit-managed is reserved by azcagit",
+		},
+		{
+			testDescription: "vault in the remote secret name",
+			rawYaml: `
+kind: AzureContainerDaprComponent
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+ name: statestore
+spec:
+  remoteSecrets:
+    - secretName: storage-account-key
+      remoteSecretName: other/storage-account-key
+  component:
+    properties:
+      componentType: state.azure.blobstorage
+`,
+			expectedResult: SourceDaprComponents{},
+			expectedLenght: 1,
+			expectedError:  "remoteSecretName \"other/storage-account-key\" can't contain /, use vault to read it from another vault",
+		},
+		{
+			testDescription: "duplicate name",
+			rawYaml: `
+kind: AzureContainerDaprComponent
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+ name: statestore
+spec:
+  component:
+    properties:
+      componentType: state.azure.blobstorage
+---
+kind: AzureContainerDaprComponent
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+ name: statestore
+spec:
+  component:
+    properties:
+      componentType: state.azure.blobstorage
+`,
+			expectedResult: SourceDaprComponents{
+				"statestore": {
+					Kind:       "AzureContainerDaprComponent",
+					APIVersion: "aca.xenit.io/v1alpha2",
+					Metadata: map[string]string{
+						"name": "statestore",
+					},
+					Specification: &SourceDaprComponentSpecification{
+						Component: &armappcontainers.DaprComponent{
+							Properties: &armappcontainers.DaprComponentProperties{
+								ComponentType: toPtr("state.azure.blobstorage"),
+								Secrets: []*armappcontainers.Secret{
+									{
+										Name:  toPtr("azcagit-managed"),
+										Value: toPtr("true"),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedLenght: 2,
+			expectedError:  "as name is a duplicate",
+		},
+	}
+
+	for i, c := range cases {
+		t.Logf("Test #%d: %s", i, c.testDescription)
+		components := SourceDaprComponents{}
+		components.Unmarshal("foobar/baz.yaml", []byte(c.rawYaml), config.ReconcileConfig{
+			Location:             "ze-location",
+			ManagedEnvironmentID: "ze-EnvironmentID",
+		}, nil)
+		require.Len(t, components, c.expectedLenght)
+		if c.expectedError != "" {
+			require.ErrorContains(t, components.Error(), c.expectedError)
+		} else {
+			require.NoError(t, components.Error())
+		}
+
+		componentsWithoutErrors := SourceDaprComponents{}
+		for name, component := range components {
+			if component.Error() != nil {
+				continue
+			}
+			componentsWithoutErrors[name] = component
+		}
+		require.Equal(t, c.expectedResult, componentsWithoutErrors)
+	}
+}
+
+func TestSourceDaprComponentsGetRemoteSecret(t *testing.T) {
+	components := SourceDaprComponents{
+		"foo": {
+			Specification: &SourceDaprComponentSpecification{
+				RemoteSecrets: []RemoteSecretSpecification{
+					{
+						SecretName:       toPtr("foo"),
+						RemoteSecretName: toPtr("remote-foo"),
+					},
+					{
+						SecretName: toPtr("invalid"),
+					},
+				},
+			},
+		},
+		"bar": {
+			Specification: &SourceDaprComponentSpecification{
+				RemoteSecrets: []RemoteSecretSpecification{
+					{
+						SecretName:       toPtr("foo"),
+						RemoteSecretName: toPtr("remote-foo"),
+					},
+					{
+						SecretName:       toPtr("bar"),
+						RemoteSecretName: toPtr("remote-bar"),
+					},
+				},
+			},
+		},
+	}
+
+	require.Equal(t, []string{"remote-bar", "remote-foo"}, components.GetUniqueRemoteSecretNames())
+
+	sources := Sources{
+		DaprComponents: &components,
+	}
+	require.Equal(t, []string{"remote-bar", "remote-foo"}, sources.GetUniqueRemoteSecretNames())
+}
@@ -0,0 +1,105 @@
+package source
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/appcontainers/armappcontainers/v2"
+)
+
+// ImageDigestResolver returns the digest for the image name and tag
+type ImageDigestResolver func(ctx context.Context, imageName string, tag string) (string, error)
+
+// NewCachedImageDigestResolver returns a resolver asking resolve once for every image name and tag, it's used
+// for the apps and jobs of a single reconcile
+func NewCachedImageDigestResolver(resolve ImageDigestResolver) ImageDigestResolver {
+	digests := make(map[string]string)
+	errs := make(map[string]error)
+	return func(ctx context.Context, imageName string, tag string) (string, error) {
+		key := fmt.Sprintf("%s:%s", imageName, tag)
+		if err, ok := errs[key]; ok {
+			return "", err
+		}
+
+		digest, ok := digests[key]
+		if ok {
+			return digest, nil
+		}
+
+		digest, err := resolve(ctx, imageName, tag)
+		if err != nil {
+			errs[key] = err
+			return "", err
+		}
+
+		digests[key] = digest
+		return digest, nil
+	}
+}
+
+// ResolveImageDigests pins the images of the apps to the digests of their tags (as name:tag@digest), making the
+// app change when a mutable tag (like latest) is moved. Images that already have a digest are kept. The apps with
+// images that can't be resolved are returned with the error, the other apps are still resolved.
+func (apps *SourceApps) ResolveImageDigests(ctx context.Context, resolve ImageDigestResolver) map[string]error {
+	failed := make(map[string]error)
+	for _, name := range apps.GetSortedNames() {
+		app, _ := apps.Get(name)
+		if app.Specification == nil || app.Specification.App == nil || app.Specification.App.Properties == nil || app.Specification.App.Properties.Template == nil {
+			continue
+		}
+
+		template := app.Specification.App.Properties.Template
+		err := resolveContainerImageDigests(ctx, template.Containers, template.InitContainers, resolve)
+		if err != nil {
+			failed[name] = err
+		}
+	}
+
+	return failed
+}
+
+// ResolveImageDigests pins the images of the jobs to the digests of their tags, see SourceApps.ResolveImageDigests
+func (jobs *SourceJobs) ResolveImageDigests(ctx context.Context, resolve ImageDigestResolver) map[string]error {
+	failed := make(map[string]error)
+	for _, name := range jobs.GetSortedNames() {
+		job, _ := jobs.Get(name)
+		if job.Specification == nil || job.Specification.Job == nil || job.Specification.Job.Properties == nil || job.Specification.Job.Properties.Template == nil {
+			continue
+		}
+
+		template := job.Specification.Job.Properties.Template
+		err := resolveContainerImageDigests(ctx, template.Containers, template.InitContainers, resolve)
+		if err != nil {
+			failed[name] = err
+		}
+	}
+
+	return failed
+}
+
+func resolveContainerImageDigests(ctx context.Context, containers []*armappcontainers.Container, initContainers []*armappcontainers.InitContainer, resolve ImageDigestResolver) error {
+	for _, container := range toReplacementContainers(containers, initContainers) {
+		image := container.image
+		if image == nil || *image == "" {
+			continue
+		}
+
+		imageName, tag, digest := parseImageReference(*image)
+		if digest != "" {
+			continue
+		}
+
+		if tag == "" {
+			tag = "latest"
+		}
+
+		digest, err := resolve(ctx, imageName, tag)
+		if err != nil {
+			return fmt.Errorf("unable to resolve digest for %s:%s: %w", imageName, tag, err)
+		}
+
+		*image = fmt.Sprintf("%s:%s@%s", imageName, tag, digest)
+	}
+
+	return nil
+}
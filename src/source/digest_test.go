@@ -0,0 +1,126 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xenitab/azcagit/src/config"
+)
+
+func TestResolveImageDigests(t *testing.T) {
+	files := map[string][]byte{
+		"app.yaml": []byte(`
+kind: AzureContainerApp
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+  name: foo
+spec:
+  app:
+    properties:
+      template:
+        initContainers:
+        - name: init
+          image: myregistry:5000/init
+        containers:
+        - name: foo
+          image: myregistry:5000/foo:v1
+        - name: bar
+          image: bar@sha256:pinned
+`),
+		"job.yaml": []byte(`
+kind: AzureContainerJob
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+  name: foo
+spec:
+  job:
+    properties:
+      configuration:
+        triggerType: Manual
+      template:
+        containers:
+        - name: foo
+          image: myregistry:5000/foo:v1
+`),
+	}
+	sources := getSourcesFromFiles(&files, config.ReconcileConfig{
+		Location:             "ze-location",
+		ManagedEnvironmentID: "ze-managedEnvironmentID",
+	}, nil)
+	require.NoError(t, sources.Apps.Error())
+	require.NoError(t, sources.Jobs.Error())
+
+	resolved := []string{}
+	resolve := NewCachedImageDigestResolver(func(ctx context.Context, imageName string, tag string) (string, error) {
+		resolved = append(resolved, fmt.Sprintf("%s:%s", imageName, tag))
+		return fmt.Sprintf("sha256:%s", tag), nil
+	})
+	require.Empty(t, sources.Apps.ResolveImageDigests(context.Background(), resolve))
+	require.Empty(t, sources.Jobs.ResolveImageDigests(context.Background(), resolve))
+	require.ElementsMatch(t, []string{"myregistry:5000/foo:v1", "myregistry:5000/init:latest"}, resolved, "every image is resolved once")
+
+	app, ok := sources.Apps.Get("foo")
+	require.True(t, ok)
+	require.Equal(t, "myregistry:5000/foo:v1@sha256:v1", *app.Specification.App.Properties.Template.Containers[0].Image)
+	require.Equal(t, "bar@sha256:pinned", *app.Specification.App.Properties.Template.Containers[1].Image)
+	require.Equal(t, "myregistry:5000/init:latest@sha256:latest", *app.Specification.App.Properties.Template.InitContainers[0].Image)
+
+	job, ok := sources.Jobs.Get("foo")
+	require.True(t, ok)
+	require.Equal(t, "myregistry:5000/foo:v1@sha256:v1", *job.Specification.Job.Properties.Template.Containers[0].Image)
+
+	notFound := func(ctx context.Context, imageName string, tag string) (string, error) {
+		return "", fmt.Errorf("not found")
+	}
+	require.Empty(t, sources.Apps.ResolveImageDigests(context.Background(), notFound), "images already resolved")
+}
+
+func TestResolveImageDigestsFailure(t *testing.T) {
+	files := map[string][]byte{
+		"app.yaml": []byte(`
+kind: AzureContainerApp
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+  name: foo
+spec:
+  app:
+    properties:
+      template:
+        containers:
+        - name: foo
+          image: myregistry:5000/foo:v1
+---
+kind: AzureContainerApp
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+  name: bar
+spec:
+  app:
+    properties:
+      template:
+        containers:
+        - name: bar
+          image: myregistry:5000/bar:v1
+`),
+	}
+	sources := getSourcesFromFiles(&files, config.ReconcileConfig{
+		Location:             "ze-location",
+		ManagedEnvironmentID: "ze-managedEnvironmentID",
+	}, nil)
+	require.NoError(t, sources.Apps.Error())
+
+	failed := sources.Apps.ResolveImageDigests(context.Background(), func(ctx context.Context, imageName string, tag string) (string, error) {
+		if imageName == "myregistry:5000/foo" {
+			return "", fmt.Errorf("not found")
+		}
+		return "sha256:bar", nil
+	})
+	require.Len(t, failed, 1)
+	require.ErrorContains(t, failed["foo"], "unable to resolve digest for myregistry:5000/foo:v1: not found")
+
+	app, ok := sources.Apps.Get("bar")
+	require.True(t, ok)
+	require.Equal(t, "myregistry:5000/bar:v1@sha256:bar", *app.Specification.App.Properties.Template.Containers[0].Image)
+}
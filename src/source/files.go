@@ -1,9 +1,13 @@
 package source
 
 import (
+	"fmt"
 	"io/fs"
 	"os"
+	"path"
 	"strings"
+
+	"github.com/xenitab/azcagit/src/secret"
 )
 
 func listYamlFromPath(path string) (*map[string][]byte, error) {
@@ -38,3 +42,33 @@ func listYamlFromFS(fsys fs.FS) (*map[string][]byte, error) {
 
 	return &files, nil
 }
+
+// loadSecretFiles loads the files used by the secret client (paths relative to the repository root)
+// and removes them from the yaml files
+func loadSecretFiles(fsys fs.FS, yamlPath string, yamlFiles *map[string][]byte, secretClient secret.Secret) error {
+	fileLoader, ok := secretClient.(secret.FileLoader)
+	if !ok {
+		return nil
+	}
+
+	yamlDir := strings.TrimPrefix(path.Clean("/"+yamlPath), "/")
+	files := make(map[string][]byte)
+	for _, filePath := range fileLoader.FilePaths() {
+		cleanPath := strings.TrimPrefix(path.Clean("/"+filePath), "/")
+		b, err := fs.ReadFile(fsys, cleanPath)
+		if err != nil {
+			return fmt.Errorf("unable to read secret file %q: %w", filePath, err)
+		}
+
+		files[filePath] = b
+
+		switch {
+		case yamlDir == "":
+			delete(*yamlFiles, cleanPath)
+		case strings.HasPrefix(cleanPath, yamlDir+"/"):
+			delete(*yamlFiles, strings.TrimPrefix(cleanPath, yamlDir+"/"))
+		}
+	}
+
+	return fileLoader.LoadFiles(files)
+}
@@ -5,6 +5,7 @@ import (
 	"testing/fstest"
 
 	"github.com/stretchr/testify/require"
+	"github.com/xenitab/azcagit/src/secret"
 )
 
 func TestListYamlFromFS(t *testing.T) {
@@ -29,3 +30,50 @@ func TestListYamlFromFS(t *testing.T) {
 	require.NoError(t, err)
 	require.Len(t, *yamlFiles, 3)
 }
+
+type testFileLoaderSecret struct {
+	*secret.InMemSecret
+	paths []string
+	files map[string][]byte
+}
+
+func (s *testFileLoaderSecret) FilePaths() []string {
+	return s.paths
+}
+
+func (s *testFileLoaderSecret) LoadFiles(files map[string][]byte) error {
+	s.files = files
+	return nil
+}
+
+func TestLoadSecretFiles(t *testing.T) {
+	fsys := fstest.MapFS{
+		"apps/foo.yaml": {
+			Data: []byte("foo"),
+		},
+		"apps/secrets.enc.yaml": {
+			Data: []byte("secrets"),
+		},
+	}
+
+	yamlFiles := map[string][]byte{
+		"foo.yaml":         []byte("foo"),
+		"secrets.enc.yaml": []byte("secrets"),
+	}
+	err := loadSecretFiles(fsys, "apps", &yamlFiles, secret.NewInMemSecret())
+	require.NoError(t, err)
+	require.Len(t, yamlFiles, 2)
+
+	secretClient := &testFileLoaderSecret{
+		InMemSecret: secret.NewInMemSecret(),
+		paths:       []string{"apps/secrets.enc.yaml"},
+	}
+	err = loadSecretFiles(fsys, "apps", &yamlFiles, secretClient)
+	require.NoError(t, err)
+	require.Equal(t, map[string][]byte{"foo.yaml": []byte("foo")}, yamlFiles)
+	require.Equal(t, map[string][]byte{"apps/secrets.enc.yaml": []byte("secrets")}, secretClient.files)
+
+	secretClient.paths = []string{"missing.yaml"}
+	err = loadSecretFiles(fsys, "", &yamlFiles, secretClient)
+	require.ErrorContains(t, err, "unable to read secret file \"missing.yaml\"")
+}
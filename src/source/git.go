@@ -3,6 +3,7 @@ package source
 import (
 	"context"
 	"fmt"
+	"maps"
 	"net/url"
 	"os"
 	"path/filepath"
@@ -14,52 +15,211 @@ import (
 	"github.com/go-logr/logr"
 	"github.com/xenitab/azcagit/src/cache"
 	"github.com/xenitab/azcagit/src/config"
+	"github.com/xenitab/azcagit/src/secret"
 )
 
 type GitSource struct {
 	cfg           config.ReconcileConfig
 	revisionCache cache.RevisionCache
+	secretClient  secret.Secret
+	// shared is set by ForTarget to the source the checkout of the repository is read from
+	shared       *GitSource
+	lastCheckout *gitCheckout
+}
+
+type gitCheckout struct {
+	yamlFiles map[string][]byte
+	policy    *Policy
+	revision  string
 }
 
 var _ Source = (*GitSource)(nil)
 
-func NewGitSource(cfg config.ReconcileConfig, revisionCache cache.RevisionCache) (*GitSource, error) {
+func NewGitSource(cfg config.ReconcileConfig, revisionCache cache.RevisionCache, secretClient secret.Secret) (*GitSource, error) {
 	return &GitSource{
 		cfg,
 		revisionCache,
+		secretClient,
+		nil,
+		nil,
 	}, nil
 }
 
+// ForTarget returns the source of a target, which uses the last checkout of s instead of cloning the
+// repository again. The manifests are still filtered and have their variables substituted for the target.
+func (s *GitSource) ForTarget(cfg config.ReconcileConfig, revisionCache cache.RevisionCache) *GitSource {
+	return &GitSource{
+		cfg:           cfg,
+		revisionCache: revisionCache,
+		secretClient:  s.secretClient,
+		shared:        s,
+	}
+}
+
 func (s *GitSource) Get(ctx context.Context) (*Sources, string, error) {
-	yamlFiles, revision, err := s.checkout(ctx)
+	yamlFiles, policy, revision, err := s.getCheckout(ctx)
 	if err != nil {
 		return nil, "", err
 	}
 
-	sources := getSourcesFromFiles(yamlFiles, s.cfg)
+	err = s.setRevision(ctx, revision)
+	if err != nil {
+		return nil, revision, err
+	}
+
+	yamlFiles, variables, err := getVariables(ctx, yamlFiles, s.cfg, s.secretClient)
+	if err != nil {
+		return nil, revision, err
+	}
+
+	yamlFiles, err = substituteVariables(yamlFiles, variables)
+	if err != nil {
+		return nil, revision, err
+	}
+
+	yamlFiles, err = expandTemplates(yamlFiles, s.cfg)
+	if err != nil {
+		return nil, revision, err
+	}
+
+	yamlFiles, err = applyOverlays(yamlFiles, s.cfg)
+	if err != nil {
+		return nil, revision, err
+	}
+
+	yamlFiles, err = filterTarget(yamlFiles, s.cfg)
+	if err != nil {
+		return nil, revision, err
+	}
+
+	sources := getSourcesFromFiles(yamlFiles, s.cfg, policy)
 	return sources, revision, nil
 }
 
-func (s *GitSource) checkout(ctx context.Context) (*map[string][]byte, string, error) {
+// getCheckout returns a copy of the last checkout of the shared source, the repository is only cloned when
+// there isn't a shared source or it hasn't been checked out
+func (s *GitSource) getCheckout(ctx context.Context) (*map[string][]byte, *Policy, string, error) {
+	if s.shared == nil || s.shared.lastCheckout == nil {
+		return s.checkout(ctx)
+	}
+
+	yamlFiles := maps.Clone(s.shared.lastCheckout.yamlFiles)
+	return &yamlFiles, s.shared.lastCheckout.policy, s.shared.lastCheckout.revision, nil
+}
+
+func (s *GitSource) setRevision(ctx context.Context, revision string) error {
+	log := logr.FromContextOrDiscard(ctx)
+
+	lastRevision, err := s.revisionCache.Get(ctx)
+	if err != nil {
+		return err
+	}
+
+	if revision != lastRevision {
+		log.Info("new commit hash", "new_revision", revision, "last_revision", lastRevision)
+
+		err := s.revisionCache.Set(ctx, revision)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *GitSource) checkout(ctx context.Context) (*map[string][]byte, *Policy, string, error) {
 	log := logr.FromContextOrDiscard(ctx)
 
+	// the targets shouldn't use an earlier checkout when this one fails
+	s.lastCheckout = nil
+
 	tmpDir, tmpDirCleanup, err := createTemporaryDirectory(ctx, s.cfg.CheckoutPath)
 	if err != nil {
-		return nil, "", err
+		return nil, nil, "", err
 	}
 
 	defer tmpDirCleanup()
 
+	commit, err := s.clone(ctx, tmpDir, s.cfg.GitBranch)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	log.V(1).Info("commit data", "ShortMessage", commit.ShortMessage(), "String", commit.String(), "commit", commit)
+
+	revision := commit.Hash.String()
+	log.V(1).Info("current revision", "revision", revision)
+
+	yamlPath := filepath.Clean(tmpDir)
+	if s.cfg.GitYamlPath != "" {
+		yamlPath = filepath.Clean(fmt.Sprintf("%s/%s", yamlPath, s.cfg.GitYamlPath))
+	}
+
+	yamlFiles, err := listYamlFromPath(yamlPath)
+	if err != nil {
+		log.V(1).Error(err, "failed to list yamls from path", "yaml_path", yamlPath)
+		return nil, nil, revision, err
+	}
+
+	err = loadSecretFiles(os.DirFS(tmpDir), s.cfg.GitYamlPath, yamlFiles, s.secretClient)
+	if err != nil {
+		return nil, nil, revision, err
+	}
+
+	policy, err := s.loadPolicy(ctx, tmpDir, yamlFiles)
+	if err != nil {
+		return nil, nil, revision, err
+	}
+
+	// the pull request could otherwise reference every secret azcagit can read
+	if s.cfg.PullRequest() {
+		policy = policy.forPullRequest(s.cfg.PreviewAllowedSecretNames)
+	}
+
+	s.lastCheckout = &gitCheckout{
+		yamlFiles: maps.Clone(*yamlFiles),
+		policy:    policy,
+		revision:  revision,
+	}
+
+	return yamlFiles, policy, revision, nil
+}
+
+// loadPolicy reads the policy file from the policy branch, which is cloned separately for pull requests so the
+// policy can't be changed by the pull request it applies to
+func (s *GitSource) loadPolicy(ctx context.Context, tmpDir string, yamlFiles *map[string][]byte) (*Policy, error) {
+	if s.cfg.GitPolicyPath == "" || s.cfg.PolicyBranch() == s.cfg.GitBranch {
+		return loadPolicy(os.DirFS(tmpDir), s.cfg.GitYamlPath, yamlFiles, s.cfg.GitPolicyPath)
+	}
+
+	policyDir, policyDirCleanup, err := createTemporaryDirectory(ctx, s.cfg.CheckoutPath)
+	if err != nil {
+		return nil, err
+	}
+
+	defer policyDirCleanup()
+
+	_, err = s.clone(ctx, policyDir, s.cfg.PolicyBranch())
+	if err != nil {
+		return nil, fmt.Errorf("unable to clone policy branch %q: %w", s.cfg.PolicyBranch(), err)
+	}
+
+	return loadPolicy(os.DirFS(policyDir), s.cfg.GitYamlPath, yamlFiles, s.cfg.GitPolicyPath)
+}
+
+func (s *GitSource) clone(ctx context.Context, dir string, branch string) (*git.Commit, error) {
+	log := logr.FromContextOrDiscard(ctx)
+
 	gitUrl, err := url.Parse(s.cfg.GitUrl)
 	if err != nil {
 		log.V(1).Error(err, "failed to parse git url")
-		return nil, "", err
+		return nil, err
 	}
 
 	authOpts, err := git.NewAuthOptions(*gitUrl, nil)
 	if err != nil {
 		log.V(1).Error(err, "failed to parse auth options")
-		return nil, "", err
+		return nil, err
 	}
 
 	clientOpts := []gogit.ClientOption{gogit.WithDiskStorage()}
@@ -67,10 +227,10 @@ func (s *GitSource) checkout(ctx context.Context) (*map[string][]byte, string, e
 		clientOpts = append(clientOpts, gogit.WithInsecureCredentialsOverHTTP())
 	}
 
-	gitReader, err := gogit.NewClient(tmpDir, authOpts, clientOpts...)
+	gitReader, err := gogit.NewClient(dir, authOpts, clientOpts...)
 	if err != nil {
 		log.V(1).Error(err, "failed to create git client")
-		return nil, "", err
+		return nil, err
 	}
 	defer gitReader.Close()
 
@@ -78,47 +238,17 @@ func (s *GitSource) checkout(ctx context.Context) (*map[string][]byte, string, e
 		ShallowClone:      true,
 		RecurseSubmodules: true,
 		CheckoutStrategy: repository.CheckoutStrategy{
-			Branch: s.cfg.GitBranch,
+			Branch: branch,
 		},
 	}
 	commit, err := gitReader.Clone(ctx, s.cfg.GitUrl, cloneOpts)
 	if err != nil {
 		redactedErr := redactGitSecretFromError(s.cfg.GitUrl, err)
 		log.V(1).Error(redactedErr, "failed to clone")
-		return nil, "", redactedErr
-	}
-
-	log.V(1).Info("commit data", "ShortMessage", commit.ShortMessage(), "String", commit.String(), "commit", commit)
-
-	revision := commit.Hash.String()
-	log.V(1).Info("current revision", "revision", revision)
-
-	lastRevision, err := s.revisionCache.Get(ctx)
-	if err != nil {
-		return nil, "", err
-	}
-
-	if revision != lastRevision {
-		log.Info("new commit hash", "new_revision", revision, "last_revision", lastRevision)
-
-		err := s.revisionCache.Set(ctx, revision)
-		if err != nil {
-			return nil, revision, err
-		}
-	}
-
-	yamlPath := filepath.Clean(tmpDir)
-	if s.cfg.GitYamlPath != "" {
-		yamlPath = filepath.Clean(fmt.Sprintf("%s/%s", yamlPath, s.cfg.GitYamlPath))
-	}
-
-	yamlFiles, err := listYamlFromPath(yamlPath)
-	if err != nil {
-		log.V(1).Error(err, "failed to list yamls from path", "yaml_path", yamlPath)
-		return nil, revision, err
+		return nil, redactedErr
 	}
 
-	return yamlFiles, revision, nil
+	return commit, nil
 }
 
 func redactGitSecretFromError(gitUrl string, inputErr error) error {
@@ -31,6 +31,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/xenitab/azcagit/src/cache"
 	"github.com/xenitab/azcagit/src/config"
+	"github.com/xenitab/azcagit/src/secret"
 )
 
 const (
@@ -101,7 +102,7 @@ func TestGitSource(t *testing.T) {
 		GitBranch:            defaultBranch,
 		ManagedEnvironmentID: "ze-managed-id",
 		Location:             "ze-location",
-	}, revisionCache)
+	}, revisionCache, secret.NewInMemSecret())
 	require.NoError(t, err)
 
 	tmp := t.TempDir()
@@ -137,6 +138,146 @@ func TestGitSource(t *testing.T) {
 	require.Len(t, secondSources.Apps.GetSortedNames(), 2)
 }
 
+func TestGitSourceForTarget(t *testing.T) {
+	server, err := gittestserver.NewTempGitServer()
+	require.NoError(t, err)
+	defer os.RemoveAll(server.Root())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err = server.StartHTTP()
+	require.NoError(t, err)
+	defer server.StopHTTP()
+
+	repoPath := "test.git"
+	defaultBranch := "master"
+
+	tmpFixtureDir := t.TempDir()
+	err = os.WriteFile(filepath.Clean(fmt.Sprintf("%s/foo.txt", tmpFixtureDir)), []byte("test file"), 0600)
+	require.NoError(t, err)
+	err = server.InitRepo(tmpFixtureDir, defaultBranch, repoPath)
+	require.NoError(t, err)
+
+	repoURL := server.HTTPAddress() + "/" + repoPath
+	tmp := t.TempDir()
+	ggc, err := gg.NewClient(tmp, &git.AuthOptions{
+		Transport: git.HTTP,
+	})
+	require.NoError(t, err)
+	defer ggc.Close()
+
+	_, err = ggc.Clone(ctx, repoURL, repository.CloneConfig{})
+	require.NoError(t, err)
+
+	_, err = testCommitFile(t, ctx, ggc, "foo1.yaml", testFixtureYAML1)
+	require.NoError(t, err)
+	firstCommit, err := testCommitFile(t, ctx, ggc, "tenants/foo/foo2.yaml", testFixtureYAML2)
+	require.NoError(t, err)
+
+	cfg := config.ReconcileConfig{
+		GitUrl:               repoURL,
+		GitBranch:            defaultBranch,
+		ResourceGroupName:    "rg-default",
+		ManagedEnvironmentID: "ze-managed-id",
+		Location:             "ze-location",
+		Targets:              []string{"resourceGroup=rg-foo;path=tenants/foo"},
+	}
+	targets, err := cfg.GetTargets()
+	require.NoError(t, err)
+
+	sourceClient, err := NewGitSource(cfg, cache.NewInMemRevisionCache(), secret.NewInMemSecret())
+	require.NoError(t, err)
+	sources, revision, err := sourceClient.Get(ctx)
+	require.NoError(t, err)
+	require.Equal(t, firstCommit, revision)
+	require.Equal(t, []string{"foo1"}, sources.Apps.GetSortedNames())
+
+	// the target uses the checkout of the environment, without the commit made after it
+	_, err = testCommitFile(t, ctx, ggc, "tenants/foo/foo1.yaml", testFixtureYAML1)
+	require.NoError(t, err)
+
+	targetRevisionCache := cache.NewInMemRevisionCache()
+	targetSources, targetRevision, err := sourceClient.ForTarget(cfg.ForTarget(targets[1]), targetRevisionCache).Get(ctx)
+	require.NoError(t, err)
+	require.Equal(t, firstCommit, targetRevision)
+	require.Equal(t, []string{"foo2"}, targetSources.Apps.GetSortedNames())
+	cachedRevision, err := targetRevisionCache.Get(ctx)
+	require.NoError(t, err)
+	require.Equal(t, firstCommit, cachedRevision)
+
+	// the checkout isn't changed by the target
+	sources, _, err = sourceClient.ForTarget(cfg, cache.NewInMemRevisionCache()).Get(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"foo1"}, sources.Apps.GetSortedNames())
+}
+
+func TestGitSourcePolicyBranch(t *testing.T) {
+	server, err := gittestserver.NewTempGitServer()
+	require.NoError(t, err)
+	defer os.RemoveAll(server.Root())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err = server.StartHTTP()
+	require.NoError(t, err)
+	defer server.StopHTTP()
+
+	repoPath := "test.git"
+	defaultBranch := "master"
+
+	tmpFixtureDir := t.TempDir()
+	err = os.WriteFile(filepath.Clean(fmt.Sprintf("%s/foo.txt", tmpFixtureDir)), []byte("test file"), 0600)
+	require.NoError(t, err)
+	err = server.InitRepo(tmpFixtureDir, defaultBranch, repoPath)
+	require.NoError(t, err)
+
+	repoURL := server.HTTPAddress() + "/" + repoPath
+	tmp := t.TempDir()
+	ggc, err := gg.NewClient(tmp, &git.AuthOptions{
+		Transport: git.HTTP,
+	})
+	require.NoError(t, err)
+	defer ggc.Close()
+
+	_, err = ggc.Clone(ctx, repoURL, repository.CloneConfig{})
+	require.NoError(t, err)
+
+	_, err = testCommitFile(t, ctx, ggc, "policy.yaml", "tenants:\n- name: team-a\n  paths: [teams/a]\n  allowedImages: [\"foo:*\"]")
+	require.NoError(t, err)
+
+	// the pull request changes the policy to allow its image
+	err = ggc.SwitchBranch(ctx, "feature")
+	require.NoError(t, err)
+	_, err = testCommitFile(t, ctx, ggc, "policy.yaml", "tenants:\n- name: team-a\n  paths: [teams/a]")
+	require.NoError(t, err)
+	_, err = testCommitFile(t, ctx, ggc, "teams/a/foo1.yaml", strings.ReplaceAll(testFixtureYAML1, "foo:latest", "bar:latest"))
+	require.NoError(t, err)
+
+	cfg := config.ReconcileConfig{
+		GitUrl:               repoURL,
+		GitBranch:            defaultBranch,
+		GitPolicyPath:        "policy.yaml",
+		ManagedEnvironmentID: "ze-managed-id",
+		Location:             "ze-location",
+	}
+
+	previewSourceClient, err := NewGitSource(cfg.ForPullRequest("feature"), cache.NewInMemRevisionCache(), secret.NewInMemSecret())
+	require.NoError(t, err)
+	previewSources, _, err := previewSourceClient.Get(ctx)
+	require.NoError(t, err)
+	require.ErrorContains(t, previewSources.Apps.Error(), "tenant \"team-a\" doesn't allow image \"bar:latest\"")
+
+	cfg.GitBranch = "feature"
+	branchSourceClient, err := NewGitSource(cfg, cache.NewInMemRevisionCache(), secret.NewInMemSecret())
+	require.NoError(t, err)
+	branchSources, _, err := branchSourceClient.Get(ctx)
+	require.NoError(t, err)
+	require.NoError(t, branchSources.Apps.Error())
+	require.Len(t, branchSources.Apps.GetSortedNames(), 1)
+}
+
 func testCommitFile(t *testing.T, ctx context.Context, ggc *gg.Client, path, content string) (string, error) {
 	t.Helper()
 
@@ -23,6 +23,8 @@ type SourceJobSpecification struct {
 	RemoteSecrets  []RemoteSecretSpecification   `json:"remoteSecrets,omitempty" yaml:"remoteSecrets,omitempty"`
 	LocationFilter []LocationFilterSpecification `json:"locationFilter,omitempty" yaml:"locationFilter,omitempty"`
 	Replacements   *ReplacementsSpecification    `json:"replacements,omitempty" yaml:"replacements,omitempty"`
+	// DisableRegistries opts out of the container registries added by azcagit
+	DisableRegistries bool `json:"disableRegistries,omitempty" yaml:"disableRegistries,omitempty"`
 }
 
 type SourceJob struct {
@@ -31,6 +33,8 @@ type SourceJob struct {
 	Metadata      map[string]string       `json:"metadata,omitempty" yaml:"metadata,omitempty"`
 	Specification *SourceJobSpecification `json:"spec,omitempty" yaml:"spec,omitempty"`
 	Err           error
+	// tenant is the policy of the tenant with the path of the manifest, nil if it isn't in the path of a tenant
+	tenant *TenantPolicy
 }
 
 func (job *SourceJob) Error() error {
@@ -51,6 +55,22 @@ func (job *SourceJob) Name() string {
 }
 
 func (job *SourceJob) SetSecret(name string, value string) error {
+	return job.addSecret(&armappcontainers.Secret{
+		Name:  &name,
+		Value: &value,
+	})
+}
+
+// SetSecretReference adds a secret referencing KeyVault, the value is read by Container Apps using the identity
+func (job *SourceJob) SetSecretReference(name string, keyVaultURL string, identity string) error {
+	return job.addSecret(&armappcontainers.Secret{
+		Name:        &name,
+		KeyVaultURL: &keyVaultURL,
+		Identity:    &identity,
+	})
+}
+
+func (job *SourceJob) addSecret(secret *armappcontainers.Secret) error {
 	if job == nil || job.Specification == nil || job.Specification.Job == nil {
 		return fmt.Errorf("job is nil")
 	}
@@ -72,19 +92,36 @@ func (job *SourceJob) SetSecret(name string, value string) error {
 			continue
 		}
 
-		if *v.Name == name {
-			return fmt.Errorf("a secret with name %q already exists", name)
+		if *v.Name == *secret.Name {
+			return fmt.Errorf("a secret with name %q already exists", *secret.Name)
 		}
 	}
 
-	job.Specification.Job.Properties.Configuration.Secrets = append(job.Specification.Job.Properties.Configuration.Secrets, &armappcontainers.Secret{
-		Name:  &name,
-		Value: &value,
-	})
+	job.Specification.Job.Properties.Configuration.Secrets = append(job.Specification.Job.Properties.Configuration.Secrets, secret)
 
 	return nil
 }
 
+// GetVolumeStorageNames returns the managed environment storages referenced by the volumes
+func (job *SourceJob) GetVolumeStorageNames() []string {
+	if job == nil || job.Specification == nil || job.Specification.Job == nil || job.Specification.Job.Properties == nil || job.Specification.Job.Properties.Template == nil {
+		return []string{}
+	}
+
+	names := []string{}
+	for _, volume := range job.Specification.Job.Properties.Template.Volumes {
+		if volume == nil || volume.StorageName == nil || *volume.StorageName == "" {
+			continue
+		}
+		if volume.StorageType != nil && *volume.StorageType != armappcontainers.StorageTypeAzureFile {
+			continue
+		}
+		names = append(names, *volume.StorageName)
+	}
+
+	return names
+}
+
 func (job *SourceJob) SetRegistry(server string, username string, password string) error {
 	if job == nil || job.Specification == nil || job.Specification.Job == nil {
 		return fmt.Errorf("job is nil")
@@ -102,22 +139,13 @@ func (job *SourceJob) SetRegistry(server string, username string, password strin
 		job.Specification.Job.Properties.Configuration.Registries = []*armappcontainers.RegistryCredentials{}
 	}
 
-	for _, v := range job.Specification.Job.Properties.Configuration.Registries {
-		if v == nil || v.Server == nil || v.Username == nil {
-			continue
-		}
-
-		if v.Identity == nil && v.PasswordSecretRef == nil {
-			continue
-		}
-
-		if *v.Server == server {
-			return fmt.Errorf("the server %q already exists", server)
-		}
+	err := validateRegistryNotConfigured(job.Specification.Job.Properties.Configuration.Registries, server)
+	if err != nil {
+		return err
 	}
 
-	passwordSecretRef := "azcagit-reg-cred"
-	err := job.SetSecret(passwordSecretRef, password)
+	passwordSecretRef := getRegistryPasswordSecretName(job.Specification.Job.Properties.Configuration.Secrets)
+	err = job.SetSecret(passwordSecretRef, password)
 	if err != nil {
 		return err
 	}
@@ -132,6 +160,46 @@ func (job *SourceJob) SetRegistry(server string, username string, password strin
 	return nil
 }
 
+// SetRegistryIdentity adds a registry using a managed identity, which has to be assigned to the job
+func (job *SourceJob) SetRegistryIdentity(server string, identity string) error {
+	if job == nil || job.Specification == nil || job.Specification.Job == nil {
+		return fmt.Errorf("job is nil")
+	}
+
+	if !isIdentityAssigned(identity, job.Specification.Job.Identity) {
+		return fmt.Errorf("identity %q for the server %q is not assigned", identity, server)
+	}
+
+	if job.Specification.Job.Properties == nil {
+		job.Specification.Job.Properties = &armappcontainers.JobProperties{}
+	}
+
+	if job.Specification.Job.Properties.Configuration == nil {
+		job.Specification.Job.Properties.Configuration = &armappcontainers.JobConfiguration{}
+	}
+
+	err := validateRegistryNotConfigured(job.Specification.Job.Properties.Configuration.Registries, server)
+	if err != nil {
+		return err
+	}
+
+	job.Specification.Job.Properties.Configuration.Registries = append(job.Specification.Job.Properties.Configuration.Registries, &armappcontainers.RegistryCredentials{
+		Server:   &server,
+		Identity: &identity,
+	})
+
+	return nil
+}
+
+// GetImageRegistryServers returns the registry servers used by the container images
+func (job *SourceJob) GetImageRegistryServers() []string {
+	if job == nil || job.Specification == nil || job.Specification.Job == nil || job.Specification.Job.Properties == nil || job.Specification.Job.Properties.Template == nil {
+		return []string{}
+	}
+
+	return getImageRegistryServers(job.Specification.Job.Properties.Template.Containers, job.Specification.Job.Properties.Template.InitContainers)
+}
+
 func (job *SourceJob) GetRemoteSecrets() []RemoteSecretSpecification {
 	secretsMap := make(map[string]struct{})
 	if job == nil || job.Specification == nil || job.Specification.RemoteSecrets == nil || len(job.Specification.RemoteSecrets) == 0 {
@@ -191,12 +259,54 @@ func (job *SourceJob) ValidateFields() error {
 		result = multierror.Append(fmt.Errorf("location is disabled and set through azcagit"), result)
 	}
 
+	if job.Specification != nil && job.Specification.Job != nil {
+		err := validateRemoteSecretIdentities(job.Specification.RemoteSecrets, job.Specification.Job.Identity)
+		if err != nil {
+			result = multierror.Append(err, result)
+		}
+	}
+
+	if job.Specification != nil {
+		err := validateRemoteSecretNames(job.Specification.RemoteSecrets)
+		if err != nil {
+			result = multierror.Append(err, result)
+		}
+	}
+
+	err := job.validatePolicy()
+	if err != nil {
+		result = multierror.Append(err, result)
+	}
+
 	return result.ErrorOrNil()
 }
 
+func (job *SourceJob) validatePolicy() error {
+	if job.tenant == nil || job.Specification == nil || job.Specification.Job == nil || job.Specification.Job.Properties == nil {
+		return nil
+	}
+
+	properties := job.Specification.Job.Properties
+	var secrets []*armappcontainers.Secret
+	if properties.Configuration != nil {
+		secrets = properties.Configuration.Secrets
+	}
+
+	var containers []*armappcontainers.Container
+	var initContainers []*armappcontainers.InitContainer
+	if properties.Template != nil {
+		containers = properties.Template.Containers
+		initContainers = properties.Template.InitContainers
+	}
+
+	return job.tenant.validate(containers, initContainers, nil, secrets, job.Specification.RemoteSecrets)
+}
+
 func validateJsonIsJobKind(j []byte) (bool, error) {
 	dec := json.NewDecoder(bytes.NewReader(j))
-	var newjob SourceJob
+	var newjob struct {
+		Kind string `json:"kind,omitempty"`
+	}
 	err := dec.Decode(&newjob)
 	if err != nil {
 		return false, err
@@ -236,11 +346,23 @@ func (job *SourceJob) Unmarshal(y []byte, cfg config.ReconcileConfig) (bool, err
 		return true, err
 	}
 
+	newjob.tenant = job.tenant
 	err = newjob.ValidateFields()
 	if err != nil {
 		return true, err
 	}
 
+	err = newjob.applyReplacements()
+	if err != nil {
+		return true, err
+	}
+
+	// the replacements can change the fields constrained by the policy
+	err = newjob.validatePolicy()
+	if err != nil {
+		return true, err
+	}
+
 	if cfg.ManagedEnvironmentID == "" {
 		return true, fmt.Errorf("cfg.ManagedEnvironmentID is not set")
 	}
@@ -269,34 +391,43 @@ func (job *SourceJob) Unmarshal(y []byte, cfg config.ReconcileConfig) (bool, err
 
 	newjob.Specification.Job.Tags["aca.xenit.io"] = toPtr("true")
 
-	err = newjob.applyReplacements()
-	if err != nil {
-		return true, err
-	}
-
 	*job = newjob
 	return true, nil
 }
 
 func (job *SourceJob) applyReplacements() error {
-	if job.Specification.Replacements != nil && job.Specification.Replacements.Images != nil && len(job.Specification.Replacements.Images) != 0 {
-		if job.Specification.Job.Properties.Template == nil || job.Specification.Job.Properties.Template.Containers == nil || len(job.Specification.Job.Properties.Template.Containers) == 0 {
-			return fmt.Errorf("no containers found")
+	replacements := job.Specification.Replacements
+	if replacements.isEmpty() {
+		return nil
+	}
+
+	err := replacements.validate()
+	if err != nil {
+		return err
+	}
+
+	if replacements.hasContainerReplacements() {
+		containers := []replacementContainer{}
+		if job.Specification.Job.Properties != nil && job.Specification.Job.Properties.Template != nil {
+			template := job.Specification.Job.Properties.Template
+			containers = toReplacementContainers(template.Containers, template.InitContainers)
 		}
-		for i, container := range job.Specification.Job.Properties.Template.Containers {
-			if container.Image == nil || *container.Image == "" {
-				return fmt.Errorf("no image found for container %d", i)
-			}
-			oldImage := *container.Image
-			imageParts := strings.Split(oldImage, ":")
-			imageName := imageParts[0]
-			for _, replacementImage := range job.Specification.Replacements.Images {
-				if imageName == *replacementImage.ImageName {
-					*job.Specification.Job.Properties.Template.Containers[i].Image = fmt.Sprintf("%s:%s", imageName, *replacementImage.NewImageTag)
-				}
-			}
+		err := replacements.applyContainers(containers)
+		if err != nil {
+			return err
 		}
 	}
+
+	if replacements.Scale != nil {
+		return fmt.Errorf("scale replacement isn't supported for jobs")
+	}
+
+	newJob, err := applyJSONPathReplacements(job.Specification.Job, replacements.JSONPaths)
+	if err != nil {
+		return err
+	}
+	job.Specification.Job = newJob
+
 	return nil
 }
 
@@ -317,13 +448,15 @@ func (job *SourceJob) ShoudRunInLocation(currentLocation string) bool {
 
 type SourceJobs map[string]SourceJob
 
-func (jobs *SourceJobs) Unmarshal(path string, y []byte, cfg config.ReconcileConfig) {
+func (jobs *SourceJobs) Unmarshal(path string, y []byte, cfg config.ReconcileConfig, policy *Policy) {
 	if jobs == nil {
 		jobs = toPtr(make(SourceJobs))
 	}
 	parts := strings.Split(string(y), "---")
 	for i, part := range parts {
-		var job SourceJob
+		job := SourceJob{
+			tenant: policy.GetTenant(path),
+		}
 		isContainerJob, err := job.Unmarshal([]byte(part), cfg)
 		if err != nil {
 			job.Err = fmt.Errorf("unable to unmarshal SourceJob from %s (document %d): %w", path, i, err)
@@ -383,6 +516,22 @@ func (jobs *SourceJobs) SetSecret(name string, secretName string, secretValue st
 	return nil
 }
 
+func (jobs *SourceJobs) SetSecretReference(name string, secretName string, keyVaultURL string, identity string) error {
+	job, ok := (*jobs)[name]
+	if !ok {
+		return fmt.Errorf("no SourceJob with name %q", name)
+	}
+
+	err := job.SetSecretReference(secretName, keyVaultURL, identity)
+	if err != nil {
+		return err
+	}
+
+	(*jobs)[name] = job
+
+	return nil
+}
+
 func (jobs *SourceJobs) SetRegistry(name string, server string, username string, password string) error {
 	job, ok := (*jobs)[name]
 	if !ok {
@@ -399,13 +548,55 @@ func (jobs *SourceJobs) SetRegistry(name string, server string, username string,
 	return nil
 }
 
+func (jobs *SourceJobs) SetRegistryIdentity(name string, server string, identity string) error {
+	job, ok := (*jobs)[name]
+	if !ok {
+		return fmt.Errorf("no SourceJob with name %q", name)
+	}
+
+	err := job.SetRegistryIdentity(server, identity)
+	if err != nil {
+		return err
+	}
+
+	(*jobs)[name] = job
+
+	return nil
+}
+
+// GetUniqueKeyVaultReferenceNames returns the remote secrets used as KeyVault references, they aren't read
+// by azcagit but their changes are tracked
+func (jobs *SourceJobs) GetUniqueKeyVaultReferenceNames() []string {
+	secretsMap := make(map[string]struct{})
+	for _, jobName := range jobs.GetSortedNames() {
+		job, _ := jobs.Get(jobName)
+		for _, remoteSecret := range job.GetRemoteSecrets() {
+			if !remoteSecret.IsKeyVaultReference() {
+				continue
+			}
+			secretsMap[remoteSecret.SecretKey()] = struct{}{}
+		}
+	}
+
+	secrets := []string{}
+	for secret := range secretsMap {
+		secrets = append(secrets, secret)
+	}
+	sort.Strings(secrets)
+
+	return secrets
+}
+
 func (jobs *SourceJobs) GetUniqueRemoteSecretNames() []string {
 	secretsMap := make(map[string]struct{})
 	for _, jobName := range jobs.GetSortedNames() {
 		job, _ := jobs.Get(jobName)
 		jobSecrets := job.GetRemoteSecrets()
 		for _, remoteSecret := range jobSecrets {
-			secretsMap[*remoteSecret.RemoteSecretName] = struct{}{}
+			if remoteSecret.IsKeyVaultReference() {
+				continue
+			}
+			secretsMap[remoteSecret.SecretKey()] = struct{}{}
 		}
 	}
 
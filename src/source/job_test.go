@@ -467,7 +467,7 @@ spec:
 		jobs.Unmarshal("foobar/baz.yaml", []byte(c.rawYaml), config.ReconcileConfig{
 			Location:             "ze-location",
 			ManagedEnvironmentID: "ze-EnvironmentID",
-		})
+		}, nil)
 		require.Len(t, jobs, c.expectedLenght)
 		if c.expectedError != "" {
 			require.ErrorContains(t, jobs.Error(), c.expectedError)
@@ -0,0 +1,310 @@
+package source
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/xenitab/azcagit/src/config"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	AzureContainerPatchVersion = "aca.xenit.io/v1alpha2"
+	AzureContainerPatchKind    = "AzureContainerPatch"
+)
+
+type PatchTargetSpecification struct {
+	Kind string `json:"kind,omitempty" yaml:"kind,omitempty"`
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+}
+
+type SourcePatchSpecification struct {
+	Target  *PatchTargetSpecification `json:"target,omitempty" yaml:"target,omitempty"`
+	Patches []json.RawMessage         `json:"patches,omitempty" yaml:"patches,omitempty"`
+}
+
+type SourcePatch struct {
+	Kind          string                    `json:"kind,omitempty" yaml:"kind,omitempty"`
+	APIVersion    string                    `json:"apiVersion,omitempty" yaml:"apiVersion,omitempty"`
+	Metadata      map[string]string         `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+	Specification *SourcePatchSpecification `json:"spec,omitempty" yaml:"spec,omitempty"`
+}
+
+type overlayPatch struct {
+	origin    string
+	kind      string
+	name      string
+	jsonPatch jsonpatch.Patch
+	mergeDoc  []byte
+	matched   bool
+}
+
+func (p *overlayPatch) apply(doc []byte) ([]byte, error) {
+	if p.jsonPatch != nil {
+		return p.jsonPatch.Apply(doc)
+	}
+
+	return strategicMerge(doc, p.mergeDoc)
+}
+
+// applyOverlays patches the base documents with the documents found in the overlay directory
+// for the current environment. Files in the overlays path, for any environment, are not part
+// of the base and are removed from the result.
+func applyOverlays(yamlFiles *map[string][]byte, cfg config.ReconcileConfig) (*map[string][]byte, error) {
+	if cfg.GitOverlaysPath == "" {
+		return yamlFiles, nil
+	}
+
+	if cfg.Environment == "" {
+		return nil, fmt.Errorf("environment needs to be set to use overlays")
+	}
+
+	overlaysPath := path.Clean(strings.Trim(cfg.GitOverlaysPath, "/"))
+	environmentPath := path.Join(overlaysPath, cfg.Environment)
+
+	basePaths := []string{}
+	overlayPaths := []string{}
+	for filePath := range *yamlFiles {
+		switch {
+		case strings.HasPrefix(filePath, environmentPath+"/"):
+			overlayPaths = append(overlayPaths, filePath)
+		case strings.HasPrefix(filePath, overlaysPath+"/"):
+			continue
+		default:
+			basePaths = append(basePaths, filePath)
+		}
+	}
+	sort.Strings(overlayPaths)
+
+	patches := []*overlayPatch{}
+	for _, overlayPath := range overlayPaths {
+		for i, part := range strings.Split(string((*yamlFiles)[overlayPath]), "---") {
+			patch, err := newOverlayPatch(fmt.Sprintf("%s (document %d)", overlayPath, i), []byte(part))
+			if err != nil {
+				return nil, fmt.Errorf("unable to parse overlay %s (document %d): %w", overlayPath, i, err)
+			}
+			if patch == nil {
+				continue
+			}
+			patches = append(patches, patch)
+		}
+	}
+
+	files := make(map[string][]byte)
+	for _, basePath := range basePaths {
+		parts := strings.Split(string((*yamlFiles)[basePath]), "---")
+		for i, part := range parts {
+			patched, err := applyOverlayPatches([]byte(part), patches)
+			if err != nil {
+				return nil, fmt.Errorf("unable to apply overlay to %s (document %d): %w", basePath, i, err)
+			}
+			parts[i] = string(patched)
+		}
+		files[basePath] = []byte(strings.Join(parts, "\n---\n"))
+	}
+
+	for _, patch := range patches {
+		if !patch.matched {
+			return nil, fmt.Errorf("overlay %s for %s %q did not match any base document", patch.origin, patch.kind, patch.name)
+		}
+	}
+
+	return &files, nil
+}
+
+func isOverlayPath(filePath string, cfg config.ReconcileConfig) bool {
+	if cfg.GitOverlaysPath == "" {
+		return false
+	}
+
+	overlaysPath := path.Clean(strings.Trim(cfg.GitOverlaysPath, "/"))
+	return strings.HasPrefix(filePath, overlaysPath+"/")
+}
+
+func newOverlayPatch(origin string, y []byte) (*overlayPatch, error) {
+	j, err := yaml.YAMLToJSON(y)
+	if err != nil {
+		return nil, err
+	}
+
+	if isEmptyDocument(j) {
+		return nil, nil
+	}
+
+	header := documentHeader{}
+	err = json.Unmarshal(j, &header)
+	if err != nil {
+		return nil, err
+	}
+
+	if header.Kind != AzureContainerPatchKind {
+		name := header.Metadata["name"]
+		if header.Kind == "" || name == "" {
+			return nil, fmt.Errorf("kind and metadata.name are required for an overlay")
+		}
+
+		return &overlayPatch{
+			origin:   origin,
+			kind:     header.Kind,
+			name:     name,
+			mergeDoc: j,
+		}, nil
+	}
+
+	var sourcePatch SourcePatch
+	err = decodeStrict(j, &sourcePatch)
+	if err != nil {
+		return nil, err
+	}
+
+	if sourcePatch.APIVersion != "" && sourcePatch.APIVersion != AzureContainerPatchVersion {
+		return nil, fmt.Errorf("apiVersion for %s should be %s", sourcePatch.Kind, AzureContainerPatchVersion)
+	}
+
+	if sourcePatch.Specification == nil || sourcePatch.Specification.Target == nil {
+		return nil, fmt.Errorf("spec.target is missing")
+	}
+
+	target := sourcePatch.Specification.Target
+	if target.Kind == "" || target.Name == "" {
+		return nil, fmt.Errorf("both spec.target.kind and spec.target.name are required")
+	}
+
+	b, err := json.Marshal(sourcePatch.Specification.Patches)
+	if err != nil {
+		return nil, err
+	}
+
+	jsonPatch, err := jsonpatch.DecodePatch(b)
+	if err != nil {
+		return nil, err
+	}
+
+	return &overlayPatch{
+		origin:    origin,
+		kind:      target.Kind,
+		name:      target.Name,
+		jsonPatch: jsonPatch,
+	}, nil
+}
+
+func applyOverlayPatches(y []byte, patches []*overlayPatch) ([]byte, error) {
+	j, err := yaml.YAMLToJSON(y)
+	if err != nil {
+		// leave the document as is, the error will be reported when unmarshaling the sources
+		return y, nil
+	}
+
+	if isEmptyDocument(j) {
+		return y, nil
+	}
+
+	header := documentHeader{}
+	err = json.Unmarshal(j, &header)
+	if err != nil {
+		return y, nil
+	}
+
+	name := header.Metadata["name"]
+	patched := false
+	for _, patch := range patches {
+		if patch.kind != header.Kind || patch.name != name {
+			continue
+		}
+
+		j, err = patch.apply(j)
+		if err != nil {
+			return nil, fmt.Errorf("overlay %s failed: %w", patch.origin, err)
+		}
+		patch.matched = true
+		patched = true
+	}
+
+	if !patched {
+		return y, nil
+	}
+
+	return j, nil
+}
+
+// strategicMerge merges patch into doc using JSON merge patch (RFC 7386) semantics, with the
+// exception that lists of objects with a name field are merged by name instead of replaced.
+func strategicMerge(doc []byte, patch []byte) ([]byte, error) {
+	var docValue interface{}
+	err := json.Unmarshal(doc, &docValue)
+	if err != nil {
+		return nil, err
+	}
+
+	var patchValue interface{}
+	err = json.Unmarshal(patch, &patchValue)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(strategicMergeValue(docValue, patchValue))
+}
+
+func strategicMergeValue(doc interface{}, patch interface{}) interface{} {
+	switch patchTyped := patch.(type) {
+	case map[string]interface{}:
+		docTyped, ok := doc.(map[string]interface{})
+		if !ok {
+			docTyped = make(map[string]interface{})
+		}
+		for key, patchValue := range patchTyped {
+			if patchValue == nil {
+				delete(docTyped, key)
+				continue
+			}
+			docTyped[key] = strategicMergeValue(docTyped[key], patchValue)
+		}
+		return docTyped
+	case []interface{}:
+		docTyped, ok := doc.([]interface{})
+		if !ok || !isNamedList(docTyped) || !isNamedList(patchTyped) {
+			return patchTyped
+		}
+		for _, patchItem := range patchTyped {
+			patchItemTyped := patchItem.(map[string]interface{})
+			found := false
+			for i, docItem := range docTyped {
+				docItemTyped := docItem.(map[string]interface{})
+				if docItemTyped["name"] == patchItemTyped["name"] {
+					docTyped[i] = strategicMergeValue(docItemTyped, patchItemTyped)
+					found = true
+					break
+				}
+			}
+			if !found {
+				docTyped = append(docTyped, patchItemTyped)
+			}
+		}
+		return docTyped
+	default:
+		return patch
+	}
+}
+
+func isNamedList(list []interface{}) bool {
+	if len(list) == 0 {
+		return false
+	}
+
+	for _, item := range list {
+		itemTyped, ok := item.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		name, ok := itemTyped["name"].(string)
+		if !ok || name == "" {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,243 @@
+package source
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xenitab/azcagit/src/config"
+)
+
+const testOverlayBaseYAML = `
+kind: AzureContainerApp
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+  name: foo
+spec:
+  app:
+    properties:
+      template:
+        containers:
+        - name: foo
+          image: foo:latest
+          env:
+          - name: ENVIRONMENT
+            value: base
+          - name: UNCHANGED
+            value: base
+        scale:
+          minReplicas: 1
+          maxReplicas: 1
+---
+kind: AzureContainerJob
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+  name: bar
+spec:
+  job:
+    properties:
+      configuration:
+        replicaTimeout: 60
+        triggerType: Manual
+      template:
+        containers:
+        - name: bar
+          image: bar:latest`
+
+func TestApplyOverlays(t *testing.T) {
+	cfg := config.ReconcileConfig{
+		Environment:          "dev",
+		GitOverlaysPath:      "overlays",
+		ManagedEnvironmentID: "foobar",
+		Location:             "westeurope",
+	}
+
+	cases := []struct {
+		testDescription string
+		cfg             config.ReconcileConfig
+		files           map[string][]byte
+		expectedError   string
+		expectedFiles   []string
+		validate        func(t *testing.T, sources *Sources)
+	}{
+		{
+			testDescription: "overlays disabled",
+			cfg: config.ReconcileConfig{
+				Environment:          "dev",
+				ManagedEnvironmentID: "foobar",
+				Location:             "westeurope",
+			},
+			files: map[string][]byte{
+				"base/foo.yaml":         []byte(testOverlayBaseYAML),
+				"overlays/dev/foo.yaml": []byte("kind: AzureContainerApp\nmetadata:\n  name: foo"),
+			},
+			expectedFiles: []string{"base/foo.yaml", "overlays/dev/foo.yaml"},
+		},
+		{
+			testDescription: "strategic merge with lists merged by name",
+			cfg:             cfg,
+			files: map[string][]byte{
+				"base/foo.yaml": []byte(testOverlayBaseYAML),
+				"overlays/dev/foo.yaml": []byte(`
+kind: AzureContainerApp
+metadata:
+  name: foo
+spec:
+  app:
+    properties:
+      template:
+        containers:
+        - name: foo
+          env:
+          - name: ENVIRONMENT
+            value: dev
+          - name: ADDED
+            value: dev
+        scale:
+          maxReplicas: 3`),
+				"overlays/prod/foo.yaml": []byte(`
+kind: AzureContainerApp
+metadata:
+  name: foo
+spec:
+  app:
+    properties:
+      template:
+        scale:
+          maxReplicas: 10`),
+			},
+			expectedFiles: []string{"base/foo.yaml"},
+			validate: func(t *testing.T, sources *Sources) {
+				t.Helper()
+				app, ok := sources.Apps.Get("foo")
+				require.True(t, ok)
+				template := app.Specification.App.Properties.Template
+				require.Equal(t, int32(1), *template.Scale.MinReplicas)
+				require.Equal(t, int32(3), *template.Scale.MaxReplicas)
+				require.Len(t, template.Containers, 1)
+				require.Equal(t, "foo:latest", *template.Containers[0].Image)
+				env := template.Containers[0].Env
+				require.Len(t, env, 3)
+				require.Equal(t, "ENVIRONMENT", *env[0].Name)
+				require.Equal(t, "dev", *env[0].Value)
+				require.Equal(t, "UNCHANGED", *env[1].Name)
+				require.Equal(t, "base", *env[1].Value)
+				require.Equal(t, "ADDED", *env[2].Name)
+				_, ok = sources.Jobs.Get("bar")
+				require.True(t, ok)
+			},
+		},
+		{
+			testDescription: "json patch",
+			cfg:             cfg,
+			files: map[string][]byte{
+				"base/foo.yaml": []byte(testOverlayBaseYAML),
+				"overlays/dev/patches.yaml": []byte(`
+kind: AzureContainerPatch
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+  name: bar-timeout
+spec:
+  target:
+    kind: AzureContainerJob
+    name: bar
+  patches:
+  - op: replace
+    path: /spec/job/properties/configuration/replicaTimeout
+    value: 3600
+  - op: remove
+    path: /spec/job/properties/template/containers/0/image
+  - op: add
+    path: /spec/job/properties/template/containers/0/image
+    value: bar:dev`),
+			},
+			expectedFiles: []string{"base/foo.yaml"},
+			validate: func(t *testing.T, sources *Sources) {
+				t.Helper()
+				job, ok := sources.Jobs.Get("bar")
+				require.True(t, ok)
+				require.Equal(t, int32(3600), *job.Specification.Job.Properties.Configuration.ReplicaTimeout)
+				require.Equal(t, "bar:dev", *job.Specification.Job.Properties.Template.Containers[0].Image)
+			},
+		},
+		{
+			testDescription: "overlay not matching any base document",
+			cfg:             cfg,
+			files: map[string][]byte{
+				"base/foo.yaml": []byte(testOverlayBaseYAML),
+				"overlays/dev/foo.yaml": []byte(`
+kind: AzureContainerApp
+metadata:
+  name: missing`),
+			},
+			expectedError: "overlay overlays/dev/foo.yaml (document 0) for AzureContainerApp \"missing\" did not match any base document",
+		},
+		{
+			testDescription: "overlay without name",
+			cfg:             cfg,
+			files: map[string][]byte{
+				"base/foo.yaml":         []byte(testOverlayBaseYAML),
+				"overlays/dev/foo.yaml": []byte("kind: AzureContainerApp"),
+			},
+			expectedError: "kind and metadata.name are required for an overlay",
+		},
+		{
+			testDescription: "json patch failing",
+			cfg:             cfg,
+			files: map[string][]byte{
+				"base/foo.yaml": []byte(testOverlayBaseYAML),
+				"overlays/dev/patches.yaml": []byte(`
+kind: AzureContainerPatch
+metadata:
+  name: foo
+spec:
+  target:
+    kind: AzureContainerApp
+    name: foo
+  patches:
+  - op: replace
+    path: /spec/app/doesnotexist
+    value: 1`),
+			},
+			expectedError: "unable to apply overlay to base/foo.yaml (document 0)",
+		},
+		{
+			testDescription: "json patch without target",
+			cfg:             cfg,
+			files: map[string][]byte{
+				"base/foo.yaml": []byte(testOverlayBaseYAML),
+				"overlays/dev/patches.yaml": []byte(`
+kind: AzureContainerPatch
+metadata:
+  name: foo
+spec:
+  patches: []`),
+			},
+			expectedError: "spec.target is missing",
+		},
+	}
+
+	for i, c := range cases {
+		t.Logf("Test #%d: %s", i, c.testDescription)
+		files, err := applyOverlays(&c.files, c.cfg)
+		if c.expectedError != "" {
+			require.ErrorContains(t, err, c.expectedError)
+			continue
+		}
+		require.NoError(t, err)
+
+		paths := []string{}
+		for path := range *files {
+			paths = append(paths, path)
+		}
+		require.ElementsMatch(t, c.expectedFiles, paths)
+
+		if c.validate == nil {
+			continue
+		}
+
+		sources := getSourcesFromFiles(files, c.cfg, nil)
+		require.NoError(t, sources.Apps.Error())
+		require.NoError(t, sources.Jobs.Error())
+		c.validate(t, sources)
+	}
+}
@@ -0,0 +1,368 @@
+package source
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/appcontainers/armappcontainers/v2"
+	"github.com/hashicorp/go-multierror"
+	"sigs.k8s.io/yaml"
+)
+
+// Policy maps directories to tenants, the apps and jobs in the paths of a tenant have to follow its constraints
+type Policy struct {
+	Tenants []TenantPolicy `json:"tenants"`
+	// DenyOutsideTenants rejects the manifests that aren't in the path of a tenant
+	DenyOutsideTenants bool `json:"denyOutsideTenants,omitempty"`
+	// outsideTenant is set by forPullRequest for the manifests outside the paths of every tenant
+	outsideTenant *TenantPolicy
+}
+
+// TenantPolicy constrains the apps and jobs of a tenant, constraints that aren't set allow everything. The
+// allowed secret names also constrain the Dapr components, certificates and storages.
+type TenantPolicy struct {
+	Name string `json:"name"`
+	// Paths are the directories, relative to the yaml path, with the manifests of the tenant
+	Paths []string `json:"paths"`
+	// MaxCPU and MaxMemory (like 2Gi) limit the total resources of the containers
+	MaxCPU    *float64 `json:"maxCpu,omitempty"`
+	MaxMemory *string  `json:"maxMemory,omitempty"`
+	// AllowedImages and AllowedSecretNames are patterns matched with path.Match, like myregistry.azurecr.io/team-a/*
+	AllowedImages        []string `json:"allowedImages,omitempty"`
+	AllowedRegistries    []string `json:"allowedRegistries,omitempty"`
+	AllowExternalIngress *bool    `json:"allowExternalIngress,omitempty"`
+	// AllowedSecretNames are the remote secrets (<vault>/<name> for the secret vaults) and KeyVault secrets the tenant can use
+	AllowedSecretNames []string `json:"allowedSecretNames,omitempty"`
+	maxMemoryGi        float64
+	// restrictSecrets is set by forPullRequest, an empty AllowedSecretNames then allows no secrets
+	restrictSecrets bool
+	// outside is set for the manifests outside the paths of every tenant when the policy denies them
+	outside bool
+}
+
+// loadPolicy loads the policy file (path relative to the repository root) and removes it from the yaml files
+func loadPolicy(fsys fs.FS, yamlPath string, yamlFiles *map[string][]byte, policyPath string) (*Policy, error) {
+	if policyPath == "" {
+		return nil, nil
+	}
+
+	cleanPath := strings.TrimPrefix(path.Clean("/"+policyPath), "/")
+	b, err := fs.ReadFile(fsys, cleanPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read policy file %q: %w", policyPath, err)
+	}
+
+	yamlDir := strings.TrimPrefix(path.Clean("/"+yamlPath), "/")
+	switch {
+	case yamlDir == "":
+		delete(*yamlFiles, cleanPath)
+	case strings.HasPrefix(cleanPath, yamlDir+"/"):
+		delete(*yamlFiles, strings.TrimPrefix(cleanPath, yamlDir+"/"))
+	}
+
+	policy, err := parsePolicy(b)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse policy file %q: %w", policyPath, err)
+	}
+
+	return policy, nil
+}
+
+func parsePolicy(b []byte) (*Policy, error) {
+	policy := &Policy{}
+	err := yaml.UnmarshalStrict(b, policy)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]struct{})
+	paths := make(map[string]string)
+	for i := range policy.Tenants {
+		tenant := &policy.Tenants[i]
+		if tenant.Name == "" {
+			return nil, fmt.Errorf("name missing from tenant %d", i)
+		}
+
+		if _, ok := names[tenant.Name]; ok {
+			return nil, fmt.Errorf("tenant %q is defined more than once", tenant.Name)
+		}
+		names[tenant.Name] = struct{}{}
+
+		if len(tenant.Paths) == 0 {
+			return nil, fmt.Errorf("paths missing from tenant %q", tenant.Name)
+		}
+
+		for j, tenantPath := range tenant.Paths {
+			tenantPath = path.Clean(strings.Trim(tenantPath, "/"))
+			if tenantPath == "." || tenantPath == ".." || strings.HasPrefix(tenantPath, "../") {
+				return nil, fmt.Errorf("path %q of tenant %q needs to be a directory in the yaml path", tenant.Paths[j], tenant.Name)
+			}
+			if existing, ok := paths[tenantPath]; ok {
+				return nil, fmt.Errorf("path %q is used by both tenant %q and %q", tenantPath, existing, tenant.Name)
+			}
+			paths[tenantPath] = tenant.Name
+			tenant.Paths[j] = tenantPath
+		}
+
+		if tenant.MaxMemory != nil {
+			tenant.maxMemoryGi, err = parseMemoryGi(*tenant.MaxMemory)
+			if err != nil {
+				return nil, fmt.Errorf("maxMemory of tenant %q: %w", tenant.Name, err)
+			}
+		}
+
+		for _, pattern := range append(append([]string{}, tenant.AllowedImages...), tenant.AllowedSecretNames...) {
+			_, err := path.Match(pattern, "")
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern %q of tenant %q: %w", pattern, tenant.Name, err)
+			}
+		}
+	}
+
+	return policy, nil
+}
+
+// forPullRequest returns a copy of the policy for the preview of a pull request, the tenants without
+// AllowedSecretNames (and the manifests outside of the tenants) can only use the allowed secret names of previews
+func (p *Policy) forPullRequest(allowedSecretNames []string) *Policy {
+	result := &Policy{}
+	if p != nil {
+		result.DenyOutsideTenants = p.DenyOutsideTenants
+		result.Tenants = append([]TenantPolicy{}, p.Tenants...)
+	}
+
+	for i := range result.Tenants {
+		tenant := &result.Tenants[i]
+		if len(tenant.AllowedSecretNames) == 0 {
+			tenant.AllowedSecretNames = allowedSecretNames
+		}
+		tenant.restrictSecrets = true
+	}
+
+	result.outsideTenant = &TenantPolicy{
+		Name:               "preview",
+		AllowedSecretNames: allowedSecretNames,
+		restrictSecrets:    true,
+	}
+
+	return result
+}
+
+// GetTenant returns the tenant with the longest path containing the file, nil if the file isn't in the path of a tenant
+// and the policy doesn't deny it (or the preview policy of forPullRequest)
+func (p *Policy) GetTenant(filePath string) *TenantPolicy {
+	if p == nil {
+		return nil
+	}
+
+	var result *TenantPolicy
+	longest := 0
+	for i := range p.Tenants {
+		for _, tenantPath := range p.Tenants[i].Paths {
+			if !strings.HasPrefix(filePath, tenantPath+"/") || len(tenantPath) <= longest {
+				continue
+			}
+			result = &p.Tenants[i]
+			longest = len(tenantPath)
+		}
+	}
+
+	if result == nil && p.DenyOutsideTenants {
+		return &TenantPolicy{
+			outside: true,
+		}
+	}
+
+	if result == nil {
+		return p.outsideTenant
+	}
+
+	return result
+}
+
+func (tenant *TenantPolicy) validate(containers []*armappcontainers.Container, initContainers []*armappcontainers.InitContainer, ingress *armappcontainers.Ingress, secrets []*armappcontainers.Secret, remoteSecrets []RemoteSecretSpecification) error {
+	if tenant == nil {
+		return nil
+	}
+
+	if tenant.outside {
+		return fmt.Errorf("the manifest isn't in the path of a tenant, which the policy denies")
+	}
+
+	var result *multierror.Error
+	err := tenant.validateResources(containers, initContainers)
+	if err != nil {
+		result = multierror.Append(err, result)
+	}
+
+	for _, container := range toReplacementContainers(containers, initContainers) {
+		if container.image == nil || *container.image == "" {
+			continue
+		}
+
+		image := *container.image
+		if len(tenant.AllowedRegistries) != 0 && !containsFold(tenant.AllowedRegistries, getImageRegistryServer(image)) {
+			result = multierror.Append(fmt.Errorf("tenant %q doesn't allow registry %q of image %q", tenant.Name, getImageRegistryServer(image), image), result)
+		}
+
+		if len(tenant.AllowedImages) != 0 && !matchesAny(tenant.AllowedImages, image) {
+			result = multierror.Append(fmt.Errorf("tenant %q doesn't allow image %q", tenant.Name, image), result)
+		}
+	}
+
+	if tenant.AllowExternalIngress != nil && !*tenant.AllowExternalIngress && ingress != nil && ingress.External != nil && *ingress.External {
+		result = multierror.Append(fmt.Errorf("tenant %q doesn't allow external ingress", tenant.Name), result)
+	}
+
+	if len(tenant.AllowedSecretNames) != 0 || tenant.restrictSecrets {
+		for _, remoteSecret := range remoteSecrets {
+			if remoteSecret.RemoteSecretName == nil {
+				continue
+			}
+			// the secrets of a secret vault are matched with the vault, as they are read using the access of azcagit
+			name := remoteSecret.SecretKey()
+			if matchesAny(tenant.AllowedSecretNames, name) {
+				continue
+			}
+			result = multierror.Append(fmt.Errorf("tenant %q doesn't allow remote secret %q", tenant.Name, name), result)
+		}
+
+		for _, secret := range secrets {
+			if secret == nil || secret.KeyVaultURL == nil {
+				continue
+			}
+			name := getKeyVaultSecretName(*secret.KeyVaultURL)
+			if matchesAny(tenant.AllowedSecretNames, name) {
+				continue
+			}
+			result = multierror.Append(fmt.Errorf("tenant %q doesn't allow KeyVault secret %q", tenant.Name, name), result)
+		}
+	}
+
+	return result.ErrorOrNil()
+}
+
+func (tenant *TenantPolicy) validateResources(containers []*armappcontainers.Container, initContainers []*armappcontainers.InitContainer) error {
+	if tenant.MaxCPU == nil && tenant.MaxMemory == nil {
+		return nil
+	}
+
+	resources := []*armappcontainers.ContainerResources{}
+	for _, container := range containers {
+		if container != nil {
+			resources = append(resources, container.Resources)
+		}
+	}
+
+	cpu, memoryGi, err := sumResources(resources)
+	if err != nil {
+		return err
+	}
+
+	// init containers run before the containers, so they are only limited one by one
+	for _, container := range initContainers {
+		if container == nil {
+			continue
+		}
+		initCPU, initMemoryGi, err := sumResources([]*armappcontainers.ContainerResources{container.Resources})
+		if err != nil {
+			return err
+		}
+		if initCPU > cpu {
+			cpu = initCPU
+		}
+		if initMemoryGi > memoryGi {
+			memoryGi = initMemoryGi
+		}
+	}
+
+	var result *multierror.Error
+	if tenant.MaxCPU != nil && cpu > *tenant.MaxCPU {
+		result = multierror.Append(fmt.Errorf("tenant %q allows a max cpu of %g, but the containers use %g", tenant.Name, *tenant.MaxCPU, cpu), result)
+	}
+
+	if tenant.MaxMemory != nil && memoryGi > tenant.maxMemoryGi {
+		result = multierror.Append(fmt.Errorf("tenant %q allows a max memory of %s, but the containers use %gGi", tenant.Name, *tenant.MaxMemory, memoryGi), result)
+	}
+
+	return result.ErrorOrNil()
+}
+
+func sumResources(resources []*armappcontainers.ContainerResources) (float64, float64, error) {
+	cpu := 0.0
+	memoryGi := 0.0
+	for _, resource := range resources {
+		if resource == nil {
+			continue
+		}
+		if resource.CPU != nil {
+			cpu += *resource.CPU
+		}
+		if resource.Memory != nil {
+			value, err := parseMemoryGi(*resource.Memory)
+			if err != nil {
+				return 0, 0, err
+			}
+			memoryGi += value
+		}
+	}
+
+	return cpu, memoryGi, nil
+}
+
+// parseMemoryGi parses memory like 1.5Gi or 512Mi to Gi
+func parseMemoryGi(s string) (float64, error) {
+	value := strings.TrimSpace(s)
+	divisor := 1.0
+	switch {
+	case strings.HasSuffix(value, "Gi"):
+		value = strings.TrimSuffix(value, "Gi")
+	case strings.HasSuffix(value, "Mi"):
+		value = strings.TrimSuffix(value, "Mi")
+		divisor = 1024
+	default:
+		return 0, fmt.Errorf("unable to parse memory %q, expected Gi or Mi", s)
+	}
+
+	gi, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unable to parse memory %q: %w", s, err)
+	}
+
+	return gi / divisor, nil
+}
+
+// getKeyVaultSecretName returns the name of the secret in a KeyVault url like https://<vault>.vault.azure.net/secrets/<name>/<version>
+func getKeyVaultSecretName(keyVaultURL string) string {
+	_, name, ok := strings.Cut(keyVaultURL, "/secrets/")
+	if !ok {
+		return keyVaultURL
+	}
+
+	name, _, _ = strings.Cut(name, "/")
+	return name
+}
+
+func containsFold(values []string, s string) bool {
+	for _, value := range values {
+		if strings.EqualFold(value, s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchesAny(patterns []string, s string) bool {
+	for _, pattern := range patterns {
+		ok, err := path.Match(pattern, s)
+		if err == nil && ok {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,577 @@
+package source
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/stretchr/testify/require"
+	"github.com/xenitab/azcagit/src/config"
+)
+
+const testPolicyYAML = `
+tenants:
+- name: team-a
+  paths:
+  - teams/a/
+  maxCpu: 1
+  maxMemory: 2Gi
+  allowedRegistries:
+  - myregistry.azurecr.io
+  allowedImages:
+  - myregistry.azurecr.io/team-a/*
+  allowExternalIngress: false
+  allowedSecretNames:
+  - team-a-*
+- name: team-a-public
+  paths:
+  - teams/a/public
+  allowExternalIngress: true
+`
+
+func TestLoadPolicy(t *testing.T) {
+	fsys := fstest.MapFS{
+		"yaml/policy.yaml": {Data: []byte(testPolicyYAML)},
+	}
+	yamlFiles := map[string][]byte{
+		"policy.yaml": []byte(testPolicyYAML),
+		"foo.yaml":    []byte("kind: AzureContainerApp"),
+	}
+
+	policy, err := loadPolicy(fsys, "yaml", &yamlFiles, "/yaml/policy.yaml")
+	require.NoError(t, err)
+	require.Len(t, policy.Tenants, 2)
+	require.Equal(t, []string{"teams/a"}, policy.Tenants[0].Paths)
+	require.Equal(t, 2.0, policy.Tenants[0].maxMemoryGi)
+	require.Equal(t, map[string][]byte{"foo.yaml": []byte("kind: AzureContainerApp")}, yamlFiles)
+
+	require.Equal(t, "team-a", policy.GetTenant("teams/a/foo.yaml").Name)
+	require.Equal(t, "team-a-public", policy.GetTenant("teams/a/public/foo.yaml").Name)
+	require.Nil(t, policy.GetTenant("teams/ab/foo.yaml"))
+	require.Nil(t, policy.GetTenant("foo.yaml"))
+
+	policy.DenyOutsideTenants = true
+	require.Equal(t, "team-a", policy.GetTenant("teams/a/foo.yaml").Name)
+	require.True(t, policy.GetTenant("foo.yaml").outside)
+
+	policy, err = loadPolicy(fsys, "yaml", &yamlFiles, "")
+	require.NoError(t, err)
+	require.Nil(t, policy)
+
+	_, err = loadPolicy(fsys, "yaml", &yamlFiles, "missing.yaml")
+	require.ErrorContains(t, err, "unable to read policy file \"missing.yaml\"")
+}
+
+func TestParsePolicy(t *testing.T) {
+	cases := []struct {
+		testDescription string
+		rawYaml         string
+		expectedError   string
+	}{
+		{
+			testDescription: "valid policy",
+			rawYaml:         testPolicyYAML,
+		},
+		{
+			testDescription: "unknown field",
+			rawYaml:         "tenants:\n- name: foo\n  paths: [foo]\n  maxDisk: 1",
+			expectedError:   "unknown field \"maxDisk\"",
+		},
+		{
+			testDescription: "name missing",
+			rawYaml:         "tenants:\n- paths: [foo]",
+			expectedError:   "name missing from tenant 0",
+		},
+		{
+			testDescription: "duplicate tenant",
+			rawYaml:         "tenants:\n- name: foo\n  paths: [foo]\n- name: foo\n  paths: [bar]",
+			expectedError:   "tenant \"foo\" is defined more than once",
+		},
+		{
+			testDescription: "paths missing",
+			rawYaml:         "tenants:\n- name: foo",
+			expectedError:   "paths missing from tenant \"foo\"",
+		},
+		{
+			testDescription: "duplicate path",
+			rawYaml:         "tenants:\n- name: foo\n  paths: [foo]\n- name: bar\n  paths: [foo/]",
+			expectedError:   "path \"foo\" is used by both tenant \"foo\" and \"bar\"",
+		},
+		{
+			testDescription: "path outside the yaml path",
+			rawYaml:         "tenants:\n- name: foo\n  paths: [/]",
+			expectedError:   "path \"/\" of tenant \"foo\" needs to be a directory in the yaml path",
+		},
+		{
+			testDescription: "invalid memory",
+			rawYaml:         "tenants:\n- name: foo\n  paths: [foo]\n  maxMemory: 2GB",
+			expectedError:   "maxMemory of tenant \"foo\": unable to parse memory \"2GB\", expected Gi or Mi",
+		},
+		{
+			testDescription: "invalid pattern",
+			rawYaml:         "tenants:\n- name: foo\n  paths: [foo]\n  allowedImages: [\"foo/[\"]",
+			expectedError:   "invalid pattern \"foo/[\" of tenant \"foo\"",
+		},
+	}
+
+	for i, c := range cases {
+		t.Logf("Test #%d: %s", i, c.testDescription)
+		_, err := parsePolicy([]byte(c.rawYaml))
+		if c.expectedError != "" {
+			require.ErrorContains(t, err, c.expectedError)
+			continue
+		}
+		require.NoError(t, err)
+	}
+}
+
+func TestPolicyValidation(t *testing.T) {
+	policy, err := parsePolicy([]byte(testPolicyYAML))
+	require.NoError(t, err)
+
+	cfg := config.ReconcileConfig{
+		Location:             "ze-location",
+		ManagedEnvironmentID: "ze-managedEnvironmentID",
+	}
+
+	cases := []struct {
+		testDescription    string
+		path               string
+		rawYaml            string
+		denyOutsideTenants bool
+		expectedErrors     []string
+	}{
+		{
+			testDescription: "app following the policy",
+			path:            "teams/a/foo.yaml",
+			rawYaml: `
+kind: AzureContainerApp
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+  name: foo
+spec:
+  remoteSecrets:
+  - secretName: password
+    remoteSecretName: team-a-password
+  app:
+    properties:
+      configuration:
+        ingress:
+          external: false
+      template:
+        containers:
+        - name: foo
+          image: myregistry.azurecr.io/team-a/foo:v1
+          resources:
+            cpu: 0.5
+            memory: 1Gi
+        - name: sidecar
+          image: myregistry.azurecr.io/team-a/sidecar:v1
+          resources:
+            cpu: 0.5
+            memory: 1024Mi`,
+		},
+		{
+			testDescription: "app violating the policy",
+			path:            "teams/a/foo.yaml",
+			rawYaml: `
+kind: AzureContainerApp
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+  name: foo
+spec:
+  remoteSecrets:
+  - secretName: password
+    remoteSecretName: team-b-password
+  app:
+    properties:
+      configuration:
+        ingress:
+          external: true
+        secrets:
+        - name: other
+          keyVaultUrl: https://ze-kv.vault.azure.net/secrets/team-b-other
+          identity: system
+      template:
+        containers:
+        - name: foo
+          image: docker.io/library/nginx:latest
+          resources:
+            cpu: 1.5
+            memory: 3Gi`,
+			expectedErrors: []string{
+				"tenant \"team-a\" allows a max cpu of 1, but the containers use 1.5",
+				"tenant \"team-a\" allows a max memory of 2Gi, but the containers use 3Gi",
+				"tenant \"team-a\" doesn't allow registry \"docker.io\" of image \"docker.io/library/nginx:latest\"",
+				"tenant \"team-a\" doesn't allow image \"docker.io/library/nginx:latest\"",
+				"tenant \"team-a\" doesn't allow external ingress",
+				"tenant \"team-a\" doesn't allow remote secret \"team-b-password\"",
+				"tenant \"team-a\" doesn't allow KeyVault secret \"team-b-other\"",
+			},
+		},
+		{
+			testDescription: "allowed secret name from a secret vault",
+			path:            "teams/a/foo.yaml",
+			rawYaml: `
+kind: AzureContainerApp
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+  name: foo
+spec:
+  remoteSecrets:
+  - secretName: password
+    remoteSecretName: team-a-password
+    vault: team-b
+  app:
+    properties:
+      template:
+        containers:
+        - name: foo
+          image: myregistry.azurecr.io/team-a/foo:v1`,
+			expectedErrors: []string{
+				"tenant \"team-a\" doesn't allow remote secret \"team-b/team-a-password\"",
+			},
+		},
+		{
+			testDescription: "replacement violating the policy",
+			path:            "teams/a/foo.yaml",
+			rawYaml: `
+kind: AzureContainerApp
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+  name: foo
+spec:
+  replacements:
+    jsonPaths:
+    - path: /properties/configuration/ingress/external
+      value: true
+  app:
+    properties:
+      configuration:
+        ingress:
+          external: false
+      template:
+        containers:
+        - name: foo
+          image: myregistry.azurecr.io/team-a/foo:v1`,
+			expectedErrors: []string{
+				"tenant \"team-a\" doesn't allow external ingress",
+			},
+		},
+		{
+			testDescription: "external ingress allowed by a nested path",
+			path:            "teams/a/public/foo.yaml",
+			rawYaml: `
+kind: AzureContainerApp
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+  name: foo
+spec:
+  app:
+    properties:
+      configuration:
+        ingress:
+          external: true
+      template:
+        containers:
+        - name: foo
+          image: docker.io/library/nginx:latest`,
+		},
+		{
+			testDescription: "app outside the paths of the tenants",
+			path:            "platform/foo.yaml",
+			rawYaml: `
+kind: AzureContainerApp
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+  name: foo
+spec:
+  app:
+    properties:
+      configuration:
+        ingress:
+          external: true
+      template:
+        containers:
+        - name: foo
+          image: docker.io/library/nginx:latest`,
+		},
+		{
+			testDescription:    "app outside the paths of the tenants denied by the policy",
+			path:               "platform/foo.yaml",
+			denyOutsideTenants: true,
+			rawYaml: `
+kind: AzureContainerApp
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+  name: foo
+spec:
+  app:
+    properties:
+      template:
+        containers:
+        - name: foo
+          image: myregistry.azurecr.io/platform/foo:v1`,
+			expectedErrors: []string{
+				"the manifest isn't in the path of a tenant, which the policy denies",
+			},
+		},
+		{
+			testDescription:    "app in the path of a tenant with outside manifests denied",
+			path:               "teams/a/foo.yaml",
+			denyOutsideTenants: true,
+			rawYaml: `
+kind: AzureContainerApp
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+  name: foo
+spec:
+  app:
+    properties:
+      template:
+        containers:
+        - name: foo
+          image: myregistry.azurecr.io/team-a/foo:v1`,
+		},
+		{
+			testDescription: "job violating the policy",
+			path:            "teams/a/bar.yaml",
+			rawYaml: `
+kind: AzureContainerJob
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+  name: bar
+spec:
+  job:
+    properties:
+      configuration:
+        replicaTimeout: 60
+        triggerType: Manual
+      template:
+        initContainers:
+        - name: init
+          image: myregistry.azurecr.io/team-a/init:v1
+          resources:
+            cpu: 2
+            memory: 1Gi
+        containers:
+        - name: bar
+          image: ghcr.io/team-a/bar:v1`,
+			expectedErrors: []string{
+				"tenant \"team-a\" allows a max cpu of 1, but the containers use 2",
+				"tenant \"team-a\" doesn't allow registry \"ghcr.io\" of image \"ghcr.io/team-a/bar:v1\"",
+			},
+		},
+		{
+			testDescription: "dapr component violating the policy",
+			path:            "teams/a/statestore.yaml",
+			rawYaml: `
+kind: AzureContainerDaprComponent
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+  name: statestore
+spec:
+  remoteSecrets:
+  - secretName: storage-account-key
+    remoteSecretName: team-b-storage-account-key
+  component:
+    properties:
+      componentType: state.azure.blobstorage
+      secrets:
+      - name: other
+        keyVaultUrl: https://ze-kv.vault.azure.net/secrets/team-b-other
+        identity: system`,
+			expectedErrors: []string{
+				"tenant \"team-a\" doesn't allow remote secret \"team-b-storage-account-key\"",
+				"tenant \"team-a\" doesn't allow KeyVault secret \"team-b-other\"",
+			},
+		},
+		{
+			testDescription: "storage violating the policy",
+			path:            "teams/a/data.yaml",
+			rawYaml: `
+kind: AzureContainerStorage
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+  name: data
+spec:
+  remoteSecrets:
+  - secretName: accountKey
+    remoteSecretName: team-a-storage-key
+    vault: team-b
+  storage:
+    properties:
+      azureFile:
+        accountName: foo
+        shareName: data
+        accessMode: ReadOnly`,
+			expectedErrors: []string{
+				"tenant \"team-a\" doesn't allow remote secret \"team-b/team-a-storage-key\"",
+			},
+		},
+		{
+			testDescription: "certificate violating the policy",
+			path:            "teams/a/cert.yaml",
+			rawYaml: `
+kind: AzureContainerCertificate
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+  name: foo-example-com
+spec:
+  keyVaultCertificate:
+    remoteSecretName: team-b-certificate`,
+			expectedErrors: []string{
+				"tenant \"team-a\" doesn't allow remote secret \"team-b-certificate\"",
+			},
+		},
+		{
+			testDescription: "storage following the policy",
+			path:            "teams/a/data.yaml",
+			rawYaml: `
+kind: AzureContainerStorage
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+  name: data
+spec:
+  remoteSecrets:
+  - secretName: accountKey
+    remoteSecretName: team-a-storage-key
+  storage:
+    properties:
+      azureFile:
+        accountName: foo
+        shareName: data
+        accessMode: ReadOnly`,
+		},
+		{
+			testDescription:    "dapr component outside the paths of the tenants denied by the policy",
+			path:               "platform/statestore.yaml",
+			denyOutsideTenants: true,
+			rawYaml: `
+kind: AzureContainerDaprComponent
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+  name: statestore
+spec:
+  component:
+    properties:
+      componentType: state.azure.blobstorage`,
+			expectedErrors: []string{
+				"the manifest isn't in the path of a tenant, which the policy denies",
+			},
+		},
+	}
+
+	for i, c := range cases {
+		t.Logf("Test #%d: %s", i, c.testDescription)
+		policy.DenyOutsideTenants = c.denyOutsideTenants
+		sources := getSourcesFromFiles(&map[string][]byte{c.path: []byte(c.rawYaml)}, cfg, policy)
+		var result *multierror.Error
+		for _, err := range []error{sources.Apps.Error(), sources.Jobs.Error(), sources.DaprComponents.Error(), sources.Certificates.Error(), sources.Storages.Error()} {
+			if err != nil {
+				result = multierror.Append(err, result)
+			}
+		}
+		err := result.ErrorOrNil()
+		if len(c.expectedErrors) == 0 {
+			require.NoError(t, err)
+			continue
+		}
+		require.Error(t, err)
+		for _, expectedError := range c.expectedErrors {
+			require.ErrorContains(t, err, expectedError)
+		}
+	}
+}
+
+func TestPolicyForPullRequest(t *testing.T) {
+	policy, err := parsePolicy([]byte(testPolicyYAML))
+	require.NoError(t, err)
+
+	cfg := config.ReconcileConfig{
+		Location:             "ze-location",
+		ManagedEnvironmentID: "ze-managedEnvironmentID",
+	}
+
+	rawYaml := `
+kind: AzureContainerApp
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+  name: foo
+spec:
+  remoteSecrets:
+  - secretName: password
+    remoteSecretName: team-a-password
+  app:
+    properties:
+      template:
+        containers:
+        - name: foo
+          image: myregistry.azurecr.io/team-a/foo:v1`
+
+	cases := []struct {
+		testDescription    string
+		policy             *Policy
+		path               string
+		allowedSecretNames []string
+		expectedError      string
+	}{
+		{
+			testDescription: "secret denied by default outside the tenants",
+			policy:          policy,
+			path:            "platform/foo.yaml",
+			expectedError:   "tenant \"preview\" doesn't allow remote secret \"team-a-password\"",
+		},
+		{
+			testDescription: "secret denied by default without a policy",
+			policy:          nil,
+			path:            "platform/foo.yaml",
+			expectedError:   "tenant \"preview\" doesn't allow remote secret \"team-a-password\"",
+		},
+		{
+			testDescription: "secret denied by default for a tenant without allowed secret names",
+			policy:          policy,
+			path:            "teams/a/public/foo.yaml",
+			expectedError:   "tenant \"team-a-public\" doesn't allow remote secret \"team-a-password\"",
+		},
+		{
+			testDescription:    "secret allowed for previews",
+			policy:             policy,
+			path:               "teams/a/public/foo.yaml",
+			allowedSecretNames: []string{"*-password"},
+		},
+		{
+			testDescription:    "secret allowed for previews outside the tenants",
+			policy:             nil,
+			path:               "platform/foo.yaml",
+			allowedSecretNames: []string{"team-a-password"},
+		},
+		{
+			testDescription: "secret allowed by the tenant",
+			policy:          policy,
+			path:            "teams/a/foo.yaml",
+		},
+		{
+			testDescription:    "allowed secret names of the tenant are used before the ones of previews",
+			policy:             policy,
+			path:               "teams/a/foo.yaml",
+			allowedSecretNames: []string{"team-b-*"},
+		},
+	}
+
+	for i, c := range cases {
+		t.Logf("Test #%d: %s", i, c.testDescription)
+		previewPolicy := c.policy.forPullRequest(c.allowedSecretNames)
+		sources := getSourcesFromFiles(&map[string][]byte{c.path: []byte(rawYaml)}, cfg, previewPolicy)
+		err := sources.Apps.Error()
+		if c.expectedError == "" {
+			require.NoError(t, err)
+			continue
+		}
+		require.ErrorContains(t, err, c.expectedError)
+	}
+
+	// the policy of the environment isn't changed
+	sources := getSourcesFromFiles(&map[string][]byte{"teams/a/public/foo.yaml": []byte(rawYaml)}, cfg, policy)
+	require.NoError(t, sources.Apps.Error())
+}
@@ -0,0 +1,84 @@
+package source
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/appcontainers/armappcontainers/v2"
+)
+
+const (
+	registryPasswordSecretName = "azcagit-reg-cred"
+	dockerHubRegistryServer    = "docker.io"
+)
+
+// validateRegistryNotConfigured returns an error if the manifest already has credentials for the server
+func validateRegistryNotConfigured(registries []*armappcontainers.RegistryCredentials, server string) error {
+	for _, v := range registries {
+		if v == nil || v.Server == nil {
+			continue
+		}
+
+		if v.Identity == nil && v.PasswordSecretRef == nil {
+			continue
+		}
+
+		if strings.EqualFold(*v.Server, server) {
+			return fmt.Errorf("the server %q already exists", server)
+		}
+	}
+
+	return nil
+}
+
+// getRegistryPasswordSecretName returns azcagit-reg-cred for the first registry with a password and
+// azcagit-reg-cred-<n> for the following
+func getRegistryPasswordSecretName(secrets []*armappcontainers.Secret) string {
+	existing := make(map[string]struct{})
+	for _, secret := range secrets {
+		if secret == nil || secret.Name == nil {
+			continue
+		}
+		existing[*secret.Name] = struct{}{}
+	}
+
+	name := registryPasswordSecretName
+	for i := 2; ; i++ {
+		_, ok := existing[name]
+		if !ok {
+			return name
+		}
+		name = fmt.Sprintf("%s-%d", registryPasswordSecretName, i)
+	}
+}
+
+// getImageRegistryServers returns the unique registry servers of the container images, images without
+// a registry are from Docker Hub
+func getImageRegistryServers(containers []*armappcontainers.Container, initContainers []*armappcontainers.InitContainer) []string {
+	serversMap := make(map[string]struct{})
+	for _, container := range toReplacementContainers(containers, initContainers) {
+		if container.image == nil || *container.image == "" {
+			continue
+		}
+
+		serversMap[getImageRegistryServer(*container.image)] = struct{}{}
+	}
+
+	servers := []string{}
+	for server := range serversMap {
+		servers = append(servers, server)
+	}
+	sort.Strings(servers)
+
+	return servers
+}
+
+func getImageRegistryServer(image string) string {
+	host, _, ok := strings.Cut(image, "/")
+	if ok && (strings.ContainsAny(host, ".:") || host == "localhost") {
+		return host
+	}
+
+	return dockerHubRegistryServer
+}
@@ -0,0 +1,289 @@
+package source
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/appcontainers/armappcontainers/v2"
+	"github.com/Masterminds/semver/v3"
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/hashicorp/go-multierror"
+)
+
+type ImageReplacementSpecification struct {
+	ImageName      *string `json:"imageName,omitempty" yaml:"imageName,omitempty"`
+	NewImageTag    *string `json:"newImageTag,omitempty" yaml:"newImageTag,omitempty"`
+	NewImageDigest *string `json:"newImageDigest,omitempty" yaml:"newImageDigest,omitempty"`
+	// Policy is used by the image-update subcommand to update newImageTag in git
+	Policy *ImagePolicySpecification `json:"policy,omitempty" yaml:"policy,omitempty"`
+}
+
+type ImagePolicySpecification struct {
+	// Semver selects the highest tag within the semver range
+	Semver *string `json:"semver,omitempty" yaml:"semver,omitempty"`
+	// Pattern filters the tags with a regular expression before they are ordered
+	Pattern *string `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+	// Latest selects the most recently created image
+	Latest *bool `json:"latest,omitempty" yaml:"latest,omitempty"`
+}
+
+func (p *ImagePolicySpecification) Validate() error {
+	hasSemver := p.Semver != nil && *p.Semver != ""
+	isLatest := p.Latest != nil && *p.Latest
+	if hasSemver == isLatest {
+		return fmt.Errorf("policy should have exactly one of semver or latest")
+	}
+
+	if hasSemver {
+		_, err := semver.NewConstraint(*p.Semver)
+		if err != nil {
+			return fmt.Errorf("policy has an invalid semver range %q: %w", *p.Semver, err)
+		}
+	}
+
+	if p.Pattern != nil {
+		_, err := regexp.Compile(*p.Pattern)
+		if err != nil {
+			return fmt.Errorf("policy has an invalid pattern %q: %w", *p.Pattern, err)
+		}
+	}
+
+	return nil
+}
+
+type EnvReplacementSpecification struct {
+	// ContainerName limits the replacement to a single container, all containers are matched if empty
+	ContainerName *string `json:"containerName,omitempty" yaml:"containerName,omitempty"`
+	Name          *string `json:"name,omitempty" yaml:"name,omitempty"`
+	Value         *string `json:"value,omitempty" yaml:"value,omitempty"`
+}
+
+type ScaleReplacementSpecification struct {
+	MinReplicas *int32 `json:"minReplicas,omitempty" yaml:"minReplicas,omitempty"`
+	MaxReplicas *int32 `json:"maxReplicas,omitempty" yaml:"maxReplicas,omitempty"`
+}
+
+type JSONPathReplacementSpecification struct {
+	// Path is a JSON pointer (RFC 6901) relative to spec.app or spec.job
+	Path  *string     `json:"path,omitempty" yaml:"path,omitempty"`
+	Value interface{} `json:"value,omitempty" yaml:"value,omitempty"`
+}
+
+type ReplacementsSpecification struct {
+	Images    []ImageReplacementSpecification    `json:"images,omitempty" yaml:"images,omitempty"`
+	Env       []EnvReplacementSpecification      `json:"env,omitempty" yaml:"env,omitempty"`
+	Scale     *ScaleReplacementSpecification     `json:"scale,omitempty" yaml:"scale,omitempty"`
+	JSONPaths []JSONPathReplacementSpecification `json:"jsonPaths,omitempty" yaml:"jsonPaths,omitempty"`
+}
+
+func (r *ReplacementsSpecification) isEmpty() bool {
+	if r == nil {
+		return true
+	}
+	return len(r.Images) == 0 && len(r.Env) == 0 && r.Scale == nil && len(r.JSONPaths) == 0
+}
+
+func (r *ReplacementsSpecification) hasContainerReplacements() bool {
+	return r != nil && (len(r.Images) != 0 || len(r.Env) != 0)
+}
+
+func (r *ReplacementsSpecification) validate() error {
+	var result *multierror.Error
+	for i, image := range r.Images {
+		if image.ImageName == nil || *image.ImageName == "" {
+			result = multierror.Append(fmt.Errorf("image replacement %d is missing imageName", i), result)
+		}
+		hasTag := image.NewImageTag != nil && *image.NewImageTag != ""
+		hasDigest := image.NewImageDigest != nil && *image.NewImageDigest != ""
+		if hasTag == hasDigest {
+			result = multierror.Append(fmt.Errorf("image replacement %d should have exactly one of newImageTag or newImageDigest", i), result)
+		}
+		if image.Policy != nil {
+			if !hasTag {
+				result = multierror.Append(fmt.Errorf("image replacement %d with policy requires newImageTag", i), result)
+			}
+			err := image.Policy.Validate()
+			if err != nil {
+				result = multierror.Append(fmt.Errorf("image replacement %d: %w", i, err), result)
+			}
+		}
+	}
+
+	for i, env := range r.Env {
+		if env.Name == nil || *env.Name == "" {
+			result = multierror.Append(fmt.Errorf("env replacement %d is missing name", i), result)
+		}
+		if env.Value == nil {
+			result = multierror.Append(fmt.Errorf("env replacement %d is missing value", i), result)
+		}
+	}
+
+	if r.Scale != nil && r.Scale.MinReplicas == nil && r.Scale.MaxReplicas == nil {
+		result = multierror.Append(fmt.Errorf("scale replacement requires minReplicas and/or maxReplicas"), result)
+	}
+
+	for i, jsonPath := range r.JSONPaths {
+		if jsonPath.Path == nil || !strings.HasPrefix(*jsonPath.Path, "/") {
+			result = multierror.Append(fmt.Errorf("json path replacement %d should have a path starting with /", i), result)
+		}
+	}
+
+	return result.ErrorOrNil()
+}
+
+type replacementContainer struct {
+	name  *string
+	image *string
+	env   []*armappcontainers.EnvironmentVar
+}
+
+func toReplacementContainers(containers []*armappcontainers.Container, initContainers []*armappcontainers.InitContainer) []replacementContainer {
+	result := []replacementContainer{}
+	for _, container := range containers {
+		if container == nil {
+			continue
+		}
+		result = append(result, replacementContainer{name: container.Name, image: container.Image, env: container.Env})
+	}
+	for _, container := range initContainers {
+		if container == nil {
+			continue
+		}
+		result = append(result, replacementContainer{name: container.Name, image: container.Image, env: container.Env})
+	}
+	return result
+}
+
+// applyContainers replaces images and environment variables in place and returns an error
+// if any of the replacements didn't match a container.
+func (r *ReplacementsSpecification) applyContainers(containers []replacementContainer) error {
+	if !r.hasContainerReplacements() {
+		return nil
+	}
+
+	if len(containers) == 0 {
+		return fmt.Errorf("no containers found")
+	}
+
+	var result *multierror.Error
+	imagesMatched := make([]bool, len(r.Images))
+	envMatched := make([]bool, len(r.Env))
+	for i, container := range containers {
+		if container.image == nil || *container.image == "" {
+			return fmt.Errorf("no image found for container %d", i)
+		}
+
+		imageName, _, _ := parseImageReference(*container.image)
+		for j, replacementImage := range r.Images {
+			if imageName != *replacementImage.ImageName {
+				continue
+			}
+			imagesMatched[j] = true
+			if replacementImage.NewImageDigest != nil && *replacementImage.NewImageDigest != "" {
+				*container.image = fmt.Sprintf("%s@%s", imageName, *replacementImage.NewImageDigest)
+				continue
+			}
+			*container.image = fmt.Sprintf("%s:%s", imageName, *replacementImage.NewImageTag)
+		}
+
+		for j, replacementEnv := range r.Env {
+			if replacementEnv.ContainerName != nil && *replacementEnv.ContainerName != "" {
+				if container.name == nil || *container.name != *replacementEnv.ContainerName {
+					continue
+				}
+			}
+			for _, env := range container.env {
+				if env == nil || env.Name == nil || *env.Name != *replacementEnv.Name {
+					continue
+				}
+				envMatched[j] = true
+				// replacing a secret reference would turn the secret into a plain value
+				if env.SecretRef != nil && *env.SecretRef != "" {
+					result = multierror.Append(fmt.Errorf("env replacement for %q can't replace the secret reference %q", *replacementEnv.Name, *env.SecretRef), result)
+					continue
+				}
+				env.Value = toPtr(*replacementEnv.Value)
+			}
+		}
+	}
+
+	for i, matched := range imagesMatched {
+		if !matched {
+			result = multierror.Append(fmt.Errorf("image replacement for %q didn't match any container", *r.Images[i].ImageName), result)
+		}
+	}
+	for i, matched := range envMatched {
+		if !matched {
+			result = multierror.Append(fmt.Errorf("env replacement for %q didn't match any container", *r.Env[i].Name), result)
+		}
+	}
+
+	return result.ErrorOrNil()
+}
+
+// applyJSONPathReplacements replaces the values at the configured paths of obj and returns
+// a new object, every path is required to exist.
+func applyJSONPathReplacements[T any](obj *T, replacements []JSONPathReplacementSpecification) (*T, error) {
+	if len(replacements) == 0 {
+		return obj, nil
+	}
+
+	doc, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, replacement := range replacements {
+		operation := []map[string]interface{}{
+			{
+				"op":    "replace",
+				"path":  *replacement.Path,
+				"value": replacement.Value,
+			},
+		}
+		b, err := json.Marshal(operation)
+		if err != nil {
+			return nil, err
+		}
+
+		patch, err := jsonpatch.DecodePatch(b)
+		if err != nil {
+			return nil, err
+		}
+
+		doc, err = patch.Apply(doc)
+		if err != nil {
+			return nil, fmt.Errorf("json path replacement for %q didn't match: %w", *replacement.Path, err)
+		}
+	}
+
+	var result T
+	err = json.Unmarshal(doc, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// parseImageReference splits an image reference into name, tag and digest. A registry
+// with a port (myregistry:5000/foo:bar) is kept as part of the name.
+func parseImageReference(image string) (string, string, string) {
+	name := image
+	digest := ""
+	if i := strings.Index(name, "@"); i >= 0 {
+		digest = name[i+1:]
+		name = name[:i]
+	}
+
+	tag := ""
+	lastSlash := strings.LastIndex(name, "/")
+	if i := strings.LastIndex(name, ":"); i > lastSlash {
+		tag = name[i+1:]
+		name = name[:i]
+	}
+
+	return name, tag, digest
+}
@@ -0,0 +1,282 @@
+package source
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/appcontainers/armappcontainers/v2"
+	"github.com/stretchr/testify/require"
+	"github.com/xenitab/azcagit/src/config"
+)
+
+func TestParseImageReference(t *testing.T) {
+	cases := []struct {
+		testDescription string
+		image           string
+		expectedName    string
+		expectedTag     string
+		expectedDigest  string
+	}{
+		{
+			testDescription: "name only",
+			image:           "foo",
+			expectedName:    "foo",
+		},
+		{
+			testDescription: "name and tag",
+			image:           "mcr.microsoft.com/azuredocs/containerapps-helloworld:latest",
+			expectedName:    "mcr.microsoft.com/azuredocs/containerapps-helloworld",
+			expectedTag:     "latest",
+		},
+		{
+			testDescription: "registry with port",
+			image:           "myregistry:5000/foo/bar:v1",
+			expectedName:    "myregistry:5000/foo/bar",
+			expectedTag:     "v1",
+		},
+		{
+			testDescription: "registry with port without tag",
+			image:           "myregistry:5000/foo/bar",
+			expectedName:    "myregistry:5000/foo/bar",
+		},
+		{
+			testDescription: "digest",
+			image:           "myregistry:5000/foo/bar@sha256:abc",
+			expectedName:    "myregistry:5000/foo/bar",
+			expectedDigest:  "sha256:abc",
+		},
+		{
+			testDescription: "tag and digest",
+			image:           "foo/bar:v1@sha256:abc",
+			expectedName:    "foo/bar",
+			expectedTag:     "v1",
+			expectedDigest:  "sha256:abc",
+		},
+	}
+
+	for i, c := range cases {
+		t.Logf("Test #%d: %s", i, c.testDescription)
+		name, tag, digest := parseImageReference(c.image)
+		require.Equal(t, c.expectedName, name)
+		require.Equal(t, c.expectedTag, tag)
+		require.Equal(t, c.expectedDigest, digest)
+	}
+}
+
+func TestAppReplacements(t *testing.T) {
+	cases := []struct {
+		testDescription string
+		replacements    string
+		expectedError   string
+		validate        func(t *testing.T, app *armappcontainers.ContainerApp)
+	}{
+		{
+			testDescription: "image tag with registry port",
+			replacements: `
+    images:
+      - imageName: "myregistry:5000/foo"
+        newImageTag: "v2"`,
+			validate: func(t *testing.T, app *armappcontainers.ContainerApp) {
+				require.Equal(t, "myregistry:5000/foo:v2", *app.Properties.Template.Containers[0].Image)
+			},
+		},
+		{
+			testDescription: "image digest",
+			replacements: `
+    images:
+      - imageName: "myregistry:5000/foo"
+        newImageDigest: "sha256:abc"`,
+			validate: func(t *testing.T, app *armappcontainers.ContainerApp) {
+				require.Equal(t, "myregistry:5000/foo@sha256:abc", *app.Properties.Template.Containers[0].Image)
+			},
+		},
+		{
+			testDescription: "init container image",
+			replacements: `
+    images:
+      - imageName: "init"
+        newImageTag: "v3"`,
+			validate: func(t *testing.T, app *armappcontainers.ContainerApp) {
+				require.Equal(t, "init:v3", *app.Properties.Template.InitContainers[0].Image)
+			},
+		},
+		{
+			testDescription: "env value",
+			replacements: `
+    env:
+      - containerName: foo
+        name: FOO
+        value: baz`,
+			validate: func(t *testing.T, app *armappcontainers.ContainerApp) {
+				require.Equal(t, "baz", *app.Properties.Template.Containers[0].Env[0].Value)
+			},
+		},
+		{
+			testDescription: "scale",
+			replacements: `
+    scale:
+      minReplicas: 2
+      maxReplicas: 5`,
+			validate: func(t *testing.T, app *armappcontainers.ContainerApp) {
+				require.Equal(t, int32(2), *app.Properties.Template.Scale.MinReplicas)
+				require.Equal(t, int32(5), *app.Properties.Template.Scale.MaxReplicas)
+			},
+		},
+		{
+			testDescription: "json path",
+			replacements: `
+    jsonPaths:
+      - path: /properties/template/containers/0/resources/cpu
+        value: 0.5
+      - path: /properties/configuration/activeRevisionsMode
+        value: Multiple`,
+			validate: func(t *testing.T, app *armappcontainers.ContainerApp) {
+				require.Equal(t, float64(0.5), *app.Properties.Template.Containers[0].Resources.CPU)
+				require.Equal(t, armappcontainers.ActiveRevisionsModeMultiple, *app.Properties.Configuration.ActiveRevisionsMode)
+			},
+		},
+		{
+			testDescription: "image not matched",
+			replacements: `
+    images:
+      - imageName: "myregistry:5000/bar"
+        newImageTag: "v2"`,
+			expectedError: "image replacement for \"myregistry:5000/bar\" didn't match any container",
+		},
+		{
+			testDescription: "env not matched for container",
+			replacements: `
+    env:
+      - containerName: init
+        name: FOO
+        value: baz`,
+			expectedError: "env replacement for \"FOO\" didn't match any container",
+		},
+		{
+			testDescription: "env with a secret reference",
+			replacements: `
+    env:
+      - name: PASSWORD
+        value: plaintext`,
+			expectedError: "env replacement for \"PASSWORD\" can't replace the secret reference \"password\"",
+		},
+		{
+			testDescription: "json path not matched",
+			replacements: `
+    jsonPaths:
+      - path: /properties/template/containers/3/image
+        value: foo`,
+			expectedError: "json path replacement for \"/properties/template/containers/3/image\" didn't match",
+		},
+		{
+			testDescription: "both tag and digest",
+			replacements: `
+    images:
+      - imageName: "myregistry:5000/foo"
+        newImageTag: "v2"
+        newImageDigest: "sha256:abc"`,
+			expectedError: "image replacement 0 should have exactly one of newImageTag or newImageDigest",
+		},
+	}
+
+	for i, c := range cases {
+		t.Logf("Test #%d: %s", i, c.testDescription)
+		rawYaml := `
+kind: AzureContainerApp
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+  name: foo
+spec:
+  replacements:` + c.replacements + `
+  app:
+    properties:
+      configuration:
+        activeRevisionsMode: Single
+      template:
+        initContainers:
+        - name: init
+          image: init:latest
+        containers:
+        - name: foo
+          image: myregistry:5000/foo:v1
+          env:
+          - name: FOO
+            value: bar
+          - name: PASSWORD
+            secretRef: password
+          resources:
+            cpu: 0.25
+            memory: .5Gi
+        scale:
+          minReplicas: 1
+          maxReplicas: 1
+`
+		app := SourceApp{}
+		_, err := app.Unmarshal([]byte(rawYaml), config.ReconcileConfig{
+			Location:             "ze-location",
+			ManagedEnvironmentID: "ze-managedEnvironmentID",
+		})
+		if c.expectedError != "" {
+			require.ErrorContains(t, err, c.expectedError)
+			continue
+		}
+		require.NoError(t, err)
+		c.validate(t, app.Specification.App)
+		require.Equal(t, "ze-location", *app.Specification.App.Location)
+	}
+}
+
+func TestJobReplacements(t *testing.T) {
+	rawYaml := `
+kind: AzureContainerJob
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+  name: foo
+spec:
+  replacements:
+    images:
+      - imageName: "myregistry:5000/foo"
+        newImageDigest: "sha256:abc"
+    jsonPaths:
+      - path: /properties/configuration/replicaTimeout
+        value: 60
+  job:
+    properties:
+      configuration:
+        triggerType: Manual
+        replicaTimeout: 1800
+      template:
+        containers:
+        - name: foo
+          image: myregistry:5000/foo:v1
+`
+	cfg := config.ReconcileConfig{
+		Location:             "ze-location",
+		ManagedEnvironmentID: "ze-managedEnvironmentID",
+	}
+	job := SourceJob{}
+	_, err := job.Unmarshal([]byte(rawYaml), cfg)
+	require.NoError(t, err)
+	require.Equal(t, "myregistry:5000/foo@sha256:abc", *job.Specification.Job.Properties.Template.Containers[0].Image)
+	require.Equal(t, int32(60), *job.Specification.Job.Properties.Configuration.ReplicaTimeout)
+
+	scaleJob := SourceJob{}
+	_, err = scaleJob.Unmarshal([]byte(`
+kind: AzureContainerJob
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+  name: foo
+spec:
+  replacements:
+    scale:
+      minReplicas: 1
+  job:
+    properties:
+      configuration:
+        triggerType: Manual
+      template:
+        containers:
+        - name: foo
+          image: foo:v1
+`), cfg)
+	require.ErrorContains(t, err, "scale replacement isn't supported for jobs")
+}
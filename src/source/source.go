@@ -6,8 +6,11 @@ import (
 )
 
 type Sources struct {
-	Apps *SourceApps
-	Jobs *SourceJobs
+	Apps           *SourceApps
+	Jobs           *SourceJobs
+	DaprComponents *SourceDaprComponents
+	Certificates   *SourceCertificates
+	Storages       *SourceStorages
 }
 
 func (srcs *Sources) GetUniqueRemoteSecretNames() []string {
@@ -29,6 +32,52 @@ func (srcs *Sources) GetUniqueRemoteSecretNames() []string {
 		}
 	}
 
+	if srcs.DaprComponents != nil {
+		for _, remoteSecretName := range srcs.DaprComponents.GetUniqueRemoteSecretNames() {
+			secretsMap[remoteSecretName] = struct{}{}
+		}
+	}
+
+	if srcs.Certificates != nil {
+		for _, remoteSecretName := range srcs.Certificates.GetUniqueRemoteSecretNames() {
+			secretsMap[remoteSecretName] = struct{}{}
+		}
+	}
+
+	if srcs.Storages != nil {
+		for _, remoteSecretName := range srcs.Storages.GetUniqueRemoteSecretNames() {
+			secretsMap[remoteSecretName] = struct{}{}
+		}
+	}
+
+	secrets := []string{}
+	for secret := range secretsMap {
+		secrets = append(secrets, secret)
+	}
+	sort.Strings(secrets)
+
+	return secrets
+}
+
+func (srcs *Sources) GetUniqueKeyVaultReferenceNames() []string {
+	secretsMap := make(map[string]struct{})
+
+	if srcs == nil {
+		return nil
+	}
+
+	if srcs.Apps != nil {
+		for _, remoteSecretName := range srcs.Apps.GetUniqueKeyVaultReferenceNames() {
+			secretsMap[remoteSecretName] = struct{}{}
+		}
+	}
+
+	if srcs.Jobs != nil {
+		for _, remoteSecretName := range srcs.Jobs.GetUniqueKeyVaultReferenceNames() {
+			secretsMap[remoteSecretName] = struct{}{}
+		}
+	}
+
 	secrets := []string{}
 	for secret := range secretsMap {
 		secrets = append(secrets, secret)
@@ -0,0 +1,344 @@
+package source
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/appcontainers/armappcontainers/v2"
+	"github.com/hashicorp/go-multierror"
+	"github.com/xenitab/azcagit/src/config"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	AzureContainerStorageVersion = "aca.xenit.io/v1alpha2"
+	AzureContainerStorageKind    = "AzureContainerStorage"
+	// StorageAccountKeySecretName is the only secret name supported by storages, setting the account key
+	StorageAccountKeySecretName = "accountKey"
+)
+
+type SourceStorageSpecification struct {
+	Storage        *armappcontainers.ManagedEnvironmentStorage `json:"storage,omitempty" yaml:"storage,omitempty"`
+	RemoteSecrets  []RemoteSecretSpecification                 `json:"remoteSecrets,omitempty" yaml:"remoteSecrets,omitempty"`
+	LocationFilter []LocationFilterSpecification               `json:"locationFilter,omitempty" yaml:"locationFilter,omitempty"`
+}
+
+type SourceStorage struct {
+	Kind          string                      `json:"kind,omitempty" yaml:"kind,omitempty"`
+	APIVersion    string                      `json:"apiVersion,omitempty" yaml:"apiVersion,omitempty"`
+	Metadata      map[string]string           `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+	Specification *SourceStorageSpecification `json:"spec,omitempty" yaml:"spec,omitempty"`
+	Err           error
+	// tenant is the policy of the tenant with the path of the manifest, nil if it isn't in the path of a tenant
+	tenant *TenantPolicy
+}
+
+func (storage *SourceStorage) Error() error {
+	return storage.Err
+}
+
+func (storage *SourceStorage) Name() string {
+	if storage.Metadata == nil {
+		return ""
+	}
+
+	name, ok := storage.Metadata["name"]
+	if !ok {
+		return ""
+	}
+
+	return name
+}
+
+func (storage *SourceStorage) SetSecret(name string, value string) error {
+	if storage == nil || storage.Specification == nil || storage.Specification.Storage == nil {
+		return fmt.Errorf("storage is nil")
+	}
+
+	if name != StorageAccountKeySecretName {
+		return fmt.Errorf("storages only support the secret %q, not %q", StorageAccountKeySecretName, name)
+	}
+
+	if storage.Specification.Storage.Properties == nil {
+		storage.Specification.Storage.Properties = &armappcontainers.ManagedEnvironmentStorageProperties{}
+	}
+
+	if storage.Specification.Storage.Properties.AzureFile == nil {
+		storage.Specification.Storage.Properties.AzureFile = &armappcontainers.AzureFileProperties{}
+	}
+
+	if storage.Specification.Storage.Properties.AzureFile.AccountKey != nil {
+		return fmt.Errorf("a secret with name %q already exists", name)
+	}
+
+	storage.Specification.Storage.Properties.AzureFile.AccountKey = &value
+
+	return nil
+}
+
+func (storage *SourceStorage) GetRemoteSecrets() []RemoteSecretSpecification {
+	if storage == nil || storage.Specification == nil || len(storage.Specification.RemoteSecrets) == 0 {
+		return []RemoteSecretSpecification{}
+	}
+
+	secrets := []RemoteSecretSpecification{}
+	for _, secret := range storage.Specification.RemoteSecrets {
+		if !secret.Valid() {
+			continue
+		}
+		secrets = append(secrets, secret)
+	}
+
+	return secrets
+}
+
+func (storage *SourceStorage) ValidateFields() error {
+	var result *multierror.Error
+	if storage.Kind == "" {
+		return fmt.Errorf("kind is missing")
+	}
+	if storage.Kind != "" && storage.Kind != AzureContainerStorageKind {
+		return fmt.Errorf("kind not AzureContainerStorage")
+	}
+	requiredVersion := AzureContainerStorageVersion
+	if storage.APIVersion != "" && storage.APIVersion != requiredVersion {
+		result = multierror.Append(fmt.Errorf("apiVersion for %s should be %s", storage.Kind, requiredVersion), result)
+	}
+
+	if storage.Specification == nil {
+		result = multierror.Append(fmt.Errorf("spec is missing"), result)
+	}
+
+	if storage.Specification != nil && storage.Specification.Storage == nil {
+		result = multierror.Append(fmt.Errorf("storage is missing"), result)
+	}
+
+	if storage.Specification != nil && storage.Specification.Storage != nil && (storage.Specification.Storage.Properties == nil || storage.Specification.Storage.Properties.AzureFile == nil) {
+		result = multierror.Append(fmt.Errorf("azureFile is missing"), result)
+	}
+
+	if storage.Specification != nil {
+		err := validateRemoteSecretsNotKeyVaultReferences(storage.Specification.RemoteSecrets)
+		if err != nil {
+			result = multierror.Append(err, result)
+		}
+
+		err = validateRemoteSecretNames(storage.Specification.RemoteSecrets)
+		if err != nil {
+			result = multierror.Append(err, result)
+		}
+	}
+
+	if storage.Specification != nil {
+		for _, secret := range storage.Specification.RemoteSecrets {
+			if secret.SecretName != nil && *secret.SecretName != StorageAccountKeySecretName {
+				result = multierror.Append(fmt.Errorf("remoteSecrets only support the secretName %s", StorageAccountKeySecretName), result)
+			}
+		}
+	}
+
+	err := storage.validatePolicy()
+	if err != nil {
+		result = multierror.Append(err, result)
+	}
+
+	if storage.Metadata == nil {
+		result = multierror.Append(fmt.Errorf("metadata is missing"), result)
+	}
+
+	if storage.Metadata != nil {
+		_, ok := storage.Metadata["name"]
+		if !ok {
+			result = multierror.Append(fmt.Errorf("name missing from metadata"), result)
+		}
+	}
+
+	return result.ErrorOrNil()
+}
+
+func (storage *SourceStorage) validatePolicy() error {
+	if storage.tenant == nil || storage.Specification == nil {
+		return nil
+	}
+
+	return storage.tenant.validate(nil, nil, nil, nil, storage.Specification.RemoteSecrets)
+}
+
+func validateJsonIsStorageKind(j []byte) (bool, error) {
+	dec := json.NewDecoder(bytes.NewReader(j))
+	var newstorage struct {
+		Kind string `json:"kind,omitempty"`
+	}
+	err := dec.Decode(&newstorage)
+	if err != nil {
+		return false, err
+	}
+
+	if newstorage.Kind == "" {
+		return false, fmt.Errorf("kind is missing")
+	}
+
+	if newstorage.Kind != "" && newstorage.Kind != AzureContainerStorageKind {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+func (storage *SourceStorage) Unmarshal(y []byte, cfg config.ReconcileConfig) (bool, error) {
+	j, err := yaml.YAMLToJSON(y)
+	if err != nil {
+		return true, err
+	}
+
+	isStorage, err := validateJsonIsStorageKind(j)
+	if err != nil {
+		return isStorage, err
+	}
+
+	if !isStorage {
+		return false, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(j))
+	dec.DisallowUnknownFields()
+	var newstorage SourceStorage
+	err = dec.Decode(&newstorage)
+	if err != nil {
+		return true, err
+	}
+
+	newstorage.tenant = storage.tenant
+	err = newstorage.ValidateFields()
+	if err != nil {
+		return true, err
+	}
+
+	if len(newstorage.Specification.LocationFilter) != 0 {
+		sanitizedLocationFilters := []LocationFilterSpecification{}
+		for _, filter := range newstorage.Specification.LocationFilter {
+			sanitizedLocationFilters = append(sanitizedLocationFilters, LocationFilterSpecification(sanitizeAzureLocation(filter)))
+		}
+		newstorage.Specification.LocationFilter = sanitizedLocationFilters
+	}
+
+	*storage = newstorage
+	return true, nil
+}
+
+func (storage *SourceStorage) ShoudRunInLocation(currentLocation string) bool {
+	if storage == nil || storage.Specification == nil || len(storage.Specification.LocationFilter) == 0 {
+		return true
+	}
+
+	fixedCurrentLocation := sanitizeAzureLocation(LocationFilterSpecification(currentLocation))
+	for _, filter := range storage.Specification.LocationFilter {
+		if fixedCurrentLocation == filter {
+			return true
+		}
+	}
+
+	return false
+}
+
+type SourceStorages map[string]SourceStorage
+
+func (storages *SourceStorages) Unmarshal(path string, y []byte, cfg config.ReconcileConfig, policy *Policy) {
+	if storages == nil {
+		storages = toPtr(make(SourceStorages))
+	}
+	parts := strings.Split(string(y), "---")
+	for i, part := range parts {
+		storage := SourceStorage{
+			tenant: policy.GetTenant(path),
+		}
+		isStorage, err := storage.Unmarshal([]byte(part), cfg)
+		if err != nil {
+			storage.Err = fmt.Errorf("unable to unmarshal SourceStorage from %s (document %d): %w", path, i, err)
+			(*storages)[fmt.Sprintf("%s-%d", path, i)] = storage
+			continue
+		}
+		if !isStorage {
+			continue
+		}
+		_, ok := (*storages)[storage.Name()]
+		if ok {
+			storage.Err = fmt.Errorf("unable to add %s (document %d) with name %s as name is a duplicate", path, i, storage.Name())
+			(*storages)[fmt.Sprintf("%s-%d-%s", path, i, storage.Name())] = storage
+			continue
+		}
+		(*storages)[storage.Name()] = storage
+	}
+}
+
+func (storages *SourceStorages) GetSortedNames() []string {
+	names := []string{}
+	for name, storage := range *storages {
+		if storage.Error() != nil {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (storages *SourceStorages) Get(name string) (SourceStorage, bool) {
+	storage, ok := (*storages)[name]
+	if storage.Error() != nil {
+		return SourceStorage{}, false
+	}
+	return storage, ok
+}
+
+func (storages *SourceStorages) Delete(name string) {
+	delete(*storages, name)
+}
+
+func (storages *SourceStorages) SetSecret(name string, secretName string, secretValue string) error {
+	storage, ok := (*storages)[name]
+	if !ok {
+		return fmt.Errorf("no SourceStorage with name %q", name)
+	}
+
+	err := storage.SetSecret(secretName, secretValue)
+	if err != nil {
+		return err
+	}
+
+	(*storages)[name] = storage
+
+	return nil
+}
+
+func (storages *SourceStorages) GetUniqueRemoteSecretNames() []string {
+	secretsMap := make(map[string]struct{})
+	for _, storageName := range storages.GetSortedNames() {
+		storage, _ := storages.Get(storageName)
+		for _, remoteSecret := range storage.GetRemoteSecrets() {
+			secretsMap[remoteSecret.SecretKey()] = struct{}{}
+		}
+	}
+
+	secrets := []string{}
+	for secret := range secretsMap {
+		secrets = append(secrets, secret)
+	}
+	sort.Strings(secrets)
+
+	return secrets
+}
+
+func (storages *SourceStorages) Error() error {
+	var result *multierror.Error
+	for _, storage := range *storages {
+		if storage.Error() != nil {
+			result = multierror.Append(storage.Error(), result)
+		}
+	}
+
+	return result.ErrorOrNil()
+}
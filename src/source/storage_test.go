@@ -0,0 +1,194 @@
+package source
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/appcontainers/armappcontainers/v2"
+	"github.com/stretchr/testify/require"
+	"github.com/xenitab/azcagit/src/config"
+)
+
+func TestSourceStorages(t *testing.T) {
+	cases := []struct {
+		testDescription string
+		rawYaml         string
+		expectedResult  SourceStorages
+		expectedLenght  int
+		expectedError   string
+	}{
+		{
+			testDescription: "plain working, single document",
+			rawYaml: `
+kind: AzureContainerStorage
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+ name: data
+spec:
+  remoteSecrets:
+    - secretName: accountKey
+      remoteSecretName: storage-key
+  storage:
+    properties:
+      azureFile:
+        accountName: foo
+        shareName: data
+        accessMode: ReadOnly
+`,
+			expectedResult: SourceStorages{
+				"data": {
+					Kind:       "AzureContainerStorage",
+					APIVersion: "aca.xenit.io/v1alpha2",
+					Metadata: map[string]string{
+						"name": "data",
+					},
+					Specification: &SourceStorageSpecification{
+						Storage: &armappcontainers.ManagedEnvironmentStorage{
+							Properties: &armappcontainers.ManagedEnvironmentStorageProperties{
+								AzureFile: &armappcontainers.AzureFileProperties{
+									AccountName: toPtr("foo"),
+									ShareName:   toPtr("data"),
+									AccessMode:  toPtr(armappcontainers.AccessModeReadOnly),
+								},
+							},
+						},
+						RemoteSecrets: []RemoteSecretSpecification{
+							{
+								SecretName:       toPtr("accountKey"),
+								RemoteSecretName: toPtr("storage-key"),
+							},
+						},
+					},
+				},
+			},
+			expectedLenght: 1,
+			expectedError:  "",
+		},
+		{
+			testDescription: "azureFile missing",
+			rawYaml: `
+kind: AzureContainerStorage
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+ name: data
+spec:
+  storage:
+    properties: {}
+`,
+			expectedResult: SourceStorages{},
+			expectedLenght: 1,
+			expectedError:  "azureFile is missing",
+		},
+		{
+			testDescription: "unsupported secret name",
+			rawYaml: `
+kind: AzureContainerStorage
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+ name: data
+spec:
+  remoteSecrets:
+    - secretName: foo
+      remoteSecretName: storage-key
+  storage:
+    properties:
+      azureFile:
+        accountName: foo
+`,
+			expectedResult: SourceStorages{},
+			expectedLenght: 1,
+			expectedError:  "remoteSecrets only support the secretName accountKey",
+		},
+		{
+			testDescription: "duplicate name",
+			rawYaml: `
+kind: AzureContainerStorage
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+ name: data
+spec:
+  storage:
+    properties:
+      azureFile:
+        accountName: foo
+---
+kind: AzureContainerStorage
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+ name: data
+spec:
+  storage:
+    properties:
+      azureFile:
+        accountName: foo
+`,
+			expectedResult: SourceStorages{
+				"data": {
+					Kind:       "AzureContainerStorage",
+					APIVersion: "aca.xenit.io/v1alpha2",
+					Metadata: map[string]string{
+						"name": "data",
+					},
+					Specification: &SourceStorageSpecification{
+						Storage: &armappcontainers.ManagedEnvironmentStorage{
+							Properties: &armappcontainers.ManagedEnvironmentStorageProperties{
+								AzureFile: &armappcontainers.AzureFileProperties{
+									AccountName: toPtr("foo"),
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedLenght: 2,
+			expectedError:  "as name is a duplicate",
+		},
+	}
+
+	for i, c := range cases {
+		t.Logf("Test #%d: %s", i, c.testDescription)
+		storages := SourceStorages{}
+		storages.Unmarshal("foobar/baz.yaml", []byte(c.rawYaml), config.ReconcileConfig{
+			Location:             "ze-location",
+			ManagedEnvironmentID: "ze-EnvironmentID",
+		}, nil)
+		require.Len(t, storages, c.expectedLenght)
+		if c.expectedError != "" {
+			require.ErrorContains(t, storages.Error(), c.expectedError)
+		} else {
+			require.NoError(t, storages.Error())
+		}
+
+		storagesWithoutErrors := SourceStorages{}
+		for name, storage := range storages {
+			if storage.Error() != nil {
+				continue
+			}
+			storagesWithoutErrors[name] = storage
+		}
+		require.Equal(t, c.expectedResult, storagesWithoutErrors)
+	}
+}
+
+func TestSourceStoragesSetSecret(t *testing.T) {
+	storages := SourceStorages{
+		"data": {
+			Specification: &SourceStorageSpecification{
+				Storage: &armappcontainers.ManagedEnvironmentStorage{},
+			},
+		},
+	}
+
+	err := storages.SetSecret("data", "foo", "ze-key")
+	require.ErrorContains(t, err, "storages only support the secret \"accountKey\", not \"foo\"")
+
+	err = storages.SetSecret("data", "accountKey", "ze-key")
+	require.NoError(t, err)
+	storage, _ := storages.Get("data")
+	require.Equal(t, "ze-key", *storage.Specification.Storage.Properties.AzureFile.AccountKey)
+
+	err = storages.SetSecret("data", "accountKey", "ze-key")
+	require.ErrorContains(t, err, "already exists")
+
+	err = storages.SetSecret("missing", "accountKey", "ze-key")
+	require.Error(t, err)
+}
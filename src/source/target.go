@@ -0,0 +1,108 @@
+package source
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/xenitab/azcagit/src/config"
+	"sigs.k8s.io/yaml"
+)
+
+// filterTarget removes the documents of the other targets. A document targets the resource group in
+// metadata.resourceGroup, otherwise the target with the longest path containing the file or the default target
+func filterTarget(yamlFiles *map[string][]byte, cfg config.ReconcileConfig) (*map[string][]byte, error) {
+	targets, err := cfg.GetTargets()
+	if err != nil {
+		return nil, err
+	}
+
+	current, ok := findTarget(targets, cfg.ResourceGroupName)
+	if !ok {
+		return nil, fmt.Errorf("resource group %q isn't a configured target", cfg.ResourceGroupName)
+	}
+
+	files := make(map[string][]byte)
+	for filePath, content := range *yamlFiles {
+		pathTarget := getPathTarget(targets, filePath)
+		newParts := []string{}
+		for i, part := range strings.Split(string(content), "---") {
+			target, err := getDocumentTarget(targets, pathTarget, []byte(part))
+			if err != nil {
+				return nil, fmt.Errorf("unable to get target of %s (document %d): %w", filePath, i, err)
+			}
+
+			if target.ResourceGroupName != current.ResourceGroupName {
+				continue
+			}
+
+			newParts = append(newParts, part)
+		}
+
+		if len(newParts) == 0 {
+			continue
+		}
+
+		files[filePath] = []byte(strings.Join(newParts, "---"))
+	}
+
+	return &files, nil
+}
+
+func getDocumentTarget(targets []config.Target, pathTarget config.Target, y []byte) (config.Target, error) {
+	j, err := yaml.YAMLToJSON(y)
+	if err != nil || isEmptyDocument(j) {
+		return pathTarget, nil
+	}
+
+	header := documentHeader{}
+	err = json.Unmarshal(j, &header)
+	if err != nil {
+		return pathTarget, nil
+	}
+
+	target := pathTarget
+	resourceGroupName, ok := header.Metadata["resourceGroup"]
+	if ok {
+		target, ok = findTarget(targets, resourceGroupName)
+		if !ok {
+			return config.Target{}, fmt.Errorf("resource group %q isn't an allowed target", resourceGroupName)
+		}
+		if !pathTarget.Default && target.ResourceGroupName != pathTarget.ResourceGroupName {
+			return config.Target{}, fmt.Errorf("resource group %q doesn't match the target %q of the path %q", resourceGroupName, pathTarget.ResourceGroupName, pathTarget.Path)
+		}
+	}
+
+	switch header.Kind {
+	case AzureContainerDaprComponentKind, AzureContainerCertificateKind, AzureContainerStorageKind:
+		if target.SharedEnvironmentWith != "" {
+			return config.Target{}, fmt.Errorf("%s is shared by the managed environment, which is reconciled with resource group %q", header.Kind, target.SharedEnvironmentWith)
+		}
+	}
+
+	return target, nil
+}
+
+func getPathTarget(targets []config.Target, filePath string) config.Target {
+	pathTarget := targets[0]
+	for _, target := range targets {
+		if target.Path == "" || !strings.HasPrefix(filePath, target.Path+"/") {
+			continue
+		}
+		if pathTarget.Path == "" || len(target.Path) > len(pathTarget.Path) {
+			pathTarget = target
+		}
+	}
+
+	return pathTarget
+}
+
+func findTarget(targets []config.Target, resourceGroupName string) (config.Target, bool) {
+	for _, target := range targets {
+		if strings.EqualFold(target.ResourceGroupName, resourceGroupName) {
+			return target, true
+		}
+	}
+
+	return config.Target{}, false
+}
@@ -0,0 +1,121 @@
+package source
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xenitab/azcagit/src/config"
+	"sigs.k8s.io/yaml"
+)
+
+func testTargetYAML(kind string, name string, resourceGroup string) string {
+	metadata := fmt.Sprintf("  name: %s", name)
+	if resourceGroup != "" {
+		metadata = fmt.Sprintf("%s\n  resourceGroup: %s", metadata, resourceGroup)
+	}
+
+	return fmt.Sprintf("\nkind: %s\napiVersion: aca.xenit.io/v1alpha2\nmetadata:\n%s\nspec: {}\n", kind, metadata)
+}
+
+func TestFilterTarget(t *testing.T) {
+	cfg := config.ReconcileConfig{
+		ResourceGroupName:    "rg-default",
+		SubscriptionID:       "ze-sub",
+		ManagedEnvironmentID: "ze-me",
+		Targets: []string{
+			"resourceGroup=rg-foo;path=tenants/foo",
+			"resourceGroup=rg-bar;managedEnvironmentID=other-me",
+		},
+	}
+	targets, err := cfg.GetTargets()
+	require.NoError(t, err)
+
+	files := map[string][]byte{
+		"default.yaml":         []byte(testTargetYAML(AzureContainerAppKind, "default", "") + "---" + testTargetYAML(AzureContainerAppKind, "bar", "RG-Bar")),
+		"tenants/foo/foo.yaml": []byte(testTargetYAML(AzureContainerAppKind, "foo", "") + "---" + testTargetYAML(AzureContainerJobKind, "foo-job", "rg-foo")),
+		"tenants/foobar.yaml":  []byte(testTargetYAML(AzureContainerAppKind, "foobar", "")),
+		"bar/dapr.yaml":        []byte(testTargetYAML(AzureContainerDaprComponentKind, "bar-dapr", "rg-bar")),
+	}
+
+	cases := []struct {
+		testDescription string
+		cfg             config.ReconcileConfig
+		files           map[string][]byte
+		expectedFiles   map[string][]string
+		expectedError   string
+	}{
+		{
+			testDescription: "default target",
+			cfg:             cfg,
+			files:           files,
+			expectedFiles: map[string][]string{
+				"default.yaml":        {"default"},
+				"tenants/foobar.yaml": {"foobar"},
+			},
+		},
+		{
+			testDescription: "target with path",
+			cfg:             cfg.ForTarget(targets[1]),
+			files:           files,
+			expectedFiles: map[string][]string{
+				"tenants/foo/foo.yaml": {"foo", "foo-job"},
+			},
+		},
+		{
+			testDescription: "target with own managed environment",
+			cfg:             cfg.ForTarget(targets[2]),
+			files:           files,
+			expectedFiles: map[string][]string{
+				"default.yaml":  {"bar"},
+				"bar/dapr.yaml": {"bar-dapr"},
+			},
+		},
+		{
+			testDescription: "resource group not allowed",
+			cfg:             cfg,
+			files: map[string][]byte{
+				"default.yaml": []byte(testTargetYAML(AzureContainerAppKind, "baz", "rg-baz")),
+			},
+			expectedError: "unable to get target of default.yaml (document 0): resource group \"rg-baz\" isn't an allowed target",
+		},
+		{
+			testDescription: "resource group not matching the path",
+			cfg:             cfg,
+			files: map[string][]byte{
+				"tenants/foo/bar.yaml": []byte(testTargetYAML(AzureContainerAppKind, "bar", "rg-bar")),
+			},
+			expectedError: "resource group \"rg-bar\" doesn't match the target \"rg-foo\" of the path \"tenants/foo\"",
+		},
+		{
+			testDescription: "certificate in shared managed environment",
+			cfg:             cfg,
+			files: map[string][]byte{
+				"tenants/foo/cert.yaml": []byte(testTargetYAML(AzureContainerCertificateKind, "foo-cert", "")),
+			},
+			expectedError: "AzureContainerCertificate is shared by the managed environment, which is reconciled with resource group \"rg-default\"",
+		},
+	}
+
+	for i, c := range cases {
+		t.Logf("Test #%d: %s", i, c.testDescription)
+		filteredFiles, err := filterTarget(&c.files, c.cfg)
+		if c.expectedError != "" {
+			require.ErrorContains(t, err, c.expectedError)
+			continue
+		}
+		require.NoError(t, err)
+
+		names := make(map[string][]string)
+		for filePath, content := range *filteredFiles {
+			for _, part := range strings.Split(string(content), "---") {
+				header := documentHeader{}
+				err := yaml.Unmarshal([]byte(part), &header)
+				require.NoError(t, err)
+				names[filePath] = append(names[filePath], header.Metadata["name"])
+			}
+		}
+		require.Equal(t, c.expectedFiles, names)
+	}
+}
@@ -0,0 +1,324 @@
+package source
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/xenitab/azcagit/src/config"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	AzureContainerAppTemplateVersion = "aca.xenit.io/v1alpha2"
+	AzureContainerAppTemplateKind    = "AzureContainerAppTemplate"
+	AzureContainerAppInstanceVersion = "aca.xenit.io/v1alpha2"
+	AzureContainerAppInstanceKind    = "AzureContainerAppInstance"
+)
+
+type TemplateParameterSpecification struct {
+	Name     string      `json:"name,omitempty" yaml:"name,omitempty"`
+	Default  interface{} `json:"default,omitempty" yaml:"default,omitempty"`
+	Required bool        `json:"required,omitempty" yaml:"required,omitempty"`
+}
+
+type SourceAppTemplateSpecification struct {
+	Parameters []TemplateParameterSpecification `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+	Template   string                           `json:"template,omitempty" yaml:"template,omitempty"`
+}
+
+type SourceAppTemplate struct {
+	Kind          string                          `json:"kind,omitempty" yaml:"kind,omitempty"`
+	APIVersion    string                          `json:"apiVersion,omitempty" yaml:"apiVersion,omitempty"`
+	Metadata      map[string]string               `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+	Specification *SourceAppTemplateSpecification `json:"spec,omitempty" yaml:"spec,omitempty"`
+	template      *template.Template
+	origin        string
+}
+
+func (tmpl *SourceAppTemplate) Name() string {
+	if tmpl.Metadata == nil {
+		return ""
+	}
+
+	return tmpl.Metadata["name"]
+}
+
+func (tmpl *SourceAppTemplate) ValidateFields() error {
+	if tmpl.APIVersion != "" && tmpl.APIVersion != AzureContainerAppTemplateVersion {
+		return fmt.Errorf("apiVersion for %s should be %s", tmpl.Kind, AzureContainerAppTemplateVersion)
+	}
+
+	if tmpl.Name() == "" {
+		return fmt.Errorf("name missing from metadata")
+	}
+
+	if tmpl.Specification == nil || tmpl.Specification.Template == "" {
+		return fmt.Errorf("spec.template is missing")
+	}
+
+	parameterNames := make(map[string]struct{})
+	for i, parameter := range tmpl.Specification.Parameters {
+		if parameter.Name == "" {
+			return fmt.Errorf("name missing from parameter %d", i)
+		}
+
+		if _, ok := parameterNames[parameter.Name]; ok {
+			return fmt.Errorf("parameter %q is a duplicate", parameter.Name)
+		}
+		parameterNames[parameter.Name] = struct{}{}
+	}
+
+	return nil
+}
+
+func (tmpl *SourceAppTemplate) render(instance SourceAppInstance) ([]byte, error) {
+	parameters := make(map[string]interface{})
+	for _, parameter := range tmpl.Specification.Parameters {
+		value, ok := instance.Specification.Parameters[parameter.Name]
+		switch {
+		case ok:
+			parameters[parameter.Name] = value
+		case parameter.Required:
+			return nil, fmt.Errorf("required parameter %q is missing", parameter.Name)
+		default:
+			parameters[parameter.Name] = parameter.Default
+		}
+	}
+
+	unknownParameters := []string{}
+	for name := range instance.Specification.Parameters {
+		if _, ok := parameters[name]; !ok {
+			unknownParameters = append(unknownParameters, name)
+		}
+	}
+	if len(unknownParameters) != 0 {
+		sort.Strings(unknownParameters)
+		return nil, fmt.Errorf("unknown parameters: %s", strings.Join(unknownParameters, ", "))
+	}
+
+	var rendered bytes.Buffer
+	err := tmpl.template.Execute(&rendered, struct {
+		Name       string
+		Parameters map[string]interface{}
+	}{
+		Name:       instance.Name(),
+		Parameters: parameters,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var spec map[string]interface{}
+	err = yaml.Unmarshal(rendered.Bytes(), &spec)
+	if err != nil {
+		return nil, fmt.Errorf("rendered template isn't valid yaml: %w", err)
+	}
+
+	metadata, err := yaml.Marshal(instance.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc bytes.Buffer
+	fmt.Fprintf(&doc, "kind: %s\napiVersion: %s\nmetadata:\n", AzureContainerAppKind, AzureContainerAppVersion)
+	writeIndented(&doc, metadata)
+	doc.WriteString("spec:\n")
+	writeIndented(&doc, rendered.Bytes())
+
+	return doc.Bytes(), nil
+}
+
+type SourceAppInstanceSpecification struct {
+	Template   string                 `json:"template,omitempty" yaml:"template,omitempty"`
+	Parameters map[string]interface{} `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+}
+
+type SourceAppInstance struct {
+	Kind          string                          `json:"kind,omitempty" yaml:"kind,omitempty"`
+	APIVersion    string                          `json:"apiVersion,omitempty" yaml:"apiVersion,omitempty"`
+	Metadata      map[string]string               `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+	Specification *SourceAppInstanceSpecification `json:"spec,omitempty" yaml:"spec,omitempty"`
+}
+
+func (instance *SourceAppInstance) Name() string {
+	if instance.Metadata == nil {
+		return ""
+	}
+
+	return instance.Metadata["name"]
+}
+
+func (instance *SourceAppInstance) ValidateFields() error {
+	if instance.APIVersion != "" && instance.APIVersion != AzureContainerAppInstanceVersion {
+		return fmt.Errorf("apiVersion for %s should be %s", instance.Kind, AzureContainerAppInstanceVersion)
+	}
+
+	if instance.Name() == "" {
+		return fmt.Errorf("name missing from metadata")
+	}
+
+	if instance.Specification == nil || instance.Specification.Template == "" {
+		return fmt.Errorf("spec.template is missing")
+	}
+
+	return nil
+}
+
+var templateFuncs = template.FuncMap{
+	"quote": func(v interface{}) string {
+		return fmt.Sprintf("%q", fmt.Sprint(v))
+	},
+	"toJson": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"default": func(d interface{}, v interface{}) interface{} {
+		if v == nil || v == "" {
+			return d
+		}
+		return v
+	},
+}
+
+// expandTemplates replaces every AzureContainerAppInstance with an AzureContainerApp rendered
+// from the referenced AzureContainerAppTemplate. The templates are removed from the files.
+// Files in the overlays path are kept as is, the overlays are applied to the rendered apps.
+func expandTemplates(yamlFiles *map[string][]byte, cfg config.ReconcileConfig) (*map[string][]byte, error) {
+	templates := make(map[string]*SourceAppTemplate)
+	for _, filePath := range sortedPaths(yamlFiles) {
+		if isOverlayPath(filePath, cfg) {
+			continue
+		}
+
+		for i, part := range strings.Split(string((*yamlFiles)[filePath]), "---") {
+			kind, j := getDocumentKind([]byte(part))
+			if kind != AzureContainerAppTemplateKind {
+				continue
+			}
+
+			tmpl := &SourceAppTemplate{}
+			err := decodeStrict(j, tmpl)
+			if err != nil {
+				return nil, fmt.Errorf("unable to unmarshal %s from %s (document %d): %w", AzureContainerAppTemplateKind, filePath, i, err)
+			}
+
+			err = tmpl.ValidateFields()
+			if err != nil {
+				return nil, fmt.Errorf("unable to unmarshal %s from %s (document %d): %w", AzureContainerAppTemplateKind, filePath, i, err)
+			}
+
+			origin := fmt.Sprintf("%s (document %d)", filePath, i)
+			if existing, ok := templates[tmpl.Name()]; ok {
+				return nil, fmt.Errorf("unable to add template %s with name %s as name is a duplicate of %s", origin, tmpl.Name(), existing.origin)
+			}
+
+			tmpl.template, err = template.New(tmpl.Name()).Option("missingkey=error").Funcs(templateFuncs).Parse(tmpl.Specification.Template)
+			if err != nil {
+				return nil, fmt.Errorf("unable to parse template %s: %w", origin, err)
+			}
+			tmpl.origin = origin
+
+			templates[tmpl.Name()] = tmpl
+		}
+	}
+
+	files := make(map[string][]byte)
+	for filePath, content := range *yamlFiles {
+		if isOverlayPath(filePath, cfg) {
+			files[filePath] = content
+			continue
+		}
+
+		parts := strings.Split(string(content), "---")
+		newParts := []string{}
+		for i, part := range parts {
+			kind, j := getDocumentKind([]byte(part))
+			switch kind {
+			case AzureContainerAppTemplateKind:
+				continue
+			case AzureContainerAppInstanceKind:
+			default:
+				newParts = append(newParts, part)
+				continue
+			}
+
+			instance := SourceAppInstance{}
+			err := decodeStrict(j, &instance)
+			if err != nil {
+				return nil, fmt.Errorf("unable to unmarshal %s from %s (document %d): %w", AzureContainerAppInstanceKind, filePath, i, err)
+			}
+
+			err = instance.ValidateFields()
+			if err != nil {
+				return nil, fmt.Errorf("unable to unmarshal %s from %s (document %d): %w", AzureContainerAppInstanceKind, filePath, i, err)
+			}
+
+			tmpl, ok := templates[instance.Specification.Template]
+			if !ok {
+				return nil, fmt.Errorf("template %q for instance %s in %s (document %d) not found", instance.Specification.Template, instance.Name(), filePath, i)
+			}
+
+			rendered, err := tmpl.render(instance)
+			if err != nil {
+				return nil, fmt.Errorf("unable to render template %q for instance %s in %s (document %d): %w", tmpl.Name(), instance.Name(), filePath, i, err)
+			}
+
+			newParts = append(newParts, "\n"+string(rendered))
+		}
+
+		if len(newParts) == 0 {
+			continue
+		}
+
+		files[filePath] = []byte(strings.Join(newParts, "---"))
+	}
+
+	return &files, nil
+}
+
+func getDocumentKind(y []byte) (string, []byte) {
+	j, err := yaml.YAMLToJSON(y)
+	if err != nil || isEmptyDocument(j) {
+		return "", nil
+	}
+
+	header := documentHeader{}
+	err = json.Unmarshal(j, &header)
+	if err != nil {
+		return "", nil
+	}
+
+	return header.Kind, j
+}
+
+func decodeStrict(j []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(j))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+func writeIndented(buf *bytes.Buffer, b []byte) {
+	for _, line := range strings.Split(strings.TrimRight(string(b), "\n"), "\n") {
+		if strings.TrimSpace(line) == "" {
+			buf.WriteString("\n")
+			continue
+		}
+		buf.WriteString("  ")
+		buf.WriteString(line)
+		buf.WriteString("\n")
+	}
+}
+
+func sortedPaths(yamlFiles *map[string][]byte) []string {
+	paths := []string{}
+	for filePath := range *yamlFiles {
+		paths = append(paths, filePath)
+	}
+	sort.Strings(paths)
+
+	return paths
+}
@@ -0,0 +1,222 @@
+package source
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xenitab/azcagit/src/config"
+)
+
+const testTemplateYAML = `
+kind: AzureContainerAppTemplate
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+  name: microservice
+spec:
+  parameters:
+  - name: image
+    required: true
+  - name: maxReplicas
+    default: 1
+  - name: env
+    default: []
+  template: |
+    app:
+      properties:
+        template:
+          containers:
+          - name: {{ .Name }}
+            image: {{ .Parameters.image | quote }}
+            env: {{ .Parameters.env | toJson }}
+          scale:
+            minReplicas: 1
+            maxReplicas: {{ .Parameters.maxReplicas }}`
+
+func TestExpandTemplates(t *testing.T) {
+	cfg := config.ReconcileConfig{
+		ManagedEnvironmentID: "foobar",
+		Location:             "westeurope",
+		Environment:          "dev",
+		GitOverlaysPath:      "overlays",
+	}
+
+	cases := []struct {
+		testDescription string
+		files           map[string][]byte
+		expectedError   string
+		validate        func(t *testing.T, sources *Sources)
+	}{
+		{
+			testDescription: "instances expanded",
+			files: map[string][]byte{
+				"templates/microservice.yaml": []byte(testTemplateYAML),
+				"apps.yaml": []byte(`
+kind: AzureContainerAppInstance
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+  name: foo
+spec:
+  template: microservice
+  parameters:
+    image: foo:v1
+    maxReplicas: 3
+    env:
+    - name: FOO
+      value: bar
+---
+kind: AzureContainerAppInstance
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+  name: bar
+spec:
+  template: microservice
+  parameters:
+    image: bar:v1
+---
+kind: AzureContainerApp
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+  name: baz
+spec:
+  app: {}`),
+			},
+			validate: func(t *testing.T, sources *Sources) {
+				t.Helper()
+				require.ElementsMatch(t, []string{"bar", "baz", "foo"}, sources.Apps.GetSortedNames())
+
+				foo, _ := sources.Apps.Get("foo")
+				fooTemplate := foo.Specification.App.Properties.Template
+				require.Equal(t, "foo", *fooTemplate.Containers[0].Name)
+				require.Equal(t, "foo:v1", *fooTemplate.Containers[0].Image)
+				require.Equal(t, "FOO", *fooTemplate.Containers[0].Env[0].Name)
+				require.Equal(t, int32(3), *fooTemplate.Scale.MaxReplicas)
+
+				bar, _ := sources.Apps.Get("bar")
+				barTemplate := bar.Specification.App.Properties.Template
+				require.Equal(t, "bar:v1", *barTemplate.Containers[0].Image)
+				require.Empty(t, barTemplate.Containers[0].Env)
+				require.Equal(t, int32(1), *barTemplate.Scale.MaxReplicas)
+			},
+		},
+		{
+			testDescription: "missing template",
+			files: map[string][]byte{
+				"apps.yaml": []byte(`
+kind: AzureContainerAppInstance
+metadata:
+  name: foo
+spec:
+  template: microservice`),
+			},
+			expectedError: "template \"microservice\" for instance foo in apps.yaml (document 0) not found",
+		},
+		{
+			testDescription: "missing required parameter",
+			files: map[string][]byte{
+				"templates/microservice.yaml": []byte(testTemplateYAML),
+				"apps.yaml": []byte(`
+kind: AzureContainerAppInstance
+metadata:
+  name: foo
+spec:
+  template: microservice`),
+			},
+			expectedError: "required parameter \"image\" is missing",
+		},
+		{
+			testDescription: "unknown parameter",
+			files: map[string][]byte{
+				"templates/microservice.yaml": []byte(testTemplateYAML),
+				"apps.yaml": []byte(`
+kind: AzureContainerAppInstance
+metadata:
+  name: foo
+spec:
+  template: microservice
+  parameters:
+    image: foo
+    imag: foo`),
+			},
+			expectedError: "unknown parameters: imag",
+		},
+		{
+			testDescription: "duplicate template",
+			files: map[string][]byte{
+				"a.yaml": []byte(testTemplateYAML),
+				"b.yaml": []byte(testTemplateYAML),
+			},
+			expectedError: "unable to add template b.yaml (document 0) with name microservice as name is a duplicate of a.yaml (document 0)",
+		},
+		{
+			testDescription: "invalid template",
+			files: map[string][]byte{
+				"a.yaml": []byte(`
+kind: AzureContainerAppTemplate
+metadata:
+  name: broken
+spec:
+  template: "{{ .Name"`),
+			},
+			expectedError: "unable to parse template a.yaml (document 0)",
+		},
+		{
+			testDescription: "overlay on an app from an instance",
+			files: map[string][]byte{
+				"templates/microservice.yaml": []byte(testTemplateYAML),
+				"apps.yaml": []byte(`
+kind: AzureContainerAppInstance
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+  name: foo
+spec:
+  template: microservice
+  parameters:
+    image: foo:v1`),
+				"overlays/dev/foo.yaml": []byte(`
+kind: AzureContainerApp
+metadata:
+  name: foo
+spec:
+  app:
+    properties:
+      template:
+        scale:
+          maxReplicas: 5`),
+			},
+			validate: func(t *testing.T, sources *Sources) {
+				t.Helper()
+				app, ok := sources.Apps.Get("foo")
+				require.True(t, ok)
+				require.Equal(t, int32(5), *app.Specification.App.Properties.Template.Scale.MaxReplicas)
+				require.Equal(t, "foo:v1", *app.Specification.App.Properties.Template.Containers[0].Image)
+			},
+		},
+		{
+			testDescription: "template without name",
+			files: map[string][]byte{
+				"a.yaml": []byte(`
+kind: AzureContainerAppTemplate
+spec:
+  template: foo`),
+			},
+			expectedError: "name missing from metadata",
+		},
+	}
+
+	for i, c := range cases {
+		t.Logf("Test #%d: %s", i, c.testDescription)
+		files, err := expandTemplates(&c.files, cfg)
+		if err == nil {
+			files, err = applyOverlays(files, cfg)
+		}
+		if c.expectedError != "" {
+			require.ErrorContains(t, err, c.expectedError)
+			continue
+		}
+		require.NoError(t, err)
+
+		sources := getSourcesFromFiles(files, cfg, nil)
+		require.NoError(t, sources.Apps.Error())
+		c.validate(t, sources)
+	}
+}
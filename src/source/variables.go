@@ -0,0 +1,307 @@
+package source
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/xenitab/azcagit/src/config"
+	"github.com/xenitab/azcagit/src/secret"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	keyVaultVariableTag    = "aca.xenit.io-variable"
+	keyVaultVariablePrefix = "keyvault."
+)
+
+var variableRegexp = regexp.MustCompile(`\$\$\{|\$\{([^}]*)\}`)
+
+type Variables map[string]string
+
+// Substitute replaces all ${name} placeholders in the scalars of the yaml documents with the value of the
+// variable, an error is returned if any of the variables are undefined. Use $${name} for a literal ${name}.
+// The values are set on the parsed scalars, so they can't add keys or documents, and comments are ignored.
+// Nothing is substituted if the variables are nil.
+func (v Variables) Substitute(y []byte) ([]byte, error) {
+	if v == nil || !variableRegexp.Match(y) {
+		return y, nil
+	}
+
+	documents, err := decodeYamlDocuments(y)
+	if err != nil {
+		return nil, err
+	}
+
+	undefinedMap := make(map[string]struct{})
+	var valueErr error
+	changed := false
+	for _, document := range documents {
+		walkYamlScalars(document, func(node *yaml.Node) {
+			if !variableRegexp.MatchString(node.Value) {
+				return
+			}
+
+			value := variableRegexp.ReplaceAllStringFunc(node.Value, func(match string) string {
+				if match == "$${" {
+					return "${"
+				}
+
+				name := strings.TrimSpace(match[2 : len(match)-1])
+				value, ok := v[name]
+				if !ok {
+					undefinedMap[name] = struct{}{}
+					return match
+				}
+
+				// the documents of a file are split on ---, which can't be escaped in a scalar
+				if strings.Contains(value, "---") && valueErr == nil {
+					valueErr = fmt.Errorf("the value of variable %q can't contain ---", name)
+				}
+
+				return value
+			})
+
+			node.Value = value
+			changed = true
+			// plain scalars are resolved again from the value, like numbers and booleans, while quoted scalars stay strings
+			if node.Style == 0 {
+				node.Tag = ""
+			}
+			if strings.Contains(value, "\n") {
+				node.Style = yaml.DoubleQuotedStyle
+			}
+		})
+	}
+
+	if len(undefinedMap) != 0 {
+		undefined := []string{}
+		for name := range undefinedMap {
+			undefined = append(undefined, name)
+		}
+		sort.Strings(undefined)
+		return nil, fmt.Errorf("undefined variables: %s", strings.Join(undefined, ", "))
+	}
+
+	if valueErr != nil {
+		return nil, valueErr
+	}
+
+	if !changed {
+		return y, nil
+	}
+
+	return encodeYamlDocuments(documents)
+}
+
+func decodeYamlDocuments(y []byte) ([]*yaml.Node, error) {
+	documents := []*yaml.Node{}
+	dec := yaml.NewDecoder(bytes.NewReader(y))
+	for {
+		document := &yaml.Node{}
+		err := dec.Decode(document)
+		if errors.Is(err, io.EOF) {
+			return documents, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, document)
+	}
+}
+
+func encodeYamlDocuments(documents []*yaml.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	for _, document := range documents {
+		err := enc.Encode(document)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := enc.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// walkYamlScalars calls fn for every scalar (keys and values) in the node, comments aren't scalars
+func walkYamlScalars(node *yaml.Node, fn func(node *yaml.Node)) {
+	if node == nil {
+		return
+	}
+
+	if node.Kind == yaml.ScalarNode {
+		fn(node)
+		return
+	}
+
+	for _, child := range node.Content {
+		walkYamlScalars(child, fn)
+	}
+}
+
+// substituteVariables substitutes the variables in all yaml files, before overlays and templates
+// are applied so that values like numbers keep their type when the documents are merged.
+func substituteVariables(yamlFiles *map[string][]byte, variables Variables) (*map[string][]byte, error) {
+	if variables == nil {
+		return yamlFiles, nil
+	}
+
+	files := make(map[string][]byte)
+	for filePath, content := range *yamlFiles {
+		substituted, err := variables.Substitute(content)
+		if err != nil {
+			return nil, fmt.Errorf("unable to substitute variables in %s: %w", filePath, err)
+		}
+		files[filePath] = substituted
+	}
+
+	return &files, nil
+}
+
+// getReferencedVariableNames returns the variables referenced in the scalars of the yaml files, files that can't
+// be parsed are ignored as they fail when the variables are substituted
+func getReferencedVariableNames(yamlFiles *map[string][]byte) []string {
+	namesMap := make(map[string]struct{})
+	for _, content := range *yamlFiles {
+		if !variableRegexp.Match(content) {
+			continue
+		}
+
+		documents, err := decodeYamlDocuments(content)
+		if err != nil {
+			continue
+		}
+
+		for _, document := range documents {
+			walkYamlScalars(document, func(node *yaml.Node) {
+				for _, match := range variableRegexp.FindAllStringSubmatch(node.Value, -1) {
+					if match[0] == "$${" {
+						continue
+					}
+					namesMap[strings.TrimSpace(match[1])] = struct{}{}
+				}
+			})
+		}
+	}
+
+	names := []string{}
+	for name := range namesMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// getVariables returns the variables for the current environment together with the yaml files
+// without the variables files. Variables are read from the config, the variables file of the
+// environment and KeyVault secrets tagged as variables.
+func getVariables(ctx context.Context, yamlFiles *map[string][]byte, cfg config.ReconcileConfig, secretClient secret.Secret) (*map[string][]byte, Variables, error) {
+	if !cfg.VariablesEnabled {
+		return yamlFiles, nil, nil
+	}
+
+	variables := Variables{
+		"environment":       cfg.Environment,
+		"location":          cfg.Location,
+		"resourceGroupName": cfg.ResourceGroupName,
+	}
+
+	variablesPath := path.Clean(strings.Trim(cfg.GitVariablesPath, "/"))
+	environmentVariablesPath := path.Join(variablesPath, fmt.Sprintf("%s.yaml", cfg.Environment))
+
+	files := make(map[string][]byte)
+	for filePath, content := range *yamlFiles {
+		if filePath == environmentVariablesPath {
+			err := variables.unmarshal(content)
+			if err != nil {
+				return nil, nil, fmt.Errorf("unable to parse variables from %s: %w", filePath, err)
+			}
+			continue
+		}
+
+		if strings.HasPrefix(filePath, variablesPath+"/") {
+			continue
+		}
+
+		files[filePath] = content
+	}
+
+	keyVaultNames := []string{}
+	for _, name := range getReferencedVariableNames(&files) {
+		if strings.HasPrefix(name, keyVaultVariablePrefix) {
+			keyVaultNames = append(keyVaultNames, strings.TrimPrefix(name, keyVaultVariablePrefix))
+		}
+	}
+
+	if len(keyVaultNames) == 0 {
+		return &files, variables, nil
+	}
+
+	secretItems, err := secret.ListItems(ctx, secretClient, keyVaultNames)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, name := range keyVaultNames {
+		item, ok := secretItems.Get(name)
+		if !ok {
+			continue
+		}
+
+		tag, ok := item.Tag(keyVaultVariableTag)
+		if !ok || tag != "true" {
+			continue
+		}
+
+		value, _, err := secretClient.Get(ctx, name)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		variables[fmt.Sprintf("%s%s", keyVaultVariablePrefix, name)] = value
+	}
+
+	return &files, variables, nil
+}
+
+func (v Variables) unmarshal(y []byte) error {
+	raw := make(map[string]interface{})
+	err := yaml.Unmarshal(y, &raw)
+	if err != nil {
+		return err
+	}
+
+	for name, value := range raw {
+		if _, ok := v[name]; ok {
+			return fmt.Errorf("variable %q is reserved", name)
+		}
+
+		if strings.HasPrefix(name, keyVaultVariablePrefix) {
+			return fmt.Errorf("variable %q can't use the reserved prefix %q", name, keyVaultVariablePrefix)
+		}
+
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			return fmt.Errorf("variable %q needs to be a string, number or boolean", name)
+		case nil:
+			v[name] = ""
+		default:
+			v[name] = fmt.Sprint(value)
+		}
+	}
+
+	return nil
+}
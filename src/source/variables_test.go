@@ -0,0 +1,290 @@
+package source
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xenitab/azcagit/src/config"
+	"github.com/xenitab/azcagit/src/secret"
+)
+
+func TestVariablesSubstitute(t *testing.T) {
+	variables := Variables{
+		"foo":          "bar",
+		"keyvault.baz": "qux",
+		"replicas":     "3",
+		"multiline":    "bar\nkind: AzureContainerApp",
+		"separator":    "bar\n---\nkind: AzureContainerApp",
+	}
+
+	cases := []struct {
+		testDescription string
+		variables       Variables
+		input           string
+		expectedResult  string
+		expectedError   string
+	}{
+		{
+			testDescription: "nil variables disables substitution",
+			variables:       nil,
+			input:           "value: ${foo}",
+			expectedResult:  "value: ${foo}",
+		},
+		{
+			testDescription: "no placeholders",
+			variables:       variables,
+			input:           "value: foo",
+			expectedResult:  "value: foo",
+		},
+		{
+			testDescription: "placeholders",
+			variables:       variables,
+			input:           "value: ${foo}-${ keyvault.baz }",
+			expectedResult:  "value: bar-qux\n",
+		},
+		{
+			testDescription: "escaped placeholder",
+			variables:       variables,
+			input:           "value: $${foo}-${foo}",
+			expectedResult:  "value: ${foo}-bar\n",
+		},
+		{
+			testDescription: "plain scalars are resolved from the value and quoted scalars stay strings",
+			variables:       variables,
+			input:           "replicas: ${replicas}\nname: \"${replicas}\"",
+			expectedResult:  "replicas: 3\nname: \"3\"\n",
+		},
+		{
+			testDescription: "multi-line value stays a single scalar",
+			variables:       variables,
+			input:           "value: ${multiline}",
+			expectedResult:  "value: \"bar\\nkind: AzureContainerApp\"\n",
+		},
+		{
+			testDescription: "value with a document separator",
+			variables:       variables,
+			input:           "value: ${separator}",
+			expectedError:   "the value of variable \"separator\" can't contain ---",
+		},
+		{
+			testDescription: "placeholders in comments are ignored",
+			variables:       variables,
+			input:           "# uses ${undefined}\nvalue: foo # ${undefined}",
+			expectedResult:  "# uses ${undefined}\nvalue: foo # ${undefined}",
+		},
+		{
+			testDescription: "comments are kept when substituting",
+			variables:       variables,
+			input:           "# uses ${undefined}\nvalue: ${foo}",
+			expectedResult:  "# uses ${undefined}\nvalue: bar\n",
+		},
+		{
+			testDescription: "undefined variables",
+			variables:       variables,
+			input:           "value: ${foo}-${b}-${a}-${a}",
+			expectedError:   "undefined variables: a, b",
+		},
+	}
+
+	for i, c := range cases {
+		t.Logf("Test #%d: %s", i, c.testDescription)
+		result, err := c.variables.Substitute([]byte(c.input))
+		if c.expectedError != "" {
+			require.EqualError(t, err, c.expectedError)
+			continue
+		}
+		require.NoError(t, err)
+		require.Equal(t, c.expectedResult, string(result))
+	}
+}
+
+func TestGetVariables(t *testing.T) {
+	ctx := context.Background()
+	secretClient := secret.NewInMemSecret()
+	secretClient.Set("tagged", "tagged-value", time.Now())
+	secretClient.SetTags("tagged", map[string]string{"aca.xenit.io-variable": "true"})
+	secretClient.Set("untagged", "untagged-value", time.Now())
+	secretClient.Set("multiline", "line\n          - name: INJECTED\n            value: injected", time.Now())
+	secretClient.SetTags("multiline", map[string]string{"aca.xenit.io-variable": "true"})
+
+	cfg := config.ReconcileConfig{
+		Environment:          "dev",
+		Location:             "westeurope",
+		ResourceGroupName:    "ze-rg",
+		ManagedEnvironmentID: "ze-me",
+		VariablesEnabled:     true,
+		GitVariablesPath:     "variables",
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		files := map[string][]byte{"variables/dev.yaml": []byte("foo: bar")}
+		newFiles, variables, err := getVariables(ctx, &files, config.ReconcileConfig{}, secretClient)
+		require.NoError(t, err)
+		require.Nil(t, variables)
+		require.Len(t, *newFiles, 1)
+	})
+
+	t.Run("variables from config, file and keyvault", func(t *testing.T) {
+		files := map[string][]byte{
+			"variables/dev.yaml":  []byte("hostname: dev.example.com\nreplicas: 3"),
+			"variables/prod.yaml": []byte("hostname: example.com\nreplicas: 10"),
+			"foo.yaml": []byte(`
+kind: AzureContainerApp
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+  name: foo
+spec:
+  app:
+    tags:
+      environment: ${environment}
+      resourceGroupName: ${resourceGroupName}
+    properties:
+      template:
+        containers:
+        - name: foo
+          image: foo:latest
+          env:
+          - name: HOSTNAME
+            value: ${hostname}
+          - name: TAGGED
+            value: ${keyvault.tagged}
+          - name: LITERAL
+            value: $${hostname}
+        scale:
+          maxReplicas: ${replicas}`),
+		}
+		newFiles, variables, err := getVariables(ctx, &files, cfg, secretClient)
+		require.NoError(t, err)
+		require.Len(t, *newFiles, 1)
+		require.Equal(t, Variables{
+			"environment":       "dev",
+			"location":          "westeurope",
+			"resourceGroupName": "ze-rg",
+			"hostname":          "dev.example.com",
+			"replicas":          "3",
+			"keyvault.tagged":   "tagged-value",
+		}, variables)
+
+		newFiles, err = substituteVariables(newFiles, variables)
+		require.NoError(t, err)
+		sources := getSourcesFromFiles(newFiles, cfg, nil)
+		require.NoError(t, sources.Apps.Error())
+		app, ok := sources.Apps.Get("foo")
+		require.True(t, ok)
+		require.Equal(t, "dev", *app.Specification.App.Tags["environment"])
+		require.Equal(t, "ze-rg", *app.Specification.App.Tags["resourceGroupName"])
+		container := app.Specification.App.Properties.Template.Containers[0]
+		require.Equal(t, "dev.example.com", *container.Env[0].Value)
+		require.Equal(t, "tagged-value", *container.Env[1].Value)
+		require.Equal(t, "${hostname}", *container.Env[2].Value)
+		require.Equal(t, int32(3), *app.Specification.App.Properties.Template.Scale.MaxReplicas)
+	})
+
+	t.Run("untagged keyvault secret is undefined", func(t *testing.T) {
+		files := map[string][]byte{
+			"foo.yaml": []byte(`
+kind: AzureContainerApp
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+  name: foo
+spec:
+  app:
+    tags:
+      untagged: ${keyvault.untagged}`),
+		}
+		newFiles, variables, err := getVariables(ctx, &files, cfg, secretClient)
+		require.NoError(t, err)
+		_, err = substituteVariables(newFiles, variables)
+		require.ErrorContains(t, err, "unable to substitute variables in foo.yaml: undefined variables: keyvault.untagged")
+	})
+
+	t.Run("multi-line keyvault value and placeholders in comments", func(t *testing.T) {
+		files := map[string][]byte{
+			"foo.yaml": []byte(`
+# the ${keyvault.untagged} secret isn't used
+kind: AzureContainerApp
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+  name: foo
+spec:
+  app:
+    properties:
+      template:
+        containers:
+        - name: foo
+          image: foo:latest # ${undefined}
+          env:
+          - name: MULTILINE
+            value: ${keyvault.multiline}`),
+		}
+		newFiles, variables, err := getVariables(ctx, &files, cfg, secretClient)
+		require.NoError(t, err)
+		require.NotContains(t, variables, "keyvault.untagged")
+		newFiles, err = substituteVariables(newFiles, variables)
+		require.NoError(t, err)
+		sources := getSourcesFromFiles(newFiles, cfg, nil)
+		require.NoError(t, sources.Apps.Error())
+		app, ok := sources.Apps.Get("foo")
+		require.True(t, ok)
+		env := app.Specification.App.Properties.Template.Containers[0].Env
+		require.Len(t, env, 1)
+		require.Equal(t, "line\n          - name: INJECTED\n            value: injected", *env[0].Value)
+	})
+
+	t.Run("numeric variable in overlay", func(t *testing.T) {
+		overlayCfg := cfg
+		overlayCfg.GitOverlaysPath = "overlays"
+		files := map[string][]byte{
+			"variables/dev.yaml": []byte("replicas: 3"),
+			"foo.yaml": []byte(`
+kind: AzureContainerApp
+apiVersion: aca.xenit.io/v1alpha2
+metadata:
+  name: foo
+spec:
+  app:
+    properties:
+      template:
+        containers:
+        - name: foo
+          image: foo:latest
+        scale:
+          maxReplicas: 1`),
+			"overlays/dev/foo.yaml": []byte(`
+kind: AzureContainerApp
+metadata:
+  name: foo
+spec:
+  app:
+    properties:
+      template:
+        scale:
+          maxReplicas: ${replicas}`),
+		}
+		newFiles, variables, err := getVariables(ctx, &files, overlayCfg, secretClient)
+		require.NoError(t, err)
+		newFiles, err = substituteVariables(newFiles, variables)
+		require.NoError(t, err)
+		newFiles, err = applyOverlays(newFiles, overlayCfg)
+		require.NoError(t, err)
+		sources := getSourcesFromFiles(newFiles, overlayCfg, nil)
+		require.NoError(t, sources.Apps.Error())
+		app, ok := sources.Apps.Get("foo")
+		require.True(t, ok)
+		require.Equal(t, int32(3), *app.Specification.App.Properties.Template.Scale.MaxReplicas)
+	})
+
+	t.Run("reserved variable in file", func(t *testing.T) {
+		files := map[string][]byte{"variables/dev.yaml": []byte("location: foobar")}
+		_, _, err := getVariables(ctx, &files, cfg, secretClient)
+		require.ErrorContains(t, err, "variable \"location\" is reserved")
+	})
+
+	t.Run("nested variable in file", func(t *testing.T) {
+		files := map[string][]byte{"variables/dev.yaml": []byte("foo:\n  bar: baz")}
+		_, _, err := getVariables(ctx, &files, cfg, secretClient)
+		require.ErrorContains(t, err, "variable \"foo\" needs to be a string, number or boolean")
+	})
+}
@@ -0,0 +1,88 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+)
+
+// BlobStore saves every key as a json blob in an Azure Blob Storage container, <partition key>/<key>.json.
+// The container needs to exist.
+type BlobStore struct {
+	client    *azblob.Client
+	container string
+	now       func() time.Time
+}
+
+var _ Store = (*BlobStore)(nil)
+
+func NewBlobStore(account string, container string, cred azcore.TokenCredential) (*BlobStore, error) {
+	return newBlobStore(fmt.Sprintf("https://%s.blob.core.windows.net", account), container, cred, nil)
+}
+
+func newBlobStore(endpoint string, container string, cred azcore.TokenCredential, options *azblob.ClientOptions) (*BlobStore, error) {
+	client, err := azblob.NewClient(endpoint, cred, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BlobStore{
+		client:    client,
+		container: container,
+		now:       time.Now,
+	}, nil
+}
+
+func getBlobName(partitionKey string, key string) string {
+	return fmt.Sprintf("%s/%s.json", partitionKey, key)
+}
+
+func (s *BlobStore) Get(ctx context.Context, partitionKey string, key string) ([]byte, error) {
+	res, err := s.client.DownloadStream(ctx, s.container, getBlobName(partitionKey, key), nil)
+	if err != nil {
+		if bloberror.HasCode(err, bloberror.BlobNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := expiringEntry{}
+	err = json.Unmarshal(b, &entry)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode blob: %w", err)
+	}
+
+	if entry.expired(s.now()) {
+		return nil, nil
+	}
+
+	return entry.Value, nil
+}
+
+func (s *BlobStore) Set(ctx context.Context, partitionKey string, key string, value []byte, ttl *int) error {
+	b, err := json.Marshal(newExpiringEntry(value, ttl, s.now()))
+	if err != nil {
+		return err
+	}
+
+	contentType := "application/json"
+	_, err = s.client.UploadBuffer(ctx, s.container, getBlobName(partitionKey, key), b, &azblob.UploadBufferOptions{
+		HTTPHeaders: &blob.HTTPHeaders{
+			BlobContentType: &contentType,
+		},
+	})
+	return err
+}
@@ -0,0 +1,47 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/xenitab/azcagit/src/azure"
+)
+
+type CosmosDBStore struct {
+	cosmosDBClient *azure.CosmosDBClient
+}
+
+var _ Store = (*CosmosDBStore)(nil)
+
+func NewCosmosDBStore(cosmosDBClient *azure.CosmosDBClient) *CosmosDBStore {
+	return &CosmosDBStore{
+		cosmosDBClient,
+	}
+}
+
+func (s *CosmosDBStore) Get(ctx context.Context, partitionKey string, key string) ([]byte, error) {
+	client, err := azure.NewCosmosDBContainerClient[json.RawMessage](s.cosmosDBClient, partitionKey, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := client.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if value == nil {
+		return nil, nil
+	}
+
+	return *value, nil
+}
+
+func (s *CosmosDBStore) Set(ctx context.Context, partitionKey string, key string, value []byte, ttl *int) error {
+	client, err := azure.NewCosmosDBContainerClient[json.RawMessage](s.cosmosDBClient, partitionKey, ttl)
+	if err != nil {
+		return err
+	}
+
+	return client.Set(ctx, key, value)
+}
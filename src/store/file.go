@@ -0,0 +1,86 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileStore saves every key as a json file in a local directory, <path>/<partition key>/<key>.json
+type FileStore struct {
+	path string
+	now  func() time.Time
+}
+
+var _ Store = (*FileStore)(nil)
+
+func NewFileStore(path string) *FileStore {
+	return &FileStore{
+		path: path,
+		now:  time.Now,
+	}
+}
+
+func (s *FileStore) getFilePath(partitionKey string, key string) string {
+	return filepath.Join(s.path, url.PathEscape(partitionKey), url.PathEscape(key)+".json")
+}
+
+func (s *FileStore) Get(ctx context.Context, partitionKey string, key string) ([]byte, error) {
+	b, err := os.ReadFile(s.getFilePath(partitionKey, key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	entry := expiringEntry{}
+	err = json.Unmarshal(b, &entry)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry.expired(s.now()) {
+		return nil, nil
+	}
+
+	return entry.Value, nil
+}
+
+func (s *FileStore) Set(ctx context.Context, partitionKey string, key string, value []byte, ttl *int) error {
+	b, err := json.Marshal(newExpiringEntry(value, ttl, s.now()))
+	if err != nil {
+		return err
+	}
+
+	filePath := s.getFilePath(partitionKey, key)
+	err = os.MkdirAll(filepath.Dir(filePath), 0o700)
+	if err != nil {
+		return err
+	}
+
+	// write to a temporary file first to not leave a partially written file behind
+	tmpFile, err := os.CreateTemp(filepath.Dir(filePath), ".azcagit*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	_, err = tmpFile.Write(b)
+	if err != nil {
+		tmpFile.Close()
+		return err
+	}
+
+	err = tmpFile.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmpFile.Name(), filePath)
+}
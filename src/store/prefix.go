@@ -0,0 +1,26 @@
+package store
+
+import "context"
+
+// PrefixStore prefixes the partition keys, making it possible to keep separate caches in the same store
+type PrefixStore struct {
+	store  Store
+	prefix string
+}
+
+var _ Store = (*PrefixStore)(nil)
+
+func NewPrefixStore(store Store, prefix string) *PrefixStore {
+	return &PrefixStore{
+		store:  store,
+		prefix: prefix,
+	}
+}
+
+func (s *PrefixStore) Get(ctx context.Context, partitionKey string, key string) ([]byte, error) {
+	return s.store.Get(ctx, s.prefix+partitionKey, key)
+}
+
+func (s *PrefixStore) Set(ctx context.Context, partitionKey string, key string, value []byte, ttl *int) error {
+	return s.store.Set(ctx, s.prefix+partitionKey, key, value, ttl)
+}
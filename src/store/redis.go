@@ -0,0 +1,65 @@
+package store
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStore saves every key as azcagit:<partition key>:<key> in Redis, using the redis expiration for the ttl
+type RedisStore struct {
+	client *redis.Client
+}
+
+var _ Store = (*RedisStore)(nil)
+
+func NewRedisStore(address string, password string, useTLS bool) *RedisStore {
+	options := &redis.Options{
+		Addr:     address,
+		Password: password,
+	}
+	if useTLS {
+		options.TLSConfig = &tls.Config{
+			MinVersion: tls.VersionTLS12,
+		}
+	}
+
+	return newRedisStore(options)
+}
+
+func newRedisStore(options *redis.Options) *RedisStore {
+	return &RedisStore{
+		client: redis.NewClient(options),
+	}
+}
+
+func getRedisKey(partitionKey string, key string) string {
+	return fmt.Sprintf("azcagit:%s:%s", partitionKey, key)
+}
+
+func (s *RedisStore) Get(ctx context.Context, partitionKey string, key string) ([]byte, error) {
+	value, err := s.client.Get(ctx, getRedisKey(partitionKey, key)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+func (s *RedisStore) Set(ctx context.Context, partitionKey string, key string, value []byte, ttl *int) error {
+	// an expiration of 0 keeps the key until it's replaced
+	expiration := time.Duration(0)
+	if ttl != nil && *ttl > 0 {
+		expiration = time.Duration(*ttl) * time.Second
+	}
+
+	return s.client.Set(ctx, getRedisKey(partitionKey, key), value, expiration).Err()
+}
@@ -0,0 +1,55 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisStore(t *testing.T) {
+	ctx := context.Background()
+	srv := miniredis.RunT(t)
+	srv.RequireAuth("ze-password")
+	store := newRedisStore(&redis.Options{Addr: srv.Addr(), Password: "ze-password"})
+
+	value, err := store.Get(ctx, "foo-cache", "foo")
+	require.NoError(t, err)
+	require.Nil(t, value)
+
+	err = store.Set(ctx, "foo-cache", "foo", []byte(`{"foo":"bar"}`), nil)
+	require.NoError(t, err)
+
+	value, err = store.Get(ctx, "foo-cache", "foo")
+	require.NoError(t, err)
+	require.Equal(t, `{"foo":"bar"}`, string(value))
+	require.Equal(t, time.Duration(0), srv.TTL("azcagit:foo-cache:foo"))
+
+	ttl := 60
+	err = store.Set(ctx, "ttl-cache", "foo", []byte(`"bar"`), &ttl)
+	require.NoError(t, err)
+	require.Equal(t, 60*time.Second, srv.TTL("azcagit:ttl-cache:foo"))
+
+	srv.FastForward(60 * time.Second)
+	value, err = store.Get(ctx, "ttl-cache", "foo")
+	require.NoError(t, err)
+	require.Nil(t, value)
+
+	disabled := -1
+	err = store.Set(ctx, "revision-cache", "revision", []byte(`"abc"`), &disabled)
+	require.NoError(t, err)
+	require.Equal(t, time.Duration(0), srv.TTL("azcagit:revision-cache:revision"))
+}
+
+func TestRedisStoreWrongPassword(t *testing.T) {
+	ctx := context.Background()
+	srv := miniredis.RunT(t)
+	srv.RequireAuth("ze-password")
+	store := newRedisStore(&redis.Options{Addr: srv.Addr(), Password: "foobar"})
+
+	_, err := store.Get(ctx, "foo-cache", "foo")
+	require.ErrorContains(t, err, "WRONGPASS")
+}
@@ -0,0 +1,139 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/data/aztables"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestStorageServer fakes the entity and blob operations of the storage account, saving the request
+// bodies by path
+func newTestStorageServer(t *testing.T, putStatusCode int) *httptest.Server {
+	t.Helper()
+
+	mu := sync.Mutex{}
+	objects := make(map[string][]byte)
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer ze-token" || r.Header.Get("x-ms-version") == "" {
+			w.Header().Set("x-ms-error-code", "AuthenticationFailed")
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch r.Method {
+		case http.MethodGet:
+			b, ok := objects[r.URL.EscapedPath()]
+			if !ok {
+				writeTestStorageNotFound(w, r)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write(b)
+		case http.MethodPut:
+			b, err := io.ReadAll(r.Body)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			objects[r.URL.EscapedPath()] = b
+			w.WriteHeader(putStatusCode)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+// writeTestStorageNotFound returns the error like the storage account, in the body for tables and in a header for blobs
+func writeTestStorageNotFound(w http.ResponseWriter, r *http.Request) {
+	if strings.Contains(r.URL.Path, "(") {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"odata.error":{"code":"ResourceNotFound","message":{"lang":"en-US","value":"The specified resource does not exist."}}}`))
+		return
+	}
+
+	w.Header().Set("x-ms-error-code", "BlobNotFound")
+	w.WriteHeader(http.StatusNotFound)
+}
+
+func newTestStorageClientOptions(srv *httptest.Server) azcore.ClientOptions {
+	return azcore.ClientOptions{
+		Transport: srv.Client(),
+		Retry:     policy.RetryOptions{MaxRetries: -1},
+	}
+}
+
+func TestTableStore(t *testing.T) {
+	srv := newTestStorageServer(t, http.StatusNoContent)
+	store, err := newTableStore(srv.URL, "azcagit", &testTokenCredential{}, &aztables.ClientOptions{ClientOptions: newTestStorageClientOptions(srv)})
+	require.NoError(t, err)
+	testStore(t, store, func(now time.Time) {
+		store.now = func() time.Time { return now }
+	})
+}
+
+func TestTableStoreEntity(t *testing.T) {
+	expiresOn := time.Date(2023, 10, 1, 12, 1, 0, 0, time.UTC)
+	b, err := json.Marshal(tableEntity{
+		PartitionKey:      "foo-cache",
+		RowKey:            "foo",
+		Value:             `{"foo":"bar"}`,
+		ExpiresOn:         &expiresOn,
+		ExpiresOnDataType: "Edm.DateTime",
+	})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"PartitionKey":"foo-cache","RowKey":"foo","Value":"{\"foo\":\"bar\"}","ExpiresOn":"2023-10-01T12:01:00Z","ExpiresOn@odata.type":"Edm.DateTime"}`, string(b))
+}
+
+func TestBlobStore(t *testing.T) {
+	srv := newTestStorageServer(t, http.StatusCreated)
+	store, err := newBlobStore(srv.URL, "azcagit", &testTokenCredential{}, &azblob.ClientOptions{ClientOptions: newTestStorageClientOptions(srv)})
+	require.NoError(t, err)
+	testStore(t, store, func(now time.Time) {
+		store.now = func() time.Time { return now }
+	})
+}
+
+func TestStorageUnexpectedStatusCode(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("x-ms-error-code", "AuthorizationPermissionMismatch")
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	blobStore, err := newBlobStore(srv.URL, "azcagit", &testTokenCredential{}, &azblob.ClientOptions{ClientOptions: newTestStorageClientOptions(srv)})
+	require.NoError(t, err)
+
+	_, err = blobStore.Get(context.Background(), "foo-cache", "foo")
+	require.ErrorContains(t, err, "AuthorizationPermissionMismatch")
+
+	err = blobStore.Set(context.Background(), "foo-cache", "foo", []byte(`"bar"`), nil)
+	require.ErrorContains(t, err, "AuthorizationPermissionMismatch")
+
+	tableStore, err := newTableStore(srv.URL, "azcagit", &testTokenCredential{}, &aztables.ClientOptions{ClientOptions: newTestStorageClientOptions(srv)})
+	require.NoError(t, err)
+
+	_, err = tableStore.Get(context.Background(), "foo-cache", "foo")
+	require.ErrorContains(t, err, "AuthorizationPermissionMismatch")
+
+	err = tableStore.Set(context.Background(), "foo-cache", "foo", []byte(`"bar"`), nil)
+	require.ErrorContains(t, err, "AuthorizationPermissionMismatch")
+}
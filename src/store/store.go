@@ -0,0 +1,124 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/xenitab/azcagit/src/azure"
+	"github.com/xenitab/azcagit/src/config"
+)
+
+// Store saves values per partition key and key, Get returns nil if the key doesn't exist or has expired.
+// A ttl of nil or -1 disables expiration, except for CosmosDB where nil uses the container default.
+type Store interface {
+	Get(ctx context.Context, partitionKey string, key string) ([]byte, error)
+	Set(ctx context.Context, partitionKey string, key string, value []byte, ttl *int) error
+}
+
+// NewStore creates the store configured with --cache-store
+func NewStore(cfg config.CacheConfig, cred azcore.TokenCredential) (Store, error) {
+	cacheStore, err := cfg.GetCacheStore()
+	if err != nil {
+		return nil, err
+	}
+
+	switch cacheStore.Type {
+	case config.CacheStoreTypeCosmosDB:
+		cosmosDBClient, err := azure.NewCosmosDBClient(cfg.CosmosDBAccount, cfg.CosmosDBSqlDb, cfg.CosmosDBCacheContainer, cred)
+		if err != nil {
+			return nil, err
+		}
+		return NewCosmosDBStore(cosmosDBClient), nil
+	case config.CacheStoreTypeTable:
+		return NewTableStore(cacheStore.Account, cacheStore.Table, cred)
+	case config.CacheStoreTypeBlob:
+		return NewBlobStore(cacheStore.Account, cacheStore.Container, cred)
+	case config.CacheStoreTypeRedis:
+		return NewRedisStore(cacheStore.Address, cacheStore.Password, cacheStore.TLS), nil
+	case config.CacheStoreTypeFile:
+		return NewFileStore(cacheStore.Path), nil
+	}
+
+	return nil, fmt.Errorf("unknown cache store type %q", cacheStore.Type)
+}
+
+// Client reads and writes values of type T as json in a partition of the store
+type Client[T any] struct {
+	store        Store
+	partitionKey string
+	ttl          *int
+}
+
+func NewClient[T any](store Store, partitionKey string, ttl *int) (*Client[T], error) {
+	return &Client[T]{
+		store:        store,
+		partitionKey: partitionKey,
+		ttl:          ttl,
+	}, nil
+}
+
+func (client *Client[T]) Get(ctx context.Context, key string) (*T, error) {
+	b, err := client.store.Get(ctx, client.partitionKey, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(b) == 0 {
+		return nil, nil
+	}
+
+	value := new(T)
+	err = json.Unmarshal(b, value)
+	if err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+func (client *Client[T]) Set(ctx context.Context, key string, value T) error {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	return client.store.Set(ctx, client.partitionKey, key, b, client.ttl)
+}
+
+// expiringEntry is used by the stores without native expiration
+type expiringEntry struct {
+	ExpiresOn *time.Time      `json:"expires_on,omitempty"`
+	Value     json.RawMessage `json:"value"`
+}
+
+func newExpiringEntry(value []byte, ttl *int, now time.Time) expiringEntry {
+	return expiringEntry{
+		ExpiresOn: getExpiresOn(ttl, now),
+		Value:     value,
+	}
+}
+
+func (e expiringEntry) expired(now time.Time) bool {
+	return isExpired(e.ExpiresOn, now)
+}
+
+func getExpiresOn(ttl *int, now time.Time) *time.Time {
+	if ttl == nil || *ttl < 0 {
+		return nil
+	}
+
+	// truncated to seconds since table storage only keeps 7 digits of fractional seconds
+	expiresOn := now.Add(time.Duration(*ttl) * time.Second).UTC().Truncate(time.Second)
+	return &expiresOn
+}
+
+func isExpired(expiresOn *time.Time, now time.Time) bool {
+	if expiresOn == nil {
+		return false
+	}
+
+	return !now.Before(*expiresOn)
+}
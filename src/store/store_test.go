@@ -0,0 +1,114 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/stretchr/testify/require"
+)
+
+type testValue struct {
+	Foo string `json:"foo"`
+}
+
+// testStore runs the same checks against all the store implementations
+func testStore(t *testing.T, store Store, setNow func(now time.Time)) {
+	t.Helper()
+
+	ctx := context.Background()
+	now := time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+	setNow(now)
+
+	client, err := NewClient[testValue](store, "foo-cache", nil)
+	require.NoError(t, err)
+
+	value, err := client.Get(ctx, "foo")
+	require.NoError(t, err)
+	require.Nil(t, value)
+
+	err = client.Set(ctx, "foo", testValue{Foo: "bar"})
+	require.NoError(t, err)
+
+	value, err = client.Get(ctx, "foo")
+	require.NoError(t, err)
+	require.Equal(t, &testValue{Foo: "bar"}, value)
+
+	err = client.Set(ctx, "foo", testValue{Foo: "baz"})
+	require.NoError(t, err)
+
+	value, err = client.Get(ctx, "foo")
+	require.NoError(t, err)
+	require.Equal(t, &testValue{Foo: "baz"}, value)
+
+	otherClient, err := NewClient[testValue](store, "bar-cache", nil)
+	require.NoError(t, err)
+
+	value, err = otherClient.Get(ctx, "foo")
+	require.NoError(t, err)
+	require.Nil(t, value)
+
+	ttl := 60
+	ttlClient, err := NewClient[testValue](store, "ttl-cache", &ttl)
+	require.NoError(t, err)
+
+	err = ttlClient.Set(ctx, "foo", testValue{Foo: "bar"})
+	require.NoError(t, err)
+
+	setNow(now.Add(59 * time.Second))
+	value, err = ttlClient.Get(ctx, "foo")
+	require.NoError(t, err)
+	require.Equal(t, &testValue{Foo: "bar"}, value)
+
+	setNow(now.Add(60 * time.Second))
+	value, err = ttlClient.Get(ctx, "foo")
+	require.NoError(t, err)
+	require.Nil(t, value)
+}
+
+func TestFileStore(t *testing.T) {
+	store := NewFileStore(t.TempDir())
+	testStore(t, store, func(now time.Time) {
+		store.now = func() time.Time { return now }
+	})
+}
+
+func TestGetExpiresOn(t *testing.T) {
+	now := time.Date(2023, 10, 1, 12, 0, 0, 500, time.UTC)
+	require.Nil(t, getExpiresOn(nil, now))
+
+	disabled := -1
+	require.Nil(t, getExpiresOn(&disabled, now))
+
+	ttl := 3600
+	require.Equal(t, time.Date(2023, 10, 1, 13, 0, 0, 0, time.UTC), *getExpiresOn(&ttl, now))
+}
+
+type testTokenCredential struct{}
+
+func (c *testTokenCredential) GetToken(ctx context.Context, options policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	return azcore.AccessToken{Token: "ze-token", ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
+
+func TestPrefixStore(t *testing.T) {
+	ctx := context.Background()
+	fileStore := NewFileStore(t.TempDir())
+	prefixStore := NewPrefixStore(fileStore, "preview-1-")
+
+	err := prefixStore.Set(ctx, "app-cache", "foo", []byte(`"bar"`), nil)
+	require.NoError(t, err)
+
+	value, err := prefixStore.Get(ctx, "app-cache", "foo")
+	require.NoError(t, err)
+	require.Equal(t, []byte(`"bar"`), value)
+
+	value, err = fileStore.Get(ctx, "app-cache", "foo")
+	require.NoError(t, err)
+	require.Nil(t, value)
+
+	value, err = fileStore.Get(ctx, "preview-1-app-cache", "foo")
+	require.NoError(t, err)
+	require.Equal(t, []byte(`"bar"`), value)
+}
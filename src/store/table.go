@@ -0,0 +1,89 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/data/aztables"
+)
+
+// TableStore saves every key as an entity in an Azure Table Storage table, the table needs to exist
+type TableStore struct {
+	client *aztables.Client
+	now    func() time.Time
+}
+
+var _ Store = (*TableStore)(nil)
+
+type tableEntity struct {
+	PartitionKey      string     `json:"PartitionKey"`
+	RowKey            string     `json:"RowKey"`
+	Value             string     `json:"Value"`
+	ExpiresOn         *time.Time `json:"ExpiresOn,omitempty"`
+	ExpiresOnDataType string     `json:"ExpiresOn@odata.type,omitempty"`
+}
+
+func NewTableStore(account string, table string, cred azcore.TokenCredential) (*TableStore, error) {
+	return newTableStore(fmt.Sprintf("https://%s.table.core.windows.net", account), table, cred, nil)
+}
+
+func newTableStore(endpoint string, table string, cred azcore.TokenCredential, options *aztables.ClientOptions) (*TableStore, error) {
+	client, err := aztables.NewClient(fmt.Sprintf("%s/%s", endpoint, table), cred, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TableStore{
+		client: client,
+		now:    time.Now,
+	}, nil
+}
+
+func (s *TableStore) Get(ctx context.Context, partitionKey string, key string) ([]byte, error) {
+	res, err := s.client.GetEntity(ctx, partitionKey, key, nil)
+	if err != nil {
+		var responseErr *azcore.ResponseError
+		if errors.As(err, &responseErr) && (responseErr.ErrorCode == string(aztables.ResourceNotFound) || responseErr.ErrorCode == string(aztables.EntityNotFound)) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	entity := tableEntity{}
+	err = json.Unmarshal(res.Value, &entity)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode table entity: %w", err)
+	}
+
+	if isExpired(entity.ExpiresOn, s.now()) {
+		return nil, nil
+	}
+
+	return []byte(entity.Value), nil
+}
+
+func (s *TableStore) Set(ctx context.Context, partitionKey string, key string, value []byte, ttl *int) error {
+	entity := tableEntity{
+		PartitionKey: partitionKey,
+		RowKey:       key,
+		Value:        string(value),
+		ExpiresOn:    getExpiresOn(ttl, s.now()),
+	}
+	if entity.ExpiresOn != nil {
+		entity.ExpiresOnDataType = "Edm.DateTime"
+	}
+
+	b, err := json.Marshal(entity)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.UpsertEntity(ctx, b, &aztables.UpsertEntityOptions{
+		UpdateMode: aztables.UpdateModeReplace,
+	})
+	return err
+}